@@ -0,0 +1,223 @@
+// Package access holds the API keys, roles and rate limiter shared by the HTTP and gRPC APIs.
+package access
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// Role is the level of access granted to an API key. Higher roles include every lower role.
+type Role int
+
+const (
+	RolePublic       Role = iota // no authentication required
+	RoleReader                   // read products and prices
+	RoleMerchandiser             // change products and pricing
+	RoleAdmin                    // manage the service, such as reloading configuration
+)
+
+var roleNames = map[Role]string{
+	RolePublic:       "public",
+	RoleReader:       "reader",
+	RoleMerchandiser: "merchandiser",
+	RoleAdmin:        "admin",
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// parseRole converts a role name such as "admin" into a Role.
+func parseRole(name string) (Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for role, roleName := range roleNames {
+		if roleName == name && role != RolePublic {
+			return role, nil
+		}
+	}
+	return RolePublic, fmt.Errorf("unknown role %q", name)
+}
+
+var (
+	// ErrMissingKey is returned by Authenticate when no key was presented.
+	ErrMissingKey = errors.New("no API key supplied")
+	// ErrInvalidKey is returned by Authenticate for a malformed, unknown or wrong key.
+	ErrInvalidKey = errors.New("invalid API key")
+)
+
+// apiKey is a configured key. Only the SHA-256 hash of the secret is kept in memory.
+type apiKey struct {
+	id   string
+	role Role
+	hash [sha256.Size]byte
+}
+
+/*
+KeyStore holds the API keys that may call the service. Keys are presented as "<id>.<secret>",
+over HTTP in either an "Authorization: Bearer" or an "X-API-Key" header. The ID is not secret and is used
+to identify the caller in logs; the secret is only ever compared by hash.
+*/
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]apiKey
+}
+
+// NewKeyStore returns a KeyStore without any keys, so only anonymous access is possible.
+func NewKeyStore() *KeyStore {
+	return &KeyStore{keys: make(map[string]apiKey)}
+}
+
+/*
+LoadKeyStore reads API keys from the file named by API_KEYS_FILE and from the comma separated
+API_KEYS environment variable. Each entry has the form "<id>:<role>:<secret>", where the secret
+may be given as "sha256:<hex>" so that plain secrets never need to be written down.
+*/
+func LoadKeyStore() (*KeyStore, error) {
+	var entries []string
+
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening API keys file: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			// ignore empty lines and comments
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			entries = append(entries, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading API keys file: %w", err)
+		}
+	}
+
+	for _, entry := range strings.Split(os.Getenv("API_KEYS"), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	store := NewKeyStore()
+	for _, entry := range entries {
+		key, err := parseAPIKey(entry)
+		if err != nil {
+			return nil, err
+		}
+		store.keys[key.id] = key
+	}
+	return store, nil
+}
+
+// parseAPIKey parses a single "<id>:<role>:<secret>" entry. Errors never include the secret.
+func parseAPIKey(entry string) (apiKey, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return apiKey{}, errors.New("API key entries must have the form <id>:<role>:<secret>")
+	}
+
+	id := strings.TrimSpace(parts[0])
+	if strings.Contains(id, ".") {
+		return apiKey{}, fmt.Errorf("API key id %q must not contain '.'", id)
+	}
+	role, err := parseRole(parts[1])
+	if err != nil {
+		return apiKey{}, fmt.Errorf("API key %q: %w", id, err)
+	}
+
+	key := apiKey{id: id, role: role}
+	secret := strings.TrimSpace(parts[2])
+	if hexHash, ok := strings.CutPrefix(secret, "sha256:"); ok {
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil || len(hash) != sha256.Size {
+			return apiKey{}, fmt.Errorf("API key %q has an invalid sha256 hash", id)
+		}
+		copy(key.hash[:], hash)
+	} else {
+		key.hash = sha256.Sum256([]byte(secret))
+	}
+	return key, nil
+}
+
+/*
+Authenticate checks a presented "<id>.<secret>" key. It returns the key ID, which is safe to
+log, and the key's role. The ID is returned even on failure so the attempt can be traced.
+*/
+func (ks *KeyStore) Authenticate(presented string) (string, Role, error) {
+	if presented == "" {
+		return "", RolePublic, ErrMissingKey
+	}
+
+	id, secret, ok := strings.Cut(presented, ".")
+	if !ok || id == "" || secret == "" {
+		return "", RolePublic, ErrInvalidKey
+	}
+
+	ks.mu.RLock()
+	key, found := ks.keys[id]
+	ks.mu.RUnlock()
+
+	// hash even when the ID is unknown so both paths take the same time
+	hash := sha256.Sum256([]byte(secret))
+	if !found || subtle.ConstantTimeCompare(hash[:], key.hash[:]) != 1 {
+		return id, RolePublic, ErrInvalidKey
+	}
+	return id, key.role, nil
+}
+
+// Replace swaps the keys in the store for those in other, used when configuration is reloaded.
+func (ks *KeyStore) Replace(other *KeyStore) {
+	other.mu.RLock()
+	keys := other.keys
+	other.mu.RUnlock()
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+}
+
+// Principal describes the caller of an authenticated request.
+type Principal struct {
+	KeyID string // empty for anonymous callers
+	Role  Role
+}
+
+/*
+AnonymousRole reads the role given to requests without an API key from AUTH_ANONYMOUS_ROLE.
+It defaults to reader so the storefront can read prices without a key; set it to "none" to
+require a key on every protected route. Roles above reader would let anyone change prices or
+manage the service, so they are logged as an error and capped at reader.
+*/
+func AnonymousRole() Role {
+	value := strings.TrimSpace(os.Getenv("AUTH_ANONYMOUS_ROLE"))
+	if value == "" {
+		return RoleReader
+	}
+	if strings.EqualFold(value, "none") {
+		return RolePublic
+	}
+
+	role, err := parseRole(value)
+	if err != nil {
+		logs.Logs(2, "invalid AUTH_ANONYMOUS_ROLE, requiring an API key instead: "+err.Error(), "")
+		return RolePublic
+	}
+	if role > RoleReader {
+		logs.Logs(3, "AUTH_ANONYMOUS_ROLE "+value+" would give every caller without a key that role, allowing reader instead", "")
+		return RoleReader
+	}
+	return role
+}
@@ -0,0 +1,104 @@
+package access
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFlags(0)
+	go logs.ProcessLogs()
+	os.Exit(m.Run())
+}
+
+// TestKeyStoreAuthenticate tests plain and pre-hashed secrets and rejected keys
+func TestKeyStoreAuthenticate(t *testing.T) {
+	hash := sha256.Sum256([]byte("s3cret"))
+	t.Setenv("API_KEYS", "web:reader:plain, ops:admin:sha256:"+hex.EncodeToString(hash[:]))
+
+	keys, err := LoadKeyStore()
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		presented    string
+		expectedID   string
+		expectedRole Role
+		expectErr    bool
+	}{
+		{name: "plain secret", presented: "web.plain", expectedID: "web", expectedRole: RoleReader},
+		{name: "hashed secret", presented: "ops.s3cret", expectedID: "ops", expectedRole: RoleAdmin},
+		{name: "wrong secret keeps id for logging", presented: "ops.guess", expectedID: "ops", expectErr: true},
+		{name: "unknown id", presented: "nobody.plain", expectedID: "nobody", expectErr: true},
+		{name: "malformed key", presented: "plain", expectErr: true},
+		{name: "missing key", presented: "", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			id, role, err := keys.Authenticate(tc.presented)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t, got %v", tc.expectErr, err)
+			}
+			if id != tc.expectedID {
+				t.Errorf("expected id %q, got %q", tc.expectedID, id)
+			}
+			if !tc.expectErr && role != tc.expectedRole {
+				t.Errorf("expected role %s, got %s", tc.expectedRole, role)
+			}
+		})
+	}
+}
+
+// TestLoadKeyStoreFromFile tests loading keys from API_KEYS_FILE
+func TestLoadKeyStoreFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	os.WriteFile(path, []byte("# merchandising team\nmerch:merchandiser:secret\n\n"), 0o600)
+	t.Setenv("API_KEYS_FILE", path)
+
+	keys, err := LoadKeyStore()
+	if err != nil {
+		t.Fatalf("failed to load keys: %v", err)
+	}
+	if _, role, err := keys.Authenticate("merch.secret"); err != nil || role != RoleMerchandiser {
+		t.Errorf("expected merchandiser role, got %s, %v", role, err)
+	}
+
+	t.Setenv("API_KEYS", "broken:superuser:secret")
+	if _, err := LoadKeyStore(); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+}
+
+// TestAnonymousRole tests reading AUTH_ANONYMOUS_ROLE, which may not grant more than reader
+func TestAnonymousRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected Role
+	}{
+		{name: "default", expected: RoleReader},
+		{name: "none", value: "none", expected: RolePublic},
+		{name: "reader", value: "reader", expected: RoleReader},
+		{name: "merchandiser capped", value: "merchandiser", expected: RoleReader},
+		{name: "admin capped", value: "admin", expected: RoleReader},
+		{name: "unknown requires a key", value: "superuser", expected: RolePublic},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AUTH_ANONYMOUS_ROLE", tc.value)
+			if role := AnonymousRole(); role != tc.expected {
+				t.Errorf("expected role %s, got %s", tc.expected, role)
+			}
+		})
+	}
+}
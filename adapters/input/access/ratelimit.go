@@ -0,0 +1,121 @@
+package access
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// RateLimit is a token bucket policy: Burst requests may be made at once, refilled at Rate per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of asking a Limiter whether a request may proceed.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // bucket size
+	Remaining  int           // whole requests left in the bucket
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next request would be allowed, when not Allowed
+}
+
+/*
+Limiter decides whether the client identified by key may make another request under limit.
+The in-memory implementation only limits a single replica; an implementation backed by a
+shared store such as Redis can be swapped in to enforce limits across replicas.
+*/
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (Decision, error)
+}
+
+// bucket is the state of one client's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// memoryLimiter is a Limiter that keeps token buckets in process memory.
+type memoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter returns a Limiter that keeps its state in memory.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// sweepInterval is how often idle buckets are removed from a memoryLimiter.
+const sweepInterval = time.Minute
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, limit RateLimit) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	burst := float64(limit.Burst)
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+
+	// refill the bucket for the time since the last request
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	decision := Decision{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = secondsToDuration((burst - b.tokens) / limit.Rate)
+	return decision, nil
+}
+
+// sweep drops buckets that have refilled completely, as they hold no state worth keeping.
+func (l *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+/*
+LoadRateLimit reads the limit for a route from RATE_LIMIT_<ROUTE>_RPS and RATE_LIMIT_<ROUTE>_BURST,
+falling back to RATE_LIMIT_RPS and RATE_LIMIT_BURST and then to the given defaults.
+A rate of zero or less disables limiting for the route.
+*/
+func LoadRateLimit(route string, defaults RateLimit) RateLimit {
+	prefix := "RATE_LIMIT_" + strings.ToUpper(route)
+	rate := env.Float(prefix+"_RPS", env.Float("RATE_LIMIT_RPS", defaults.Rate))
+	burst := env.Int(prefix+"_BURST", env.Int("RATE_LIMIT_BURST", defaults.Burst))
+	if burst < 1 {
+		burst = 1
+	}
+	return RateLimit{Rate: rate, Burst: burst}
+}
@@ -0,0 +1,48 @@
+package access
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryLimiter tests the token bucket refills over time
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	limiter := &memoryLimiter{buckets: map[string]*bucket{}, now: func() time.Time { return now }}
+	limit := RateLimit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if d, _ := limiter.Allow(context.Background(), "client", limit); !d.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	d, _ := limiter.Allow(context.Background(), "client", limit)
+	if d.Allowed {
+		t.Fatal("third request should be limited")
+	}
+	if d.RetryAfter != time.Second || d.Remaining != 0 {
+		t.Errorf("expected retry after 1s with 0 remaining, got %v and %d", d.RetryAfter, d.Remaining)
+	}
+
+	if d, _ := limiter.Allow(context.Background(), "other", limit); !d.Allowed {
+		t.Error("other clients should have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if d, _ := limiter.Allow(context.Background(), "client", limit); !d.Allowed {
+		t.Error("request should be allowed after the bucket refills")
+	}
+}
+
+// TestLoadRateLimit tests per-route limits override the global ones
+func TestLoadRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RPS", "3")
+	t.Setenv("RATE_LIMIT_PRODUCTS_BURST", "7")
+
+	limit := LoadRateLimit("products", RateLimit{Rate: 1, Burst: 2})
+	if limit.Rate != 3 || limit.Burst != 7 {
+		t.Errorf("expected rate 3 and burst 7, got %+v", limit)
+	}
+}
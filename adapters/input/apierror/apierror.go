@@ -0,0 +1,90 @@
+// Package apierror classifies domain and storage errors the same way for every API.
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// Error codes reported for domain and storage errors.
+const (
+	CodeInvalidProvider    = "invalid_provider"
+	CodeProviderPriceUnset = "provider_price_missing"
+	CodeProviderPriceBad   = "provider_price_invalid"
+	CodeInvalidProduct     = "invalid_product"
+	CodeProductNotFound    = "product_not_found"
+	CodeStorageUnavailable = "storage_unavailable"
+	CodeTimeout            = "timeout"
+	CodeInvalidRequest     = "invalid_request"
+	CodeNoPriceHistory     = "price_history_not_found"
+	CodeDiscountRejected   = "discount_code_rejected"
+	CodeInvalidDestination = "invalid_destination"
+	CodeZoneNotServed      = "zone_not_served"
+	CodeInternal           = "internal_error"
+)
+
+/*
+Failure is how an API reports an error to its client. Status is an HTTP status code, which other
+transports map to their own. Message never contains the error's text, so storage paths and
+configuration values are not leaked.
+*/
+type Failure struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]string
+}
+
+/*
+Classify returns the Failure for an error from the domain or storage layers. details is
+returned unchanged for errors that carry no details of their own.
+Errors we do not recognise are reported as an internal error.
+*/
+func Classify(err error, details map[string]string) Failure {
+	var invalidPrice *domain.ErrInvalidProviderPrice
+	var invalidDivisor *domain.ErrInvalidVolumetricDivisor
+	var invalidProduct *domain.ErrInvalidProduct
+	var rejectedCode *domain.ErrDiscountCodeRejected
+	var invalidDestination *domain.ErrInvalidDestination
+	var notServed *domain.ErrZoneNotServed
+	var invalidShipment *domain.ErrInvalidShipment
+
+	switch {
+	case errors.Is(err, domain.ErrUnknownProvider):
+		return Failure{http.StatusBadRequest, CodeInvalidProvider, "Unknown delivery provider", details}
+	case errors.Is(err, domain.ErrProviderPriceMissing):
+		return Failure{http.StatusServiceUnavailable, CodeProviderPriceUnset, "Delivery provider is not configured", details}
+	case errors.As(err, &invalidPrice):
+		return Failure{http.StatusInternalServerError, CodeProviderPriceBad, "Delivery provider is misconfigured", map[string]string{"provider": invalidPrice.Provider}}
+	case errors.As(err, &invalidDivisor):
+		return Failure{http.StatusInternalServerError, CodeProviderPriceBad, "Delivery provider is misconfigured", map[string]string{"provider": invalidDivisor.Provider}}
+	case errors.As(err, &invalidProduct):
+		return Failure{http.StatusInternalServerError, CodeInvalidProduct, "Product data is invalid", map[string]string{"name": invalidProduct.Name, "reason": invalidProduct.Reason}}
+	case errors.Is(err, domain.ErrProductNotFound):
+		return Failure{http.StatusNotFound, CodeProductNotFound, "Product not found", details}
+	case errors.As(err, &rejectedCode):
+		details := map[string]string{"code": rejectedCode.Code, "reason": string(rejectedCode.Reason)}
+		if rejectedCode.Detail != "" {
+			details["detail"] = rejectedCode.Detail
+		}
+		return Failure{http.StatusBadRequest, CodeDiscountRejected, "Discount code cannot be applied", details}
+	case errors.As(err, &invalidDestination):
+		return Failure{http.StatusBadRequest, CodeInvalidDestination, "Destination is invalid", map[string]string{"country": invalidDestination.Country, "postcode": invalidDestination.Postcode, "reason": invalidDestination.Reason}}
+	case errors.As(err, &notServed):
+		return Failure{http.StatusBadRequest, CodeZoneNotServed, "Delivery provider does not deliver to this destination", map[string]string{"provider": notServed.Provider, "zone": string(notServed.Zone), "country": notServed.Country}}
+	case errors.As(err, &invalidShipment):
+		return Failure{http.StatusBadRequest, CodeInvalidRequest, "Shipment is invalid", map[string]string{"reason": invalidShipment.Reason}}
+	case errors.Is(err, domain.ErrNoPriceHistory):
+		return Failure{http.StatusNotFound, CodeNoPriceHistory, "No prices were recorded by as_of", details}
+	case errors.Is(err, domain.ErrRedemptionNotSaved):
+		return Failure{http.StatusServiceUnavailable, CodeStorageUnavailable, "Discount code redemption could not be saved", nil}
+	case errors.Is(err, domain.ErrStorageUnavailable):
+		return Failure{http.StatusServiceUnavailable, CodeStorageUnavailable, "Product storage is unavailable", nil}
+	case errors.Is(err, context.DeadlineExceeded):
+		return Failure{http.StatusServiceUnavailable, CodeTimeout, "Request timed out", nil}
+	}
+	return Failure{http.StatusInternalServerError, CodeInternal, "Internal server error", nil}
+}
@@ -0,0 +1,163 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpb"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+)
+
+var (
+	// authFailuresTotal counts RPCs rejected because of a missing, invalid or under-privileged API key.
+	authFailuresTotal = metrics.Counter("grpc_auth_failures_total")
+
+	// rateLimitedTotal counts RPCs rejected with ResourceExhausted by the rate limiter.
+	rateLimitedTotal = metrics.Counter("grpc_rate_limited_total")
+)
+
+/*
+Access is how RPCs are authenticated and rate limited. It shares the HTTP API's key store and
+limiter, so a key works on both APIs, a reload replaces it on both, and a client's requests
+count against the same buckets whichever API they use.
+*/
+type Access struct {
+	Keys      *access.KeyStore
+	Anonymous access.Role // role of callers without a key, see access.AnonymousRole
+	Limiter   access.Limiter
+}
+
+// methodPolicy is the role a method requires and the rate limit route it counts against.
+type methodPolicy struct {
+	role   access.Role
+	route  string           // named like the HTTP routes, so RATE_LIMIT_<ROUTE>_RPS and _BURST apply to both
+	limits access.RateLimit // defaults when the route's limit is not configured
+}
+
+// methodPolicies lists the methods that need a key and are rate limited. Health checks and reflection are public.
+var methodPolicies = map[string]methodPolicy{
+	pricingpb.PricingService_ListPricedProducts_FullMethodName: {role: access.RoleReader, route: "products", limits: access.RateLimit{Rate: 5, Burst: 20}},
+	pricingpb.PricingService_GetPricedProduct_FullMethodName:   {role: access.RoleReader, route: "product", limits: access.RateLimit{Rate: 20, Burst: 40}},
+	pricingpb.PricingService_QuoteShipment_FullMethodName:      {role: access.RoleReader, route: "shipment", limits: access.RateLimit{Rate: 5, Burst: 20}},
+	pricingpb.PricingService_Checkout_FullMethodName:           {role: access.RoleReader, route: "checkout", limits: access.RateLimit{Rate: 5, Burst: 20}},
+	pricingpb.PricingService_ListProviders_FullMethodName:      {role: access.RoleReader, route: "providers", limits: access.RateLimit{Rate: 20, Burst: 40}},
+}
+
+type principalKey struct{}
+
+// presentedKey returns the API key sent in the "authorization: Bearer" or "x-api-key" metadata, if any.
+func presentedKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+/*
+authInterceptor only lets callers holding a method's role through, as the HTTP API's requireRole
+does: calls without a key get the anonymous role, and a bad key is always rejected. Missing and
+bad keys are Unauthenticated, and keys without the role PermissionDenied. Failures are logged
+with the key ID only, never the secret.
+*/
+func authInterceptor(keys *access.KeyStore, anonymous access.Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		policy, ok := methodPolicies[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		principal := access.Principal{Role: anonymous}
+		if presented := presentedKey(ctx); presented != "" {
+			id, role, err := keys.Authenticate(presented)
+			if err != nil {
+				authFailuresTotal.Add(1)
+				logs.Logs(2, fmt.Sprintf("authentication failed for key id %q on gRPC %s: %s", id, info.FullMethod, err.Error()), "")
+				return nil, status.Error(codes.Unauthenticated, "invalid API key")
+			}
+			principal = access.Principal{KeyID: id, Role: role}
+		}
+
+		if principal.Role < policy.role {
+			authFailuresTotal.Add(1)
+			if principal.KeyID == "" {
+				logs.Logs(2, fmt.Sprintf("anonymous call to gRPC %s requires role %s", info.FullMethod, policy.role), "")
+				return nil, status.Error(codes.Unauthenticated, "an API key with role "+policy.role.String()+" is required")
+			}
+			logs.Logs(2, fmt.Sprintf("key id %q with role %s denied gRPC %s, requires role %s", principal.KeyID, principal.Role, info.FullMethod, policy.role), "")
+			return nil, status.Error(codes.PermissionDenied, "API key does not have role "+policy.role.String())
+		}
+		return handler(context.WithValue(ctx, principalKey{}, principal), req)
+	}
+}
+
+/*
+rateLimitKey identifies the caller for rate limiting as the HTTP API does: the API key ID when
+the call was authenticated, otherwise the peer's IP address.
+*/
+func rateLimitKey(ctx context.Context) string {
+	if principal, ok := ctx.Value(principalKey{}).(access.Principal); ok && principal.KeyID != "" {
+		return "key:" + principal.KeyID
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+/*
+rateLimitInterceptor limits calls to each method per caller with the limit configured for its
+route, read once when the server is built. Rejected calls get ResourceExhausted with a
+retry-after header in seconds. If the limiter itself fails the call is let through.
+*/
+func rateLimitInterceptor(limiter access.Limiter) grpc.UnaryServerInterceptor {
+	limits := make(map[string]access.RateLimit, len(methodPolicies))
+	for method, policy := range methodPolicies {
+		limits[method] = access.LoadRateLimit(policy.route, policy.limits)
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		limit, ok := limits[info.FullMethod]
+		if !ok || limit.Rate <= 0 {
+			return handler(ctx, req)
+		}
+
+		key := rateLimitKey(ctx)
+		route := methodPolicies[info.FullMethod].route
+		decision, err := limiter.Allow(ctx, route+"|"+key, limit)
+		if err != nil {
+			logs.Logs(2, "rate limiter unavailable, allowing call: "+err.Error(), "")
+			return handler(ctx, req)
+		}
+		if !decision.Allowed {
+			rateLimitedTotal.Add(1)
+			retryAfter := strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds())))
+			logs.Logs(2, fmt.Sprintf("rate limit exceeded for %s on gRPC %s", key, info.FullMethod), "")
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+			return nil, status.Error(codes.ResourceExhausted, "too many requests, retry after "+retryAfter+" seconds")
+		}
+		return handler(ctx, req)
+	}
+}
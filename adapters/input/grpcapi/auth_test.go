@@ -0,0 +1,58 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpb"
+)
+
+// TestAuthAndRateLimits tests authenticating calls with API keys and limiting them per key
+func TestAuthAndRateLimits(t *testing.T) {
+	t.Setenv("API_KEYS", "web:reader:r,other:reader:o")
+	t.Setenv("AUTH_ANONYMOUS_ROLE", "none")
+	t.Setenv("RATE_LIMIT_PRODUCTS_RPS", "0.001")
+	t.Setenv("RATE_LIMIT_PRODUCTS_BURST", "1")
+	conn := newTestClient(t)
+	client := pricingpb.NewPricingServiceClient(conn)
+
+	withKey := func(header, value string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), header, value)
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		expectedCode codes.Code
+	}{
+		{name: "no key", ctx: context.Background(), expectedCode: codes.Unauthenticated},
+		{name: "bad key", ctx: withKey("authorization", "Bearer web.wrong"), expectedCode: codes.Unauthenticated},
+		{name: "bearer key", ctx: withKey("authorization", "Bearer web.r"), expectedCode: codes.OK},
+		{name: "same key over the limit", ctx: withKey("x-api-key", "web.r"), expectedCode: codes.ResourceExhausted},
+		{name: "other key has its own bucket", ctx: withKey("x-api-key", "other.o"), expectedCode: codes.OK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var header metadata.MD
+			_, err := client.ListPricedProducts(tc.ctx, &pricingpb.ListPricedProductsRequest{}, grpc.Header(&header))
+			if status.Code(err) != tc.expectedCode {
+				t.Fatalf("expected code %s, got %v", tc.expectedCode, err)
+			}
+			if tc.expectedCode == codes.ResourceExhausted && len(header.Get("retry-after")) == 0 {
+				t.Error("expected a retry-after header")
+			}
+		})
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected health checks without a key, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,50 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/apierror"
+)
+
+// errorDomain is the domain of the ErrorInfo detail attached to every failed RPC.
+const errorDomain = "pricing.base_techtest"
+
+/*
+statusFromError maps a domain error to a gRPC status with apierror.Classify, the classifier the
+HTTP API uses, so both APIs report failures with the same code and client-safe message.
+The error code and details are attached as an ErrorInfo so clients can tell failures apart.
+*/
+func statusFromError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, "Request cancelled")
+	}
+
+	failure := apierror.Classify(err, nil)
+	st := status.New(grpcCode(failure), failure.Message)
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: failure.Code, Domain: errorDomain, Metadata: failure.Details})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// grpcCode returns the gRPC code matching a failure's HTTP status.
+func grpcCode(failure apierror.Failure) codes.Code {
+	switch {
+	case failure.Code == apierror.CodeTimeout:
+		return codes.DeadlineExceeded
+	case failure.Status == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case failure.Status == http.StatusNotFound:
+		return codes.NotFound
+	case failure.Status == http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
@@ -0,0 +1,4 @@
+// Package pricingpb holds the protobuf messages and gRPC service for the pricing API, generated from pricing.proto.
+package pricingpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pricing.proto
@@ -0,0 +1,1200 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: pricing.proto
+
+package pricingpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// PricedProduct matches the JSON returned by GET /v1/products, with money as two decimal strings.
+type PricedProduct struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ProductPrice    string                 `protobuf:"bytes,2,opt,name=product_price,json=productPrice,proto3" json:"product_price,omitempty"`
+	DeliveryPrice   string                 `protobuf:"bytes,3,opt,name=delivery_price,json=deliveryPrice,proto3" json:"delivery_price,omitempty"`
+	TotalPrice      string                 `protobuf:"bytes,4,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	DeliveryService string                 `protobuf:"bytes,5,opt,name=delivery_service,json=deliveryService,proto3" json:"delivery_service,omitempty"`
+	// original_product_price and original_delivery_price are the prices before promotions, set only if any applied.
+	OriginalProductPrice  string `protobuf:"bytes,6,opt,name=original_product_price,json=originalProductPrice,proto3" json:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string `protobuf:"bytes,7,opt,name=original_delivery_price,json=originalDeliveryPrice,proto3" json:"original_delivery_price,omitempty"`
+	// promotions lists the promotions and discount code that took money off, in the order applied.
+	Promotions []*AppliedPromotion `protobuf:"bytes,8,rep,name=promotions,proto3" json:"promotions,omitempty"`
+	// chargeable_weight is the weight delivery was charged on, the greater of the actual and volumetric weights.
+	ChargeableWeight float64 `protobuf:"fixed64,9,opt,name=chargeable_weight,json=chargeableWeight,proto3" json:"chargeable_weight,omitempty"`
+	// weight_basis is "actual" or "volumetric".
+	WeightBasis string `protobuf:"bytes,10,opt,name=weight_basis,json=weightBasis,proto3" json:"weight_basis,omitempty"`
+	// zone is the delivery zone priced for, set only when a destination was given.
+	Zone string `protobuf:"bytes,11,opt,name=zone,proto3" json:"zone,omitempty"`
+	// delivery_options lists the provider's service levels the product can be delivered with, if it has any.
+	DeliveryOptions []*DeliveryOption `protobuf:"bytes,12,rep,name=delivery_options,json=deliveryOptions,proto3" json:"delivery_options,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PricedProduct) Reset() {
+	*x = PricedProduct{}
+	mi := &file_pricing_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PricedProduct) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PricedProduct) ProtoMessage() {}
+
+func (x *PricedProduct) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PricedProduct.ProtoReflect.Descriptor instead.
+func (*PricedProduct) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PricedProduct) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetProductPrice() string {
+	if x != nil {
+		return x.ProductPrice
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetDeliveryPrice() string {
+	if x != nil {
+		return x.DeliveryPrice
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetTotalPrice() string {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetDeliveryService() string {
+	if x != nil {
+		return x.DeliveryService
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetOriginalProductPrice() string {
+	if x != nil {
+		return x.OriginalProductPrice
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetOriginalDeliveryPrice() string {
+	if x != nil {
+		return x.OriginalDeliveryPrice
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetPromotions() []*AppliedPromotion {
+	if x != nil {
+		return x.Promotions
+	}
+	return nil
+}
+
+func (x *PricedProduct) GetChargeableWeight() float64 {
+	if x != nil {
+		return x.ChargeableWeight
+	}
+	return 0
+}
+
+func (x *PricedProduct) GetWeightBasis() string {
+	if x != nil {
+		return x.WeightBasis
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *PricedProduct) GetDeliveryOptions() []*DeliveryOption {
+	if x != nil {
+		return x.DeliveryOptions
+	}
+	return nil
+}
+
+// DeliveryOption is a service level, its prices and when it is expected to arrive.
+type DeliveryOption struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// service is "standard", "next_day" or "timed".
+	Service       string `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	DeliveryPrice string `protobuf:"bytes,2,opt,name=delivery_price,json=deliveryPrice,proto3" json:"delivery_price,omitempty"`
+	TotalPrice    string `protobuf:"bytes,3,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	// estimated_delivery is a date, such as 2026-10-20.
+	EstimatedDelivery string `protobuf:"bytes,4,opt,name=estimated_delivery,json=estimatedDelivery,proto3" json:"estimated_delivery,omitempty"`
+	// deliver_by is the time of day timed services arrive by, such as 10:30.
+	DeliverBy     string `protobuf:"bytes,5,opt,name=deliver_by,json=deliverBy,proto3" json:"deliver_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryOption) Reset() {
+	*x = DeliveryOption{}
+	mi := &file_pricing_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryOption) ProtoMessage() {}
+
+func (x *DeliveryOption) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryOption.ProtoReflect.Descriptor instead.
+func (*DeliveryOption) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DeliveryOption) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *DeliveryOption) GetDeliveryPrice() string {
+	if x != nil {
+		return x.DeliveryPrice
+	}
+	return ""
+}
+
+func (x *DeliveryOption) GetTotalPrice() string {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return ""
+}
+
+func (x *DeliveryOption) GetEstimatedDelivery() string {
+	if x != nil {
+		return x.EstimatedDelivery
+	}
+	return ""
+}
+
+func (x *DeliveryOption) GetDeliverBy() string {
+	if x != nil {
+		return x.DeliverBy
+	}
+	return ""
+}
+
+// AppliedPromotion is a promotion or discount code that took money off a price.
+type AppliedPromotion struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// target is "product" or "delivery".
+	Target string `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	// discount is the amount taken off, to two decimal places.
+	Discount string `protobuf:"bytes,4,opt,name=discount,proto3" json:"discount,omitempty"`
+	// discount_code is true for a discount code entered by the customer, whose id is the code.
+	DiscountCode  bool `protobuf:"varint,5,opt,name=discount_code,json=discountCode,proto3" json:"discount_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppliedPromotion) Reset() {
+	*x = AppliedPromotion{}
+	mi := &file_pricing_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppliedPromotion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppliedPromotion) ProtoMessage() {}
+
+func (x *AppliedPromotion) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppliedPromotion.ProtoReflect.Descriptor instead.
+func (*AppliedPromotion) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AppliedPromotion) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetDiscount() string {
+	if x != nil {
+		return x.Discount
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetDiscountCode() bool {
+	if x != nil {
+		return x.DiscountCode
+	}
+	return false
+}
+
+type ListPricedProductsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// provider to price with, case-insensitive. Defaults to DELIVERY_PROVIDER.
+	Provider string `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	// code is a discount code to apply, case-insensitive. It is not redeemed.
+	Code string `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	// destination prices delivery at the provider's rate for the zone it is in.
+	Destination   *Destination `protobuf:"bytes,3,opt,name=destination,proto3" json:"destination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPricedProductsRequest) Reset() {
+	*x = ListPricedProductsRequest{}
+	mi := &file_pricing_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPricedProductsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPricedProductsRequest) ProtoMessage() {}
+
+func (x *ListPricedProductsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPricedProductsRequest.ProtoReflect.Descriptor instead.
+func (*ListPricedProductsRequest) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListPricedProductsRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ListPricedProductsRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ListPricedProductsRequest) GetDestination() *Destination {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+type ListPricedProductsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Products      []*PricedProduct       `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPricedProductsResponse) Reset() {
+	*x = ListPricedProductsResponse{}
+	mi := &file_pricing_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPricedProductsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPricedProductsResponse) ProtoMessage() {}
+
+func (x *ListPricedProductsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPricedProductsResponse.ProtoReflect.Descriptor instead.
+func (*ListPricedProductsResponse) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListPricedProductsResponse) GetProducts() []*PricedProduct {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+type GetPricedProductRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// provider to price with, case-insensitive. Defaults to DELIVERY_PROVIDER.
+	Provider string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	// code is a discount code to apply, case-insensitive. It is not redeemed.
+	Code string `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	// destination prices delivery at the provider's rate for the zone it is in.
+	Destination   *Destination `protobuf:"bytes,4,opt,name=destination,proto3" json:"destination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPricedProductRequest) Reset() {
+	*x = GetPricedProductRequest{}
+	mi := &file_pricing_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPricedProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPricedProductRequest) ProtoMessage() {}
+
+func (x *GetPricedProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPricedProductRequest.ProtoReflect.Descriptor instead.
+func (*GetPricedProductRequest) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetPricedProductRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetPricedProductRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *GetPricedProductRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *GetPricedProductRequest) GetDestination() *Destination {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+type ShipmentItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name of a catalogue product, case-insensitive.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// quantity must be at least 1.
+	Quantity      int32 `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShipmentItem) Reset() {
+	*x = ShipmentItem{}
+	mi := &file_pricing_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShipmentItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShipmentItem) ProtoMessage() {}
+
+func (x *ShipmentItem) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShipmentItem.ProtoReflect.Descriptor instead.
+func (*ShipmentItem) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ShipmentItem) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ShipmentItem) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type QuoteShipmentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items []*ShipmentItem        `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// provider to price with, case-insensitive. Defaults to DELIVERY_PROVIDER.
+	Provider string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	// code is a discount code to apply to the whole shipment, case-insensitive. Only Checkout redeems it.
+	Code string `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	// destination prices delivery at the provider's rate for the zone it is in.
+	Destination   *Destination `protobuf:"bytes,4,opt,name=destination,proto3" json:"destination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuoteShipmentRequest) Reset() {
+	*x = QuoteShipmentRequest{}
+	mi := &file_pricing_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteShipmentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteShipmentRequest) ProtoMessage() {}
+
+func (x *QuoteShipmentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteShipmentRequest.ProtoReflect.Descriptor instead.
+func (*QuoteShipmentRequest) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QuoteShipmentRequest) GetItems() []*ShipmentItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *QuoteShipmentRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *QuoteShipmentRequest) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *QuoteShipmentRequest) GetDestination() *Destination {
+	if x != nil {
+		return x.Destination
+	}
+	return nil
+}
+
+// Destination is where products are delivered to.
+type Destination struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// country is an ISO 3166-1 alpha-2 code, such as GB or FR.
+	Country string `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	// postcode is needed in the United Kingdom, where it decides the zone.
+	Postcode      string `protobuf:"bytes,2,opt,name=postcode,proto3" json:"postcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Destination) Reset() {
+	*x = Destination{}
+	mi := &file_pricing_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Destination) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Destination) ProtoMessage() {}
+
+func (x *Destination) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Destination.ProtoReflect.Descriptor instead.
+func (*Destination) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Destination) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Destination) GetPostcode() string {
+	if x != nil {
+		return x.Postcode
+	}
+	return ""
+}
+
+type QuoteLine struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Name     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Quantity int32                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// product_price is the price of all units of the product.
+	ProductPrice  string `protobuf:"bytes,3,opt,name=product_price,json=productPrice,proto3" json:"product_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuoteLine) Reset() {
+	*x = QuoteLine{}
+	mi := &file_pricing_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteLine) ProtoMessage() {}
+
+func (x *QuoteLine) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteLine.ProtoReflect.Descriptor instead.
+func (*QuoteLine) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *QuoteLine) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *QuoteLine) GetQuantity() int32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *QuoteLine) GetProductPrice() string {
+	if x != nil {
+		return x.ProductPrice
+	}
+	return ""
+}
+
+type QuoteShipmentResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Lines        []*QuoteLine           `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	ProductPrice string                 `protobuf:"bytes,2,opt,name=product_price,json=productPrice,proto3" json:"product_price,omitempty"`
+	// delivery_price is charged on the combined weight of every item.
+	DeliveryPrice   string `protobuf:"bytes,3,opt,name=delivery_price,json=deliveryPrice,proto3" json:"delivery_price,omitempty"`
+	TotalPrice      string `protobuf:"bytes,4,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	DeliveryService string `protobuf:"bytes,5,opt,name=delivery_service,json=deliveryService,proto3" json:"delivery_service,omitempty"`
+	// original_product_price and original_delivery_price are the shipment's prices before promotions, set only if any applied.
+	OriginalProductPrice  string `protobuf:"bytes,6,opt,name=original_product_price,json=originalProductPrice,proto3" json:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string `protobuf:"bytes,7,opt,name=original_delivery_price,json=originalDeliveryPrice,proto3" json:"original_delivery_price,omitempty"`
+	// promotions lists the promotions and discount code that took money off the shipment, in the order applied.
+	Promotions []*AppliedPromotion `protobuf:"bytes,8,rep,name=promotions,proto3" json:"promotions,omitempty"`
+	// chargeable_weight is the weight delivery was charged on, the greater of the parcel's combined actual and volumetric weights.
+	ChargeableWeight float64 `protobuf:"fixed64,9,opt,name=chargeable_weight,json=chargeableWeight,proto3" json:"chargeable_weight,omitempty"`
+	// weight_basis is "actual" or "volumetric".
+	WeightBasis string `protobuf:"bytes,10,opt,name=weight_basis,json=weightBasis,proto3" json:"weight_basis,omitempty"`
+	// zone is the delivery zone priced for, set only when a destination was given.
+	Zone string `protobuf:"bytes,11,opt,name=zone,proto3" json:"zone,omitempty"`
+	// delivery_options lists the provider's service levels the parcel can be delivered with, if it has any.
+	DeliveryOptions []*DeliveryOption `protobuf:"bytes,12,rep,name=delivery_options,json=deliveryOptions,proto3" json:"delivery_options,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *QuoteShipmentResponse) Reset() {
+	*x = QuoteShipmentResponse{}
+	mi := &file_pricing_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteShipmentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteShipmentResponse) ProtoMessage() {}
+
+func (x *QuoteShipmentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteShipmentResponse.ProtoReflect.Descriptor instead.
+func (*QuoteShipmentResponse) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QuoteShipmentResponse) GetLines() []*QuoteLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *QuoteShipmentResponse) GetProductPrice() string {
+	if x != nil {
+		return x.ProductPrice
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetDeliveryPrice() string {
+	if x != nil {
+		return x.DeliveryPrice
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetTotalPrice() string {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetDeliveryService() string {
+	if x != nil {
+		return x.DeliveryService
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetOriginalProductPrice() string {
+	if x != nil {
+		return x.OriginalProductPrice
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetOriginalDeliveryPrice() string {
+	if x != nil {
+		return x.OriginalDeliveryPrice
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetPromotions() []*AppliedPromotion {
+	if x != nil {
+		return x.Promotions
+	}
+	return nil
+}
+
+func (x *QuoteShipmentResponse) GetChargeableWeight() float64 {
+	if x != nil {
+		return x.ChargeableWeight
+	}
+	return 0
+}
+
+func (x *QuoteShipmentResponse) GetWeightBasis() string {
+	if x != nil {
+		return x.WeightBasis
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *QuoteShipmentResponse) GetDeliveryOptions() []*DeliveryOption {
+	if x != nil {
+		return x.DeliveryOptions
+	}
+	return nil
+}
+
+type ListProvidersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersRequest) Reset() {
+	*x = ListProvidersRequest{}
+	mi := &file_pricing_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersRequest) ProtoMessage() {}
+
+func (x *ListProvidersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersRequest.ProtoReflect.Descriptor instead.
+func (*ListProvidersRequest) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{11}
+}
+
+type Provider struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// configured is true if the provider's delivery price is set and valid.
+	Configured bool `protobuf:"varint,2,opt,name=configured,proto3" json:"configured,omitempty"`
+	// is_default is true for the provider named by DELIVERY_PROVIDER.
+	IsDefault     bool `protobuf:"varint,3,opt,name=is_default,json=isDefault,proto3" json:"is_default,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Provider) Reset() {
+	*x = Provider{}
+	mi := &file_pricing_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Provider) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Provider) ProtoMessage() {}
+
+func (x *Provider) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Provider.ProtoReflect.Descriptor instead.
+func (*Provider) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Provider) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Provider) GetConfigured() bool {
+	if x != nil {
+		return x.Configured
+	}
+	return false
+}
+
+func (x *Provider) GetIsDefault() bool {
+	if x != nil {
+		return x.IsDefault
+	}
+	return false
+}
+
+type ListProvidersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Providers     []*Provider            `protobuf:"bytes,1,rep,name=providers,proto3" json:"providers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersResponse) Reset() {
+	*x = ListProvidersResponse{}
+	mi := &file_pricing_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersResponse) ProtoMessage() {}
+
+func (x *ListProvidersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pricing_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersResponse.ProtoReflect.Descriptor instead.
+func (*ListProvidersResponse) Descriptor() ([]byte, []int) {
+	return file_pricing_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListProvidersResponse) GetProviders() []*Provider {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+var File_pricing_proto protoreflect.FileDescriptor
+
+const file_pricing_proto_rawDesc = "" +
+	"\n" +
+	"\rpricing.proto\x12\n" +
+	"pricing.v1\"\x92\x04\n" +
+	"\rPricedProduct\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12#\n" +
+	"\rproduct_price\x18\x02 \x01(\tR\fproductPrice\x12%\n" +
+	"\x0edelivery_price\x18\x03 \x01(\tR\rdeliveryPrice\x12\x1f\n" +
+	"\vtotal_price\x18\x04 \x01(\tR\n" +
+	"totalPrice\x12)\n" +
+	"\x10delivery_service\x18\x05 \x01(\tR\x0fdeliveryService\x124\n" +
+	"\x16original_product_price\x18\x06 \x01(\tR\x14originalProductPrice\x126\n" +
+	"\x17original_delivery_price\x18\a \x01(\tR\x15originalDeliveryPrice\x12<\n" +
+	"\n" +
+	"promotions\x18\b \x03(\v2\x1c.pricing.v1.AppliedPromotionR\n" +
+	"promotions\x12+\n" +
+	"\x11chargeable_weight\x18\t \x01(\x01R\x10chargeableWeight\x12!\n" +
+	"\fweight_basis\x18\n" +
+	" \x01(\tR\vweightBasis\x12\x12\n" +
+	"\x04zone\x18\v \x01(\tR\x04zone\x12E\n" +
+	"\x10delivery_options\x18\f \x03(\v2\x1a.pricing.v1.DeliveryOptionR\x0fdeliveryOptions\"\xc0\x01\n" +
+	"\x0eDeliveryOption\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12%\n" +
+	"\x0edelivery_price\x18\x02 \x01(\tR\rdeliveryPrice\x12\x1f\n" +
+	"\vtotal_price\x18\x03 \x01(\tR\n" +
+	"totalPrice\x12-\n" +
+	"\x12estimated_delivery\x18\x04 \x01(\tR\x11estimatedDelivery\x12\x1d\n" +
+	"\n" +
+	"deliver_by\x18\x05 \x01(\tR\tdeliverBy\"\x8f\x01\n" +
+	"\x10AppliedPromotion\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\tR\x06target\x12\x1a\n" +
+	"\bdiscount\x18\x04 \x01(\tR\bdiscount\x12#\n" +
+	"\rdiscount_code\x18\x05 \x01(\bR\fdiscountCode\"\x86\x01\n" +
+	"\x19ListPricedProductsRequest\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x129\n" +
+	"\vdestination\x18\x03 \x01(\v2\x17.pricing.v1.DestinationR\vdestination\"S\n" +
+	"\x1aListPricedProductsResponse\x125\n" +
+	"\bproducts\x18\x01 \x03(\v2\x19.pricing.v1.PricedProductR\bproducts\"\x98\x01\n" +
+	"\x17GetPricedProductRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x129\n" +
+	"\vdestination\x18\x04 \x01(\v2\x17.pricing.v1.DestinationR\vdestination\">\n" +
+	"\fShipmentItem\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\"\xb1\x01\n" +
+	"\x14QuoteShipmentRequest\x12.\n" +
+	"\x05items\x18\x01 \x03(\v2\x18.pricing.v1.ShipmentItemR\x05items\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x129\n" +
+	"\vdestination\x18\x04 \x01(\v2\x17.pricing.v1.DestinationR\vdestination\"C\n" +
+	"\vDestination\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\x12\x1a\n" +
+	"\bpostcode\x18\x02 \x01(\tR\bpostcode\"`\n" +
+	"\tQuoteLine\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x05R\bquantity\x12#\n" +
+	"\rproduct_price\x18\x03 \x01(\tR\fproductPrice\"\xb3\x04\n" +
+	"\x15QuoteShipmentResponse\x12+\n" +
+	"\x05lines\x18\x01 \x03(\v2\x15.pricing.v1.QuoteLineR\x05lines\x12#\n" +
+	"\rproduct_price\x18\x02 \x01(\tR\fproductPrice\x12%\n" +
+	"\x0edelivery_price\x18\x03 \x01(\tR\rdeliveryPrice\x12\x1f\n" +
+	"\vtotal_price\x18\x04 \x01(\tR\n" +
+	"totalPrice\x12)\n" +
+	"\x10delivery_service\x18\x05 \x01(\tR\x0fdeliveryService\x124\n" +
+	"\x16original_product_price\x18\x06 \x01(\tR\x14originalProductPrice\x126\n" +
+	"\x17original_delivery_price\x18\a \x01(\tR\x15originalDeliveryPrice\x12<\n" +
+	"\n" +
+	"promotions\x18\b \x03(\v2\x1c.pricing.v1.AppliedPromotionR\n" +
+	"promotions\x12+\n" +
+	"\x11chargeable_weight\x18\t \x01(\x01R\x10chargeableWeight\x12!\n" +
+	"\fweight_basis\x18\n" +
+	" \x01(\tR\vweightBasis\x12\x12\n" +
+	"\x04zone\x18\v \x01(\tR\x04zone\x12E\n" +
+	"\x10delivery_options\x18\f \x03(\v2\x1a.pricing.v1.DeliveryOptionR\x0fdeliveryOptions\"\x16\n" +
+	"\x14ListProvidersRequest\"]\n" +
+	"\bProvider\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\n" +
+	"configured\x18\x02 \x01(\bR\n" +
+	"configured\x12\x1d\n" +
+	"\n" +
+	"is_default\x18\x03 \x01(\bR\tisDefault\"K\n" +
+	"\x15ListProvidersResponse\x122\n" +
+	"\tproviders\x18\x01 \x03(\v2\x14.pricing.v1.ProviderR\tproviders2\xc6\x03\n" +
+	"\x0ePricingService\x12c\n" +
+	"\x12ListPricedProducts\x12%.pricing.v1.ListPricedProductsRequest\x1a&.pricing.v1.ListPricedProductsResponse\x12R\n" +
+	"\x10GetPricedProduct\x12#.pricing.v1.GetPricedProductRequest\x1a\x19.pricing.v1.PricedProduct\x12T\n" +
+	"\rQuoteShipment\x12 .pricing.v1.QuoteShipmentRequest\x1a!.pricing.v1.QuoteShipmentResponse\x12O\n" +
+	"\bCheckout\x12 .pricing.v1.QuoteShipmentRequest\x1a!.pricing.v1.QuoteShipmentResponse\x12T\n" +
+	"\rListProviders\x12 .pricing.v1.ListProvidersRequest\x1a!.pricing.v1.ListProvidersResponseBGZEgithub.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpbb\x06proto3"
+
+var (
+	file_pricing_proto_rawDescOnce sync.Once
+	file_pricing_proto_rawDescData []byte
+)
+
+func file_pricing_proto_rawDescGZIP() []byte {
+	file_pricing_proto_rawDescOnce.Do(func() {
+		file_pricing_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pricing_proto_rawDesc), len(file_pricing_proto_rawDesc)))
+	})
+	return file_pricing_proto_rawDescData
+}
+
+var file_pricing_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_pricing_proto_goTypes = []any{
+	(*PricedProduct)(nil),              // 0: pricing.v1.PricedProduct
+	(*DeliveryOption)(nil),             // 1: pricing.v1.DeliveryOption
+	(*AppliedPromotion)(nil),           // 2: pricing.v1.AppliedPromotion
+	(*ListPricedProductsRequest)(nil),  // 3: pricing.v1.ListPricedProductsRequest
+	(*ListPricedProductsResponse)(nil), // 4: pricing.v1.ListPricedProductsResponse
+	(*GetPricedProductRequest)(nil),    // 5: pricing.v1.GetPricedProductRequest
+	(*ShipmentItem)(nil),               // 6: pricing.v1.ShipmentItem
+	(*QuoteShipmentRequest)(nil),       // 7: pricing.v1.QuoteShipmentRequest
+	(*Destination)(nil),                // 8: pricing.v1.Destination
+	(*QuoteLine)(nil),                  // 9: pricing.v1.QuoteLine
+	(*QuoteShipmentResponse)(nil),      // 10: pricing.v1.QuoteShipmentResponse
+	(*ListProvidersRequest)(nil),       // 11: pricing.v1.ListProvidersRequest
+	(*Provider)(nil),                   // 12: pricing.v1.Provider
+	(*ListProvidersResponse)(nil),      // 13: pricing.v1.ListProvidersResponse
+}
+var file_pricing_proto_depIdxs = []int32{
+	2,  // 0: pricing.v1.PricedProduct.promotions:type_name -> pricing.v1.AppliedPromotion
+	1,  // 1: pricing.v1.PricedProduct.delivery_options:type_name -> pricing.v1.DeliveryOption
+	8,  // 2: pricing.v1.ListPricedProductsRequest.destination:type_name -> pricing.v1.Destination
+	0,  // 3: pricing.v1.ListPricedProductsResponse.products:type_name -> pricing.v1.PricedProduct
+	8,  // 4: pricing.v1.GetPricedProductRequest.destination:type_name -> pricing.v1.Destination
+	6,  // 5: pricing.v1.QuoteShipmentRequest.items:type_name -> pricing.v1.ShipmentItem
+	8,  // 6: pricing.v1.QuoteShipmentRequest.destination:type_name -> pricing.v1.Destination
+	9,  // 7: pricing.v1.QuoteShipmentResponse.lines:type_name -> pricing.v1.QuoteLine
+	2,  // 8: pricing.v1.QuoteShipmentResponse.promotions:type_name -> pricing.v1.AppliedPromotion
+	1,  // 9: pricing.v1.QuoteShipmentResponse.delivery_options:type_name -> pricing.v1.DeliveryOption
+	12, // 10: pricing.v1.ListProvidersResponse.providers:type_name -> pricing.v1.Provider
+	3,  // 11: pricing.v1.PricingService.ListPricedProducts:input_type -> pricing.v1.ListPricedProductsRequest
+	5,  // 12: pricing.v1.PricingService.GetPricedProduct:input_type -> pricing.v1.GetPricedProductRequest
+	7,  // 13: pricing.v1.PricingService.QuoteShipment:input_type -> pricing.v1.QuoteShipmentRequest
+	7,  // 14: pricing.v1.PricingService.Checkout:input_type -> pricing.v1.QuoteShipmentRequest
+	11, // 15: pricing.v1.PricingService.ListProviders:input_type -> pricing.v1.ListProvidersRequest
+	4,  // 16: pricing.v1.PricingService.ListPricedProducts:output_type -> pricing.v1.ListPricedProductsResponse
+	0,  // 17: pricing.v1.PricingService.GetPricedProduct:output_type -> pricing.v1.PricedProduct
+	10, // 18: pricing.v1.PricingService.QuoteShipment:output_type -> pricing.v1.QuoteShipmentResponse
+	10, // 19: pricing.v1.PricingService.Checkout:output_type -> pricing.v1.QuoteShipmentResponse
+	13, // 20: pricing.v1.PricingService.ListProviders:output_type -> pricing.v1.ListProvidersResponse
+	16, // [16:21] is the sub-list for method output_type
+	11, // [11:16] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_pricing_proto_init() }
+func file_pricing_proto_init() {
+	if File_pricing_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pricing_proto_rawDesc), len(file_pricing_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pricing_proto_goTypes,
+		DependencyIndexes: file_pricing_proto_depIdxs,
+		MessageInfos:      file_pricing_proto_msgTypes,
+	}.Build()
+	File_pricing_proto = out.File
+	file_pricing_proto_goTypes = nil
+	file_pricing_proto_depIdxs = nil
+}
@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pricing.proto
+
+package pricingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PricingService_ListPricedProducts_FullMethodName = "/pricing.v1.PricingService/ListPricedProducts"
+	PricingService_GetPricedProduct_FullMethodName   = "/pricing.v1.PricingService/GetPricedProduct"
+	PricingService_QuoteShipment_FullMethodName      = "/pricing.v1.PricingService/QuoteShipment"
+	PricingService_Checkout_FullMethodName           = "/pricing.v1.PricingService/Checkout"
+	PricingService_ListProviders_FullMethodName      = "/pricing.v1.PricingService/ListProviders"
+)
+
+// PricingServiceClient is the client API for PricingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PricingService prices the product catalogue with a delivery provider, like the HTTP /v1 routes.
+type PricingServiceClient interface {
+	// ListPricedProducts prices every product in the catalogue.
+	ListPricedProducts(ctx context.Context, in *ListPricedProductsRequest, opts ...grpc.CallOption) (*ListPricedProductsResponse, error)
+	// GetPricedProduct prices a single product looked up by name, case-insensitively.
+	GetPricedProduct(ctx context.Context, in *GetPricedProductRequest, opts ...grpc.CallOption) (*PricedProduct, error)
+	// QuoteShipment prices a shipment of catalogue products sent together as one parcel.
+	QuoteShipment(ctx context.Context, in *QuoteShipmentRequest, opts ...grpc.CallOption) (*QuoteShipmentResponse, error)
+	// Checkout prices a shipment like QuoteShipment and redeems its discount code.
+	Checkout(ctx context.Context, in *QuoteShipmentRequest, opts ...grpc.CallOption) (*QuoteShipmentResponse, error)
+	// ListProviders lists the supported delivery providers and whether each is configured.
+	ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error)
+}
+
+type pricingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPricingServiceClient(cc grpc.ClientConnInterface) PricingServiceClient {
+	return &pricingServiceClient{cc}
+}
+
+func (c *pricingServiceClient) ListPricedProducts(ctx context.Context, in *ListPricedProductsRequest, opts ...grpc.CallOption) (*ListPricedProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPricedProductsResponse)
+	err := c.cc.Invoke(ctx, PricingService_ListPricedProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) GetPricedProduct(ctx context.Context, in *GetPricedProductRequest, opts ...grpc.CallOption) (*PricedProduct, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PricedProduct)
+	err := c.cc.Invoke(ctx, PricingService_GetPricedProduct_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) QuoteShipment(ctx context.Context, in *QuoteShipmentRequest, opts ...grpc.CallOption) (*QuoteShipmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuoteShipmentResponse)
+	err := c.cc.Invoke(ctx, PricingService_QuoteShipment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) Checkout(ctx context.Context, in *QuoteShipmentRequest, opts ...grpc.CallOption) (*QuoteShipmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuoteShipmentResponse)
+	err := c.cc.Invoke(ctx, PricingService_Checkout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pricingServiceClient) ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProvidersResponse)
+	err := c.cc.Invoke(ctx, PricingService_ListProviders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PricingServiceServer is the server API for PricingService service.
+// All implementations must embed UnimplementedPricingServiceServer
+// for forward compatibility.
+//
+// PricingService prices the product catalogue with a delivery provider, like the HTTP /v1 routes.
+type PricingServiceServer interface {
+	// ListPricedProducts prices every product in the catalogue.
+	ListPricedProducts(context.Context, *ListPricedProductsRequest) (*ListPricedProductsResponse, error)
+	// GetPricedProduct prices a single product looked up by name, case-insensitively.
+	GetPricedProduct(context.Context, *GetPricedProductRequest) (*PricedProduct, error)
+	// QuoteShipment prices a shipment of catalogue products sent together as one parcel.
+	QuoteShipment(context.Context, *QuoteShipmentRequest) (*QuoteShipmentResponse, error)
+	// Checkout prices a shipment like QuoteShipment and redeems its discount code.
+	Checkout(context.Context, *QuoteShipmentRequest) (*QuoteShipmentResponse, error)
+	// ListProviders lists the supported delivery providers and whether each is configured.
+	ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error)
+	mustEmbedUnimplementedPricingServiceServer()
+}
+
+// UnimplementedPricingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPricingServiceServer struct{}
+
+func (UnimplementedPricingServiceServer) ListPricedProducts(context.Context, *ListPricedProductsRequest) (*ListPricedProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPricedProducts not implemented")
+}
+func (UnimplementedPricingServiceServer) GetPricedProduct(context.Context, *GetPricedProductRequest) (*PricedProduct, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPricedProduct not implemented")
+}
+func (UnimplementedPricingServiceServer) QuoteShipment(context.Context, *QuoteShipmentRequest) (*QuoteShipmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteShipment not implemented")
+}
+func (UnimplementedPricingServiceServer) Checkout(context.Context, *QuoteShipmentRequest) (*QuoteShipmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Checkout not implemented")
+}
+func (UnimplementedPricingServiceServer) ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProviders not implemented")
+}
+func (UnimplementedPricingServiceServer) mustEmbedUnimplementedPricingServiceServer() {}
+func (UnimplementedPricingServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePricingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PricingServiceServer will
+// result in compilation errors.
+type UnsafePricingServiceServer interface {
+	mustEmbedUnimplementedPricingServiceServer()
+}
+
+func RegisterPricingServiceServer(s grpc.ServiceRegistrar, srv PricingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPricingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PricingService_ServiceDesc, srv)
+}
+
+func _PricingService_ListPricedProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPricedProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).ListPricedProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_ListPricedProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).ListPricedProducts(ctx, req.(*ListPricedProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_GetPricedProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricedProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).GetPricedProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_GetPricedProduct_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).GetPricedProduct(ctx, req.(*GetPricedProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_QuoteShipment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteShipmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).QuoteShipment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_QuoteShipment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).QuoteShipment(ctx, req.(*QuoteShipmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_Checkout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteShipmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).Checkout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_Checkout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).Checkout(ctx, req.(*QuoteShipmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PricingService_ListProviders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProvidersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PricingServiceServer).ListProviders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PricingService_ListProviders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PricingServiceServer).ListProviders(ctx, req.(*ListProvidersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PricingService_ServiceDesc is the grpc.ServiceDesc for PricingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PricingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pricing.v1.PricingService",
+	HandlerType: (*PricingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPricedProducts",
+			Handler:    _PricingService_ListPricedProducts_Handler,
+		},
+		{
+			MethodName: "GetPricedProduct",
+			Handler:    _PricingService_GetPricedProduct_Handler,
+		},
+		{
+			MethodName: "QuoteShipment",
+			Handler:    _PricingService_QuoteShipment_Handler,
+		},
+		{
+			MethodName: "Checkout",
+			Handler:    _PricingService_Checkout_Handler,
+		},
+		{
+			MethodName: "ListProviders",
+			Handler:    _PricingService_ListProviders_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pricing.proto",
+}
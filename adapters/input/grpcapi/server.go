@@ -0,0 +1,121 @@
+// Package grpcapi serves the pricing API over gRPC for internal services, alongside the HTTP API.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpb"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+)
+
+// defaultPort is used when GRPC_PORT is not set.
+const defaultPort = "9090"
+
+// panicsTotal counts RPC panics caught by recoveryInterceptor.
+var panicsTotal = metrics.Counter("grpc_panics_total")
+
+/*
+NewServer builds a gRPC server with the pricing service, the standard health service reporting
+SERVING, and server reflection so tools such as grpcurl can discover the API. Every RPC is
+logged and protected by panic recovery, and the pricing RPCs are authenticated and rate limited
+with access, like the HTTP routes.
+*/
+func NewServer(access Access) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(loggingInterceptor, recoveryInterceptor, authInterceptor(access.Keys, access.Anonymous), rateLimitInterceptor(access.Limiter)))
+	pricingpb.RegisterPricingServiceServer(server, pricingService{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pricingpb.PricingService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server
+}
+
+/*
+StartGRPCServer serves gRPC on GRPC_PORT (default 9090), with access, until ctx is done or the
+listener fails. Setting GRPC_PORT to "off" disables the gRPC API. When ctx is done
+the server stops accepting RPCs and waits for those in flight to finish.
+*/
+func StartGRPCServer(ctx context.Context, access Access) {
+	port := strings.TrimSpace(os.Getenv("GRPC_PORT"))
+	if port == "" {
+		port = defaultPort
+	}
+	if strings.EqualFold(port, "off") {
+		logs.Logs(1, "gRPC server disabled", "")
+		return
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logs.Logs(3, fmt.Sprintf("failed to listen for gRPC: %s", err.Error()), "")
+		return
+	}
+
+	server := NewServer(access)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			logs.Logs(1, "shutting down gRPC server", "")
+			server.GracefulStop()
+		case <-stopped:
+		}
+	}()
+	defer close(stopped)
+
+	logs.Logs(1, "gRPC server started successfully on port "+port, "")
+	err = server.Serve(listener)
+	if err != nil {
+		logs.Logs(3, fmt.Sprintf("failed to start gRPC server: %s", err.Error()), "")
+	}
+}
+
+// loggingInterceptor logs the method, status code and duration of every RPC.
+func loggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	logType := 1
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		logType = 3
+	default:
+		logType = 2
+	}
+	logs.Logs(logType, fmt.Sprintf("gRPC %s %s %s", info.FullMethod, code, time.Since(start).Round(time.Microsecond)), "")
+	return resp, err
+}
+
+/*
+recoveryInterceptor turns a panic in an RPC into an Internal error instead of crashing the
+process. The panic and stack trace are logged and grpc_panics_total is incremented.
+*/
+func recoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicsTotal.Add(1)
+			logs.Logs(3, fmt.Sprintf("panic serving gRPC %s: %v\n%s", info.FullMethod, recovered, debug.Stack()), "")
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
@@ -0,0 +1,391 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpb"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFlags(0)
+	go logs.ProcessLogs()
+	os.Exit(m.Run())
+}
+
+// newTestClient serves the gRPC API on an in-process bufconn listener with a stub catalogue and
+// the API keys, anonymous role and rate limits configured in the environment
+func newTestClient(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	originalLoadProductsFunc := storage.LoadProductsFunc
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "TV", Weight: 1.5, Price: 20}, {Name: "Radio", Weight: 0.5, Price: 7.5}}, nil
+	}
+	t.Setenv("DELIVERY_PROVIDER", "DHL")
+	t.Setenv("DHL_DELIVERY_PRICE", "2.00")
+
+	keys, err := access.LoadKeyStore()
+	if err != nil {
+		t.Fatalf("failed to load API keys: %v", err)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer(Access{Keys: keys, Anonymous: access.AnonymousRole(), Limiter: access.NewMemoryLimiter()})
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		storage.LoadProductsFunc = originalLoadProductsFunc
+	})
+	return conn
+}
+
+// TestListPricedProducts tests pricing the catalogue with the default and a requested provider
+func TestListPricedProducts(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+
+	tests := []struct {
+		name         string
+		provider     string
+		expectedCode codes.Code
+		expectedTV   string
+	}{
+		{name: "default provider", expectedCode: codes.OK, expectedTV: "23.00"},
+		{name: "provider is case-insensitive", provider: "dhl", expectedCode: codes.OK, expectedTV: "23.00"},
+		{name: "unknown provider", provider: "FEDEX", expectedCode: codes.InvalidArgument},
+		{name: "provider price not configured", provider: "UPS", expectedCode: codes.Unavailable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.ListPricedProducts(context.Background(), &pricingpb.ListPricedProductsRequest{Provider: tc.provider})
+			if status.Code(err) != tc.expectedCode {
+				t.Fatalf("expected code %s, got %v", tc.expectedCode, err)
+			}
+			if tc.expectedCode != codes.OK {
+				return
+			}
+			if len(resp.GetProducts()) != 2 || resp.GetProducts()[0].GetTotalPrice() != tc.expectedTV {
+				t.Errorf("unexpected products %v", resp.GetProducts())
+			}
+		})
+	}
+}
+
+// TestGetPricedProduct tests pricing a single product by name
+func TestGetPricedProduct(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+
+	product, err := client.GetPricedProduct(context.Background(), &pricingpb.GetPricedProductRequest{Name: "tv"})
+	if err != nil {
+		t.Fatalf("GetPricedProduct failed: %v", err)
+	}
+	if product.GetName() != "TV" || product.GetDeliveryPrice() != "3.00" || product.GetDeliveryService() != "DHL" {
+		t.Errorf("unexpected product %v", product)
+	}
+
+	_, err = client.GetPricedProduct(context.Background(), &pricingpb.GetPricedProductRequest{Name: "Fridge"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+// TestQuoteShipment tests quoting several products sent together
+func TestQuoteShipment(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+
+	tests := []struct {
+		name          string
+		items         []*pricingpb.ShipmentItem
+		expectedCode  codes.Code
+		expectedTotal string
+	}{
+		{
+			name:          "two products as one parcel",
+			items:         []*pricingpb.ShipmentItem{{Name: "TV", Quantity: 1}, {Name: "radio", Quantity: 2}},
+			expectedCode:  codes.OK,
+			expectedTotal: "40.00", // 20 + 2*7.5 = 35 plus (1.5 + 2*0.5) * 2 = 5 delivery
+		},
+		{name: "no items", expectedCode: codes.InvalidArgument},
+		{name: "zero quantity", items: []*pricingpb.ShipmentItem{{Name: "TV"}}, expectedCode: codes.InvalidArgument},
+		{name: "unknown product", items: []*pricingpb.ShipmentItem{{Name: "Fridge", Quantity: 1}}, expectedCode: codes.NotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: tc.items})
+			if status.Code(err) != tc.expectedCode {
+				t.Fatalf("expected code %s, got %v", tc.expectedCode, err)
+			}
+			if tc.expectedCode != codes.OK {
+				return
+			}
+			if resp.GetTotalPrice() != tc.expectedTotal || resp.GetDeliveryPrice() != "5.00" {
+				t.Errorf("unexpected quote %v", resp)
+			}
+			if len(resp.GetLines()) != 2 || resp.GetLines()[1].GetProductPrice() != "15.00" {
+				t.Errorf("unexpected lines %v", resp.GetLines())
+			}
+		})
+	}
+}
+
+// TestQuoteShipmentWithCode tests applying a discount code to a whole shipment, and only redeeming it at checkout
+func TestQuoteShipmentWithCode(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+	if err := domain.SetDiscountCodes([]domain.DiscountCode{{Code: "SHIPFREE", Kind: domain.DiscountFreeDelivery, MinSpend: 30, UsageLimit: 1}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { domain.SetDiscountCodes(nil) })
+
+	// the radio alone is under the minimum spend, so the code is rejected and not redeemed
+	_, err := client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "Radio", Quantity: 1}}, Code: "shipfree"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+
+	req := &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "TV", Quantity: 1}, {Name: "radio", Quantity: 2}}, Code: "shipfree"}
+	// quotes apply the code without redeeming it, so it can be quoted again
+	for range 2 {
+		resp, err := client.QuoteShipment(context.Background(), req)
+		if err != nil {
+			t.Fatalf("QuoteShipment failed: %v", err)
+		}
+		promotions := resp.GetPromotions()
+		if resp.GetTotalPrice() != "35.00" || resp.GetDeliveryPrice() != "0.00" || resp.GetOriginalDeliveryPrice() != "5.00" || len(promotions) != 1 || !promotions[0].GetDiscountCode() || promotions[0].GetId() != "SHIPFREE" {
+			t.Errorf("unexpected quote %v", resp)
+		}
+		if len(resp.GetLines()) != 2 || resp.GetLines()[0].GetProductPrice() != "20.00" {
+			t.Errorf("unexpected lines %v", resp.GetLines())
+		}
+	}
+	if redemptions := domain.DiscountCodes()[0].Redemptions; redemptions != 0 {
+		t.Errorf("expected quotes not to redeem the code, got %d redemptions", redemptions)
+	}
+
+	resp, err := client.Checkout(context.Background(), req)
+	if err != nil || resp.GetTotalPrice() != "35.00" {
+		t.Fatalf("expected the checkout to be priced like the quote, got %v %v", resp, err)
+	}
+
+	// the only use has been redeemed
+	_, err = client.GetPricedProduct(context.Background(), &pricingpb.GetPricedProductRequest{Name: "TV", Code: "SHIPFREE"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected the exhausted code to be rejected, got %v", err)
+	}
+}
+
+// TestListProviders tests that every provider is listed with its configuration state
+func TestListProviders(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+
+	resp, err := client.ListProviders(context.Background(), &pricingpb.ListProvidersRequest{})
+	if err != nil {
+		t.Fatalf("ListProviders failed: %v", err)
+	}
+	if len(resp.GetProviders()) != len(domain.Providers()) {
+		t.Fatalf("expected %d providers, got %d", len(domain.Providers()), len(resp.GetProviders()))
+	}
+	for _, provider := range resp.GetProviders() {
+		isDHL := provider.GetName() == "DHL"
+		if provider.GetConfigured() != isDHL || provider.GetIsDefault() != isDHL {
+			t.Errorf("unexpected provider %v", provider)
+		}
+	}
+}
+
+// TestHealthAndReflection tests the standard health and reflection services
+func TestHealthAndReflection(t *testing.T) {
+	conn := newTestClient(t)
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: pricingpb.PricingService_ServiceDesc.ServiceName})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING, got %v %v", health, err)
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatalf("reflection failed: %v", err)
+	}
+	stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}})
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("reflection failed: %v", err)
+	}
+	found := false
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		if service.GetName() == pricingpb.PricingService_ServiceDesc.ServiceName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("pricing service not listed by reflection: %v", resp)
+	}
+}
+
+// TestQuoteShipmentVolumetric tests charging a shipment on its combined volume when that weighs more
+func TestQuoteShipmentVolumetric(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "Pillow", Weight: 1, Price: 10, Length: 50, Width: 40, Height: 30}, {Name: "Radio", Weight: 0.5, Price: 7.5}}, nil
+	}
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "5000")
+
+	// two pillows take up 120000, which weighs 24 against 2.5 actual
+	resp, err := client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "pillow", Quantity: 2}, {Name: "Radio", Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("QuoteShipment failed: %v", err)
+	}
+	if resp.GetChargeableWeight() != 24 || resp.GetWeightBasis() != "volumetric" || resp.GetDeliveryPrice() != "48.00" {
+		t.Errorf("unexpected quote %v", resp)
+	}
+
+	resp, err = client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "Radio", Quantity: 3}}})
+	if err != nil {
+		t.Fatalf("QuoteShipment failed: %v", err)
+	}
+	if resp.GetChargeableWeight() != 1.5 || resp.GetWeightBasis() != "actual" || resp.GetDeliveryPrice() != "3.00" {
+		t.Errorf("unexpected quote %v", resp)
+	}
+}
+
+// TestPricingWithDestination tests pricing for a destination and rejecting destinations that are not served
+func TestPricingWithDestination(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+	if err := domain.SetZoneTables([]domain.ZoneTable{{Provider: "DHL", Rates: map[domain.Zone]float64{domain.ZoneNorthernIreland: 4}}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { domain.SetZoneTables(nil) })
+
+	product, err := client.GetPricedProduct(context.Background(), &pricingpb.GetPricedProductRequest{Name: "TV", Destination: &pricingpb.Destination{Country: "GB", Postcode: "BT1 1AA"}})
+	if err != nil || product.GetDeliveryPrice() != "6.00" || product.GetZone() != "northern_ireland" {
+		t.Errorf("expected the Northern Ireland rate, got %v %v", product, err)
+	}
+
+	// the parcel weighs 2.5 at the zone's rate of 4
+	quote, err := client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "TV", Quantity: 1}, {Name: "radio", Quantity: 2}}, Destination: &pricingpb.Destination{Country: "GB", Postcode: "BT1"}})
+	if err != nil || quote.GetDeliveryPrice() != "10.00" || quote.GetZone() != "northern_ireland" {
+		t.Errorf("expected the shipment at the Northern Ireland rate, got %v %v", quote, err)
+	}
+
+	_, err = client.ListPricedProducts(context.Background(), &pricingpb.ListPricedProductsRequest{Destination: &pricingpb.Destination{Country: "US"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a zone that is not served, got %v", err)
+	}
+	_, err = client.ListPricedProducts(context.Background(), &pricingpb.ListPricedProductsRequest{Destination: &pricingpb.Destination{Country: "GB"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a destination without a postcode, got %v", err)
+	}
+}
+
+// TestPricingWithServiceLevels tests listing the provider's service levels as delivery options
+func TestPricingWithServiceLevels(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+	err := domain.SetServiceLevels([]domain.ServiceLevel{
+		{Provider: "DHL", Service: domain.ServiceStandard, TransitDays: 3},
+		{Provider: "DHL", Service: domain.ServiceTimed, Rate: 6, TransitDays: 1, DeliverBy: "10:30"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { domain.SetServiceLevels(nil) })
+
+	product, err := client.GetPricedProduct(context.Background(), &pricingpb.GetPricedProductRequest{Name: "TV"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	options := product.GetDeliveryOptions()
+	if len(options) != 2 || options[0].GetDeliveryPrice() != "3.00" || options[1].GetDeliveryPrice() != "9.00" || options[1].GetTotalPrice() != "29.00" || options[1].GetDeliverBy() != "10:30" || options[1].GetEstimatedDelivery() == "" {
+		t.Errorf("unexpected delivery options %v", options)
+	}
+
+	// the parcel weighs 2.5 at the timed rate of 6
+	quote, err := client.QuoteShipment(context.Background(), &pricingpb.QuoteShipmentRequest{Items: []*pricingpb.ShipmentItem{{Name: "TV", Quantity: 1}, {Name: "radio", Quantity: 2}}})
+	if err != nil || len(quote.GetDeliveryOptions()) != 2 || quote.GetDeliveryOptions()[1].GetDeliveryPrice() != "15.00" {
+		t.Errorf("expected the shipment's delivery options, got %v %v", quote, err)
+	}
+}
+
+// TestErrorStatus tests that failures are reported with the HTTP API's code and message, without leaking the error's text
+func TestErrorStatus(t *testing.T) {
+	client := pricingpb.NewPricingServiceClient(newTestClient(t))
+
+	tests := []struct {
+		name            string
+		loadErr         error
+		provider        string
+		expectedCode    codes.Code
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "storage unavailable",
+			loadErr:         fmt.Errorf("%w: open /srv/secret/products.json: permission denied", domain.ErrStorageUnavailable),
+			expectedCode:    codes.Unavailable,
+			expectedReason:  "storage_unavailable",
+			expectedMessage: "Product storage is unavailable",
+		},
+		{
+			name:            "provider price not configured",
+			provider:        "UPS",
+			expectedCode:    codes.Unavailable,
+			expectedReason:  "provider_price_missing",
+			expectedMessage: "Delivery provider is not configured",
+		},
+		{
+			name:            "unknown provider",
+			provider:        "FEDEX",
+			expectedCode:    codes.InvalidArgument,
+			expectedReason:  "invalid_provider",
+			expectedMessage: "Unknown delivery provider",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.loadErr != nil {
+				stub := storage.LoadProductsFunc
+				storage.LoadProductsFunc = func() ([]domain.Product, error) { return nil, tc.loadErr }
+				t.Cleanup(func() { storage.LoadProductsFunc = stub })
+			}
+
+			_, err := client.ListPricedProducts(context.Background(), &pricingpb.ListPricedProductsRequest{Provider: tc.provider})
+			st := status.Convert(err)
+			if st.Code() != tc.expectedCode || st.Message() != tc.expectedMessage {
+				t.Fatalf("expected %s %q, got %v", tc.expectedCode, tc.expectedMessage, err)
+			}
+			details := st.Details()
+			if len(details) != 1 {
+				t.Fatalf("expected one detail, got %v", details)
+			}
+			info, ok := details[0].(*errdetails.ErrorInfo)
+			if !ok || info.GetReason() != tc.expectedReason {
+				t.Errorf("expected reason %q, got %v", tc.expectedReason, details[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi/pricingpb"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// pricingService implements the PricingService RPCs with the same domain functions as the HTTP handlers.
+type pricingService struct {
+	pricingpb.UnimplementedPricingServiceServer
+}
+
+// ListPricedProducts prices every product in the catalogue, applying the request's discount code and destination if given.
+func (pricingService) ListPricedProducts(ctx context.Context, req *pricingpb.ListPricedProductsRequest) (*pricingpb.ListPricedProductsResponse, error) {
+	provider, err := resolveProvider(req.GetProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	priced, err := domain.PriceProductsWithOptionsFunc(products, provider, pricingOptions(req.GetCode(), req.GetDestination()))
+	if err != nil {
+		logs.Logs(3, "Failed to price products: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+
+	resp := &pricingpb.ListPricedProductsResponse{Products: make([]*pricingpb.PricedProduct, len(priced))}
+	for i, p := range priced {
+		resp.Products[i] = toPricedProduct(p)
+	}
+	return resp, nil
+}
+
+// GetPricedProduct prices a single product looked up by name, applying the request's discount code and destination if given.
+func (pricingService) GetPricedProduct(ctx context.Context, req *pricingpb.GetPricedProductRequest) (*pricingpb.PricedProduct, error) {
+	provider, err := resolveProvider(req.GetProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+	product, err := domain.FindProduct(products, req.GetName())
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	priced, err := domain.PriceProductsWithOptionsFunc([]domain.Product{product}, provider, pricingOptions(req.GetCode(), req.GetDestination()))
+	if err != nil {
+		logs.Logs(3, "Failed to price product: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+	if len(priced) != 1 {
+		logs.Logs(3, "Pricing returned an unexpected number of products for "+product.Name, provider)
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return toPricedProduct(priced[0]), nil
+}
+
+/*
+QuoteShipment prices a shipment of several products sent as one parcel with domain.PriceShipment,
+applying the request's discount code and destination if given. The code is not redeemed.
+*/
+func (pricingService) QuoteShipment(ctx context.Context, req *pricingpb.QuoteShipmentRequest) (*pricingpb.QuoteShipmentResponse, error) {
+	return priceShipment(ctx, req, domain.PriceShipment)
+}
+
+// Checkout prices a shipment like QuoteShipment and redeems its discount code, saving the redemption as the HTTP API does.
+func (pricingService) Checkout(ctx context.Context, req *pricingpb.QuoteShipmentRequest) (*pricingpb.QuoteShipmentResponse, error) {
+	return priceShipment(ctx, req, func(items []domain.ShipmentItem, provider string, options domain.PricingOptions) (domain.PricedShipment, error) {
+		return domain.CheckOut(items, provider, options, storage.SaveCurrentDiscountCodes)
+	})
+}
+
+// priceShipment looks up the request's items in the catalogue and prices them as a shipment with price.
+func priceShipment(ctx context.Context, req *pricingpb.QuoteShipmentRequest, price func([]domain.ShipmentItem, string, domain.PricingOptions) (domain.PricedShipment, error)) (*pricingpb.QuoteShipmentResponse, error) {
+	provider, err := resolveProvider(req.GetProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+
+	items := make([]domain.ShipmentItem, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		product, err := domain.FindProduct(products, item.GetName())
+		if err != nil {
+			return nil, statusFromError(err)
+		}
+		items = append(items, domain.ShipmentItem{Product: product, Quantity: int(item.GetQuantity())})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, statusFromError(err)
+	}
+
+	shipment, err := price(items, provider, pricingOptions(req.GetCode(), req.GetDestination()))
+	if err != nil {
+		logs.Logs(3, "Failed to price shipment: "+err.Error(), provider)
+		return nil, statusFromError(err)
+	}
+
+	total := shipment.Order
+	resp := &pricingpb.QuoteShipmentResponse{
+		ProductPrice:          total.ProductPrice,
+		DeliveryPrice:         total.DeliveryPrice,
+		TotalPrice:            total.TotalPrice,
+		DeliveryService:       total.DeliveryService,
+		OriginalProductPrice:  total.OriginalProductPrice,
+		OriginalDeliveryPrice: total.OriginalDeliveryPrice,
+		Promotions:            toAppliedPromotions(total.Promotions),
+		ChargeableWeight:      total.ChargeableWeight,
+		WeightBasis:           string(total.WeightBasis),
+		Zone:                  string(total.Zone),
+		DeliveryOptions:       toDeliveryOptions(total.DeliveryOptions),
+	}
+	for _, line := range shipment.Lines {
+		resp.Lines = append(resp.Lines, &pricingpb.QuoteLine{Name: line.Name, Quantity: int32(line.Quantity), ProductPrice: line.ProductPrice})
+	}
+	return resp, nil
+}
+
+// ListProviders lists the supported delivery providers, whether each has a valid price and which is the default.
+func (pricingService) ListProviders(ctx context.Context, req *pricingpb.ListProvidersRequest) (*pricingpb.ListProvidersResponse, error) {
+	defaultProvider := domain.DefaultProvider()
+
+	resp := &pricingpb.ListProvidersResponse{}
+	for _, name := range domain.Providers() {
+		_, err := domain.ProviderRate(name)
+		resp.Providers = append(resp.Providers, &pricingpb.Provider{Name: name, Configured: err == nil, IsDefault: name == defaultProvider})
+	}
+	return resp, nil
+}
+
+/*
+resolveProvider works out which delivery provider to price with, as the HTTP API does: the
+request's provider if given, otherwise DELIVERY_PROVIDER, both case-insensitive.
+*/
+func resolveProvider(requested string) (string, error) {
+	if provider := strings.ToUpper(strings.TrimSpace(requested)); provider != "" {
+		return provider, nil
+	}
+	provider := domain.DefaultProvider()
+	if provider == "" {
+		logs.Logs(3, "DELIVERY_PROVIDER environment variable not set", "")
+		return "", status.Error(codes.FailedPrecondition, "delivery provider not set")
+	}
+	return provider, nil
+}
+
+// pricingOptions returns the domain pricing options for a request's discount code and destination.
+func pricingOptions(code string, destination *pricingpb.Destination) domain.PricingOptions {
+	options := domain.PricingOptions{Code: strings.TrimSpace(code)}
+	if destination != nil {
+		options.Destination = &domain.Destination{Country: destination.GetCountry(), Postcode: destination.GetPostcode()}
+	}
+	return options
+}
+
+// toPricedProduct converts a domain priced product to its protobuf message.
+func toPricedProduct(p domain.PricedProduct) *pricingpb.PricedProduct {
+	return &pricingpb.PricedProduct{
+		Name:                  p.Name,
+		ProductPrice:          p.ProductPrice,
+		DeliveryPrice:         p.DeliveryPrice,
+		TotalPrice:            p.TotalPrice,
+		DeliveryService:       p.DeliveryService,
+		OriginalProductPrice:  p.OriginalProductPrice,
+		OriginalDeliveryPrice: p.OriginalDeliveryPrice,
+		Promotions:            toAppliedPromotions(p.Promotions),
+		ChargeableWeight:      p.ChargeableWeight,
+		WeightBasis:           string(p.WeightBasis),
+		Zone:                  string(p.Zone),
+		DeliveryOptions:       toDeliveryOptions(p.DeliveryOptions),
+	}
+}
+
+// toDeliveryOptions converts the ways a product can be delivered to their protobuf messages.
+func toDeliveryOptions(options []domain.DeliveryOption) []*pricingpb.DeliveryOption {
+	var converted []*pricingpb.DeliveryOption
+	for _, o := range options {
+		converted = append(converted, &pricingpb.DeliveryOption{
+			Service:           string(o.Service),
+			DeliveryPrice:     o.DeliveryPrice,
+			TotalPrice:        o.TotalPrice,
+			EstimatedDelivery: o.EstimatedDelivery,
+			DeliverBy:         o.DeliverBy,
+		})
+	}
+	return converted
+}
+
+// toAppliedPromotions converts the promotions applied to a price to their protobuf messages.
+func toAppliedPromotions(applied []domain.AppliedPromotion) []*pricingpb.AppliedPromotion {
+	var promotions []*pricingpb.AppliedPromotion
+	for _, a := range applied {
+		promotions = append(promotions, &pricingpb.AppliedPromotion{Id: a.ID, Name: a.Name, Target: string(a.Target), Discount: a.Discount, DiscountCode: a.DiscountCode})
+	}
+	return promotions
+}
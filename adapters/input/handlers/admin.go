@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// envFile is the file environment variables are loaded from at start up and on reload.
+var envFile = "env/.env"
+
+/*
+reloadConfigHandler re-reads the environment file, the API keys, the pricing schedule, the
+promotions, the discount codes, the zone tables, the service levels and the bank holidays without
+restarting. Every source is read and validated before any is applied, so a bad file fails the
+reload and leaves the whole configuration as it was. Pricing reads provider settings from the
+environment on every request, so new prices and the default provider take effect immediately, and
+open price streams are sent the changes. Every change is audited against the caller, with the
+reason given by ?reason=.
+*/
+func (s *Server) reloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	principal, _ := principalFromContext(r.Context())
+
+	vars, err := env.ReadEnv(envFile)
+	if err != nil {
+		logs.Logs(3, "failed to reload environment variables: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to reload configuration", nil)
+		return
+	}
+	// the API keys and the storage files are named in the environment, so it is set while they are read
+	restore := env.Apply(vars)
+
+	keys, err := access.LoadKeyStore()
+	if err != nil {
+		restore()
+		logs.Logs(3, "failed to reload API keys: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to reload API keys", nil)
+		return
+	}
+	config, err := readConfig()
+	if err != nil {
+		restore()
+		logs.Logs(3, "failed to reload configuration: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to reload configuration", nil)
+		return
+	}
+
+	before := currentConfig()
+	if err := s.schedule.setConfig(config); err != nil {
+		restore()
+		logs.Logs(3, "failed to reload configuration: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to reload configuration", nil)
+		return
+	}
+	s.keys.Replace(keys)
+
+	reason := auditReason(r, "configuration reloaded")
+	for _, entry := range configAuditEntries(before, currentConfig()) {
+		entry.Reason = reason
+		s.auditChange(r, entry)
+	}
+	observePrices(time.Now(), principal.KeyID, reason)
+	s.feeds.refresh()
+
+	logs.Logs(1, "configuration reloaded by key id "+principal.KeyID, "")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+}
+
+// readConfig reads every part of the pricing configuration from storage without applying it.
+func readConfig() (domain.Config, error) {
+	var config domain.Config
+	var err error
+	if config.Schedule, err = storage.LoadScheduleFunc(); err != nil {
+		return config, fmt.Errorf("pricing schedule: %w", err)
+	}
+	if config.Promotions, err = storage.LoadPromotionsFunc(); err != nil {
+		return config, fmt.Errorf("promotions: %w", err)
+	}
+	if config.DiscountCodes, err = storage.LoadDiscountCodesFunc(); err != nil {
+		return config, fmt.Errorf("discount codes: %w", err)
+	}
+	if config.ZoneTables, err = storage.LoadZoneTablesFunc(); err != nil {
+		return config, fmt.Errorf("zone tables: %w", err)
+	}
+	if config.ServiceLevels, err = storage.LoadServiceLevelsFunc(); err != nil {
+		return config, fmt.Errorf("service levels: %w", err)
+	}
+	if config.BankHolidays, err = storage.LoadBankHolidaysFunc(); err != nil {
+		return config, fmt.Errorf("bank holidays: %w", err)
+	}
+	return config, nil
+}
+
+// currentConfig returns the pricing configuration in force.
+func currentConfig() domain.Config {
+	return domain.Config{
+		Schedule:      domain.Schedule(),
+		Promotions:    domain.Promotions(),
+		DiscountCodes: domain.DiscountCodes(),
+		ZoneTables:    domain.ZoneTables(),
+		ServiceLevels: domain.ServiceLevels(),
+		BankHolidays:  domain.BankHolidays(),
+	}
+}
+
+// configAuditEntries returns an audit entry, without an actor or reason, for everything a reload changed.
+func configAuditEntries(before, after domain.Config) []audit.Entry {
+	return slices.Concat(
+		configChanges("schedule", before.Schedule, after.Schedule, func(c domain.ScheduledChange) string { return c.ID }),
+		promotionChanges(before.Promotions, after.Promotions),
+		configChanges("discount_code", before.DiscountCodes, after.DiscountCodes, func(c domain.DiscountCode) string { return c.Code }),
+		zoneTableChanges(before.ZoneTables, after.ZoneTables),
+		serviceLevelChanges(before.ServiceLevels, after.ServiceLevels),
+		configChanges("bank_holiday", before.BankHolidays, after.BankHolidays, func(date string) string { return date }),
+	)
+}
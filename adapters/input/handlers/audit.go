@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+const (
+	// defaultAuditLimit is the number of records returned by GET /audit when no limit is given.
+	defaultAuditLimit = 100
+	// maxAuditLimit is the most records GET /audit returns at once.
+	maxAuditLimit = 1000
+)
+
+// auditResponse is the body of GET /audit.
+type auditResponse struct {
+	Records []audit.Record `json:"records"`
+}
+
+// auditReason returns the ?reason= query parameter of a request making a change, or fallback if it is not given.
+func auditReason(r *http.Request, fallback string) string {
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		return reason
+	}
+	return fallback
+}
+
+/*
+auditChange records a change made through the API against the caller's key. The change has
+already been made, so a failure to audit it is logged rather than failing the request.
+*/
+func (s *Server) auditChange(r *http.Request, entry audit.Entry) {
+	principal, _ := principalFromContext(r.Context())
+	entry.Actor = principal.KeyID
+	if _, err := s.audit.Append(entry); err != nil {
+		logs.Logs(3, "failed to audit "+entry.Action+" of "+entry.Entity+": "+err.Error(), "")
+	}
+}
+
+/*
+configChanges compares two versions of a configured list by key, returning an audit entry without
+an actor or reason for each item added, edited or removed, as "<kind>.changed" of "<kind>:<key>".
+*/
+func configChanges[T any](kind string, before, after []T, key func(T) string) []audit.Entry {
+	old := make(map[string]T, len(before))
+	for _, item := range before {
+		old[key(item)] = item
+	}
+
+	var entries []audit.Entry
+	for _, item := range after {
+		previous, existed := old[key(item)]
+		delete(old, key(item))
+		switch {
+		case !existed:
+			entries = append(entries, audit.Entry{Action: kind + ".changed", Entity: kind + ":" + key(item), After: item})
+		case !reflect.DeepEqual(previous, item):
+			entries = append(entries, audit.Entry{Action: kind + ".changed", Entity: kind + ":" + key(item), Before: previous, After: item})
+		}
+	}
+	for _, item := range before {
+		if _, removed := old[key(item)]; removed {
+			entries = append(entries, audit.Entry{Action: kind + ".changed", Entity: kind + ":" + key(item), Before: item})
+		}
+	}
+	return entries
+}
+
+/*
+auditHandler lists audit records, most recent first. They can be filtered by actor, action,
+entity (a whole entity such as rate:DHL, or a type such as rate) and an RFC 3339 since/until
+window, and limited to the most recent records.
+*/
+func (s *Server) auditHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Entity: query.Get("entity"),
+		Limit:  defaultAuditLimit,
+	}
+
+	for name, field := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if !query.Has(name) {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, query.Get(name))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeInvalidRequest, name+" must be an RFC 3339 time, such as 2026-10-01T12:00:00Z", map[string]string{name: query.Get(name)})
+			return
+		}
+		*field = at
+	}
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "limit must be a whole number from 1 to "+strconv.Itoa(maxAuditLimit), map[string]string{"limit": query.Get("limit")})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	writeJSON(w, http.StatusOK, auditResponse{Records: s.audit.Query(filter)})
+}
+
+// verifyAuditHandler checks that no audit record has been edited, removed or reordered.
+func (s *Server) verifyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := s.audit.Verify()
+	if err != nil {
+		logs.Logs(3, "failed to verify audit log: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to read the audit log", nil)
+		return
+	}
+	if !result.Valid {
+		logs.Logs(3, "audit log failed verification: "+result.Reason, "")
+	}
+	writeJSON(w, http.StatusOK, result)
+}
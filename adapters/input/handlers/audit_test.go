@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+)
+
+// auditRecords queries the audit log and returns the records
+func auditRecords(t *testing.T, url string) []audit.Record {
+	t.Helper()
+	resp, body := adminRequest(t, "GET", url, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var result auditResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("failed to decode audit records: %v", err)
+	}
+	return result.Records
+}
+
+// TestAuditTrail tests that pricing changes are audited with who made them and why
+func TestAuditTrail(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\nDHL_DELIVERY_PRICE=3.00\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	t.Cleanup(func() { envFile = originalEnvFile })
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("AUDIT_LOG_FILE", auditFile)
+	t.Setenv("API_KEYS", "cat:merchandiser:m,ops:admin:a")
+	useSchedule(t)
+	_, ts := newTestServer(t)
+
+	if resp, body := adminRequest(t, "POST", ts.URL+"/admin/reload?reason=carrier+price+rise", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d: %s", resp.StatusCode, body)
+	}
+	rates := auditRecords(t, ts.URL+"/audit?entity=rate:DHL&actor=ops")
+	if len(rates) != 1 || rates[0].Action != "rate.changed" || rates[0].Reason != "carrier price rise" || string(rates[0].Before) != "2" || string(rates[0].After) != "3" {
+		t.Fatalf("expected the reload to audit DHL going from 2 to 3, got %+v", rates)
+	}
+
+	effective := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/schedule", "cat.m", `{"id": "chg_sale", "effective_at": "`+effective+`", "rates": {"DHL": 1}, "note": "winter sale"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := scheduleRequest(t, "DELETE", ts.URL+"/v1/schedule/chg_sale?reason=sale+postponed", "cat.m", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	schedule := auditRecords(t, ts.URL+"/audit?entity=schedule")
+	if len(schedule) != 2 {
+		t.Fatalf("expected 2 schedule records, got %+v", schedule)
+	}
+	cancelled, created := schedule[0], schedule[1]
+	if created.Action != "schedule.created" || created.Actor != "cat" || created.Reason != "winter sale" || string(created.Before) != "null" {
+		t.Errorf("unexpected created record %+v", created)
+	}
+	if cancelled.Action != "schedule.cancelled" || cancelled.Reason != "sale postponed" || string(cancelled.After) != "null" || cancelled.PrevHash != created.Hash {
+		t.Errorf("unexpected cancelled record %+v", cancelled)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "limit", path: "/audit?limit=1", expectedStatus: http.StatusOK},
+		{name: "window", path: "/audit?since=2020-01-01T00:00:00Z&until=2999-01-01T00:00:00Z", expectedStatus: http.StatusOK},
+		{name: "limit too high", path: "/audit?limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "limit not a number", path: "/audit?limit=all", expectedStatus: http.StatusBadRequest},
+		{name: "since not a time", path: "/audit?since=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _ := adminRequest(t, "GET", ts.URL+tc.path, "")
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+	if records := auditRecords(t, ts.URL+"/audit?limit=1"); len(records) != 1 || records[0].Hash != cancelled.Hash {
+		t.Errorf("expected the most recent record, got %+v", records)
+	}
+
+	var verification audit.Verification
+	_, body = adminRequest(t, "GET", ts.URL+"/audit/verify", "")
+	if json.Unmarshal(body, &verification); !verification.Valid {
+		t.Fatalf("expected the audit log to verify, got %s", body)
+	}
+
+	// rewriting the reason for the rate change in the file is detected
+	data, _ := os.ReadFile(auditFile)
+	os.WriteFile(auditFile, []byte(strings.Replace(string(data), "carrier price rise", "routine update", 1)), 0o600)
+	_, body = adminRequest(t, "GET", ts.URL+"/audit/verify", "")
+	if json.Unmarshal(body, &verification); verification.Valid || verification.BrokenAt != rates[0].Seq {
+		t.Errorf("expected the audit log to break at record %d, got %s", rates[0].Seq, body)
+	}
+}
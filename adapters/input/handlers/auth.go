@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+)
+
+// authFailuresTotal counts requests rejected because of a missing, invalid or under-privileged API key.
+var authFailuresTotal = metrics.Counter("http_auth_failures_total")
+
+type principalKey struct{}
+
+// principalFromContext returns the caller stored by the authentication middleware.
+func principalFromContext(ctx context.Context) (access.Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(access.Principal)
+	return p, ok
+}
+
+// presentedKey returns the API key sent with the request, if any.
+func presentedKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+/*
+requireRole returns middleware that only lets through callers holding at least the given role.
+Requests without a key are treated as the anonymous role; requests with a bad key are always
+rejected, so a typo is reported rather than silently downgraded. Failures are logged with the
+key ID only, never the secret.
+*/
+func requireRole(keys *access.KeyStore, anonymous access.Role, required access.Role) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if required == access.RolePublic {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal := access.Principal{Role: anonymous}
+			id, role, err := keys.Authenticate(presentedKey(r))
+			switch {
+			case err == nil:
+				principal = access.Principal{KeyID: id, Role: role}
+			case errors.Is(err, access.ErrMissingKey):
+				// fall through as the anonymous caller
+			default:
+				authFailuresTotal.Add(1)
+				logs.Logs(2, fmt.Sprintf("authentication failed for key id %q on %s %s: %s", id, r.Method, r.URL.Path, err.Error()), "")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid API key", nil)
+				return
+			}
+
+			if principal.Role < required {
+				authFailuresTotal.Add(1)
+				if principal.KeyID == "" {
+					logs.Logs(2, fmt.Sprintf("anonymous request to %s %s requires role %s", r.Method, r.URL.Path, required), "")
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+					writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "An API key is required", map[string]string{"required_role": required.String()})
+					return
+				}
+				logs.Logs(2, fmt.Sprintf("key id %q with role %s denied %s %s, requires role %s", principal.KeyID, principal.Role, r.Method, r.URL.Path, required), "")
+				writeError(w, r, http.StatusForbidden, codeForbidden, "API key does not have the required role", map[string]string{"required_role": required.String()})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+		})
+	}
+}
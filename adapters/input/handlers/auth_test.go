@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRouteRoles tests that each route enforces the role it declares
+func TestRouteRoles(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	defer func() { envFile = originalEnvFile }()
+
+	t.Setenv("API_KEYS", "web:reader:r,ops:admin:a")
+
+	tests := []struct {
+		name         string
+		anonymous    string
+		method       string
+		path         string
+		key          string
+		expectedCode int
+	}{
+		{name: "anonymous reader can list products", method: "GET", path: "/products", expectedCode: http.StatusOK},
+		{name: "public route needs no key", anonymous: "none", method: "GET", path: "/", expectedCode: http.StatusOK},
+		{name: "anonymous rejected when disabled", anonymous: "none", method: "GET", path: "/products", expectedCode: http.StatusUnauthorized},
+		{name: "reader key when anonymous disabled", anonymous: "none", method: "GET", path: "/products", key: "web.r", expectedCode: http.StatusOK},
+		{name: "bad key is rejected even if anonymous could read", method: "GET", path: "/products", key: "web.wrong", expectedCode: http.StatusUnauthorized},
+		{name: "anonymous cannot reload", method: "POST", path: "/admin/reload", expectedCode: http.StatusUnauthorized},
+		{name: "reader cannot reload", method: "POST", path: "/admin/reload", key: "web.r", expectedCode: http.StatusForbidden},
+		{name: "admin can reload", method: "POST", path: "/admin/reload", key: "ops.a", expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AUTH_ANONYMOUS_ROLE", tc.anonymous)
+			_, ts := newTestServer(t)
+
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+/*
+pricedETag builds a strong ETag for a priced response from everything that affects its bytes:
+the catalogue contents, the provider, the provider's price configuration and any extra parts
+such as the product name. Empty parts are skipped, so an optional part such as the delivery zone
+leaves the other ETags as they were. It is computed without pricing the catalogue.
+*/
+func pricedETag(products []domain.Product, provider string, parts ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", domain.CatalogueVersion(products), provider, domain.PricingConfigVersion(provider))
+	for _, part := range parts {
+		if part != "" {
+			fmt.Fprintf(h, "%s\n", part)
+		}
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+/*
+writeCacheHeaders sets ETag, Cache-Control and, when the catalogue's modification time is known,
+Last-Modified. It reports true, having written a 304, if the request's If-None-Match matches the
+ETag, in which case the caller must not write a body.
+*/
+func writeCacheHeaders(w http.ResponseWriter, r *http.Request, etag string, provider string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl())
+
+	modTime, err := storage.CatalogueModTimeFunc()
+	if err == nil && !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		logs.Logs(1, "catalogue unchanged, returning 304 Not Modified", provider)
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+/*
+cacheControl returns the Cache-Control value for priced responses. By default clients must
+revalidate every time, which is cheap thanks to the ETag. PRODUCTS_CACHE_MAX_AGE lets clients
+reuse a response for a while without asking.
+*/
+func cacheControl() string {
+	maxAge := env.Duration("PRODUCTS_CACHE_MAX_AGE", 0)
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second))
+}
+
+/*
+etagMatches reports whether an If-None-Match header matches etag. As required for If-None-Match,
+the comparison is weak, so a W/ prefix added by an intermediary still matches, as does the
+ETag of a compressed representation of the same response.
+*/
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		candidate = withoutEncodingSuffix(strings.TrimPrefix(candidate, "W/"))
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
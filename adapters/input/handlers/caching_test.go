@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestEtagMatches tests If-None-Match parsing
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header  string
+		matches bool
+	}{
+		{header: "", matches: false},
+		{header: `"abc"`, matches: true},
+		{header: `W/"abc"`, matches: true},
+		{header: `"xyz", "abc"`, matches: true},
+		{header: `"xyz"`, matches: false},
+		{header: `*`, matches: true},
+	}
+
+	for _, tc := range tests {
+		if got := etagMatches(tc.header, `"abc"`); got != tc.matches {
+			t.Errorf("If-None-Match %q: expected %t, got %t", tc.header, tc.matches, got)
+		}
+	}
+}
+
+// TestConditionalGetProducts tests ETag, Last-Modified and 304 responses for the priced catalogue
+func TestConditionalGetProducts(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	originalModTimeFunc := storage.CatalogueModTimeFunc
+	originalPriceProductsFunc := domain.PriceProductsFunc
+	storage.CatalogueModTimeFunc = func() (time.Time, error) { return modTime, nil }
+	pricingCalls := 0
+	domain.PriceProductsFunc = func(products []domain.Product, provider string) ([]domain.PricedProduct, error) {
+		pricingCalls++
+		return domain.PriceProducts(products, provider)
+	}
+	defer func() {
+		storage.CatalogueModTimeFunc = originalModTimeFunc
+		domain.PriceProductsFunc = originalPriceProductsFunc
+	}()
+
+	get := func(ifNoneMatch string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+"/products", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	first := get("")
+	etag := first.Header.Get("ETag")
+	if first.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", first.StatusCode, etag)
+	}
+	if got := first.Header.Get("Last-Modified"); got != modTime.Format(http.TimeFormat) {
+		t.Errorf("expected Last-Modified %q, got %q", modTime.Format(http.TimeFormat), got)
+	}
+	if got := first.Header.Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected Cache-Control no-cache, got %q", got)
+	}
+
+	notModified := get(etag)
+	if notModified.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", notModified.StatusCode)
+	}
+	if pricingCalls != 1 {
+		t.Errorf("expected the catalogue to be priced once, got %d", pricingCalls)
+	}
+
+	// a price change must produce a new ETag
+	os.Setenv("DHL_DELIVERY_PRICE", "3.00")
+	changed := get(etag)
+	if changed.StatusCode != http.StatusOK || changed.Header.Get("ETag") == etag {
+		t.Errorf("expected 200 with a new ETag after a price change, got %d %q", changed.StatusCode, changed.Header.Get("ETag"))
+	}
+
+	// a different provider is a different representation
+	req, _ := http.NewRequest("GET", ts.URL+"/products?provider=ups", nil)
+	req.Header.Set("If-None-Match", changed.Header.Get("ETag"))
+	os.Setenv("UPS_DELIVERY_PRICE", "1.00")
+	defer os.Unsetenv("UPS_DELIVERY_PRICE")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for a different provider, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// maxCheckoutBodyBytes limits the size of a checkout request.
+const maxCheckoutBodyBytes = 64 << 10
+
+// checkoutRequest is the body of POST /v1/checkout.
+type checkoutRequest struct {
+	Items       []checkoutItem      `json:"items"`
+	Provider    string              `json:"provider,omitempty"`    // defaults to DELIVERY_PROVIDER
+	Code        string              `json:"code,omitempty"`        // discount code to apply and redeem
+	Destination *domain.Destination `json:"destination,omitempty"` // prices delivery for its zone
+}
+
+// checkoutItem is a catalogue product, looked up by name case-insensitively, and how many are bought.
+type checkoutItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+/*
+checkoutResponse is the body of POST /v1/checkout: the lines bought and the order's prices, in the
+v2 response shape without a name.
+*/
+type checkoutResponse struct {
+	Lines                 []checkoutLine     `json:"lines"`
+	ProductPrice          string             `json:"product_price"`
+	DeliveryPrice         string             `json:"delivery_price"`
+	TotalPrice            string             `json:"total_price"`
+	DeliveryService       string             `json:"delivery_service"`
+	ChargeableWeight      float64            `json:"chargeable_weight"`
+	WeightBasis           string             `json:"weight_basis"`
+	Zone                  string             `json:"zone,omitempty"`
+	DeliveryOptions       []v2DeliveryOption `json:"delivery_options,omitempty"`
+	OriginalProductPrice  string             `json:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string             `json:"original_delivery_price,omitempty"`
+	Promotions            []v2Promotion      `json:"promotions,omitempty"`
+}
+
+// checkoutLine is a line of a checkout, with the price of all its units before promotions.
+type checkoutLine struct {
+	Name         string `json:"name"`
+	Quantity     int    `json:"quantity"`
+	ProductPrice string `json:"product_price"`
+}
+
+/*
+checkoutHandler prices the items in the body as one order and redeems its discount code. It is
+the only HTTP route that redeems a code; GET routes given ?code= only quote it. The redemption is
+saved straight away and the checkout fails if it cannot be, and a rejected code is not redeemed.
+*/
+func (s *Server) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	var body checkoutRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCheckoutBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Request body must be a JSON object with items", map[string]string{"reason": err.Error()})
+		return
+	}
+
+	provider := strings.ToUpper(strings.TrimSpace(body.Provider))
+	if provider == "" {
+		provider = domain.DefaultProvider()
+	}
+	if provider == "" {
+		logs.Logs(3, "DELIVERY_PROVIDER environment variable not set", "")
+		writeError(w, r, http.StatusInternalServerError, codeProviderNotSet, "Delivery provider not set", nil)
+		return
+	}
+
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
+		writeDomainError(w, r, err, nil)
+		return
+	}
+	items := make([]domain.ShipmentItem, 0, len(body.Items))
+	for _, item := range body.Items {
+		product, err := domain.FindProduct(products, item.Name)
+		if err != nil {
+			writeDomainError(w, r, err, map[string]string{"name": item.Name})
+			return
+		}
+		items = append(items, domain.ShipmentItem{Product: product, Quantity: item.Quantity})
+	}
+
+	shipment, err := domain.CheckOut(items, provider, domain.PricingOptions{Code: strings.TrimSpace(body.Code), Destination: body.Destination}, storage.SaveCurrentDiscountCodes)
+	if err != nil {
+		logs.Logs(3, "Failed to check out: "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"provider": provider})
+		return
+	}
+
+	order := v2Presenter{}.present(shipment.Order).(v2Product)
+	resp := checkoutResponse{
+		Lines:                 make([]checkoutLine, len(shipment.Lines)),
+		ProductPrice:          order.ProductPrice,
+		DeliveryPrice:         order.DeliveryPrice,
+		TotalPrice:            order.TotalPrice,
+		DeliveryService:       order.DeliveryService,
+		ChargeableWeight:      order.ChargeableWeight,
+		WeightBasis:           order.WeightBasis,
+		Zone:                  order.Zone,
+		DeliveryOptions:       order.DeliveryOptions,
+		OriginalProductPrice:  order.OriginalProductPrice,
+		OriginalDeliveryPrice: order.OriginalDeliveryPrice,
+		Promotions:            order.Promotions,
+	}
+	for i, line := range shipment.Lines {
+		resp.Lines[i] = checkoutLine{Name: line.Name, Quantity: line.Quantity, ProductPrice: line.ProductPrice}
+	}
+	logs.Logs(1, "checked out an order with total price: "+resp.TotalPrice, provider)
+	writeJSON(w, http.StatusOK, resp)
+}
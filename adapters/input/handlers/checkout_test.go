@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestCheckout tests pricing an order at checkout and rejecting orders that cannot be priced
+func TestCheckout(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedCode  int
+		errorCode     string
+		expectedTotal string
+	}{
+		{name: "one line", body: `{"items": [{"name": "tv", "quantity": 2}]}`, expectedCode: http.StatusOK, expectedTotal: "46.00"},
+		{name: "provider and destination", body: `{"items": [{"name": "TV", "quantity": 1}], "provider": "dhl", "destination": {"country": "GB", "postcode": "SW1A 1AA"}}`, expectedCode: http.StatusOK, expectedTotal: "23.00"},
+		{name: "not JSON", body: `items`, expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "unknown field", body: `{"items": [], "coupon": "SAVE10"}`, expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "no items", body: `{"items": []}`, expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "zero quantity", body: `{"items": [{"name": "tv"}]}`, expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "unknown product", body: `{"items": [{"name": "Fridge", "quantity": 1}]}`, expectedCode: http.StatusNotFound, errorCode: codeProductNotFound},
+		{name: "unknown code", body: `{"items": [{"name": "tv", "quantity": 1}], "code": "NOPE"}`, expectedCode: http.StatusBadRequest, errorCode: codeDiscountRejected},
+		{name: "unknown provider", body: `{"items": [{"name": "tv", "quantity": 1}], "provider": "fedex"}`, expectedCode: http.StatusBadRequest, errorCode: codeInvalidProvider},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/checkout", "", tc.body)
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedCode, resp.StatusCode, body)
+			}
+			if tc.expectedCode != http.StatusOK {
+				var errResp ErrorResponse
+				if json.Unmarshal(body, &errResp); errResp.Code != tc.errorCode {
+					t.Errorf("expected %s, got %+v", tc.errorCode, errResp)
+				}
+				return
+			}
+
+			var order checkoutResponse
+			if err := json.Unmarshal(body, &order); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if order.TotalPrice != tc.expectedTotal || len(order.Lines) != 1 || order.Lines[0].Name != "TV" {
+				t.Errorf("unexpected order %s", body)
+			}
+			if resp.Header.Get("Cache-Control") != "no-store" {
+				t.Errorf("expected a checkout not to be cached, got %q", resp.Header.Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+// TestCheckoutSaveFails tests that a checkout fails, and its code is not redeemed, if the redemption cannot be saved
+func TestCheckoutSaveFails(t *testing.T) {
+	useDiscountCodes(t, domain.DiscountCode{Code: "SAVE10", Kind: domain.DiscountPercent, Value: 10, UsageLimit: 1})
+	_, ts := newTestServer(t)
+	storage.SaveDiscountCodesFunc = func([]domain.DiscountCode) error { return errors.New("disk full") }
+
+	resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/checkout", "", `{"items": [{"name": "tv", "quantity": 1}], "code": "save10"}`)
+	var errResp ErrorResponse
+	if json.Unmarshal(body, &errResp); resp.StatusCode != http.StatusServiceUnavailable || errResp.Code != codeStorageUnavailable {
+		t.Fatalf("expected 503 %s, got %d: %s", codeStorageUnavailable, resp.StatusCode, body)
+	}
+	if redemptions := domain.DiscountCodes()[0].Redemptions; redemptions != 0 {
+		t.Errorf("expected the redemption to be rolled back, got %d", redemptions)
+	}
+}
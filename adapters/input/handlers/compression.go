@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// defaultCompressionMinSize is the smallest response body worth compressing, in bytes.
+const defaultCompressionMinSize = 1024
+
+// encoder is a streaming compressor such as *gzip.Writer or *brotli.Writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// contentEncoding is a supported Content-Encoding with a pool of reusable encoders.
+type contentEncoding struct {
+	name string
+	pool *sync.Pool
+}
+
+// supportedEncodings are listed in order of server preference, used to break ties between equal q-values.
+var supportedEncodings = []contentEncoding{
+	{name: "br", pool: &sync.Pool{New: func() any { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }}},
+	{name: "gzip", pool: &sync.Pool{New: func() any { return gzip.NewWriter(nil) }}},
+}
+
+/*
+negotiateEncoding picks the content encoding to use from an Accept-Encoding header.
+The encoding with the highest q-value wins, ties go to the server's preference, and
+encodings given q=0 are never used. It returns nil if the response should not be compressed.
+*/
+func negotiateEncoding(acceptEncoding string) *contentEncoding {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	var best *contentEncoding
+	bestQ := 0.0
+	for i := range supportedEncodings {
+		q, ok := weights[supportedEncodings[i].name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = &supportedEncodings[i], q
+		}
+	}
+	return best
+}
+
+/*
+CompressionMiddleware compresses response bodies according to the request's Accept-Encoding,
+using brotli or gzip. Bodies smaller than COMPRESSION_MIN_SIZE bytes (default 1024) and types
+that do not compress well are sent as they are. Responses always carry Vary: Accept-Encoding
+so caches keep the encodings apart, and a compressed response's ETag gets an encoding suffix.
+*/
+func CompressionMiddleware(next http.Handler) http.Handler {
+	minSize := env.Int("COMPRESSION_MIN_SIZE", defaultCompressionMinSize)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, ifNoneMatch: r.Header.Get("If-None-Match")}
+		next.ServeHTTP(cw, r)
+		// not deferred: after a panic the buffered body is dropped so the recovery middleware can write its error
+		cw.Close()
+	})
+}
+
+/*
+compressWriter buffers the start of a response until it knows whether the body is large enough
+to compress, then either streams it through an encoder or writes it unchanged.
+*/
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    *contentEncoding
+	minSize     int
+	ifNoneMatch string
+
+	status  int
+	buf     []byte
+	started bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.started || cw.status != 0 {
+		return
+	}
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	cw.status = status
+	// a 304 must repeat the ETag the client cached, which may have been the compressed one
+	if status == http.StatusNotModified {
+		if etag := withEncodingSuffix(cw.Header().Get("ETag"), cw.encoding.name); strings.Contains(cw.ifNoneMatch, etag) {
+			cw.Header().Set("ETag", etag)
+		}
+	}
+	// responses without a body, or already encoded, are passed straight through
+	if status == http.StatusNoContent || status == http.StatusNotModified || cw.Header().Get("Content-Encoding") != "" {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.started {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) >= cw.minSize {
+			if err := cw.start(cw.compressible()); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data, deciding on compression early if needed.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(len(cw.buf) >= cw.minSize && cw.compressible())
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the response, writing out a short buffered body uncompressed.
+func (cw *compressWriter) Close() error {
+	if !cw.started {
+		if cw.status == 0 {
+			return nil // the handler wrote nothing, let net/http send its default response
+		}
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	cw.encoding.pool.Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// start writes the headers and any buffered body, compressing from here on if compress is true.
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	header := cw.Header()
+
+	if compress {
+		header.Set("Content-Encoding", cw.encoding.name)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", withEncodingSuffix(etag, cw.encoding.name))
+		}
+		cw.enc = cw.encoding.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible reports whether the response's content type is worth compressing.
+func (cw *compressWriter) compressible() bool {
+	contentType := cw.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false // events must reach the client as soon as they are flushed
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+// withEncodingSuffix marks an ETag as belonging to an encoded representation, e.g. "abc" becomes "abc-gzip".
+func withEncodingSuffix(etag string, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// withoutEncodingSuffix removes a suffix added by withEncodingSuffix, so any representation's ETag validates.
+func withoutEncodingSuffix(etag string) string {
+	for _, encoding := range supportedEncodings {
+		if trimmed, ok := strings.CutSuffix(etag, "-"+encoding.name+`"`); ok {
+			return trimmed + `"`
+		}
+	}
+	return etag
+}
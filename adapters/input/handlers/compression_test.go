@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// TestNegotiateEncoding tests choosing an encoding from Accept-Encoding
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{header: "", expected: ""},
+		{header: "identity", expected: ""},
+		{header: "gzip", expected: "gzip"},
+		{header: "gzip, deflate, br", expected: "br"},
+		{header: "br;q=0.5, gzip;q=0.8", expected: "gzip"},
+		{header: "br;q=0, gzip", expected: "gzip"},
+		{header: "*", expected: "br"},
+		{header: "*;q=0.5, br;q=0", expected: "gzip"},
+	}
+
+	for _, tc := range tests {
+		got := ""
+		if encoding := negotiateEncoding(tc.header); encoding != nil {
+			got = encoding.name
+		}
+		if got != tc.expected {
+			t.Errorf("Accept-Encoding %q: expected %q, got %q", tc.header, tc.expected, got)
+		}
+	}
+}
+
+// TestCompressionMiddleware tests compressing large bodies and passing small ones through
+func TestCompressionMiddleware(t *testing.T) {
+	large := "[" + strings.Repeat(`{"name":"Item","total_price":"10.00"},`, 100) + "{}]"
+
+	handler := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"abc"`)
+		body := large
+		if r.URL.Query().Get("size") == "small" {
+			body = "[]"
+		}
+		// write in pieces, as json.NewEncoder would stream it
+		for len(body) > 0 {
+			n := min(100, len(body))
+			io.WriteString(w, body[:n])
+			body = body[n:]
+		}
+	}))
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedETag     string
+		expectedBody     string
+	}{
+		{name: "gzip", path: "/products", acceptEncoding: "gzip", expectedEncoding: "gzip", expectedETag: `"abc-gzip"`, expectedBody: large},
+		{name: "brotli", path: "/products", acceptEncoding: "gzip, br", expectedEncoding: "br", expectedETag: `"abc-br"`, expectedBody: large},
+		{name: "not accepted", path: "/products", expectedETag: `"abc"`, expectedBody: large},
+		{name: "below minimum size", path: "/products?size=small", acceptEncoding: "gzip", expectedETag: `"abc"`, expectedBody: "[]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tc.expectedEncoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tc.expectedEncoding, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary Accept-Encoding, got %q", got)
+			}
+			if got := w.Header().Get("ETag"); got != tc.expectedETag {
+				t.Errorf("expected ETag %q, got %q", tc.expectedETag, got)
+			}
+
+			var reader io.Reader = w.Body
+			switch tc.expectedEncoding {
+			case "gzip":
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				reader = gz
+			case "br":
+				reader = brotli.NewReader(w.Body)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(body) != tc.expectedBody {
+				t.Errorf("unexpected body after decoding: %q", string(body))
+			}
+		})
+	}
+}
+
+// TestCompressedConditionalGet tests that a compressed ETag still produces a 304
+func TestCompressedConditionalGet(t *testing.T) {
+	t.Setenv("COMPRESSION_MIN_SIZE", "1")
+	_, ts := newTestServer(t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/products", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if resp.Header.Get("Content-Encoding") != "gzip" || !strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("expected a gzip response with a suffixed ETag, got %q %q", resp.Header.Get("Content-Encoding"), etag)
+	}
+
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("ETag"); got != etag {
+		t.Errorf("expected 304 to repeat ETag %q, got %q", etag, got)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// ServerConfig holds the settings for the underlying http.Server and its listeners.
+type ServerConfig struct {
+	Port              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// RedirectPort, if set while TLS is enabled, runs a plain HTTP listener that redirects to HTTPS.
+	RedirectPort string
+}
+
+/*
+LoadServerConfig reads the server configuration from environment variables,
+falling back to safe defaults so a slow or idle client cannot hold a connection forever.
+*/
+func LoadServerConfig() ServerConfig {
+	port := os.Getenv("APP_PORT") // get application port from environment variable
+
+	// if application port missing from env, default to 8080
+	if port == "" {
+		logs.Logs(2, "APP_PORT environment variable not set, defaulting to port 8080", "")
+		port = "8080" // default port
+	}
+
+	return ServerConfig{
+		Port:              port,
+		ReadHeaderTimeout: env.Duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       env.Duration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      env.Duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       env.Duration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		MaxHeaderBytes:    env.Int("HTTP_MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
+		TLSCertFile:       os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:        os.Getenv("TLS_KEY_FILE"),
+		RedirectPort:      os.Getenv("HTTP_REDIRECT_PORT"),
+	}
+}
+
+// TLSEnabled reports whether both a certificate and key file have been configured.
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// newHTTPServer creates an http.Server for handler using the timeouts and limits in cfg.
+func newHTTPServer(cfg ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+/*
+CORSConfig controls which browser origins may call a route. Allowed origins are either exact
+("https://shop.example.com"), a wildcard subdomain ("https://*.example.com") or "*" for any origin.
+Credentials are only ever allowed for origins matched exactly or by a wildcard subdomain, never "*".
+*/
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+/*
+LoadCORSConfig reads the CORS settings from the environment. Lists are comma separated.
+With no CORS_ALLOWED_ORIGINS set, no cross-origin requests are allowed.
+*/
+func LoadCORSConfig() CORSConfig {
+	cfg := CORSConfig{
+		AllowedOrigins:   splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods:   splitListOr(os.Getenv("CORS_ALLOWED_METHODS"), []string{http.MethodGet, http.MethodHead}),
+		AllowedHeaders:   splitListOr(os.Getenv("CORS_ALLOWED_HEADERS"), []string{"Authorization", "Content-Type", "X-API-Key", "X-Request-ID"}),
+		ExposedHeaders:   splitListOr(os.Getenv("CORS_EXPOSED_HEADERS"), []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Deprecation", "Sunset", "Link", "Pricing-Version", "Pricing-As-Of"}),
+		AllowCredentials: strings.EqualFold(os.Getenv("CORS_ALLOW_CREDENTIALS"), "true"),
+		MaxAge:           env.Duration("CORS_MAX_AGE", 10*time.Minute),
+	}
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		logs.Logs(2, "CORS_ALLOW_CREDENTIALS is ignored for origins only matched by \"*\"; list the origins that need credentials", "")
+	}
+	return cfg
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitListOr splits a comma separated list, returning fallback if it is empty.
+func splitListOr(value string, fallback []string) []string {
+	if items := splitList(value); len(items) > 0 {
+		return items
+	}
+	return fallback
+}
+
+/*
+matchOrigin reports whether origin matches one of the allowed origins, and whether it was only
+matched by "*". A wildcard such as "https://*.example.com" matches any subdomain with the same
+scheme and port, but not the bare domain.
+*/
+func (c CORSConfig) matchOrigin(origin string) (allowed, anyOrigin bool) {
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true, false
+		}
+
+		scheme, pattern, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Scheme, scheme) {
+			continue
+		}
+		suffix := "." + strings.ToLower(pattern)
+		if strings.HasSuffix(strings.ToLower(u.Host), suffix) && len(u.Host) > len(suffix) {
+			return true, false
+		}
+	}
+	if slices.Contains(c.AllowedOrigins, "*") {
+		return true, true
+	}
+	return false, false
+}
+
+/*
+corsMiddleware applies the CORS policy to a route. Preflight OPTIONS requests are answered
+directly; other requests get the Access-Control-Allow-Origin header when their origin is allowed.
+Requests from disallowed origins are logged and served without CORS headers, so the browser blocks them.
+*/
+func corsMiddleware(cfg CORSConfig) Middleware {
+	allowMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			w.Header().Add("Vary", "Origin")
+
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, anyOrigin := cfg.matchOrigin(origin)
+			if !allowed {
+				logs.Logs(2, "CORS request from disallowed origin "+origin+" to "+r.URL.Path, "")
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// an origin only matched by "*" never gets credentials, or any site could read a user's responses
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestOriginAllowed tests exact and wildcard subdomain origin matching
+func TestOriginAllowed(t *testing.T) {
+	cfg := CORSConfig{AllowedOrigins: []string{"https://shop.example.com", "https://*.example.co.uk"}}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://shop.example.com", allowed: true},
+		{origin: "https://SHOP.example.com", allowed: true},
+		{origin: "http://shop.example.com", allowed: false},
+		{origin: "https://evil.com", allowed: false},
+		{origin: "https://www.example.co.uk", allowed: true},
+		{origin: "https://a.b.example.co.uk", allowed: true},
+		{origin: "https://example.co.uk", allowed: false},
+		{origin: "https://notexample.co.uk", allowed: false},
+		{origin: "http://www.example.co.uk", allowed: false},
+	}
+
+	for _, tc := range tests {
+		if got, _ := cfg.matchOrigin(tc.origin); got != tc.allowed {
+			t.Errorf("origin %q: expected allowed %t, got %t", tc.origin, tc.allowed, got)
+		}
+	}
+}
+
+// TestCORSCredentials tests that credentials are only allowed for listed origins, never for "*"
+func TestCORSCredentials(t *testing.T) {
+	cfg := CORSConfig{AllowedOrigins: []string{"https://shop.example.com", "*"}, AllowCredentials: true}
+	handler := corsMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		origin              string
+		expectedAllow       string
+		expectedCredentials string
+	}{
+		{origin: "https://shop.example.com", expectedAllow: "https://shop.example.com", expectedCredentials: "true"},
+		{origin: "https://evil.com", expectedAllow: "*"},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("Origin", tc.origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.expectedAllow {
+			t.Errorf("origin %q: expected Access-Control-Allow-Origin %q, got %q", tc.origin, tc.expectedAllow, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tc.expectedCredentials {
+			t.Errorf("origin %q: expected Access-Control-Allow-Credentials %q, got %q", tc.origin, tc.expectedCredentials, got)
+		}
+	}
+}
+
+// TestCORSRoutes tests preflight and actual requests through the server
+func TestCORSRoutes(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://*.example.com")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	t.Setenv("AUTH_ANONYMOUS_ROLE", "none") // preflights must not need a key
+	t.Setenv("API_KEYS", "web:reader:r")
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		origin        string
+		preflight     bool
+		expectedCode  int
+		expectedAllow string
+	}{
+		{name: "preflight from allowed origin", method: "OPTIONS", path: "/products", origin: "https://www.example.com", preflight: true, expectedCode: http.StatusNoContent, expectedAllow: "https://www.example.com"},
+		{name: "preflight for path parameter route", method: "OPTIONS", path: "/products/TV", origin: "https://www.example.com", preflight: true, expectedCode: http.StatusNoContent, expectedAllow: "https://www.example.com"},
+		{name: "preflight from disallowed origin", method: "OPTIONS", path: "/products", origin: "https://evil.com", preflight: true, expectedCode: http.StatusForbidden},
+		{name: "request from allowed origin", method: "GET", path: "/products", origin: "https://www.example.com", expectedCode: http.StatusOK, expectedAllow: "https://www.example.com"},
+		{name: "request from disallowed origin", method: "GET", path: "/products", origin: "https://evil.com", expectedCode: http.StatusOK},
+		{name: "no CORS on routes without it", method: "GET", path: "/", origin: "https://www.example.com", expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, nil)
+			req.Header.Set("Origin", tc.origin)
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+				req.Header.Set("Access-Control-Request-Headers", "Authorization")
+			} else {
+				req.Header.Set("Authorization", "Bearer web.r")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tc.expectedAllow {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tc.expectedAllow, got)
+			}
+			if tc.preflight && tc.expectedAllow != "" {
+				if resp.Header.Get("Access-Control-Max-Age") != "3600" {
+					t.Errorf("expected max age 3600, got %q", resp.Header.Get("Access-Control-Max-Age"))
+				}
+				if resp.Header.Get("Access-Control-Allow-Methods") == "" {
+					t.Error("expected Access-Control-Allow-Methods on preflight")
+				}
+			}
+		})
+	}
+}
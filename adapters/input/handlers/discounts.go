@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// maxDiscountCodeBodyBytes limits the size of a discount code request.
+const maxDiscountCodeBodyBytes = 16 << 10
+
+// errDiscountCodeNotFound is returned when no discount code in the store matches the requested code.
+var errDiscountCodeNotFound = errors.New("discount code not found")
+
+// discountCodesResponse is the body of GET /v1/discount-codes.
+type discountCodesResponse struct {
+	Codes []domain.DiscountCode `json:"codes"` // sorted by code
+}
+
+/*
+loadDiscountCodes replaces the discount codes with those in storage, which may have been edited by
+hand. If they cannot be read or one is invalid the current codes are kept.
+*/
+func loadDiscountCodes() error {
+	codes, err := storage.LoadDiscountCodesFunc()
+	if err != nil {
+		return err
+	}
+	return domain.SetDiscountCodes(codes)
+}
+
+/*
+resolveDiscountCode returns the discount code given by ?code=, or "" if there is none. A code
+cannot be combined with ?as_of=, as codes are checked against today's store. If the code is empty or
+combined with as_of an error response is written and ok is false.
+*/
+func resolveDiscountCode(w http.ResponseWriter, r *http.Request) (code string, ok bool) {
+	query := r.URL.Query()
+	if !query.Has("code") {
+		return "", true
+	}
+	code = strings.TrimSpace(query.Get("code"))
+	if code == "" {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "code must not be empty", nil)
+		return "", false
+	}
+	if query.Has("as_of") {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "code cannot be combined with as_of", map[string]string{"code": code})
+		return "", false
+	}
+	return code, true
+}
+
+// discountCodesHandler lists the discount codes with their redemption counts.
+func (s *Server) discountCodesHandler(w http.ResponseWriter, r *http.Request) {
+	codes := domain.DiscountCodes()
+	if codes == nil {
+		codes = []domain.DiscountCode{}
+	}
+	writeJSON(w, http.StatusOK, discountCodesResponse{Codes: codes})
+}
+
+// getDiscountCodeHandler returns a single discount code, matched case-insensitively.
+func (s *Server) getDiscountCodeHandler(w http.ResponseWriter, r *http.Request) {
+	code, ok := findDiscountCode(r.PathValue("code"))
+	if !ok {
+		writeDiscountCodeError(w, r, errDiscountCodeNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, code)
+}
+
+/*
+createDiscountCodeHandler adds a discount code to the store and saves it. Codes are stored in
+upper case and start with no redemptions. If the store cannot be saved the code is not added.
+*/
+func (s *Server) createDiscountCodeHandler(w http.ResponseWriter, r *http.Request) {
+	var code domain.DiscountCode
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDiscountCodeBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&code); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Request body must be a JSON object with a code and kind", map[string]string{"reason": err.Error()})
+		return
+	}
+	code.Redemptions = 0
+
+	added, err := domain.AddDiscountCode(code)
+	if err != nil {
+		writeDiscountCodeError(w, r, err)
+		return
+	}
+	if err := storage.SaveCurrentDiscountCodes(); err != nil {
+		domain.RemoveDiscountCode(added.Code)
+		writeDiscountCodeError(w, r, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "discount code "+added.Code+" created by key id "+principal.KeyID, "")
+	s.auditChange(r, audit.Entry{Action: "discount_code.created", Entity: "discount_code:" + added.Code, After: added, Reason: auditReason(r, "")})
+	w.Header().Set("Location", "/v1/discount-codes/"+added.Code)
+	writeJSON(w, http.StatusCreated, added)
+}
+
+// deleteDiscountCodeHandler removes a discount code and saves the store, auditing it with the reason given by ?reason=.
+func (s *Server) deleteDiscountCodeHandler(w http.ResponseWriter, r *http.Request) {
+	removed, ok := domain.RemoveDiscountCode(r.PathValue("code"))
+	if !ok {
+		writeDiscountCodeError(w, r, errDiscountCodeNotFound)
+		return
+	}
+	if err := storage.SaveCurrentDiscountCodes(); err != nil {
+		// put the code back so memory matches the file; if that fails the code is gone until the next reload
+		if _, restoreErr := domain.AddDiscountCode(removed); restoreErr != nil {
+			logs.Logs(3, "failed to restore discount code "+removed.Code+" after a failed delete: "+restoreErr.Error(), "")
+		}
+		writeDiscountCodeError(w, r, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "discount code "+removed.Code+" deleted by key id "+principal.KeyID, "")
+	s.auditChange(r, audit.Entry{Action: "discount_code.deleted", Entity: "discount_code:" + removed.Code, Before: removed, Reason: auditReason(r, "")})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findDiscountCode returns the discount code matching code case-insensitively.
+func findDiscountCode(code string) (domain.DiscountCode, bool) {
+	codes := domain.DiscountCodes()
+	i := slices.IndexFunc(codes, func(c domain.DiscountCode) bool { return strings.EqualFold(c.Code, code) })
+	if i < 0 {
+		return domain.DiscountCode{}, false
+	}
+	return codes[i], true
+}
+
+// writeDiscountCodeError writes the error response for an error changing the discount code store.
+func writeDiscountCodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *domain.ErrInvalidDiscountCode
+	switch {
+	case errors.As(err, &invalid):
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Invalid discount code", map[string]string{"code": invalid.Code, "reason": invalid.Reason})
+	case errors.Is(err, errDiscountCodeNotFound):
+		writeError(w, r, http.StatusNotFound, codeDiscountNotFound, "Discount code not found", map[string]string{"code": r.PathValue("code")})
+	default:
+		logs.Logs(3, "failed to change discount codes: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to save the discount codes", nil)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+/*
+useDiscountCodes loads the given codes from storage instead of DISCOUNT_CODES_FILE until the test
+ends, and returns a function reporting the codes last saved.
+*/
+func useDiscountCodes(t *testing.T, codes ...domain.DiscountCode) func() []domain.DiscountCode {
+	t.Helper()
+	var mu sync.Mutex
+	var saved []domain.DiscountCode
+	originalLoad, originalSave := storage.LoadDiscountCodesFunc, storage.SaveDiscountCodesFunc
+	storage.LoadDiscountCodesFunc = func() ([]domain.DiscountCode, error) { return codes, nil }
+	storage.SaveDiscountCodesFunc = func(codes []domain.DiscountCode) error {
+		mu.Lock()
+		defer mu.Unlock()
+		saved = codes
+		return nil
+	}
+	t.Cleanup(func() {
+		storage.LoadDiscountCodesFunc, storage.SaveDiscountCodesFunc = originalLoad, originalSave
+		domain.SetDiscountCodes(nil)
+	})
+	return func() []domain.DiscountCode {
+		mu.Lock()
+		defer mu.Unlock()
+		return saved
+	}
+}
+
+// TestDiscountCodesAPI tests managing discount codes, quoting them on GETs and redeeming them at checkout
+func TestDiscountCodesAPI(t *testing.T) {
+	t.Setenv("API_KEYS", "web:reader:r,cat:merchandiser:m,ops:admin:a")
+	saved := useDiscountCodes(t, domain.DiscountCode{Code: "DPDONLY", Kind: domain.DiscountPercent, Value: 5, Providers: []string{"DPD"}})
+	_, ts := newTestServer(t)
+
+	if resp, _ := scheduleRequest(t, "GET", ts.URL+"/v1/discount-codes", "web.r", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected readers not to see discount codes, got %d", resp.StatusCode)
+	}
+	resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/discount-codes?reason=winter+sale", "cat.m", `{"code": "save10", "kind": "percent", "value": 10, "usage_limit": 1, "redemptions": 5}`)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/v1/discount-codes/SAVE10" {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := scheduleRequest(t, "POST", ts.URL+"/v1/discount-codes", "cat.m", `{"code": "Save10", "kind": "fixed", "value": 1}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a duplicate code to be rejected, got %d", resp.StatusCode)
+	}
+	if codes := saved(); len(codes) != 2 || codes[1].Code != "SAVE10" || codes[1].Redemptions != 0 {
+		t.Errorf("expected the new code to be saved with no redemptions, got %+v", codes)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		errorCode    string
+		reason       string
+	}{
+		{name: "empty code", query: "?code=", expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "code with as_of", query: "?code=save10&as_of=2020-01-01T00:00:00Z", expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "unknown code", query: "?code=nope", expectedCode: http.StatusBadRequest, errorCode: codeDiscountRejected, reason: "unknown"},
+		{name: "wrong provider", query: "?code=dpdonly", expectedCode: http.StatusBadRequest, errorCode: codeDiscountRejected, reason: "wrong_provider"},
+		{name: "quoted", query: "?code=save10", expectedCode: http.StatusOK},
+		{name: "quoted again", query: "?code=save10", expectedCode: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/v2/products/tv" + tc.query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+			if tc.expectedCode != http.StatusOK {
+				errResp := decodeError(t, resp)
+				if errResp.Code != tc.errorCode || errResp.Details["reason"] != tc.reason && tc.reason != "" {
+					t.Errorf("expected %s %s, got %+v", tc.errorCode, tc.reason, errResp)
+				}
+				return
+			}
+
+			var product domain.PricedProduct
+			json.NewDecoder(resp.Body).Decode(&product)
+			if product.TotalPrice != "21.00" || len(product.Promotions) != 1 || product.Promotions[0].ID != "SAVE10" || !product.Promotions[0].DiscountCode {
+				t.Errorf("expected 10%% off the TV, got %+v", product)
+			}
+			if resp.Header.Get("ETag") != "" || resp.Header.Get("Cache-Control") != "no-store" {
+				t.Errorf("expected a price with a code not to be cached, got ETag %q Cache-Control %q", resp.Header.Get("ETag"), resp.Header.Get("Cache-Control"))
+			}
+		})
+	}
+
+	redemptions := func() int {
+		t.Helper()
+		resp, body := scheduleRequest(t, "GET", ts.URL+"/v1/discount-codes/save10", "cat.m", "")
+		var code domain.DiscountCode
+		json.Unmarshal(body, &code)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+		}
+		return code.Redemptions
+	}
+	if n := redemptions(); n != 0 {
+		t.Errorf("expected repeated GETs not to redeem the code, got %d redemptions", n)
+	}
+
+	resp, body = scheduleRequest(t, "POST", ts.URL+"/v1/checkout", "web.r", `{"items": [{"name": "tv", "quantity": 1}], "code": "save10"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if n := redemptions(); n != 1 {
+		t.Errorf("expected checking out to redeem the code once, got %d redemptions", n)
+	}
+	if codes := saved(); len(codes) != 2 || codes[1].Redemptions != 1 {
+		t.Errorf("expected the redemption to be saved, got %+v", codes)
+	}
+	if resp, _ := http.Get(ts.URL + "/v2/products/tv?code=save10"); resp.StatusCode != http.StatusBadRequest || decodeError(t, resp).Details["reason"] != "exhausted" {
+		t.Errorf("expected the redeemed code to be exhausted, got %d", resp.StatusCode)
+	}
+
+	if resp, _ := scheduleRequest(t, "DELETE", ts.URL+"/v1/discount-codes/save10?reason=sale+over", "cat.m", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if resp, _ := scheduleRequest(t, "DELETE", ts.URL+"/v1/discount-codes/save10", "cat.m", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted code, got %d", resp.StatusCode)
+	}
+	records := auditRecords(t, ts.URL+"/audit?entity=discount_code")
+	if len(records) != 2 || records[0].Action != "discount_code.deleted" || records[0].Reason != "sale over" || records[1].Action != "discount_code.created" || records[1].Actor != "cat" {
+		t.Errorf("expected the code's creation and deletion to be audited, got %+v", records)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/apierror"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// Error codes returned in the "code" field of an ErrorResponse.
+const (
+	codeInvalidProvider    = apierror.CodeInvalidProvider
+	codeProviderNotSet     = "provider_not_configured"
+	codeProviderPriceUnset = apierror.CodeProviderPriceUnset
+	codeProviderPriceBad   = apierror.CodeProviderPriceBad
+	codeInvalidProduct     = apierror.CodeInvalidProduct
+	codeProductNotFound    = apierror.CodeProductNotFound
+	codeStorageUnavailable = apierror.CodeStorageUnavailable
+	codeNotFound           = "not_found"
+	codeMethodNotAllowed   = "method_not_allowed"
+	codeTimeout            = apierror.CodeTimeout
+	codeUnauthorized       = "unauthorized"
+	codeForbidden          = "forbidden"
+	codeRateLimited        = "rate_limited"
+	codeNotAcceptable      = "not_acceptable"
+	codeInvalidRequest     = apierror.CodeInvalidRequest
+	codeQueryTooComplex    = "query_too_complex"
+	codeWebhookNotFound    = "webhook_not_found"
+	codeDeliveryNotFound   = "delivery_not_found"
+	codeScheduleNotFound   = "schedule_change_not_found"
+	codeScheduleApplied    = "schedule_change_applied"
+	codeNoPriceHistory     = apierror.CodeNoPriceHistory
+	codeDiscountRejected   = apierror.CodeDiscountRejected
+	codeDiscountNotFound   = "discount_code_not_found"
+	codeInvalidDestination = apierror.CodeInvalidDestination
+	codeZoneNotServed      = apierror.CodeZoneNotServed
+	codeInternal           = apierror.CodeInternal
+)
+
+// ErrorResponse is the JSON envelope returned for every failed request.
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id"`
+}
+
+/*
+writeError writes an ErrorResponse with the given status code.
+The request ID is taken from the request so the client can quote it when reporting a problem.
+*/
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string, details map[string]string) {
+	response := ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestID(w, r),
+	}
+
+	// errors must not be cached or revalidated as if they were the priced response
+	w.Header().Del("ETag")
+	w.Header().Del("Last-Modified")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logs.Logs(3, "Failed to write error response: "+err.Error(), "")
+	}
+}
+
+/*
+writeDomainError maps an error returned by the domain or storage layers to an HTTP status
+and error code, then writes it as an ErrorResponse. Errors we do not recognise are
+reported as a 500 without leaking their text to the client.
+*/
+func writeDomainError(w http.ResponseWriter, r *http.Request, err error, details map[string]string) {
+	failure := apierror.Classify(err, details)
+	writeError(w, r, failure.Status, failure.Code, failure.Message, failure.Details)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// responseFormat is an output format for priced products.
+type responseFormat struct {
+	name        string   // value accepted by ?format=
+	contentType string   // Content-Type sent with the response
+	mediaTypes  []string // media types in an Accept header that select this format
+	writeList   func(w io.Writer, p presenter, products []domain.PricedProduct) error
+	writeOne    func(w io.Writer, p presenter, product domain.PricedProduct) error
+}
+
+// responseFormats lists the supported formats, the first is the default.
+var responseFormats = []*responseFormat{
+	{name: "json", contentType: "application/json", mediaTypes: []string{"application/json"}, writeList: writeJSONProducts, writeOne: writeJSONProduct},
+	{name: "csv", contentType: "text/csv; charset=utf-8", mediaTypes: []string{"text/csv"}, writeList: writeCSVProducts, writeOne: writeCSVProduct},
+	{name: "xml", contentType: "application/xml; charset=utf-8", mediaTypes: []string{"application/xml", "text/xml"}, writeList: writeXMLProducts, writeOne: writeXMLProduct},
+}
+
+/*
+negotiateFormat chooses the output format. A ?format= parameter wins over the Accept header;
+within Accept the highest q-value wins, and wildcards select the default JSON format. It returns
+false if the client only accepts formats we cannot produce.
+*/
+func negotiateFormat(r *http.Request) (*responseFormat, bool) {
+	if name := strings.ToLower(r.URL.Query().Get("format")); name != "" {
+		for _, format := range responseFormats {
+			if format.name == name {
+				return format, true
+			}
+		}
+		return nil, false
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return responseFormats[0], true
+	}
+
+	type acceptedType struct {
+		mediaType string
+		q         float64
+	}
+	var accepted []acceptedType
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			accepted = append(accepted, acceptedType{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	for _, a := range accepted {
+		for _, format := range responseFormats {
+			if formatMatches(format, a.mediaType) {
+				return format, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// formatMatches reports whether an accepted media type, possibly a wildcard, selects format.
+func formatMatches(format *responseFormat, accepted string) bool {
+	if accepted == "*/*" {
+		return format == responseFormats[0]
+	}
+	for _, mediaType := range format.mediaTypes {
+		if mediaType == accepted {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(accepted, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+writeNotAcceptable writes the 406 response for a request whose format cannot be produced,
+listing the formats that can.
+*/
+func writeNotAcceptable(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for _, format := range responseFormats {
+		names = append(names, format.name)
+	}
+	writeError(w, r, http.StatusNotAcceptable, codeNotAcceptable, "Requested format is not supported", map[string]string{"supported_formats": strings.Join(names, ",")})
+}
+
+// writeJSONProducts writes products as a JSON array of the version's DTOs.
+func writeJSONProducts(w io.Writer, p presenter, products []domain.PricedProduct) error {
+	dtos := make([]any, len(products))
+	for i, product := range products {
+		dtos[i] = p.present(product)
+	}
+	return json.NewEncoder(w).Encode(dtos)
+}
+
+// writeJSONProduct writes a single product as a JSON object.
+func writeJSONProduct(w io.Writer, p presenter, product domain.PricedProduct) error {
+	return json.NewEncoder(w).Encode(p.present(product))
+}
+
+// writeCSVProducts writes products as CSV with the version's header row and one record per product.
+func writeCSVProducts(w io.Writer, p presenter, products []domain.PricedProduct) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(p.csvHeader()); err != nil {
+		return err
+	}
+	for _, product := range products {
+		record := p.csvRecord(product)
+		for i, cell := range record {
+			record[i] = escapeCSVCell(cell)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+/*
+escapeCSVCell prefixes a cell that a spreadsheet would read as a formula, one starting with =, +,
+-, @, a tab or a carriage return, with a single quote so it is shown as text. Product names come
+from the catalogue file, so a name such as =HYPERLINK(...) must not run when the export is opened.
+*/
+func escapeCSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// writeCSVProduct writes a single product as CSV, a header row followed by one data row.
+func writeCSVProduct(w io.Writer, p presenter, product domain.PricedProduct) error {
+	return writeCSVProducts(w, p, []domain.PricedProduct{product})
+}
+
+// pricedProductsXML is the root element for XML output, each DTO becomes a <product> element.
+type pricedProductsXML struct {
+	XMLName  xml.Name `xml:"products"`
+	Products []any    `xml:"product"`
+}
+
+// writeXMLProducts writes products as an XML document with a <products> root element.
+func writeXMLProducts(w io.Writer, p presenter, products []domain.PricedProduct) error {
+	doc := pricedProductsXML{Products: make([]any, len(products))}
+	for i, product := range products {
+		doc.Products[i] = p.present(product)
+	}
+	return writeXML(w, doc, xml.StartElement{Name: xml.Name{Local: "products"}})
+}
+
+// writeXMLProduct writes a single product as an XML document with a <product> root element.
+func writeXMLProduct(w io.Writer, p presenter, product domain.PricedProduct) error {
+	return writeXML(w, p.present(product), xml.StartElement{Name: xml.Name{Local: "product"}})
+}
+
+// writeXML writes v as an indented XML document with the given root element.
+func writeXML(w io.Writer, v any, root xml.StartElement) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.EncodeElement(v, root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestNegotiateFormat tests choosing a response format from ?format= and Accept
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		accept   string
+		expected string // empty means not acceptable
+	}{
+		{name: "default", expected: "json"},
+		{name: "wildcard", accept: "*/*", expected: "json"},
+		{name: "csv accept", accept: "text/csv", expected: "csv"},
+		{name: "text xml accept", accept: "text/xml", expected: "xml"},
+		{name: "highest q wins", accept: "application/json;q=0.5, application/xml;q=0.9", expected: "xml"},
+		{name: "unsupported then wildcard", accept: "text/html, */*;q=0.1", expected: "json"},
+		{name: "type wildcard", accept: "text/*", expected: "csv"},
+		{name: "query beats accept", query: "csv", accept: "application/json", expected: "csv"},
+		{name: "query is case-insensitive", query: "XML", expected: "xml"},
+		{name: "unsupported accept", accept: "text/html", expected: ""},
+		{name: "refused json", accept: "application/json;q=0", expected: ""},
+		{name: "unsupported query", query: "yaml", expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/products?format="+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			format, ok := negotiateFormat(req)
+			if tc.expected == "" {
+				if ok {
+					t.Errorf("expected not acceptable, got %q", format.name)
+				}
+				return
+			}
+			if !ok || format.name != tc.expected {
+				t.Errorf("expected %q, got %v (ok %t)", tc.expected, format, ok)
+			}
+		})
+	}
+}
+
+// TestProductFormats tests CSV, XML and 406 responses through the server
+func TestProductFormats(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	get := func(path string, accept string) (*http.Response, string) {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		resp, body := get("/products", "text/csv")
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+			t.Fatalf("expected 200 text/csv, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV: %v", err)
+		}
+		expected := [][]string{v1Presenter{}.csvHeader(), {"TV", "20.00", "3.00", "23.00", "DHL"}}
+		if len(records) != len(expected) || strings.Join(records[0], ",") != strings.Join(expected[0], ",") || strings.Join(records[1], ",") != strings.Join(expected[1], ",") {
+			t.Errorf("expected %v, got %v", expected, records)
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		resp, body := get("/products?format=xml", "")
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/xml") {
+			t.Fatalf("expected 200 application/xml, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		var doc struct {
+			Products []domain.PricedProduct `xml:"product"`
+		}
+		if err := xml.Unmarshal([]byte(body), &doc); err != nil {
+			t.Fatalf("invalid XML: %v", err)
+		}
+		if len(doc.Products) != 1 || doc.Products[0].TotalPrice != "23.00" {
+			t.Errorf("unexpected products %+v", doc.Products)
+		}
+	})
+
+	t.Run("single product xml", func(t *testing.T) {
+		resp, body := get("/products/TV", "application/xml")
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, "<product>") || !strings.Contains(body, "<delivery_price>3.00</delivery_price>") {
+			t.Errorf("unexpected response %d %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		resp, _ := get("/products", "text/html")
+		if resp.StatusCode != http.StatusNotAcceptable {
+			t.Fatalf("expected 406, got %d", resp.StatusCode)
+		}
+		if !strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "Accept") {
+			t.Errorf("expected Vary to include Accept, got %q", resp.Header.Values("Vary"))
+		}
+	})
+
+	t.Run("formats have different etags", func(t *testing.T) {
+		jsonResp, _ := get("/products", "")
+		csvResp, _ := get("/products?format=csv", "")
+		if jsonResp.Header.Get("ETag") == csvResp.Header.Get("ETag") {
+			t.Errorf("expected different ETags per format, both %q", jsonResp.Header.Get("ETag"))
+		}
+	})
+}
+
+// TestWriteCSVProductsEscapesFormulas tests that cells a spreadsheet would run as a formula are written as text
+func TestWriteCSVProductsEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "TV", expected: "TV"},
+		{name: "=HYPERLINK(\"http://evil.com\")", expected: "'=HYPERLINK(\"http://evil.com\")"},
+		{name: "+1", expected: "'+1"},
+		{name: "-1+1", expected: "'-1+1"},
+		{name: "@SUM(A1)", expected: "'@SUM(A1)"},
+		{name: "\tTV", expected: "'\tTV"},
+		{name: "TV = good", expected: "TV = good"},
+	}
+
+	for _, tc := range tests {
+		var buf strings.Builder
+		product := domain.PricedProduct{Name: tc.name, ProductPrice: "20.00", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "DHL"}
+		if err := writeCSVProducts(&buf, v1Presenter{}, []domain.PricedProduct{product}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+		if err != nil || len(records) != 2 {
+			t.Fatalf("invalid CSV %q: %v", buf.String(), err)
+		}
+		if records[1][0] != tc.expected || records[1][1] != "20.00" {
+			t.Errorf("name %q: expected %q, got %v", tc.name, tc.expected, records[1])
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/apierror"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+const (
+	defaultGraphQLPageSize = 20
+	maxGraphQLPageSize     = 100
+	maxGraphQLBodyBytes    = 1 << 20
+)
+
+// graphQLError is a resolver error carrying one of our error codes in its "extensions".
+type graphQLError struct {
+	code    string
+	message string
+}
+
+func (e *graphQLError) Error() string { return e.message }
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// graphQLDomainError converts a domain or storage error into a graphQLError with the same code the REST API uses.
+func graphQLDomainError(err error) error {
+	failure := apierror.Classify(err, nil)
+	return &graphQLError{code: failure.Code, message: failure.Message}
+}
+
+// productNode is the source value of a Product, remembering the page it was returned in for batch pricing.
+type productNode struct {
+	product domain.Product
+	index   int
+	batch   *pricingBatch
+}
+
+/*
+pricingBatch prices the products of one page together. However many products and fields ask
+for a provider's prices, PriceProducts is called at most once per provider for the page.
+*/
+type pricingBatch struct {
+	products []domain.Product
+
+	mu      sync.Mutex
+	results map[string]*batchResult
+}
+
+// batchResult holds one provider's prices for a page, computed once.
+type batchResult struct {
+	once   sync.Once
+	priced []domain.PricedProduct
+	err    error
+}
+
+func newPricingBatch(products []domain.Product) *pricingBatch {
+	return &pricingBatch{products: products, results: make(map[string]*batchResult)}
+}
+
+// price returns the priced product at index in the page, pricing the whole page with provider the first time it is asked.
+func (b *pricingBatch) price(provider string, index int) (domain.PricedProduct, error) {
+	b.mu.Lock()
+	result, ok := b.results[provider]
+	if !ok {
+		result = &batchResult{}
+		b.results[provider] = result
+	}
+	b.mu.Unlock()
+
+	result.once.Do(func() {
+		result.priced, result.err = domain.PriceProductsFunc(b.products, provider)
+		if result.err == nil && len(result.priced) != len(b.products) {
+			result.err = errUnexpectedPricing
+		}
+	})
+	if result.err != nil {
+		return domain.PricedProduct{}, result.err
+	}
+	return result.priced[index], nil
+}
+
+// errUnexpectedPricing is returned when pricing returns a different number of products than it was given.
+var errUnexpectedPricing = &graphQLError{code: codeInternal, message: "Internal server error"}
+
+var pricedProductType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "PricedProduct",
+	Description: "A product's prices with one delivery provider, as two decimal strings.",
+	Fields: graphql.Fields{
+		"productPrice":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: pricedField(func(p domain.PricedProduct) string { return p.ProductPrice })},
+		"deliveryPrice":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: pricedField(func(p domain.PricedProduct) string { return p.DeliveryPrice })},
+		"totalPrice":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: pricedField(func(p domain.PricedProduct) string { return p.TotalPrice })},
+		"deliveryService": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: pricedField(func(p domain.PricedProduct) string { return p.DeliveryService })},
+		"chargeableWeight": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Float),
+			Description: "The weight delivery was charged on, the greater of the actual and volumetric weights.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(domain.PricedProduct).ChargeableWeight, nil
+			},
+		},
+		"weightBasis": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Whether delivery was charged on the actual or volumetric weight.",
+			Resolve:     pricedField(func(p domain.PricedProduct) string { return string(p.WeightBasis) }),
+		},
+		"originalProductPrice": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The product price before promotions, null if none applied.",
+			Resolve:     optionalPricedField(func(p domain.PricedProduct) string { return p.OriginalProductPrice }),
+		},
+		"originalDeliveryPrice": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The delivery price before promotions, null if none applied.",
+			Resolve:     optionalPricedField(func(p domain.PricedProduct) string { return p.OriginalDeliveryPrice }),
+		},
+		"promotions": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(appliedPromotionType))),
+			Description: "The promotions that took money off, in the order they were applied.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if promotions := p.Source.(domain.PricedProduct).Promotions; promotions != nil {
+					return promotions, nil
+				}
+				return []domain.AppliedPromotion{}, nil
+			},
+		},
+		"deliveryOptions": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(deliveryOptionType))),
+			Description: "The provider's service levels the product can be delivered with, empty if it has none.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if options := p.Source.(domain.PricedProduct).DeliveryOptions; options != nil {
+					return options, nil
+				}
+				return []domain.DeliveryOption{}, nil
+			},
+		},
+	},
+})
+
+var deliveryOptionType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "DeliveryOption",
+	Description: "A delivery service level, its prices and the date it is expected to arrive.",
+	Fields: graphql.Fields{
+		"service":           &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: deliveryOptionField(func(o domain.DeliveryOption) string { return string(o.Service) })},
+		"deliveryPrice":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: deliveryOptionField(func(o domain.DeliveryOption) string { return o.DeliveryPrice })},
+		"totalPrice":        &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: deliveryOptionField(func(o domain.DeliveryOption) string { return o.TotalPrice })},
+		"estimatedDelivery": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: deliveryOptionField(func(o domain.DeliveryOption) string { return o.EstimatedDelivery })},
+		"deliverBy":         &graphql.Field{Type: graphql.String, Resolve: deliveryOptionField(func(o domain.DeliveryOption) string { return o.DeliverBy })},
+	},
+})
+
+var appliedPromotionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AppliedPromotion",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: appliedPromotionField(func(p domain.AppliedPromotion) string { return p.ID })},
+		"name":     &graphql.Field{Type: graphql.String, Resolve: appliedPromotionField(func(p domain.AppliedPromotion) string { return p.Name })},
+		"target":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: appliedPromotionField(func(p domain.AppliedPromotion) string { return string(p.Target) })},
+		"discount": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: appliedPromotionField(func(p domain.AppliedPromotion) string { return p.Discount })},
+	},
+})
+
+// pricedField resolves a field of a PricedProduct.
+func pricedField(get func(domain.PricedProduct) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(domain.PricedProduct)), nil
+	}
+}
+
+// optionalPricedField resolves a field of a PricedProduct that is null when empty.
+func optionalPricedField(get func(domain.PricedProduct) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if value := get(p.Source.(domain.PricedProduct)); value != "" {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+// appliedPromotionField resolves a field of an AppliedPromotion, null when empty.
+func appliedPromotionField(get func(domain.AppliedPromotion) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if value := get(p.Source.(domain.AppliedPromotion)); value != "" {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+// deliveryOptionField resolves a field of a DeliveryOption, null when empty.
+func deliveryOptionField(get func(domain.DeliveryOption) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if value := get(p.Source.(domain.DeliveryOption)); value != "" {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+var productType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"name":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: productField(func(p domain.Product) interface{} { return p.Name })},
+		"weight": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: productField(func(p domain.Product) interface{} { return p.Weight })},
+		"price":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: productField(func(p domain.Product) interface{} { return p.Price })},
+		"length": &graphql.Field{Type: graphql.Float, Description: "Null if the product has no dimensions.", Resolve: productDimension(func(p domain.Product) float64 { return p.Length })},
+		"width":  &graphql.Field{Type: graphql.Float, Description: "Null if the product has no dimensions.", Resolve: productDimension(func(p domain.Product) float64 { return p.Width })},
+		"height": &graphql.Field{Type: graphql.Float, Description: "Null if the product has no dimensions.", Resolve: productDimension(func(p domain.Product) float64 { return p.Height })},
+		"pricing": &graphql.Field{
+			Type:        pricedProductType,
+			Description: "Prices with a delivery provider, defaulting to DELIVERY_PROVIDER. Every product on the page is priced in one batch per provider.",
+			Args: graphql.FieldConfigArgument{
+				"provider": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: resolvePricing,
+		},
+	},
+})
+
+// productField resolves a plain field of a Product.
+func productField(get func(domain.Product) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(productNode).product), nil
+	}
+}
+
+// productDimension resolves one of a product's dimensions, null when it has none.
+func productDimension(get func(domain.Product) float64) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if value := get(p.Source.(productNode).product); value > 0 {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+var productPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductPage",
+	Fields: graphql.Fields{
+		"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType)))},
+		"totalCount":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Products matching the filter, across all pages."},
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var productFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"nameContains": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Case-insensitive substring of the name."},
+		"minPrice":     &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"maxPrice":     &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"minWeight":    &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		"maxWeight":    &graphql.InputObjectFieldConfig{Type: graphql.Float},
+	},
+})
+
+var productSortType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductSort",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{
+			Name: "ProductSortField",
+			Values: graphql.EnumValueConfigMap{
+				"NAME":   &graphql.EnumValueConfig{Value: "name"},
+				"PRICE":  &graphql.EnumValueConfig{Value: "price"},
+				"WEIGHT": &graphql.EnumValueConfig{Value: "weight"},
+			},
+		}))},
+		"descending": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false},
+	},
+})
+
+var pageInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PageInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"first":  &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: defaultGraphQLPageSize, Description: "Page size, at most 100."},
+		"offset": &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 0},
+	},
+})
+
+// graphQLSchema is the schema served at /graphql.
+var graphQLSchema = func() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"products": &graphql.Field{
+					Type: graphql.NewNonNull(productPageType),
+					Args: graphql.FieldConfigArgument{
+						"filter": &graphql.ArgumentConfig{Type: productFilterType},
+						"sort":   &graphql.ArgumentConfig{Type: productSortType},
+						"page":   &graphql.ArgumentConfig{Type: pageInputType},
+					},
+					Resolve: resolveProducts,
+				},
+				"providers": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+					Description: "The supported delivery providers.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return domain.Providers(), nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		panic("invalid GraphQL schema: " + err.Error())
+	}
+	return schema
+}()
+
+// resolveProducts loads the catalogue, then filters, sorts and pages it.
+func resolveProducts(p graphql.ResolveParams) (interface{}, error) {
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), "")
+		return nil, graphQLDomainError(err)
+	}
+
+	filter, _ := p.Args["filter"].(map[string]interface{})
+	matched := make([]domain.Product, 0, len(products))
+	for _, product := range products {
+		if matchesFilter(product, filter) {
+			matched = append(matched, product)
+		}
+	}
+
+	if sortArg, ok := p.Args["sort"].(map[string]interface{}); ok {
+		sortProducts(matched, sortArg["field"].(string), sortArg["descending"] == true)
+	}
+
+	page, _ := p.Args["page"].(map[string]interface{})
+	first, offset := pageBounds(page)
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	end := min(offset+first, len(matched))
+	pageProducts := matched[offset:end]
+
+	batch := newPricingBatch(pageProducts)
+	items := make([]productNode, len(pageProducts))
+	for i, product := range pageProducts {
+		items[i] = productNode{product: product, index: i, batch: batch}
+	}
+	return map[string]interface{}{
+		"items":       items,
+		"totalCount":  len(matched),
+		"hasNextPage": end < len(matched),
+	}, nil
+}
+
+// matchesFilter reports whether a product passes every condition set in filter.
+func matchesFilter(product domain.Product, filter map[string]interface{}) bool {
+	if name, ok := filter["nameContains"].(string); ok && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(name)) {
+		return false
+	}
+	bounds := []struct {
+		key   string
+		value float64
+		min   bool
+	}{
+		{"minPrice", product.Price, true},
+		{"maxPrice", product.Price, false},
+		{"minWeight", product.Weight, true},
+		{"maxWeight", product.Weight, false},
+	}
+	for _, bound := range bounds {
+		limit, ok := filter[bound.key].(float64)
+		if !ok {
+			continue
+		}
+		if (bound.min && bound.value < limit) || (!bound.min && bound.value > limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortProducts sorts products in place by name, price or weight.
+func sortProducts(products []domain.Product, field string, descending bool) {
+	less := func(a, b domain.Product) bool {
+		switch field {
+		case "price":
+			return a.Price < b.Price
+		case "weight":
+			return a.Weight < b.Weight
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		if descending {
+			return less(products[j], products[i])
+		}
+		return less(products[i], products[j])
+	})
+}
+
+// pageBounds returns the page size and offset from a PageInput, clamped to sensible values.
+func pageBounds(page map[string]interface{}) (first int, offset int) {
+	first = defaultGraphQLPageSize
+	if value, ok := page["first"].(int); ok {
+		first = value
+	}
+	if value, ok := page["offset"].(int); ok {
+		offset = value
+	}
+	return max(0, min(first, maxGraphQLPageSize)), max(0, offset)
+}
+
+// resolvePricing prices a product with the requested or default provider, batched across its page.
+func resolvePricing(p graphql.ResolveParams) (interface{}, error) {
+	node := p.Source.(productNode)
+
+	provider, _ := p.Args["provider"].(string)
+	provider = strings.ToUpper(strings.TrimSpace(provider))
+	if provider == "" {
+		provider = domain.DefaultProvider()
+	}
+	if provider == "" {
+		return nil, &graphQLError{code: codeProviderNotSet, message: "Delivery provider not set"}
+	}
+	if err := p.Context.Err(); err != nil {
+		return nil, graphQLDomainError(err)
+	}
+
+	priced, err := node.batch.price(provider, node.index)
+	if err != nil {
+		if _, ok := err.(*graphQLError); ok {
+			return nil, err
+		}
+		return nil, graphQLDomainError(err)
+	}
+	return priced, nil
+}
+
+// graphQLRequest is the body of a GraphQL request, as sent by standard GraphQL clients.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+/*
+GraphQLHandler serves GraphQL queries sent as a JSON POST body or in GET query parameters.
+Queries are parsed and validated, then rejected before anything runs if they are deeper than
+GRAPHQL_MAX_DEPTH or more complex than GRAPHQL_MAX_COMPLEXITY. Query errors are returned in
+the GraphQL "errors" list with a 200, as GraphQL clients expect.
+*/
+func GraphQLHandler(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "variables must be a JSON object", nil)
+				return
+			}
+		}
+	default:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes)).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Request body must be a JSON GraphQL request", nil)
+			return
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "A GraphQL query is required", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	result := executeGraphQL(r, req)
+	if result.HasErrors() {
+		logs.Logs(2, "GraphQL query returned errors: "+result.Errors[0].Message, "")
+	}
+	err := json.NewEncoder(w).Encode(result)
+	if err != nil {
+		logs.Logs(3, "Failed to write response: "+err.Error(), "")
+	}
+}
+
+// executeGraphQL parses, validates, checks the limits of and runs a query.
+func executeGraphQL(r *http.Request, req graphQLRequest) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&graphQLSchema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	limits := queryLimits{maxDepth: env.Int("GRAPHQL_MAX_DEPTH", defaultMaxQueryDepth), maxComplexity: env.Int("GRAPHQL_MAX_COMPLEXITY", defaultMaxQueryComplexity)}
+	if err := limits.check(doc, req.Variables); err != nil {
+		logs.Logs(2, "GraphQL query rejected: "+err.Error(), "")
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: err.Error(), Extensions: map[string]interface{}{"code": codeQueryTooComplex}}}}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphQLSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       r.Context(),
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	defaultMaxQueryDepth      = 6
+	defaultMaxQueryComplexity = 1000
+
+	// pricingFieldCost is the extra cost of a pricing field, as it may price the page.
+	pricingFieldCost = 2
+
+	// maxCountedComplexity is where complexity stops being counted, far above any sensible limit, so it cannot overflow.
+	maxCountedComplexity = math.MaxInt32
+)
+
+/*
+queryLimits bounds the work a GraphQL query can ask for. Depth counts nested selections; complexity
+counts every field that would be resolved, with the fields inside products multiplied by its page
+size, so asking for a large page of deeply nested data is rejected before anything runs.
+Introspection fields are not counted, as tools such as GraphiQL send deep introspection queries.
+*/
+type queryLimits struct {
+	maxDepth      int
+	maxComplexity int
+}
+
+// check returns an error describing the first limit exceeded by any operation in doc.
+func (l queryLimits) check(doc *ast.Document, variables map[string]interface{}) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		w := queryWalker{fragments: fragments, variables: variables, walked: make(map[string]walkedFragment)}
+		complexity, depth := w.walk(operation.SelectionSet)
+		if l.maxDepth > 0 && depth > l.maxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, l.maxDepth)
+		}
+		if l.maxComplexity > 0 && complexity > l.maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, l.maxComplexity)
+		}
+	}
+	return nil
+}
+
+/*
+queryWalker computes the complexity and depth of a selection set, following fragments. Each
+fragment is walked once and remembered, so fragments spreading other fragments many times cost
+no more to analyse than to write down.
+*/
+type queryWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	walked    map[string]walkedFragment // by fragment name
+}
+
+// walkedFragment is the complexity and depth of a fragment's selection set.
+type walkedFragment struct {
+	complexity int
+	depth      int
+}
+
+/*
+walk returns the complexity and depth of set, counting complexity up to maxCountedComplexity.
+Fragment cycles are rejected by validation before this runs.
+*/
+func (w queryWalker) walk(set *ast.SelectionSet) (complexity int, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			childComplexity, childDepth := w.walk(selection.SelectionSet)
+			c = 1 + min(w.multiplier(selection)*childComplexity, maxCountedComplexity)
+			if selection.Name.Value == "pricing" {
+				c += pricingFieldCost
+			}
+			d = 1 + childDepth
+		case *ast.InlineFragment:
+			c, d = w.walk(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			c, d = w.walkFragment(selection.Name.Value)
+		}
+		complexity = min(complexity+c, maxCountedComplexity)
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+// walkFragment returns the complexity and depth of the named fragment, walking it the first time it is spread.
+func (w queryWalker) walkFragment(name string) (complexity int, depth int) {
+	if walked, ok := w.walked[name]; ok {
+		return walked.complexity, walked.depth
+	}
+	fragment, ok := w.fragments[name]
+	if !ok {
+		return 0, 0
+	}
+	complexity, depth = w.walk(fragment.SelectionSet)
+	w.walked[name] = walkedFragment{complexity: complexity, depth: depth}
+	return complexity, depth
+}
+
+// multiplier returns how many times a field's children are resolved: the page size for products, otherwise once.
+func (w queryWalker) multiplier(field *ast.Field) int {
+	if field.Name.Value != "products" {
+		return 1
+	}
+	var page map[string]interface{}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value == "page" {
+			page, _ = w.value(argument.Value).(map[string]interface{})
+		}
+	}
+	first, _ := pageBounds(page)
+	return max(first, 1)
+}
+
+// value converts an argument value to Go, substituting variables, as far as needed to read page sizes.
+func (w queryWalker) value(v ast.Value) interface{} {
+	switch v := v.(type) {
+	case *ast.Variable:
+		value := w.variables[v.Name.Value]
+		if f, ok := value.(float64); ok {
+			return int(f) // JSON numbers decode as float64
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			converted := make(map[string]interface{}, len(object))
+			for key, field := range object {
+				if f, ok := field.(float64); ok {
+					field = int(f)
+				}
+				converted[key] = field
+			}
+			return converted
+		}
+		return value
+	case *ast.IntValue:
+		n, _ := strconv.Atoi(v.Value)
+		return n
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(v.Fields))
+		for _, field := range v.Fields {
+			object[field.Name.Value] = w.value(field.Value)
+		}
+		return object
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// graphQLResponse is the decoded body of a /graphql response
+type graphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// postGraphQL sends a query to /graphql and decodes the response
+func postGraphQL(t *testing.T, baseURL string, query string, variables map[string]any) graphQLResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	resp, err := http.Post(baseURL+"/graphql", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var result graphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return result
+}
+
+// TestGraphQLProducts tests filtering, sorting, paging and pricing products
+func TestGraphQLProducts(t *testing.T) {
+	_, ts := newTestServer(t)
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{
+			{Name: "TV", Weight: 1.5, Price: 20, Length: 50, Width: 40, Height: 30},
+			{Name: "Radio", Weight: 0.5, Price: 7.5},
+			{Name: "Fridge", Weight: 40, Price: 300},
+		}, nil
+	}
+	os.Setenv("UPS_DELIVERY_PRICE", "1.00")
+	defer os.Unsetenv("UPS_DELIVERY_PRICE")
+	os.Setenv("UPS_VOLUMETRIC_DIVISOR", "5000")
+	defer os.Unsetenv("UPS_VOLUMETRIC_DIVISOR")
+
+	result := postGraphQL(t, ts.URL, `query($max: Float) {
+		products(filter: {maxWeight: $max}, sort: {field: PRICE, descending: true}, page: {first: 1}) {
+			totalCount
+			hasNextPage
+			items {
+				name
+				length
+				dhl: pricing { totalPrice weightBasis }
+				ups: pricing(provider: "ups") { deliveryPrice deliveryService chargeableWeight weightBasis }
+			}
+		}
+	}`, map[string]any{"max": 10})
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", result.Errors)
+	}
+	products := result.Data["products"].(map[string]any)
+	if products["totalCount"] != 2.0 || products["hasNextPage"] != true {
+		t.Errorf("unexpected page info %v", products)
+	}
+	items := products["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %v", items)
+	}
+	item := items[0].(map[string]any)
+	if item["name"] != "TV" || item["length"] != 50.0 || item["dhl"].(map[string]any)["totalPrice"] != "23.00" || item["dhl"].(map[string]any)["weightBasis"] != "actual" {
+		t.Errorf("unexpected item %v", item)
+	}
+	if ups := item["ups"].(map[string]any); ups["deliveryPrice"] != "12.00" || ups["deliveryService"] != "UPS" || ups["chargeableWeight"] != 12.0 || ups["weightBasis"] != "volumetric" {
+		t.Errorf("unexpected UPS pricing %v", ups)
+	}
+}
+
+// TestGraphQLBatchesPricing tests that each provider prices the page once, however many products ask
+func TestGraphQLBatchesPricing(t *testing.T) {
+	_, ts := newTestServer(t)
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "TV", Weight: 1.5, Price: 20}, {Name: "Radio", Weight: 0.5, Price: 7.5}, {Name: "Lamp", Weight: 1, Price: 12}}, nil
+	}
+	os.Setenv("UPS_DELIVERY_PRICE", "1.00")
+	defer os.Unsetenv("UPS_DELIVERY_PRICE")
+
+	originalPriceProductsFunc := domain.PriceProductsFunc
+	calls := map[string]int{}
+	domain.PriceProductsFunc = func(products []domain.Product, provider string) ([]domain.PricedProduct, error) {
+		calls[provider]++
+		return domain.PriceProducts(products, provider)
+	}
+	defer func() { domain.PriceProductsFunc = originalPriceProductsFunc }()
+
+	result := postGraphQL(t, ts.URL, `{ products { items {
+		a: pricing { totalPrice }
+		b: pricing(provider: "DHL") { deliveryPrice }
+		c: pricing(provider: "UPS") { totalPrice }
+	} } }`, nil)
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", result.Errors)
+	}
+	if calls["DHL"] != 1 || calls["UPS"] != 1 || len(calls) != 2 {
+		t.Errorf("expected one pricing call per provider, got %v", calls)
+	}
+}
+
+// TestGraphQLErrors tests resolver, validation and complexity errors
+func TestGraphQLErrors(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode string
+		expectedMsg  string
+	}{
+		{name: "unknown provider", query: `{ products { items { pricing(provider: "FEDEX") { totalPrice } } } }`, expectedCode: codeInvalidProvider},
+		{name: "complexity limit", query: `{ products(page: {first: 100}) { items {
+			a: pricing { totalPrice } b: pricing { totalPrice } c: pricing { totalPrice }
+		} } }`, expectedCode: codeQueryTooComplex, expectedMsg: "complexity"},
+		{name: "syntax error", query: `{ products {`, expectedMsg: "Syntax Error"},
+		{name: "unknown field", query: `{ products { items { colour } } }`, expectedMsg: "Cannot query field"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := postGraphQL(t, ts.URL, tc.query, nil)
+			if len(result.Errors) == 0 {
+				t.Fatalf("expected an error, got data %v", result.Data)
+			}
+			if tc.expectedCode != "" && result.Errors[0].Extensions["code"] != tc.expectedCode {
+				t.Errorf("expected code %q, got %+v", tc.expectedCode, result.Errors[0])
+			}
+			if tc.expectedMsg != "" && !strings.Contains(result.Errors[0].Message, tc.expectedMsg) {
+				t.Errorf("expected message containing %q, got %q", tc.expectedMsg, result.Errors[0].Message)
+			}
+		})
+	}
+}
+
+// TestGraphQLDepthLimit tests that queries nested beyond GRAPHQL_MAX_DEPTH are rejected
+func TestGraphQLDepthLimit(t *testing.T) {
+	t.Setenv("GRAPHQL_MAX_DEPTH", "3")
+	_, ts := newTestServer(t)
+
+	result := postGraphQL(t, ts.URL, `{ products { items { ...P } } } fragment P on Product { pricing { totalPrice } }`, nil)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != codeQueryTooComplex {
+		t.Fatalf("expected a query_too_complex error, got %+v", result.Errors)
+	}
+	if !strings.Contains(result.Errors[0].Message, "depth 4") {
+		t.Errorf("expected the depth in the message, got %q", result.Errors[0].Message)
+	}
+}
+
+// TestGraphQLGet tests queries sent in the query string, and malformed requests
+func TestGraphQLGet(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/graphql?query=" + url.QueryEscape(`{ providers }`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result graphQLResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if providers, _ := result.Data["providers"].([]any); len(providers) != len(domain.Providers()) {
+		t.Errorf("unexpected providers %v", result.Data)
+	}
+
+	resp, err = http.Post(ts.URL+"/graphql", "application/json", strings.NewReader(`not json`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if errResp := decodeError(t, resp); resp.StatusCode != http.StatusBadRequest || errResp.Code != codeInvalidRequest {
+		t.Errorf("expected 400 invalid_request, got %d %q", resp.StatusCode, errResp.Code)
+	}
+}
+
+// TestPageBounds tests clamping page sizes and offsets
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		page           map[string]interface{}
+		expectedFirst  int
+		expectedOffset int
+	}{
+		{page: nil, expectedFirst: defaultGraphQLPageSize},
+		{page: map[string]interface{}{"first": 5, "offset": 10}, expectedFirst: 5, expectedOffset: 10},
+		{page: map[string]interface{}{"first": 1000}, expectedFirst: maxGraphQLPageSize},
+		{page: map[string]interface{}{"first": -1, "offset": -3}},
+	}
+
+	for _, tc := range tests {
+		first, offset := pageBounds(tc.page)
+		if first != tc.expectedFirst || offset != tc.expectedOffset {
+			t.Errorf("pageBounds(%v) = %d, %d, expected %d, %d", tc.page, first, offset, tc.expectedFirst, tc.expectedOffset)
+		}
+	}
+}
+
+// TestQueryLimitsFragments tests that fragments spreading other fragments many times are counted without walking every spread
+func TestQueryLimitsFragments(t *testing.T) {
+	// each fragment spreads the one before it ten times, so the last stands for 10^30 names
+	query := `{ products { items { ...F30 } } } fragment F0 on Product { name }`
+	for i := 1; i <= 30; i++ {
+		query += fmt.Sprintf(" fragment F%d on Product { %s }", i, strings.Repeat(fmt.Sprintf("...F%d ", i-1), 10))
+	}
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query)})})
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		limits        queryLimits
+		expectedError string
+	}{
+		{name: "complexity", limits: queryLimits{maxDepth: 6, maxComplexity: 1000}, expectedError: fmt.Sprintf("query complexity %d exceeds", maxCountedComplexity)},
+		{name: "depth", limits: queryLimits{maxDepth: 2}, expectedError: "query depth 3 exceeds"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- tc.limits.check(doc, nil) }()
+			select {
+			case err := <-done:
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("expected %q, got %v", tc.expectedError, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("analysing the query did not finish")
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+const (
+	// actorSystem is the audit actor for changes the server noticed itself, rather than ones made through the API.
+	actorSystem = "system"
+	// actorScheduler is the audit actor for scheduled changes taking effect.
+	actorScheduler = "scheduler"
+	// reasonObserved is the audit reason for changes noticed by the catalogue watcher, such as an edited catalogue file.
+	reasonObserved = "changed in the catalogue or configuration"
+)
+
+var (
+	// historyMu serialises recording, saving and auditing the price history, so nothing is written out of order.
+	historyMu sync.Mutex
+	// priceAudit is where changes to the price history are audited. Like the history it is shared by the process.
+	priceAudit *audit.Log
+)
+
+// loadPriceHistory replaces the price history with the one in storage.
+func loadPriceHistory() error {
+	history, err := storage.LoadHistoryFunc()
+	if err != nil {
+		return err
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	domain.SetPriceHistory(history)
+	return nil
+}
+
+/*
+recordPrices records the catalogue and the carrier configuration in force at the given time in
+the price history, saving it and auditing each change against actor and reason if anything
+changed. A failed save is logged and retried with the next change, as the history is still kept
+in memory.
+*/
+func recordPrices(at time.Time, products []domain.Product, actor, reason string) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	changes := domain.RecordPrices(at, products)
+	if len(changes) == 0 {
+		return
+	}
+	if err := storage.SaveHistoryFunc(domain.History()); err != nil {
+		logs.Logs(3, "failed to save price history: "+err.Error(), "")
+	}
+	if priceAudit == nil {
+		return
+	}
+	var entries []audit.Entry
+	for _, change := range changes {
+		entityType, _, _ := strings.Cut(change.Entity, ":")
+		if entityType == "promotion" {
+			continue // audited where they are edited or reloaded
+		}
+		entries = append(entries, audit.Entry{Actor: actor, Action: entityType + ".changed", Entity: change.Entity, Before: change.Before, After: change.After, Reason: reason})
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if _, err := priceAudit.Append(entries...); err != nil {
+		logs.Logs(3, "failed to audit price changes: "+err.Error(), "")
+	}
+}
+
+// observePrices reads the catalogue and records it with the configuration in force at the given time.
+func observePrices(at time.Time, actor, reason string) {
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(2, "prices not recorded, catalogue unavailable: "+err.Error(), "")
+		return
+	}
+	recordPrices(at, products, actor, reason)
+}
+
+/*
+catalogueWatcher records the catalogue in the price history every interval, so edits to the
+products file are recorded without requests having to write the history. An edit is dated from
+the file's modification time when the store has one, otherwise from when it was noticed.
+*/
+type catalogueWatcher struct {
+	interval time.Duration
+	modTime  time.Time // modification time of the catalogue at the last check
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// newCatalogueWatcher returns a watcher checking the catalogue every interval. Call run to start it.
+func newCatalogueWatcher(interval time.Duration) *catalogueWatcher {
+	return &catalogueWatcher{interval: interval, stop: make(chan struct{})}
+}
+
+// run checks the catalogue every interval until Close is called. An interval of zero disables the watcher.
+func (cw *catalogueWatcher) run() {
+	if cw.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cw.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cw.check()
+		case <-cw.stop:
+			return
+		}
+	}
+}
+
+// check records the catalogue if it has changed. It is only called from run, and by tests.
+func (cw *catalogueWatcher) check() {
+	at := time.Now()
+	if modTime, err := storage.CatalogueModTimeFunc(); err == nil && modTime.After(cw.modTime) {
+		cw.modTime = modTime
+		if modTime.Before(at) {
+			at = modTime
+		}
+	}
+	observePrices(at, actorSystem, reasonObserved)
+}
+
+// Close stops the watcher. It is safe to call more than once.
+func (cw *catalogueWatcher) Close() {
+	cw.once.Do(func() { close(cw.stop) })
+}
+
+/*
+resolveAsOf reads the ?as_of= query parameter, an RFC 3339 time no later than now, and returns
+the catalogue and configuration in force then. It returns nil if as_of is not given. If it is
+invalid or no prices were recorded by then an error response is written and ok is false.
+*/
+func resolveAsOf(w http.ResponseWriter, r *http.Request) (pricing *domain.HistoricalPricing, ok bool) {
+	if !r.URL.Query().Has("as_of") {
+		return nil, true
+	}
+	value := r.URL.Query().Get("as_of")
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "as_of must be an RFC 3339 time, such as 2026-10-01T12:00:00Z", map[string]string{"as_of": value})
+		return nil, false
+	}
+	if at.After(time.Now()) {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "as_of must not be in the future", map[string]string{"as_of": value})
+		return nil, false
+	}
+
+	historical, err := domain.PricingAt(at)
+	if err != nil {
+		logs.Logs(2, "Historical pricing unavailable: "+err.Error(), "")
+		writeDomainError(w, r, err, map[string]string{"as_of": value})
+		return nil, false
+	}
+	return &historical, true
+}
+
+/*
+resolveHistoricalProvider works out which provider to price historical prices with: ?provider=
+if given, otherwise the default provider at the time. If there was none an error response is
+written and ok is false.
+*/
+func resolveHistoricalProvider(w http.ResponseWriter, r *http.Request, pricing *domain.HistoricalPricing) (provider string, ok bool) {
+	provider = strings.ToUpper(r.URL.Query().Get("provider"))
+	if provider == "" {
+		provider = pricing.DefaultProvider
+	}
+	if provider == "" {
+		logs.Logs(2, "No default provider recorded at "+pricing.At.Format(time.RFC3339), "")
+		writeError(w, r, http.StatusInternalServerError, codeProviderNotSet, "Delivery provider not set at as_of", map[string]string{"as_of": pricing.At.Format(time.RFC3339)})
+		return "", false
+	}
+	return provider, true
+}
+
+/*
+writePricingVersion sets the Pricing-Version header, identifying the catalogue, provider, rate,
+volumetric divisor and promotions a response was priced from, so a price quoted now can be matched with one looked up later.
+Historical responses also get Pricing-As-Of.
+*/
+func writePricingVersion(w http.ResponseWriter, version string, pricing *domain.HistoricalPricing) {
+	if version != "" {
+		w.Header().Set("Pricing-Version", version)
+	}
+	if pricing != nil {
+		w.Header().Set("Pricing-As-Of", pricing.At.Format(time.RFC3339))
+	}
+}
+
+/*
+currentPricingVersion returns the pricing version of products priced with provider now, for the
+zone if one is given, or "" if the provider has no usable rate there.
+*/
+func currentPricingVersion(products []domain.Product, provider string, zone domain.Zone) string {
+	rate, err := domain.ProviderRateIn(provider, zone)
+	if err != nil {
+		return ""
+	}
+	divisor, err := domain.VolumetricDivisor(provider)
+	if err != nil {
+		return ""
+	}
+	return domain.PricingVersion(products, provider, rate, divisor, domain.PromotionsAt(time.Now()), zone)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestAsOf tests pricing the catalogue as it was at an earlier time
+func TestAsOf(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	// the TV cost 18.00 with DHL at 1.00 until the 10th, then 20.00 with DHL at 2.00 as it does now
+	october := func(day int) time.Time { return time.Date(2025, time.October, day, 0, 0, 0, 0, time.UTC) }
+	tenth := october(10)
+	domain.SetPriceHistory(domain.PriceHistory{
+		Products: []domain.ProductVersion{
+			{Product: domain.Product{Name: "TV", Weight: 1.5, Price: 18}, Validity: domain.Validity{ValidFrom: october(1), ValidTo: &tenth}},
+			{Product: domain.Product{Name: "Radio", Weight: 0.5, Price: 7}, Validity: domain.Validity{ValidFrom: october(1), ValidTo: &tenth}},
+			{Product: domain.Product{Name: "TV", Weight: 1.5, Price: 20}, Validity: domain.Validity{ValidFrom: tenth}},
+		},
+		Rates: []domain.RateVersion{
+			{Provider: "DHL", Rate: 1, Validity: domain.Validity{ValidFrom: october(1), ValidTo: &tenth}},
+			{Provider: "DHL", Rate: 2, Validity: domain.Validity{ValidFrom: tenth}},
+		},
+		Providers: []domain.ProviderVersion{{Provider: "DHL", Validity: domain.Validity{ValidFrom: october(1)}}},
+	})
+
+	current, _ := http.Get(ts.URL + "/v1/products")
+	current.Body.Close()
+	currentVersion := current.Header.Get("Pricing-Version")
+	if currentVersion == "" {
+		t.Fatal("expected current prices to have a Pricing-Version")
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedCode    string
+		expectedTotals  []string
+		expectedVersion string
+	}{
+		{name: "before the change", path: "/v1/products?as_of=2025-10-05T12:00:00Z", expectedStatus: http.StatusOK, expectedTotals: []string{"19.50", "7.50"}, expectedVersion: domain.PricingVersion([]domain.Product{{Name: "TV", Weight: 1.5, Price: 18}, {Name: "Radio", Weight: 0.5, Price: 7}}, "DHL", 1, 0, nil, "")},
+		{name: "after the change matches now", path: "/v1/products?as_of=2025-10-15T00:00:00Z", expectedStatus: http.StatusOK, expectedTotals: []string{"23.00"}, expectedVersion: currentVersion},
+		{name: "change is inclusive", path: "/products?as_of=2025-10-10T00:00:00Z", expectedStatus: http.StatusOK, expectedTotals: []string{"23.00"}},
+		{name: "single product", path: "/v1/products/radio?as_of=2025-10-05T12:00:00%2B01:00", expectedStatus: http.StatusOK, expectedTotals: []string{"7.50"}},
+		{name: "product since removed", path: "/v1/products/Radio?as_of=2025-10-12T00:00:00Z", expectedStatus: http.StatusNotFound, expectedCode: codeProductNotFound},
+		{name: "provider without a rate then", path: "/v1/products?as_of=2025-10-05T12:00:00Z&provider=ups", expectedStatus: http.StatusServiceUnavailable, expectedCode: codeProviderPriceUnset},
+		{name: "before any history", path: "/v1/products?as_of=2025-09-01T00:00:00Z", expectedStatus: http.StatusNotFound, expectedCode: codeNoPriceHistory},
+		{name: "not a time", path: "/v1/products?as_of=last-tuesday", expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "empty", path: "/v1/products?as_of=", expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "in the future", path: "/v1/products?as_of=2999-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, resp.StatusCode)
+			}
+			if tc.expectedCode != "" {
+				if errResp := decodeError(t, resp); errResp.Code != tc.expectedCode {
+					t.Errorf("expected code %q, got %q", tc.expectedCode, errResp.Code)
+				}
+				return
+			}
+
+			var products []domain.PricedProduct
+			if strings.Contains(tc.path, "/products/") {
+				var product domain.PricedProduct
+				json.NewDecoder(resp.Body).Decode(&product)
+				products = append(products, product)
+			} else {
+				json.NewDecoder(resp.Body).Decode(&products)
+			}
+			var totals []string
+			for _, product := range products {
+				totals = append(totals, product.TotalPrice)
+			}
+			if strings.Join(totals, ",") != strings.Join(tc.expectedTotals, ",") {
+				t.Errorf("expected totals %v, got %v", tc.expectedTotals, totals)
+			}
+			if resp.Header.Get("Pricing-As-Of") == "" {
+				t.Error("expected a Pricing-As-Of header")
+			}
+			if tc.expectedVersion != "" && resp.Header.Get("Pricing-Version") != tc.expectedVersion {
+				t.Errorf("expected Pricing-Version %q, got %q", tc.expectedVersion, resp.Header.Get("Pricing-Version"))
+			}
+		})
+	}
+}
+
+// TestPricesRecorded tests that the catalogue watcher, not requests, records prices with the time they changed
+func TestPricesRecorded(t *testing.T) {
+	before := time.Now()
+	server, ts := newTestServer(t)
+
+	t.Setenv("DHL_DELIVERY_PRICE", "3.00")
+	resp, _ := http.Get(ts.URL + "/v1/products")
+	resp.Body.Close()
+	if rates := domain.History().Rates; len(rates) != 1 || rates[0].Rate != 2 {
+		t.Fatalf("expected reading prices not to record them, got %+v", rates)
+	}
+	changed := time.Now()
+	server.catalogue.check()
+
+	history := domain.History()
+	var dhl []domain.RateVersion
+	for _, version := range history.Rates {
+		if version.Provider == "DHL" {
+			dhl = append(dhl, version)
+		}
+	}
+	if len(dhl) != 2 || dhl[0].Rate != 2 || dhl[0].ValidTo == nil || dhl[0].ValidFrom.Before(before) || dhl[1].Rate != 3 || dhl[1].ValidTo != nil {
+		t.Fatalf("expected DHL at 2.00 then 3.00, got %+v", dhl)
+	}
+	if dhl[0].ValidTo.Before(changed) || !dhl[1].ValidFrom.Equal(*dhl[0].ValidTo) {
+		t.Errorf("expected the new rate to start when the change was seen, got %+v", dhl)
+	}
+	if len(history.Products) != 1 || history.Products[0].ValidTo != nil {
+		t.Errorf("expected the unchanged TV to have one open version, got %+v", history.Products)
+	}
+}
+
+// TestCatalogueWatcher tests that an edited catalogue file is recorded from its modification time
+func TestCatalogueWatcher(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	edited := time.Now()
+	originalModTimeFunc := storage.CatalogueModTimeFunc
+	originalLoadProductsFunc := storage.LoadProductsFunc
+	storage.CatalogueModTimeFunc = func() (time.Time, error) { return edited, nil }
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "TV", Weight: 1.5, Price: 18}}, nil
+	}
+	defer func() {
+		storage.CatalogueModTimeFunc = originalModTimeFunc
+		storage.LoadProductsFunc = originalLoadProductsFunc
+	}()
+	time.Sleep(time.Millisecond) // so the edit is noticed after it was made
+	server.catalogue.check()
+
+	products := domain.History().Products
+	if len(products) != 2 || products[1].Price != 18 || !products[1].ValidFrom.Equal(edited) {
+		t.Fatalf("expected the cheaper TV from %v, got %+v", edited, products)
+	}
+	server.catalogue.check()
+	if len(domain.History().Products) != 2 {
+		t.Errorf("expected an unchanged catalogue not to be recorded again, got %+v", domain.History().Products)
+	}
+}
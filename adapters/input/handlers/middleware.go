@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+)
+
+// panicsTotal counts handler panics caught by RecoveryMiddleware.
+var panicsTotal = metrics.Counter("http_panics_total")
+
+// Middleware wraps an http.Handler with extra behaviour such as logging or recovery.
+type Middleware func(http.Handler) http.Handler
+
+/*
+Chain wraps handler with the given middlewares. The first middleware is the outermost,
+so Chain(h, a, b) handles a request as a -> b -> h.
+*/
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+/*
+RequestIDMiddleware assigns every request an ID, stores it on the request context and echoes it
+in the X-Request-ID header. A client's own ID is kept if it is valid, otherwise a new one is used.
+*/
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := clientRequestID(r)
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// LoggingMiddleware logs the method, path, status and duration of every request.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(sw, r)
+
+		status := sw.Status()
+		logType := 1
+		switch {
+		case status >= 500:
+			logType = 3
+		case status >= 400:
+			logType = 2
+		}
+		logs.Logs(logType, fmt.Sprintf("%s %s %d %s request_id=%s", r.Method, r.URL.Path, status, time.Since(start).Round(time.Microsecond), requestID(w, r)), "")
+	})
+}
+
+/*
+RecoveryMiddleware turns a panic in a handler into a 500 JSON error response instead of dropping
+the connection. The panic value and stack trace are logged at error level with the request ID,
+and the http_panics_total metric is incremented.
+*/
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered) // let net/http abort the response as intended
+				}
+				panicsTotal.Add(1)
+				logs.Logs(3, fmt.Sprintf("panic serving %s %s request_id=%s: %v\n%s", r.Method, r.URL.Path, requestID(w, r), recovered, debug.Stack()), "")
+				if !sw.wroteHeader {
+					writeError(sw, r, http.StatusInternalServerError, codeInternal, "Internal server error", nil)
+				}
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+/*
+TimeoutMiddleware gives each request a context deadline. Handlers are expected to check the
+request context, and if the deadline passes before they have written anything a 503 error
+is returned. Unlike http.TimeoutHandler it does not buffer the response, so streaming still works.
+*/
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			if !sw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				logs.Logs(3, fmt.Sprintf("request timed out after %s: %s %s", timeout, r.Method, r.URL.Path), "")
+				writeError(sw, r, http.StatusServiceUnavailable, codeTimeout, "Request timed out", nil)
+			}
+		})
+	}
+}
+
+/*
+statusWriter records the status code written by a handler. It passes Flush through and
+implements Unwrap so http.ResponseController can still reach the underlying writer.
+*/
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wroteHeader {
+		sw.status = status
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Flush() {
+	if !sw.wroteHeader {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// Status returns the status code written, defaulting to 200 if the handler wrote nothing.
+func (sw *statusWriter) Status() int {
+	if !sw.wroteHeader {
+		return http.StatusOK
+	}
+	return sw.status
+}
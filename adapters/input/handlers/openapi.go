@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+/*
+openAPISpec is the OpenAPI 3 description of every route. It is maintained by hand alongside
+the handlers, and TestOpenAPIResponses checks real responses against it so the two stay in step.
+*/
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler serves the OpenAPI document.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// loadOpenAPISpec decodes the embedded OpenAPI document
+func loadOpenAPISpec(t *testing.T) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return spec
+}
+
+// resolveRef follows a local "#/..." $ref, returning the object unchanged if it has none
+func resolveRef(spec map[string]any, node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	var current any = spec
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		current = current.(map[string]any)[part]
+	}
+	return resolveRef(spec, current.(map[string]any))
+}
+
+// validateSchema checks value against the subset of JSON Schema used by openapi.json
+func validateSchema(spec map[string]any, schema map[string]any, value any, at string) []string {
+	schema = resolveRef(spec, schema)
+	var problems []string
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected object, got %T", at, value))
+		}
+		for _, name := range asSlice(schema["required"]) {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %q", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, child := range object {
+			if property, ok := properties[name].(map[string]any); ok {
+				problems = append(problems, validateSchema(spec, property, child, at+"."+name)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s: unexpected property %q", at, name))
+				}
+			case map[string]any:
+				problems = append(problems, validateSchema(spec, additional, child, at+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected array, got %T", at, value))
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			problems = append(problems, validateSchema(spec, items, item, at+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected string, got %T", at, value))
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			problems = append(problems, fmt.Sprintf("%s: %q does not match %s", at, s, pattern))
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected number, got %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected boolean, got %T", at, value))
+		}
+	}
+	return problems
+}
+
+// asSlice returns value as a slice, or nil if it is not one
+func asSlice(value any) []any {
+	s, _ := value.([]any)
+	return s
+}
+
+// specPath converts a ServeMux pattern such as "GET /products/{name}" into its OpenAPI method and path
+func specPath(pattern string) (method string, path string) {
+	method, path, _ = strings.Cut(pattern, " ")
+	// "{$}" only anchors the match, so "/{$}" is documented as "/"
+	return strings.ToLower(method), strings.TrimSuffix(path, "{$}")
+}
+
+// TestOpenAPICoversRoutes tests that every registered route is described in the OpenAPI document
+func TestOpenAPICoversRoutes(t *testing.T) {
+	server, _ := newTestServer(t)
+	spec := loadOpenAPISpec(t)
+	paths := spec["paths"].(map[string]any)
+
+	for _, pattern := range server.patterns {
+		method, path := specPath(pattern)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			t.Errorf("route %q has no path %q in openapi.json", pattern, path)
+			continue
+		}
+		if _, ok := item[method]; !ok {
+			t.Errorf("route %q has no %s operation in openapi.json", pattern, method)
+		}
+	}
+}
+
+// TestOpenAPIResponses tests that real responses match the status codes, content types and schemas in the OpenAPI document
+func TestOpenAPIResponses(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	defer func() { envFile = originalEnvFile }()
+
+	t.Setenv("API_KEYS", "web:reader:r,ops:admin:a")
+	usePromotions(t, domain.Promotion{ID: "tv-offer", Name: "TV offer", Target: domain.PromotionProduct, Kind: domain.PromotionFixed, Value: 2})
+	useServiceLevels(t,
+		domain.ServiceLevel{Provider: "DHL", Service: domain.ServiceStandard, TransitDays: 3},
+		domain.ServiceLevel{Provider: "DHL", Service: domain.ServiceTimed, Rate: 6, CutOff: "15:00", TransitDays: 1, DeliverBy: "10:30"},
+	)
+	_, ts := newTestServer(t)
+	spec := loadOpenAPISpec(t)
+	paths := spec["paths"].(map[string]any)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string // request path
+		specPath string // path in openapi.json
+		key      string
+		accept   string
+		body     string
+		status   int
+	}{
+		{name: "hello", method: "GET", path: "/", specPath: "/", status: http.StatusOK},
+		{name: "metrics", method: "GET", path: "/metrics", specPath: "/metrics", key: "ops.a", status: http.StatusOK},
+		{name: "products", method: "GET", path: "/products", specPath: "/products", status: http.StatusOK},
+		{name: "products as csv", method: "GET", path: "/products?format=csv", specPath: "/products", status: http.StatusOK},
+		{name: "products as xml", method: "GET", path: "/products", specPath: "/products", accept: "application/xml", status: http.StatusOK},
+		{name: "unknown provider", method: "GET", path: "/products?provider=fedex", specPath: "/products", status: http.StatusBadRequest},
+		{name: "not acceptable", method: "GET", path: "/products", specPath: "/products", accept: "text/html", status: http.StatusNotAcceptable},
+		{name: "bad key", method: "GET", path: "/products", specPath: "/products", key: "web.wrong", status: http.StatusUnauthorized},
+		{name: "v1 products", method: "GET", path: "/v1/products", specPath: "/v1/products", status: http.StatusOK},
+		{name: "as of before any history", method: "GET", path: "/v1/products?as_of=2020-01-01T00:00:00Z", specPath: "/v1/products", status: http.StatusNotFound},
+		{name: "invalid as of", method: "GET", path: "/v1/products?as_of=yesterday", specPath: "/v1/products", status: http.StatusBadRequest},
+		{name: "stream unknown provider", method: "GET", path: "/v1/products/stream?provider=fedex", specPath: "/v1/products/stream", status: http.StatusBadRequest},
+		{name: "v1 unknown product", method: "GET", path: "/v1/products/Radio", specPath: "/v1/products/{name}", status: http.StatusNotFound},
+		{name: "product", method: "GET", path: "/products/TV", specPath: "/products/{name}", status: http.StatusOK},
+		{name: "unknown product", method: "GET", path: "/products/Radio", specPath: "/products/{name}", status: http.StatusNotFound},
+		{name: "reload forbidden", method: "POST", path: "/admin/reload", specPath: "/admin/reload", key: "web.r", status: http.StatusForbidden},
+		{name: "reload", method: "POST", path: "/admin/reload", specPath: "/admin/reload", key: "ops.a", status: http.StatusOK},
+		{name: "webhooks", method: "GET", path: "/admin/webhooks", specPath: "/admin/webhooks", key: "ops.a", status: http.StatusOK},
+		{name: "unknown webhook", method: "GET", path: "/admin/webhooks/wh_missing", specPath: "/admin/webhooks/{id}", key: "ops.a", status: http.StatusNotFound},
+		{name: "audit", method: "GET", path: "/audit?entity=rate", specPath: "/audit", key: "ops.a", status: http.StatusOK},
+		{name: "audit forbidden", method: "GET", path: "/audit", specPath: "/audit", key: "web.r", status: http.StatusForbidden},
+		{name: "invalid audit limit", method: "GET", path: "/audit?limit=0", specPath: "/audit", key: "ops.a", status: http.StatusBadRequest},
+		{name: "verify audit", method: "GET", path: "/audit/verify", specPath: "/audit/verify", key: "ops.a", status: http.StatusOK},
+		{name: "schedule", method: "GET", path: "/v1/schedule", specPath: "/v1/schedule", key: "web.r", status: http.StatusOK},
+		{name: "unknown scheduled change", method: "DELETE", path: "/v1/schedule/chg_missing", specPath: "/v1/schedule/{id}", key: "ops.a", status: http.StatusNotFound},
+		{name: "discount codes", method: "GET", path: "/v1/discount-codes", specPath: "/v1/discount-codes", key: "ops.a", status: http.StatusOK},
+		{name: "unknown discount code", method: "GET", path: "/v1/discount-codes/NOPE", specPath: "/v1/discount-codes/{code}", key: "ops.a", status: http.StatusNotFound},
+		{name: "rejected discount code", method: "GET", path: "/v1/products?code=nope", specPath: "/v1/products", status: http.StatusBadRequest},
+		{name: "destination", method: "GET", path: "/v1/products?destination=GB,M1+1AA", specPath: "/v1/products", status: http.StatusOK},
+		{name: "v2 products", method: "GET", path: "/v2/products?destination=GB,M1+1AA", specPath: "/v2/products", status: http.StatusOK},
+		{name: "v2 products as csv", method: "GET", path: "/v2/products?format=csv", specPath: "/v2/products", status: http.StatusOK},
+		{name: "v2 product", method: "GET", path: "/v2/products/TV", specPath: "/v2/products/{name}", status: http.StatusOK},
+		{name: "checkout", method: "POST", path: "/v1/checkout", specPath: "/v1/checkout", body: `{"items": [{"name": "TV", "quantity": 2}], "destination": {"country": "GB", "postcode": "M1 1AA"}}`, status: http.StatusOK},
+		{name: "checkout without items", method: "POST", path: "/v1/checkout", specPath: "/v1/checkout", body: `{"items": []}`, status: http.StatusBadRequest},
+		{name: "zone not served", method: "GET", path: "/v1/products/TV?destination=FR", specPath: "/v1/products/{name}", status: http.StatusBadRequest},
+		{name: "openapi", method: "GET", path: "/openapi.json", specPath: "/openapi.json", status: http.StatusOK},
+		{name: "graphql", method: "GET", path: "/graphql?query=%7Bproviders%7D", specPath: "/graphql", status: http.StatusOK},
+		{name: "graphql without query", method: "GET", path: "/graphql", specPath: "/graphql", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, resp.StatusCode, body)
+			}
+
+			operation, ok := paths[tc.specPath].(map[string]any)[strings.ToLower(tc.method)].(map[string]any)
+			if !ok {
+				t.Fatalf("no %s %s operation in openapi.json", tc.method, tc.specPath)
+			}
+			response, ok := operation["responses"].(map[string]any)[strconv.Itoa(resp.StatusCode)].(map[string]any)
+			if !ok {
+				t.Fatalf("status %d is not documented for %s %s", resp.StatusCode, tc.method, tc.specPath)
+			}
+			response = resolveRef(spec, response)
+
+			mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+			content, ok := response["content"].(map[string]any)[mediaType].(map[string]any)
+			if !ok {
+				t.Fatalf("content type %q is not documented for %d", mediaType, resp.StatusCode)
+			}
+			schema := content["schema"].(map[string]any)
+
+			var value any = string(body)
+			if mediaType == "application/json" {
+				if err := json.Unmarshal(body, &value); err != nil {
+					t.Fatalf("response is not valid JSON: %v", err)
+				}
+			}
+			for _, problem := range validateSchema(spec, schema, value, "body") {
+				t.Error(problem)
+			}
+		})
+	}
+}
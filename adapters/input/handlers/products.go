@@ -1,67 +1,289 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
 	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
 	"github.com/PythonAkoto/base_techtest/domain"
 )
 
+// GetProductsHandler returns every product priced with the delivery provider, in the v1 response shape.
 func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
-	// get the default provider from environment variable
-	defaultProvider := strings.ToUpper(os.Getenv("DELIVERY_PROVIDER"))
-	if defaultProvider == "" {
-		logs.Logs(3, "DELIVERY_PROVIDER environment variable not set", "")
-		http.Error(w, "Delivery provider not set", http.StatusInternalServerError)
+	v1.productsHandler(w, r)
+}
+
+// GetProductHandler returns the priced details of a single product looked up by name, in the v1 response shape.
+func GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	v1.productHandler(w, r)
+}
+
+// productsHandler prices the whole catalogue and writes it using the version's DTOs.
+func (v apiVersion) productsHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := resolveFormat(w, r)
+	if !ok {
 		return
 	}
 
-	// check for provider query parameter
-	queryProvider := strings.ToUpper(r.URL.Query().Get("provider"))
-	var provider string
+	code, ok := resolveDiscountCode(w, r)
+	if !ok {
+		return
+	}
+	destination, ok := resolveDestination(w, r)
+	if !ok {
+		return
+	}
 
-	if queryProvider == "" {
-		// use default from env
-		provider = defaultProvider
-		logs.Logs(1, "No provider specified in URL, using default from environment", provider)
-	} else {
-		provider = queryProvider
-		if provider != defaultProvider {
-			logs.Logs(2, "Query provider differs from env provider", provider)
-		}
+	historical, ok := resolveAsOf(w, r)
+	if !ok {
+		return
+	}
+	if historical != nil {
+		v.historicalProductsHandler(w, r, format, historical)
+		return
+	}
+
+	provider, ok := resolveProvider(w, r)
+	if !ok {
+		return
 	}
 
 	// load products from storage
 	products, err := storage.LoadProductsFunc()
 	if err != nil {
 		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
-		http.Error(w, "Failed to load products", http.StatusInternalServerError)
+		writeDomainError(w, r, err, nil)
+		return
+	}
+
+	zone, ok := resolveZone(w, r, provider, destination)
+	if !ok {
+		return
+	}
+
+	// answer from the client's cache if nothing that affects the prices has changed, unless a
+	// discount code is applied, as whether it can be used changes as it is redeemed
+	if code != "" {
+		w.Header().Set("Cache-Control", "no-store")
+	} else if writeCacheHeaders(w, r, pricedETag(products, provider, v.name, format.name, string(zone)), provider) {
+		return
+	}
+
+	// stop early if the client has gone away or the request has timed out
+	if err := r.Context().Err(); err != nil {
+		logs.Logs(2, "Request cancelled before pricing: "+err.Error(), provider)
+		writeDomainError(w, r, err, nil)
 		return
 	}
 
 	// calculate prices for products
-	productPrices, err := domain.PriceProductsFunc(products, provider)
+	productPrices, err := domain.PriceProductsWithOptionsFunc(products, provider, domain.PricingOptions{Code: code, Destination: destination})
 	if err != nil {
 		logs.Logs(3, "Failed to price products: "+err.Error(), provider)
-		http.Error(w, "Failed to price products", http.StatusInternalServerError)
+		writeDomainError(w, r, err, map[string]string{"provider": provider})
 		return
 	}
 
-	// set content type to JSON
-	w.Header().Set("Content-Type", "application/json")
-
-	// encode products to JSON and write to response
-	err = json.NewEncoder(w).Encode(productPrices)
+	// write products in the negotiated format
+	writePricingVersion(w, currentPricingVersion(products, provider, zone), nil)
+	w.Header().Set("Content-Type", format.contentType)
+	err = format.writeList(w, v.presenter, productPrices)
 	if err != nil {
 		logs.Logs(3, "Failed to write response: "+err.Error(), provider)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
 
 	// log success message
 	logs.Logs(1, "successfully got the prices of the products", provider)
 }
+
+// historicalProductsHandler prices the catalogue as it was at ?as_of= and writes it using the version's DTOs.
+func (v apiVersion) historicalProductsHandler(w http.ResponseWriter, r *http.Request, format *responseFormat, pricing *domain.HistoricalPricing) {
+	provider, ok := resolveHistoricalProvider(w, r, pricing)
+	if !ok {
+		return
+	}
+	asOf := pricing.At.Format(time.RFC3339)
+
+	productPrices, err := pricing.Price(provider)
+	if err != nil {
+		logs.Logs(3, "Failed to price products as of "+asOf+": "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"provider": provider, "as_of": asOf})
+		return
+	}
+
+	writePricingVersion(w, pricing.Version(provider), pricing)
+	w.Header().Set("Content-Type", format.contentType)
+	err = format.writeList(w, v.presenter, productPrices)
+	if err != nil {
+		logs.Logs(3, "Failed to write response: "+err.Error(), provider)
+		return
+	}
+
+	logs.Logs(1, "successfully got the prices of the products as of "+asOf, provider)
+}
+
+// productHandler prices a single product looked up by name and writes it using the version's DTO.
+func (v apiVersion) productHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := resolveFormat(w, r)
+	if !ok {
+		return
+	}
+
+	code, ok := resolveDiscountCode(w, r)
+	if !ok {
+		return
+	}
+	destination, ok := resolveDestination(w, r)
+	if !ok {
+		return
+	}
+
+	historical, ok := resolveAsOf(w, r)
+	if !ok {
+		return
+	}
+	if historical != nil {
+		v.historicalProductHandler(w, r, format, historical)
+		return
+	}
+
+	provider, ok := resolveProvider(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// load products from storage
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		logs.Logs(3, "Failed to load products: "+err.Error(), provider)
+		writeDomainError(w, r, err, nil)
+		return
+	}
+
+	product, err := domain.FindProduct(products, name)
+	if err != nil {
+		logs.Logs(2, "Product lookup failed: "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"name": name})
+		return
+	}
+
+	zone, ok := resolveZone(w, r, provider, destination)
+	if !ok {
+		return
+	}
+
+	if code != "" {
+		w.Header().Set("Cache-Control", "no-store")
+	} else if writeCacheHeaders(w, r, pricedETag(products, provider, v.name, product.Name, format.name, string(zone)), provider) {
+		return
+	}
+
+	// calculate the price for the single product
+	productPrices, err := domain.PriceProductsWithOptionsFunc([]domain.Product{product}, provider, domain.PricingOptions{Code: code, Destination: destination})
+	if err != nil {
+		logs.Logs(3, "Failed to price product: "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"provider": provider})
+		return
+	}
+	if len(productPrices) != 1 {
+		logs.Logs(3, "Pricing returned an unexpected number of products for "+product.Name, provider)
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Internal server error", nil)
+		return
+	}
+
+	writePricingVersion(w, currentPricingVersion(products, provider, zone), nil)
+	w.Header().Set("Content-Type", format.contentType)
+	err = format.writeOne(w, v.presenter, productPrices[0])
+	if err != nil {
+		logs.Logs(3, "Failed to write response: "+err.Error(), provider)
+		return
+	}
+
+	logs.Logs(1, "successfully got the price of product "+product.Name, provider)
+}
+
+// historicalProductHandler prices a single product as it was at ?as_of= and writes it using the version's DTO.
+func (v apiVersion) historicalProductHandler(w http.ResponseWriter, r *http.Request, format *responseFormat, pricing *domain.HistoricalPricing) {
+	provider, ok := resolveHistoricalProvider(w, r, pricing)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	asOf := pricing.At.Format(time.RFC3339)
+
+	product, err := domain.FindProduct(pricing.Products, name)
+	if err != nil {
+		logs.Logs(2, "Product lookup as of "+asOf+" failed: "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"name": name, "as_of": asOf})
+		return
+	}
+
+	single := *pricing
+	single.Products = []domain.Product{product}
+	productPrices, err := single.Price(provider)
+	if err != nil {
+		logs.Logs(3, "Failed to price product as of "+asOf+": "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"provider": provider, "as_of": asOf})
+		return
+	}
+
+	writePricingVersion(w, pricing.Version(provider), pricing)
+	w.Header().Set("Content-Type", format.contentType)
+	err = format.writeOne(w, v.presenter, productPrices[0])
+	if err != nil {
+		logs.Logs(3, "Failed to write response: "+err.Error(), provider)
+		return
+	}
+
+	logs.Logs(1, "successfully got the price of product "+product.Name+" as of "+asOf, provider)
+}
+
+/*
+resolveFormat negotiates the response format from ?format= or the Accept header. Responses vary
+on Accept, so caches keep the formats apart. If no supported format is acceptable a 406 is
+written and ok is false.
+*/
+func resolveFormat(w http.ResponseWriter, r *http.Request) (format *responseFormat, ok bool) {
+	w.Header().Add("Vary", "Accept")
+	format, ok = negotiateFormat(r)
+	if !ok {
+		logs.Logs(2, "No acceptable format for Accept "+strconv.Quote(r.Header.Get("Accept"))+" format "+strconv.Quote(r.URL.Query().Get("format")), "")
+		writeNotAcceptable(w, r)
+	}
+	return format, ok
+}
+
+/*
+resolveProvider works out which delivery provider to price with. The ?provider= query
+parameter takes precedence over the DELIVERY_PROVIDER environment variable, and both are
+case-insensitive. If no provider can be determined an error response is written and ok is false.
+*/
+func resolveProvider(w http.ResponseWriter, r *http.Request) (provider string, ok bool) {
+	// get the default provider, following any scheduled change from DELIVERY_PROVIDER
+	defaultProvider := domain.DefaultProvider()
+	if defaultProvider == "" {
+		logs.Logs(3, "DELIVERY_PROVIDER environment variable not set", "")
+		writeError(w, r, http.StatusInternalServerError, codeProviderNotSet, "Delivery provider not set", nil)
+		return "", false
+	}
+
+	// check for provider query parameter
+	queryProvider := strings.ToUpper(r.URL.Query().Get("provider"))
+
+	if queryProvider == "" {
+		// use default from env
+		logs.Logs(1, "No provider specified in URL, using default from environment", defaultProvider)
+		return defaultProvider, true
+	}
+
+	if queryProvider != defaultProvider {
+		logs.Logs(2, "Query provider differs from env provider", queryProvider)
+	}
+	return queryProvider, true
+}
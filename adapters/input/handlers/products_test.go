@@ -32,7 +32,7 @@ func TestGetProductsHandler(t *testing.T) {
 				os.Unsetenv("DELIVERY_PROVIDER")
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: "Delivery provider not set\n",
+			expectedBody: `{"code":"provider_not_configured","message":"Delivery provider not set","request_id":"test-request-id"}` + "\n",
 		},
 		{
 			name:        "failed to load products - default provider",
@@ -45,7 +45,20 @@ func TestGetProductsHandler(t *testing.T) {
 				}
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: "Failed to load products\n",
+			expectedBody: `{"code":"internal_error","message":"Internal server error","request_id":"test-request-id"}` + "\n",
+		},
+		{
+			name:        "storage unavailable - default provider",
+			queryParams: "",
+			setupMocks: func() {
+				os.Setenv("DELIVERY_PROVIDER", "DHL")
+				os.Setenv("DHL_DELIVERY_PRICE", "2.00")
+				storage.LoadProductsFunc = func() ([]domain.Product, error) {
+					return nil, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, os.ErrNotExist)
+				}
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"code":"storage_unavailable","message":"Product storage is unavailable","request_id":"test-request-id"}` + "\n",
 		},
 		{
 			name:        "failed to price products - default provider",
@@ -63,7 +76,7 @@ func TestGetProductsHandler(t *testing.T) {
 				}
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: "Failed to price products\n",
+			expectedBody: `{"code":"internal_error","message":"Internal server error","request_id":"test-request-id"}` + "\n",
 		},
 		{
 			name:        "successfully priced products - default provider",
@@ -222,11 +235,11 @@ func TestGetProductsHandler(t *testing.T) {
 					if provider != "INVALID" {
 						t.Errorf("Expected provider INVALID, got %s", provider)
 					}
-					return nil, errors.New("delivery provider not set")
+					return domain.PriceProducts(products, provider)
 				}
 			},
-			expectedCode: http.StatusInternalServerError,
-			expectedBody: "Failed to price products\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":"invalid_provider","message":"Unknown delivery provider","details":{"provider":"INVALID"},"request_id":"test-request-id"}` + "\n",
 		},
 		{
 			name:        "test ROYALMAIL provider",
@@ -314,6 +327,7 @@ func runGetProductsTest(t *testing.T, name string, queryParams string, setupMock
 		// Create request with query parameters
 		url := "/products" + queryParams
 		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("X-Request-ID", "test-request-id")
 		w := httptest.NewRecorder()
 
 		// Execute the handler
@@ -428,3 +442,125 @@ func BenchmarkGetProductsHandlerWithLargeDataset(b *testing.B) {
 	os.Unsetenv("DELIVERY_PROVIDER")
 	os.Unsetenv("DHL_DELIVERY_PRICE")
 }
+
+// TestGetProductHandler tests looking up a single priced product by name
+func TestGetProductHandler(t *testing.T) {
+	originalLoadProductsFunc := storage.LoadProductsFunc
+	defer func() { storage.LoadProductsFunc = originalLoadProductsFunc }()
+
+	go logs.ProcessLogs()
+
+	os.Setenv("DELIVERY_PROVIDER", "DHL")
+	os.Setenv("DHL_DELIVERY_PRICE", "2.00")
+	defer os.Unsetenv("DELIVERY_PROVIDER")
+	defer os.Unsetenv("DHL_DELIVERY_PRICE")
+
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "TV", Weight: 1.5, Price: 20}}, nil
+	}
+
+	tests := []struct {
+		name         string
+		productName  string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "known product is priced",
+			productName:  "tv",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"name":"TV","product_price":"20.00","delivery_price":"3.00","total_price":"23.00","delivery_service":"DHL"}` + "\n",
+		},
+		{
+			name:         "unknown product returns 404",
+			productName:  "radio",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"code":"product_not_found","message":"Product not found","details":{"name":"radio"},"request_id":"test-request-id"}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/products/"+tc.productName, nil)
+			req.SetPathValue("name", tc.productName)
+			req.Header.Set("X-Request-ID", "test-request-id")
+			w := httptest.NewRecorder()
+
+			GetProductHandler(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, w.Code)
+			}
+			if w.Body.String() != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestGetProductsHandlerV2 tests that v2 shows the weight delivery was charged on, which v1 leaves out
+func TestGetProductsHandlerV2(t *testing.T) {
+	originalLoadProductsFunc := storage.LoadProductsFunc
+	defer func() { storage.LoadProductsFunc = originalLoadProductsFunc }()
+
+	go logs.ProcessLogs()
+
+	t.Setenv("DELIVERY_PROVIDER", "DHL")
+	t.Setenv("DHL_DELIVERY_PRICE", "2.00")
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "5000")
+
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{
+			{Name: "TV", Weight: 1.5, Price: 20},
+			{Name: "Bean Bag", Weight: 2, Price: 30, Length: 40, Width: 40, Height: 60},
+		}, nil
+	}
+
+	tests := []struct {
+		name         string
+		version      apiVersion
+		queryParams  string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "v2 has the chargeable weight and its basis",
+			version:      v2,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"name":"TV","product_price":"20.00","delivery_price":"3.00","total_price":"23.00","delivery_service":"DHL","chargeable_weight":1.5,"weight_basis":"actual"},` +
+				`{"name":"Bean Bag","product_price":"30.00","delivery_price":"38.40","total_price":"68.40","delivery_service":"DHL","chargeable_weight":19.2,"weight_basis":"volumetric"}]` + "\n",
+		},
+		{
+			name:         "v2 csv has the chargeable weight and its basis",
+			version:      v2,
+			queryParams:  "?format=csv",
+			expectedCode: http.StatusOK,
+			expectedBody: "name,product_price,delivery_price,total_price,delivery_service,chargeable_weight,weight_basis,zone,original_product_price,original_delivery_price\n" +
+				"TV,20.00,3.00,23.00,DHL,1.5,actual,,,\nBean Bag,30.00,38.40,68.40,DHL,19.2,volumetric,,,\n",
+		},
+		{
+			name:         "v1 is charged the same but keeps its fields",
+			version:      v1,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"name":"TV","product_price":"20.00","delivery_price":"3.00","total_price":"23.00","delivery_service":"DHL"},` +
+				`{"name":"Bean Bag","product_price":"30.00","delivery_price":"38.40","total_price":"68.40","delivery_service":"DHL"}]` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.version.prefix+"/products"+tc.queryParams, nil)
+			req.Header.Set("X-Request-ID", "test-request-id")
+			w := httptest.NewRecorder()
+
+			tc.version.productsHandler(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, w.Code)
+			}
+			if w.Body.String() != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
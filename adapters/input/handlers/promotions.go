@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+/*
+loadPromotions replaces the promotions with those in storage, which may have been edited by hand.
+If they cannot be read or one is invalid the current promotions are kept. It returns an audit
+entry, without an actor or reason, for each promotion added, edited or removed.
+*/
+func loadPromotions() ([]audit.Entry, error) {
+	promotions, err := storage.LoadPromotionsFunc()
+	if err != nil {
+		return nil, err
+	}
+	before := domain.Promotions()
+	if err := domain.SetPromotions(promotions); err != nil {
+		return nil, err
+	}
+	return promotionChanges(before, domain.Promotions()), nil
+}
+
+// promotionChanges compares two lists of promotions by ID.
+func promotionChanges(before, after []domain.Promotion) []audit.Entry {
+	return configChanges("promotion", before, after, func(p domain.Promotion) string { return p.ID })
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// usePromotions loads the given promotions from storage instead of PROMOTIONS_FILE, until the test ends
+func usePromotions(t *testing.T, promotions ...domain.Promotion) {
+	t.Helper()
+	original := storage.LoadPromotionsFunc
+	storage.LoadPromotionsFunc = func() ([]domain.Promotion, error) { return promotions, nil }
+	t.Cleanup(func() {
+		storage.LoadPromotionsFunc = original
+		domain.SetPromotions(nil)
+	})
+}
+
+// TestPromotionsAPI tests that promotions are applied to responses and picked up on reload
+func TestPromotionsAPI(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	t.Cleanup(func() { envFile = originalEnvFile })
+	t.Setenv("API_KEYS", "ops:admin:a")
+	usePromotions(t, domain.Promotion{ID: "half-delivery", Target: domain.PromotionDelivery, Kind: domain.PromotionPercentage, Value: 50})
+	_, ts := newTestServer(t)
+
+	resp, _ := http.Get(ts.URL + "/v2/products/tv")
+	var product domain.PricedProduct
+	json.NewDecoder(resp.Body).Decode(&product)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if product.DeliveryPrice != "1.50" || product.TotalPrice != "21.50" || product.OriginalDeliveryPrice != "3.00" || len(product.Promotions) != 1 || product.Promotions[0].Discount != "1.50" {
+		t.Fatalf("expected half price delivery, got %+v", product)
+	}
+
+	result := postGraphQL(t, ts.URL, `{ products { items { pricing { deliveryPrice originalDeliveryPrice originalProductPrice promotions { id target discount name } } } } }`, nil)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors %+v", result.Errors)
+	}
+	pricing := result.Data["products"].(map[string]any)["items"].([]any)[0].(map[string]any)["pricing"].(map[string]any)
+	promotions := pricing["promotions"].([]any)
+	if pricing["deliveryPrice"] != "1.50" || pricing["originalDeliveryPrice"] != "3.00" || pricing["originalProductPrice"] != "20.00" || len(promotions) != 1 {
+		t.Fatalf("expected half price delivery over GraphQL, got %v", pricing)
+	}
+	if promotion := promotions[0].(map[string]any); promotion["id"] != "half-delivery" || promotion["target"] != "delivery" || promotion["discount"] != "1.50" || promotion["name"] != nil {
+		t.Errorf("unexpected promotion %v", promotion)
+	}
+
+	// ending the promotion on reload changes the prices, the ETag and the audit log
+	storage.LoadPromotionsFunc = func() ([]domain.Promotion, error) { return nil, nil }
+	if resp, body := adminRequest(t, "POST", ts.URL+"/admin/reload?reason=promotion+ended", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d: %s", resp.StatusCode, body)
+	}
+	req, _ := http.NewRequest("GET", ts.URL+"/v2/products/tv", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, _ = http.DefaultClient.Do(req)
+	product = domain.PricedProduct{}
+	json.NewDecoder(resp.Body).Decode(&product)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || product.TotalPrice != "23.00" || product.OriginalDeliveryPrice != "" || product.Promotions != nil {
+		t.Errorf("expected full price after the promotion ended, got %d %+v", resp.StatusCode, product)
+	}
+
+	records := auditRecords(t, ts.URL+"/audit?entity=promotion")
+	if len(records) != 1 || records[0].Entity != "promotion:half-delivery" || records[0].Reason != "promotion ended" || string(records[0].After) != "null" {
+		t.Errorf("expected the removed promotion to be audited, got %+v", records)
+	}
+
+	// an invalid promotion fails the reload and keeps the current promotions
+	storage.LoadPromotionsFunc = func() ([]domain.Promotion, error) {
+		return []domain.Promotion{{ID: "bad", Target: domain.PromotionDelivery, Kind: domain.PromotionPercentage, Value: 150}}, nil
+	}
+	if resp, _ := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected an invalid promotion to fail the reload, got %d", resp.StatusCode)
+	}
+	if promotions := domain.Promotions(); len(promotions) != 0 {
+		t.Errorf("expected no promotions, got %+v", promotions)
+	}
+	// a later source failing validation applies nothing, not even the environment or valid promotions
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\nRELOAD_MARKER=new\n"), 0o600)
+	t.Cleanup(func() { os.Unsetenv("RELOAD_MARKER") })
+	storage.LoadPromotionsFunc = func() ([]domain.Promotion, error) {
+		return []domain.Promotion{{ID: "ten-off", Target: domain.PromotionProduct, Kind: domain.PromotionFixed, Value: 10}}, nil
+	}
+	originalLoadBankHolidaysFunc := storage.LoadBankHolidaysFunc
+	storage.LoadBankHolidaysFunc = func() ([]string, error) { return []string{"not-a-date"}, nil }
+	t.Cleanup(func() { storage.LoadBankHolidaysFunc = originalLoadBankHolidaysFunc })
+	if resp, _ := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected a malformed bank holiday to fail the reload, got %d", resp.StatusCode)
+	}
+	if promotions := domain.Promotions(); len(promotions) != 0 || os.Getenv("RELOAD_MARKER") != "" {
+		t.Errorf("expected nothing to be applied, got promotions %+v and RELOAD_MARKER %q", promotions, os.Getenv("RELOAD_MARKER"))
+	}
+	if records := auditRecords(t, ts.URL+"/audit?entity=promotion"); len(records) != 1 {
+		t.Errorf("expected nothing more to be audited, got %+v", records)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+)
+
+// rateLimitedTotal counts requests rejected with a 429.
+var rateLimitedTotal = metrics.Counter("http_rate_limited_total")
+
+/*
+rateLimitKey identifies the client for rate limiting: the API key ID when the request was
+authenticated, otherwise the client IP address.
+*/
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := principalFromContext(r.Context()); ok && principal.KeyID != "" {
+		return "key:" + principal.KeyID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+/*
+rateLimitMiddleware limits requests to a route per client. Every response carries the
+RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; rejected requests get a 429
+JSON error with Retry-After. If the limiter itself fails the request is let through, so an
+outage of a shared limiter store does not take the API down with it.
+*/
+func rateLimitMiddleware(limiter access.Limiter, route string, limit access.RateLimit) Middleware {
+	return func(next http.Handler) http.Handler {
+		if limit.Rate <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rateLimitKey(r)
+			decision, err := limiter.Allow(r.Context(), route+"|"+key, limit)
+			if err != nil {
+				logs.Logs(2, "rate limiter unavailable, allowing request: "+err.Error(), "")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+
+			if !decision.Allowed {
+				rateLimitedTotal.Add(1)
+				retryAfter := ceilSeconds(decision.RetryAfter)
+				logs.Logs(2, fmt.Sprintf("rate limit exceeded for %s on route %s", key, route), "")
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(w, r, http.StatusTooManyRequests, codeRateLimited, "Too many requests", map[string]string{"retry_after_seconds": strconv.Itoa(retryAfter)})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as required by the rate limit headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
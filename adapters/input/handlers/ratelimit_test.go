@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// failingLimiter is an access.Limiter whose backing store is unavailable
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, access.RateLimit) (access.Decision, error) {
+	return access.Decision{}, errors.New("store unavailable")
+}
+
+// TestRateLimitMiddleware tests the 429 response, headers and per-client keys
+func TestRateLimitMiddleware(t *testing.T) {
+	go logs.ProcessLogs()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := Chain(ok, RequestIDMiddleware, rateLimitMiddleware(access.NewMemoryLimiter(), "products", access.RateLimit{Rate: 0.5, Burst: 1}))
+
+	request := func(remoteAddr string, keyID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.RemoteAddr = remoteAddr
+		if keyID != "" {
+			req = req.WithContext(context.WithValue(req.Context(), principalKey{}, access.Principal{KeyID: keyID, Role: access.RoleReader}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request("10.0.0.1:1234", "")
+	if first.Code != http.StatusOK || first.Header().Get("RateLimit-Limit") != "1" || first.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected first response: %d %v", first.Code, first.Header())
+	}
+
+	limited := request("10.0.0.1:5678", "")
+	if limited.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", limited.Code)
+	}
+	if limited.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected Retry-After 2, got %q", limited.Header().Get("Retry-After"))
+	}
+	if errResp := decodeRecorderError(t, limited); errResp.Code != codeRateLimited {
+		t.Errorf("expected rate_limited error, got %q", errResp.Code)
+	}
+
+	if w := request("10.0.0.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("a different IP should not be limited, got %d", w.Code)
+	}
+	if w := request("10.0.0.1:1234", "web"); w.Code != http.StatusOK {
+		t.Errorf("an API key should be limited separately from its IP, got %d", w.Code)
+	}
+
+	failOpen := Chain(ok, rateLimitMiddleware(failingLimiter{}, "products", access.RateLimit{Rate: 1, Burst: 1}))
+	w := httptest.NewRecorder()
+	failOpen.ServeHTTP(w, httptest.NewRequest("GET", "/products", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected requests to be allowed when the limiter fails, got %d", w.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// requestIDHeader is the header used to receive and echo request IDs.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest X-Request-ID accepted from a client.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key RequestIDMiddleware stores the request ID under.
+type requestIDKey struct{}
+
+/*
+requestID returns the ID for the current request. The ID set by RequestIDMiddleware is
+preferred; handlers called directly fall back to a valid caller supplied X-Request-ID header,
+otherwise a new random ID is created. The ID is echoed back on the response so it always
+matches the one in the error body.
+*/
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		return id
+	}
+
+	id := clientRequestID(r)
+	if id == "" {
+		id = newRequestID()
+	}
+	w.Header().Set(requestIDHeader, id)
+	return id
+}
+
+/*
+clientRequestID returns the request's X-Request-ID header, or "" if it is missing or not a
+valid ID. IDs end up in logs and response headers, so only up to 128 letters, digits, dots,
+underscores and hyphens are accepted; anything else could forge log lines or bloat them.
+*/
+func clientRequestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if len(id) > maxRequestIDLength {
+		return ""
+	}
+	for _, c := range id {
+		valid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-'
+		if !valid {
+			return ""
+		}
+	}
+	return id
+}
+
+// newRequestID generates a random 16 byte hex encoded request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// maxScheduleBodyBytes limits the size of a scheduled change request.
+const maxScheduleBodyBytes = 64 << 10
+
+var (
+	// errScheduleChangeNotFound is returned when no scheduled change has the requested ID.
+	errScheduleChangeNotFound = errors.New("scheduled change not found")
+
+	// errScheduleChangeApplied is returned when removing a change that has already taken effect.
+	errScheduleChangeApplied = errors.New("scheduled change already in effect")
+)
+
+// scheduleResponse is the body of GET /v1/schedule.
+type scheduleResponse struct {
+	Now             time.Time                `json:"now"`
+	DefaultProvider string                   `json:"default_provider"`
+	Upcoming        []domain.ScheduledChange `json:"upcoming"` // soonest first
+	Past            []domain.ScheduledChange `json:"past"`     // most recent first
+}
+
+/*
+pricingSchedule keeps the domain's pricing schedule in step with its store and applies each
+change when it takes effect. The domain works out prices from the schedule on every request,
+so applying a change only means sending open price streams and webhooks the new prices.
+*/
+type pricingSchedule struct {
+	feeds *priceFeeds
+
+	mu     sync.Mutex // serialises edits to the schedule and guards timer
+	timer  *time.Timer
+	closed bool
+}
+
+// newPricingSchedule returns a schedule that refreshes feeds as changes take effect. Call load to read the store.
+func newPricingSchedule(feeds *priceFeeds) *pricingSchedule {
+	return &pricingSchedule{feeds: feeds}
+}
+
+/*
+load replaces the schedule with the one in the store, which may have been edited by hand.
+If the store cannot be read or holds an invalid change the current schedule is kept.
+*/
+func (ps *pricingSchedule) load() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	changes, err := storage.LoadScheduleFunc()
+	if err != nil {
+		return err
+	}
+	if err := domain.SetSchedule(changes); err != nil {
+		return err
+	}
+	ps.arm()
+	return nil
+}
+
+// setConfig replaces the whole pricing configuration, schedule included, with domain.SetConfig and re-arms the timer.
+func (ps *pricingSchedule) setConfig(config domain.Config) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if err := domain.SetConfig(config); err != nil {
+		return err
+	}
+	ps.arm()
+	return nil
+}
+
+// add schedules a change and saves the schedule, leaving both as they were if it cannot be saved.
+func (ps *pricingSchedule) add(change domain.ScheduledChange) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	previous := domain.Schedule()
+	if slices.ContainsFunc(previous, func(c domain.ScheduledChange) bool { return c.ID == change.ID }) {
+		return &domain.ErrInvalidScheduledChange{ID: change.ID, Reason: "id is already scheduled"}
+	}
+	if err := domain.SetSchedule(append(slices.Clone(previous), change)); err != nil {
+		return err
+	}
+	if err := storage.SaveScheduleFunc(domain.Schedule()); err != nil {
+		domain.SetSchedule(previous) // already validated, so this cannot fail
+		return err
+	}
+	ps.arm()
+	return nil
+}
+
+// remove cancels a change that has not taken effect yet and saves the schedule.
+func (ps *pricingSchedule) remove(id string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	previous := domain.Schedule()
+	i := slices.IndexFunc(previous, func(c domain.ScheduledChange) bool { return c.ID == id })
+	if i < 0 {
+		return errScheduleChangeNotFound
+	}
+	if !previous[i].EffectiveAt.After(time.Now()) {
+		return errScheduleChangeApplied
+	}
+	if err := domain.SetSchedule(slices.Delete(slices.Clone(previous), i, i+1)); err != nil {
+		return err
+	}
+	if err := storage.SaveScheduleFunc(domain.Schedule()); err != nil {
+		domain.SetSchedule(previous)
+		return err
+	}
+	ps.arm()
+	return nil
+}
+
+// arm sets the timer for the next change to take effect. The caller must hold ps.mu.
+func (ps *pricingSchedule) arm() {
+	if ps.timer != nil {
+		ps.timer.Stop()
+		ps.timer = nil
+	}
+	if ps.closed {
+		return
+	}
+	next, ok := domain.NextScheduledChange(time.Now())
+	if !ok {
+		return
+	}
+	ps.timer = time.AfterFunc(time.Until(next.EffectiveAt), func() { ps.apply(next) })
+}
+
+/*
+apply is called when a change takes effect. It records the new prices in the price history from
+the moment the change took effect, sends them to open streams and arms the timer for the next change.
+*/
+func (ps *pricingSchedule) apply(change domain.ScheduledChange) {
+	logs.Logs(1, "scheduled change "+change.ID+" is now in effect", change.Provider)
+	observePrices(change.EffectiveAt, actorScheduler, "scheduled change "+change.ID)
+	ps.feeds.refresh()
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.arm()
+}
+
+// Close stops applying changes. Prices still follow the schedule, as they are worked out per request.
+func (ps *pricingSchedule) Close() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.closed = true
+	if ps.timer != nil {
+		ps.timer.Stop()
+	}
+}
+
+// scheduleHandler lists the scheduled pricing changes, split into those still to come and those in effect or superseded.
+func (s *Server) scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	response := scheduleResponse{
+		Now:             now,
+		DefaultProvider: domain.DefaultProviderAt(now),
+		Upcoming:        []domain.ScheduledChange{},
+		Past:            []domain.ScheduledChange{},
+	}
+	for _, change := range domain.Schedule() {
+		if change.EffectiveAt.After(now) {
+			response.Upcoming = append(response.Upcoming, change)
+		} else {
+			response.Past = append(response.Past, change)
+		}
+	}
+	slices.Reverse(response.Past)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// getScheduleHandler returns a single scheduled change.
+func (s *Server) getScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	change, ok := findScheduledChange(r.PathValue("id"))
+	if !ok {
+		writeScheduleError(w, r, errScheduleChangeNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
+}
+
+/*
+createScheduleHandler schedules a change to the default provider or providers' rates. It must
+take effect in the future, as past prices are not rewritten. An ID is generated if none is given.
+*/
+func (s *Server) createScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var change domain.ScheduledChange
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxScheduleBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&change); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Request body must be a JSON object with an effective_at time", map[string]string{"reason": err.Error()})
+		return
+	}
+	if change.ID == "" {
+		change.ID = "chg_" + newRequestID()[:16]
+	}
+	if !change.EffectiveAt.After(time.Now()) {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Scheduled changes must take effect in the future", map[string]string{"effective_at": change.EffectiveAt.Format(time.RFC3339)})
+		return
+	}
+	change.EffectiveAt = change.EffectiveAt.UTC()
+
+	if err := s.schedule.add(change); err != nil {
+		writeScheduleError(w, r, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "change "+change.ID+" scheduled for "+change.EffectiveAt.Format(time.RFC3339)+" by key id "+principal.KeyID, change.Provider)
+	added, _ := findScheduledChange(change.ID)
+	s.auditChange(r, audit.Entry{Action: "schedule.created", Entity: "schedule:" + change.ID, After: added, Reason: auditReason(r, added.Note)})
+	w.Header().Set("Location", "/v1/schedule/"+change.ID)
+	writeJSON(w, http.StatusCreated, added)
+}
+
+// deleteScheduleHandler cancels a change that has not taken effect yet, auditing it with the reason given by ?reason=.
+func (s *Server) deleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	removed, _ := findScheduledChange(id)
+	if err := s.schedule.remove(id); err != nil {
+		writeScheduleError(w, r, err)
+		return
+	}
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "scheduled change "+id+" cancelled by key id "+principal.KeyID, "")
+	s.auditChange(r, audit.Entry{Action: "schedule.cancelled", Entity: "schedule:" + id, Before: removed, Reason: auditReason(r, "")})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findScheduledChange returns the scheduled change with the given ID, as normalised by the domain.
+func findScheduledChange(id string) (domain.ScheduledChange, bool) {
+	changes := domain.Schedule()
+	i := slices.IndexFunc(changes, func(c domain.ScheduledChange) bool { return c.ID == id })
+	if i < 0 {
+		return domain.ScheduledChange{}, false
+	}
+	return changes[i], true
+}
+
+// writeScheduleError writes the error response for an error changing the pricing schedule.
+func writeScheduleError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *domain.ErrInvalidScheduledChange
+	switch {
+	case errors.As(err, &invalid):
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Invalid scheduled change", map[string]string{"id": invalid.ID, "reason": invalid.Reason})
+	case errors.Is(err, errScheduleChangeNotFound):
+		writeError(w, r, http.StatusNotFound, codeScheduleNotFound, "Scheduled change not found", map[string]string{"id": r.PathValue("id")})
+	case errors.Is(err, errScheduleChangeApplied):
+		writeError(w, r, http.StatusConflict, codeScheduleApplied, "Scheduled change is already in effect and cannot be removed", map[string]string{"id": r.PathValue("id")})
+	default:
+		logs.Logs(3, "failed to change pricing schedule: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Failed to save the pricing schedule", nil)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// useSchedule starts the next test server with changes as the stored schedule, and clears the schedule afterwards
+func useSchedule(t *testing.T, changes ...domain.ScheduledChange) {
+	t.Helper()
+	originalLoad, originalSave := storage.LoadScheduleFunc, storage.SaveScheduleFunc
+	storage.LoadScheduleFunc = func() ([]domain.ScheduledChange, error) { return changes, nil }
+	storage.SaveScheduleFunc = func([]domain.ScheduledChange) error { return nil }
+	t.Cleanup(func() {
+		storage.LoadScheduleFunc, storage.SaveScheduleFunc = originalLoad, originalSave
+		domain.SetSchedule(nil)
+	})
+}
+
+// scheduleRequest sends a request with the given key and returns the response and its body
+func scheduleRequest(t *testing.T, method string, url string, key string, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+// TestScheduleAPI tests listing, adding and cancelling scheduled changes
+func TestScheduleAPI(t *testing.T) {
+	t.Setenv("API_KEYS", "web:reader:r,cat:merchandiser:m")
+	now := time.Now().UTC()
+	useSchedule(t,
+		domain.ScheduledChange{ID: "chg_old", EffectiveAt: now.Add(-2 * time.Hour), Rates: map[string]float64{"DHL": 3}},
+		domain.ScheduledChange{ID: "chg_recent", EffectiveAt: now.Add(-time.Hour), Provider: "ups", Rates: map[string]float64{"UPS": 1}},
+		domain.ScheduledChange{ID: "chg_later", EffectiveAt: now.Add(48 * time.Hour), Provider: "DHL"},
+	)
+	_, ts := newTestServer(t)
+
+	resp, body := scheduleRequest(t, "GET", ts.URL+"/v1/schedule", "web.r", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var listed scheduleResponse
+	json.Unmarshal(body, &listed)
+	if listed.DefaultProvider != "UPS" || len(listed.Upcoming) != 1 || len(listed.Past) != 2 || listed.Past[0].ID != "chg_recent" {
+		t.Errorf("unexpected schedule %s", body)
+	}
+
+	future := now.Add(time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		key            string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedBody   string
+	}{
+		{name: "add", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"id": "chg_new", "effective_at": "` + future + `", "rates": {"dpd": 1.25}}`, expectedStatus: http.StatusCreated, expectedBody: `"DPD":1.25`},
+		{name: "get", method: "GET", path: "/v1/schedule/chg_new", key: "web.r", expectedStatus: http.StatusOK, expectedBody: `"id":"chg_new"`},
+		{name: "duplicate id", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"id": "chg_new", "effective_at": "` + future + `", "provider": "DPD"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "readers cannot schedule", method: "POST", path: "/v1/schedule", key: "web.r", body: `{"effective_at": "` + future + `", "provider": "DPD"}`, expectedStatus: http.StatusForbidden, expectedCode: codeForbidden},
+		{name: "in the past", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"effective_at": "2020-01-01T00:00:00Z", "provider": "DPD"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "unknown provider", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"effective_at": "` + future + `", "provider": "FEDEX"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest, expectedBody: "unknown provider"},
+		{name: "negative rate", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"effective_at": "` + future + `", "rates": {"DHL": -1}}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "empty change", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"effective_at": "` + future + `"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "unknown field", method: "POST", path: "/v1/schedule", key: "cat.m", body: `{"effective_at": "` + future + `", "rate": 1}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "cancel", method: "DELETE", path: "/v1/schedule/chg_new", key: "cat.m", expectedStatus: http.StatusNoContent},
+		{name: "cancelled", method: "GET", path: "/v1/schedule/chg_new", key: "web.r", expectedStatus: http.StatusNotFound, expectedCode: codeScheduleNotFound},
+		{name: "cannot cancel a change in effect", method: "DELETE", path: "/v1/schedule/chg_recent", key: "cat.m", expectedStatus: http.StatusConflict, expectedCode: codeScheduleApplied},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := scheduleRequest(t, tc.method, ts.URL+tc.path, tc.key, tc.body)
+			if resp.StatusCode != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedStatus, resp.StatusCode, body)
+			}
+			if tc.expectedCode != "" {
+				var errResp ErrorResponse
+				json.Unmarshal(body, &errResp)
+				if errResp.Code != tc.expectedCode {
+					t.Errorf("expected code %q, got %q", tc.expectedCode, errResp.Code)
+				}
+			}
+			if tc.expectedBody != "" && !strings.Contains(string(body), tc.expectedBody) {
+				t.Errorf("expected body containing %q, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+// TestSchedulePricing tests that prices follow the changes in effect
+func TestSchedulePricing(t *testing.T) {
+	useSchedule(t,
+		domain.ScheduledChange{ID: "chg_rate", EffectiveAt: time.Now().Add(-time.Hour), Rates: map[string]float64{"DHL": 3}},
+		domain.ScheduledChange{ID: "chg_later", EffectiveAt: time.Now().Add(time.Hour), Rates: map[string]float64{"DHL": 10}},
+	)
+	_, ts := newTestServer(t)
+
+	resp, body := scheduleRequest(t, "GET", ts.URL+"/v1/products/TV", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	// 1.5 weight at the scheduled 3.00 rather than DHL_DELIVERY_PRICE's 2.00
+	if !strings.Contains(string(body), `"total_price":"24.50"`) {
+		t.Errorf("expected the scheduled rate to be used, got %s", body)
+	}
+}
+
+// TestScheduleAppliedToStreams tests that open price streams are sent new prices when a change takes effect
+func TestScheduleAppliedToStreams(t *testing.T) {
+	t.Setenv("API_KEYS", "cat:merchandiser:m")
+	useSchedule(t)
+	_, ts := newTestServer(t)
+
+	_, events := openStream(t, ts.URL+"/v1/products/stream", "")
+	if event := nextEvent(t, events); event.event != "snapshot" {
+		t.Fatalf("expected a snapshot, got %+v", event)
+	}
+
+	effective := time.Now().Add(100 * time.Millisecond).UTC().Format(time.RFC3339Nano)
+	resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/schedule", "cat.m", `{"effective_at": "`+effective+`", "rates": {"DHL": 4}}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+
+	// the stream polls every 5s by default, so a diff this soon comes from the schedule
+	event := nextEvent(t, events)
+	if event.event != "diff" || !strings.Contains(event.data, `"total_price":"26.00"`) {
+		t.Errorf("expected a diff with the new price, got %+v", event)
+	}
+}
+
+// TestScheduleSaveFailure tests that a change is not scheduled if the schedule cannot be saved
+func TestScheduleSaveFailure(t *testing.T) {
+	t.Setenv("API_KEYS", "cat:merchandiser:m")
+	useSchedule(t)
+	storage.SaveScheduleFunc = func([]domain.ScheduledChange) error { return errors.New("disk full") }
+	_, ts := newTestServer(t)
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	resp, body := scheduleRequest(t, "POST", ts.URL+"/v1/schedule", "cat.m", `{"effective_at": "`+future+`", "provider": "UPS"}`)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", resp.StatusCode, body)
+	}
+	if changes := domain.Schedule(); len(changes) != 0 {
+		t.Errorf("expected the schedule to be unchanged, got %+v", changes)
+	}
+}
@@ -1,45 +1,347 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
 	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+	"github.com/PythonAkoto/base_techtest/adapters/output/webhooks"
 	"github.com/PythonAkoto/base_techtest/env"
 )
 
-func StartHTTPServer() {
-	logs.Logs(1, "Starting HTTP server...", "")
-	logs.Logs(1, "Loading environment variables...", "")
-	// Load environment variables
-	err := env.LoadEnv("env/.env")
+// defaultHandlerTimeout is used when HTTP_HANDLER_TIMEOUT is not set.
+const defaultHandlerTimeout = 10 * time.Second
+
+// defaultShutdownTimeout is used when HTTP_SHUTDOWN_TIMEOUT is not set.
+const defaultShutdownTimeout = 15 * time.Second
+
+/*
+Server is the HTTP API. It owns a dedicated ServeMux rather than using http.DefaultServeMux,
+so several servers can exist side by side and tests can run one with httptest.NewServer.
+*/
+type Server struct {
+	mux       *http.ServeMux
+	handler   http.Handler
+	timeout   time.Duration
+	keys      *access.KeyStore
+	anonymous access.Role
+	limiter   access.Limiter
+	cors      CORSConfig
+	feeds     *priceFeeds
+	webhooks  *webhooks.Dispatcher
+	watchers  *webhookWatchers
+	schedule  *pricingSchedule
+	catalogue *catalogueWatcher
+	audit     *audit.Log
+	preflight map[string]bool // paths with an OPTIONS route registered
+	patterns  []string        // every pattern registered by handle and handleCORS
+}
+
+/*
+LoadDomainConfig loads the promotions, discount codes, zone tables, service levels and bank holidays
+from storage into the domain, logging any that cannot be read. The HTTP and gRPC APIs both price
+with them, so it is called once at start up before either server is built.
+*/
+func LoadDomainConfig() {
+	if _, err := loadPromotions(); err != nil {
+		logs.Logs(3, "failed to load promotions: "+err.Error(), "")
+	}
+	if err := loadDiscountCodes(); err != nil {
+		logs.Logs(3, "failed to load discount codes: "+err.Error(), "")
+	}
+	if _, err := loadZoneTables(); err != nil {
+		logs.Logs(3, "failed to load zone tables: "+err.Error(), "")
+	}
+	if _, err := loadServiceLevels(); err != nil {
+		logs.Logs(3, "failed to load service levels: "+err.Error(), "")
+	}
+	if err := loadBankHolidays(); err != nil {
+		logs.Logs(3, "failed to load bank holidays: "+err.Error(), "")
+	}
+}
+
+/*
+NewServer builds a Server with all routes registered. Every request passes through the
+request ID, logging, recovery and compression middlewares; per-route middlewares are added in routes.
+If the API keys cannot be loaded the error is logged and only anonymous access is possible.
+The domain configuration is loaded separately, by LoadDomainConfig.
+*/
+func NewServer() *Server {
+	keys, err := access.LoadKeyStore()
+	if err != nil {
+		logs.Logs(3, "failed to load API keys: "+err.Error(), "")
+		keys = access.NewKeyStore()
+	}
+
+	dispatcher, err := webhooks.NewDispatcher(webhooks.LoadConfig())
+	if err != nil {
+		logs.Logs(3, "failed to load webhook subscriptions: "+err.Error(), "")
+	}
+
+	auditLog, err := audit.Open(os.Getenv("AUDIT_LOG_FILE"))
 	if err != nil {
-		logs.Logs(3, fmt.Sprintf("failed to load environment variables: %s", err.Error()), "")
+		logs.Logs(3, "failed to load audit log: "+err.Error(), "")
 	}
 
-	// initialise HTTP templates
+	s := &Server{
+		mux:       http.NewServeMux(),
+		timeout:   env.Duration("HTTP_HANDLER_TIMEOUT", defaultHandlerTimeout),
+		keys:      keys,
+		anonymous: access.AnonymousRole(),
+		limiter:   access.NewMemoryLimiter(),
+		cors:      LoadCORSConfig(),
+		feeds:     newPriceFeeds(LoadStreamConfig()),
+		webhooks:  dispatcher,
+		audit:     auditLog,
+		catalogue: newCatalogueWatcher(env.Duration("PRICE_HISTORY_POLL_INTERVAL", 5*time.Second)),
+		preflight: make(map[string]bool),
+	}
+	s.watchers = newWebhookWatchers(s.feeds, s.webhooks)
+	s.schedule = newPricingSchedule(s.feeds)
+	historyMu.Lock()
+	priceAudit = s.audit
+	historyMu.Unlock()
+	if err := s.schedule.load(); err != nil {
+		logs.Logs(3, "failed to load pricing schedule: "+err.Error(), "")
+	}
+	if err := loadPriceHistory(); err != nil {
+		logs.Logs(3, "failed to load price history: "+err.Error(), "")
+	}
+	observePrices(time.Now(), actorSystem, "server started")
+	go s.catalogue.run()
+	s.watchers.sync()
+	s.routes()
+	s.handler = Chain(http.HandlerFunc(s.dispatch), RequestIDMiddleware, LoggingMiddleware, RecoveryMiddleware, CompressionMiddleware)
+	return s
+}
 
-	// static file server for assets like CSS, JS, images
+// routes defines the routes and handlers served by the API, along with the role each requires.
+func (s *Server) routes() {
+	s.handle("GET /{$}", access.RolePublic, http.HandlerFunc(Hello))
+	s.handle("GET /metrics", access.RoleAdmin, metrics.Handler())
+	// the unversioned paths are an alias of v1, kept for existing clients
+	s.versionRoutes(v2.withSchedule())
+	s.versionRoutes(v1.withSchedule())
+	s.versionRoutes(v1.withSchedule().alias())
+	s.handleCORS("GET /graphql", access.RoleReader, http.HandlerFunc(GraphQLHandler), s.rateLimit("graphql", access.RateLimit{Rate: 5, Burst: 20}), TimeoutMiddleware(s.timeout))
+	s.handleCORS("POST /graphql", access.RoleReader, http.HandlerFunc(GraphQLHandler), s.rateLimit("graphql", access.RateLimit{Rate: 5, Burst: 20}), TimeoutMiddleware(s.timeout))
+	s.handleCORS("POST /v1/checkout", access.RoleReader, http.HandlerFunc(s.checkoutHandler), s.rateLimit("checkout", access.RateLimit{Rate: 5, Burst: 20}), TimeoutMiddleware(s.timeout))
+	s.handle("GET /v1/schedule", access.RoleReader, http.HandlerFunc(s.scheduleHandler))
+	s.handle("POST /v1/schedule", access.RoleMerchandiser, http.HandlerFunc(s.createScheduleHandler))
+	s.handle("GET /v1/schedule/{id}", access.RoleReader, http.HandlerFunc(s.getScheduleHandler))
+	s.handle("DELETE /v1/schedule/{id}", access.RoleMerchandiser, http.HandlerFunc(s.deleteScheduleHandler))
+	s.handle("GET /v1/discount-codes", access.RoleMerchandiser, http.HandlerFunc(s.discountCodesHandler))
+	s.handle("POST /v1/discount-codes", access.RoleMerchandiser, http.HandlerFunc(s.createDiscountCodeHandler))
+	s.handle("GET /v1/discount-codes/{code}", access.RoleMerchandiser, http.HandlerFunc(s.getDiscountCodeHandler))
+	s.handle("DELETE /v1/discount-codes/{code}", access.RoleMerchandiser, http.HandlerFunc(s.deleteDiscountCodeHandler))
+	s.handle("POST /admin/reload", access.RoleAdmin, http.HandlerFunc(s.reloadConfigHandler))
+	s.handle("GET /audit", access.RoleAdmin, http.HandlerFunc(s.auditHandler))
+	s.handle("GET /audit/verify", access.RoleAdmin, http.HandlerFunc(s.verifyAuditHandler))
+	s.handle("POST /admin/webhooks", access.RoleAdmin, http.HandlerFunc(s.createWebhookHandler))
+	s.handle("GET /admin/webhooks", access.RoleAdmin, http.HandlerFunc(s.listWebhooksHandler))
+	s.handle("GET /admin/webhooks/{id}", access.RoleAdmin, http.HandlerFunc(s.getWebhookHandler))
+	s.handle("DELETE /admin/webhooks/{id}", access.RoleAdmin, http.HandlerFunc(s.deleteWebhookHandler))
+	s.handle("GET /admin/webhooks/{id}/deliveries", access.RoleAdmin, http.HandlerFunc(s.webhookDeliveriesHandler))
+	s.handle("GET /admin/webhooks/dead-letters", access.RoleAdmin, http.HandlerFunc(s.deadLettersHandler))
+	s.handle("POST /admin/webhooks/dead-letters/{id}/retry", access.RoleAdmin, http.HandlerFunc(s.redeliverHandler))
+	s.handle("GET /openapi.json", access.RolePublic, http.HandlerFunc(OpenAPIHandler))
+}
+
+/*
+versionRoutes mounts the pricing routes for an API version under its prefix. To add a version,
+write a presenter for its DTOs and mount it here, setting the previous version's successor.
+The rate limit buckets are shared by every version of a route. Streams are long lived, so they
+are not given the handler timeout.
+*/
+func (s *Server) versionRoutes(v apiVersion) {
+	s.handleCORS("GET "+v.prefix+"/products/stream", access.RoleReader, s.streamHandler(v), deprecationMiddleware(v), s.rateLimit("stream", access.RateLimit{Rate: 1, Burst: 5}))
+	s.handleCORS("GET "+v.prefix+"/products", access.RoleReader, http.HandlerFunc(v.productsHandler), deprecationMiddleware(v), s.rateLimit("products", access.RateLimit{Rate: 5, Burst: 20}), TimeoutMiddleware(s.timeout))
+	s.handleCORS("GET "+v.prefix+"/products/{name}", access.RoleReader, http.HandlerFunc(v.productHandler), deprecationMiddleware(v), s.rateLimit("product", access.RateLimit{Rate: 20, Burst: 40}), TimeoutMiddleware(s.timeout))
+}
 
-	// define roiutes and handlers
-	http.HandleFunc("/", Hello)
-	http.HandleFunc("/products", GetProductsHandler)
+/*
+handle registers a handler for a method and path pattern. The caller must hold at least the
+given role, and the handler is wrapped in any route specific middlewares.
+*/
+func (s *Server) handle(pattern string, role access.Role, handler http.Handler, middlewares ...Middleware) {
+	middlewares = append([]Middleware{requireRole(s.keys, s.anonymous, role)}, middlewares...)
+	s.mux.Handle(pattern, Chain(handler, middlewares...))
+	s.patterns = append(s.patterns, pattern)
+}
 
-	applicationPort := os.Getenv("APP_PORT") // get application port from environment variable
-	
-	// if application port missing from env, default to 8080
-	if applicationPort == "" {
-		logs.Logs(2, "APP_PORT environment variable not set, defaulting to port 8080", "")
-		applicationPort = "8080" // default port
+/*
+handleCORS registers a route like handle, but applies the CORS policy before authentication
+and registers an OPTIONS route for the path so browser preflight requests are answered.
+*/
+func (s *Server) handleCORS(pattern string, role access.Role, handler http.Handler, middlewares ...Middleware) {
+	middlewares = append([]Middleware{corsMiddleware(s.cors), requireRole(s.keys, s.anonymous, role)}, middlewares...)
+	s.mux.Handle(pattern, Chain(handler, middlewares...))
+	s.patterns = append(s.patterns, pattern)
+
+	_, path, _ := strings.Cut(pattern, " ")
+	if !s.preflight[path] {
+		s.preflight[path] = true
+		s.mux.Handle("OPTIONS "+path, Chain(http.HandlerFunc(noContent), corsMiddleware(s.cors)))
 	}
-	
-	logs.Logs(1, fmt.Sprintf("Application port set to: %s", applicationPort), "")
+}
+
+// noContent answers OPTIONS requests that are not CORS preflights.
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Keys returns the API keys the server authenticates with. A reload replaces them in place, so other APIs can share them.
+func (s *Server) Keys() *access.KeyStore {
+	return s.keys
+}
+
+// Limiter returns the server's rate limiter, so other APIs can count requests against the same buckets.
+func (s *Server) Limiter() access.Limiter {
+	return s.limiter
+}
 
-	// start HTTP server
-	logs.Logs(1, "application started successfully on http://localhost:"+applicationPort, "")
-	err = http.ListenAndServe(fmt.Sprintf(":%s", applicationPort), nil)
+// rateLimit returns the rate limiting middleware for a route, using its configured or default limit.
+func (s *Server) rateLimit(route string, defaults access.RateLimit) Middleware {
+	return rateLimitMiddleware(s.limiter, route, access.LoadRateLimit(route, defaults))
+}
+
+/*
+Close ends every open price stream, stops applying scheduled changes and watching the catalogue,
+and stops webhook deliveries, waiting for attempts in flight.
+Streams never finish on their own, so Close must be called when the server shuts down or
+http.Server.Shutdown will wait for them until it times out.
+*/
+func (s *Server) Close() {
+	s.schedule.Close()
+	s.catalogue.Close()
+	s.feeds.Close()
+	s.webhooks.Close()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+/*
+dispatch routes the request through the ServeMux. Requests that match no pattern are answered
+with a JSON 404, or a JSON 405 with an Allow header when the path exists for other methods,
+instead of the mux's plain-text responses.
+*/
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request) {
+	handler, pattern := s.mux.Handler(r)
+	if pattern != "" {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// let the mux decide between 404 and 405 without writing its plain-text body
+	capture := &headerCapture{header: http.Header{}}
+	handler.ServeHTTP(capture, r)
+
+	if capture.status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", capture.header.Get("Allow"))
+		writeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed", map[string]string{"method": r.Method})
+		return
+	}
+	writeError(w, r, http.StatusNotFound, codeNotFound, "Route not found", map[string]string{"path": r.URL.Path})
+}
+
+// headerCapture is a ResponseWriter that keeps the status and headers but discards the body.
+type headerCapture struct {
+	header http.Header
+	status int
+}
+
+func (c *headerCapture) Header() http.Header         { return c.header }
+func (c *headerCapture) Write(b []byte) (int, error) { return len(b), nil }
+func (c *headerCapture) WriteHeader(status int)      { c.status = status }
+
+/*
+StartHTTPServer serves the API until ctx is done or the listener fails. HTTPS is used when TLS_CERT_FILE and TLS_KEY_FILE are set, optionally with a
+plain HTTP listener on HTTP_REDIRECT_PORT that redirects clients to HTTPS. When ctx is done the
+server stops accepting connections, closes open price streams and waits up to
+HTTP_SHUTDOWN_TIMEOUT for in-flight requests to finish.
+*/
+func StartHTTPServer(ctx context.Context, server *Server) {
+	logs.Logs(1, "Starting HTTP server...", "")
+
+	cfg := LoadServerConfig()
+	logs.Logs(1, fmt.Sprintf("Application port set to: %s", cfg.Port), "")
+	httpServer := newHTTPServer(cfg, server)
+	httpServer.RegisterOnShutdown(server.Close)
+
+	if !cfg.TLSEnabled() {
+		// start HTTP server
+		logs.Logs(1, "application started successfully on http://localhost:"+cfg.Port, "")
+		err := serveUntilDone(ctx, httpServer, httpServer.ListenAndServe)
+		if err != nil {
+			logs.Logs(3, fmt.Sprintf("failed to start HTTP server: %s", err.Error()), "")
+		}
+		return
+	}
+
+	reloader, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		logs.Logs(3, fmt.Sprintf("failed to load TLS certificate: %s", err.Error()), "")
+		return
+	}
+	httpServer.TLSConfig = reloader.TLSConfig()
+
+	if cfg.RedirectPort != "" {
+		redirectConfig := cfg
+		redirectConfig.Port = cfg.RedirectPort
+		redirectServer := newHTTPServer(redirectConfig, httpsRedirectHandler(cfg.Port))
+		go func() {
+			logs.Logs(1, "redirecting HTTP requests on port "+cfg.RedirectPort+" to HTTPS", "")
+			err := serveUntilDone(ctx, redirectServer, redirectServer.ListenAndServe)
+			if err != nil {
+				logs.Logs(3, fmt.Sprintf("failed to start HTTP redirect server: %s", err.Error()), "")
+			}
+		}()
+	}
+
+	// start HTTPS server, the certificate comes from the reloader's TLS config
+	logs.Logs(1, "application started successfully on https://localhost:"+cfg.Port, "")
+	err = serveUntilDone(ctx, httpServer, func() error { return httpServer.ListenAndServeTLS("", "") })
 	if err != nil {
-		logs.Logs(3, fmt.Sprintf("failed to start HTTP server: %s", err.Error()), "")
+		logs.Logs(3, fmt.Sprintf("failed to start HTTPS server: %s", err.Error()), "")
+	}
+}
+
+/*
+serveUntilDone runs serve until it fails or ctx is done, then shuts httpServer down gracefully.
+It returns nil after a clean shutdown, or the error from serve or Shutdown.
+*/
+func serveUntilDone(ctx context.Context, httpServer *http.Server, serve func() error) error {
+	errs := make(chan error, 1)
+	go func() { errs <- serve() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := env.Duration("HTTP_SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	logs.Logs(1, fmt.Sprintf("shutting down HTTP server on %s, waiting up to %s for requests to finish", httpServer.Addr, timeout), "")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(shutdownCtx)
+	if serveErr := <-errs; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	if err == nil {
+		logs.Logs(1, "HTTP server on "+httpServer.Addr+" stopped", "")
 	}
+	return err
 }
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// newTestServer starts the API on an httptest.Server with a stub catalogue
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	log.SetFlags(0)
+	go logs.ProcessLogs()
+
+	originalLoadProductsFunc := storage.LoadProductsFunc
+	storage.LoadProductsFunc = func() ([]domain.Product, error) {
+		return []domain.Product{{Name: "TV", Weight: 1.5, Price: 20}}, nil
+	}
+	os.Setenv("DELIVERY_PROVIDER", "DHL")
+	os.Setenv("DHL_DELIVERY_PRICE", "2.00")
+
+	LoadDomainConfig()
+	server := NewServer()
+	ts := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Close() // end any open streams, or ts.Close waits for them
+		ts.Close()
+		storage.LoadProductsFunc = originalLoadProductsFunc
+		os.Unsetenv("DELIVERY_PROVIDER")
+		os.Unsetenv("DHL_DELIVERY_PRICE")
+	})
+	return server, ts
+}
+
+// decodeError reads an ErrorResponse from a response body
+func decodeError(t *testing.T, resp *http.Response) ErrorResponse {
+	t.Helper()
+	var errResp ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return errResp
+}
+
+// TestServerRouting tests method matching, path parameters and JSON 404/405 responses
+func TestServerRouting(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  string
+		expectedBody string
+	}{
+		{name: "hello on root", method: "GET", path: "/", expectedCode: http.StatusOK, expectedBody: "Hello Base!\n"},
+		{name: "products list", method: "GET", path: "/products", expectedCode: http.StatusOK},
+		{name: "single product by path parameter", method: "GET", path: "/products/TV", expectedCode: http.StatusOK},
+		{name: "unknown product", method: "GET", path: "/products/radio", expectedCode: http.StatusNotFound, expectedErr: codeProductNotFound},
+		{name: "unknown path no longer served by root", method: "GET", path: "/unknown", expectedCode: http.StatusNotFound, expectedErr: codeNotFound},
+		{name: "wrong method", method: "POST", path: "/products", expectedCode: http.StatusMethodNotAllowed, expectedErr: codeMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, ts.URL+tc.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedCode {
+				t.Errorf("expected code %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+			if resp.Header.Get("X-Request-ID") == "" {
+				t.Error("expected an X-Request-ID header")
+			}
+			if tc.expectedErr != "" {
+				errResp := decodeError(t, resp)
+				if errResp.Code != tc.expectedErr {
+					t.Errorf("expected error code %q, got %q", tc.expectedErr, errResp.Code)
+				}
+				if errResp.RequestID != resp.Header.Get("X-Request-ID") {
+					t.Errorf("expected request_id %q to match header, got %q", resp.Header.Get("X-Request-ID"), errResp.RequestID)
+				}
+			}
+			if tc.expectedBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != tc.expectedBody {
+					t.Errorf("expected body %q, got %q", tc.expectedBody, string(body))
+				}
+			}
+			if tc.expectedCode == http.StatusMethodNotAllowed && !strings.Contains(resp.Header.Get("Allow"), "GET") {
+				t.Errorf("expected Allow header to list GET, got %q", resp.Header.Get("Allow"))
+			}
+		})
+	}
+}
+
+// TestMetricsHandler tests that metrics need an admin key and leave out expvar's command line and memory statistics
+func TestMetricsHandler(t *testing.T) {
+	t.Setenv("API_KEYS", "web:reader:r,ops:admin:a")
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected anonymous requests to be refused, got %d", resp.StatusCode)
+	}
+
+	resp, body := adminRequest(t, "GET", ts.URL+"/metrics", "")
+	var published map[string]json.RawMessage
+	if err := json.Unmarshal(body, &published); resp.StatusCode != http.StatusOK || err != nil {
+		t.Fatalf("expected the metrics as JSON, got %d %s", resp.StatusCode, body)
+	}
+	if _, ok := published["http_panics_total"]; !ok {
+		t.Errorf("expected the application's counters, got %s", body)
+	}
+	for _, name := range []string{"cmdline", "memstats"} {
+		if _, ok := published[name]; ok {
+			t.Errorf("expected %s to be left out", name)
+		}
+	}
+}
+
+// TestRequestIDIsEchoed tests that a caller supplied request ID is reused
+func TestRequestIDIsEchoed(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/unknown", nil)
+	req.Header.Set("X-Request-ID", "caller-id")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("X-Request-ID"); got != "caller-id" {
+		t.Errorf("expected X-Request-ID caller-id, got %q", got)
+	}
+	if errResp := decodeError(t, resp); errResp.RequestID != "caller-id" {
+		t.Errorf("expected request_id caller-id, got %q", errResp.RequestID)
+	}
+}
+
+// TestRecoveryMiddleware tests that a panicking handler returns a JSON 500
+func TestRecoveryMiddleware(t *testing.T) {
+	server, ts := newTestServer(t)
+	server.handle("GET /panic", access.RolePublic, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	panicsBefore := panicsTotal.Value()
+
+	resp, err := http.Get(ts.URL + "/panic")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected code 500, got %d", resp.StatusCode)
+	}
+	if errResp := decodeError(t, resp); errResp.Code != codeInternal || errResp.RequestID == "" {
+		t.Errorf("unexpected error response: %+v", errResp)
+	}
+	if got := panicsTotal.Value() - panicsBefore; got != 1 {
+		t.Errorf("expected panic counter to increase by 1, got %d", got)
+	}
+}
+
+// TestTimeoutMiddleware tests that a handler exceeding its deadline returns a JSON 503
+func TestTimeoutMiddleware(t *testing.T) {
+	go logs.ProcessLogs()
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	handler := Chain(slow, RequestIDMiddleware, TimeoutMiddleware(10*time.Millisecond))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/products", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected code 503, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"timeout"`) {
+		t.Errorf("expected timeout error, got %q", w.Body.String())
+	}
+}
+
+// TestRequestIDMiddleware tests that a valid client request ID is kept and any other is replaced
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string // empty if a new ID should be generated
+	}{
+		{name: "client id", header: "checkout-42_a.b", expected: "checkout-42_a.b"},
+		{name: "longest client id", header: strings.Repeat("a", 128), expected: strings.Repeat("a", 128)},
+		{name: "missing"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+		{name: "space", header: "abc def"},
+		{name: "log injection", header: "abc\" level=ERROR"},
+		{name: "non ascii", header: "café"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen string
+			handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestID(w, r)
+			}))
+
+			req := httptest.NewRequest("GET", "/products", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Request-ID", tc.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get("X-Request-ID")
+			if seen != id {
+				t.Errorf("expected the handler to see %q, got %q", id, seen)
+			}
+			if tc.expected != "" && id != tc.expected {
+				t.Errorf("expected request ID %q, got %q", tc.expected, id)
+			}
+			if tc.expected == "" && (id == tc.header || len(id) != 32) {
+				t.Errorf("expected a generated request ID, got %q", id)
+			}
+		})
+	}
+}
+
+// TestChainOrder tests that the first middleware passed to Chain is the outermost
+func TestChainOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), record("a"), record("b"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(order, ",") != "a,b,handler" {
+		t.Errorf("expected order a,b,handler, got %v", order)
+	}
+}
+
+// decodeRecorderError reads an ErrorResponse from a recorded response
+func decodeRecorderError(t *testing.T, w *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var errResp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return errResp
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+/*
+loadServiceLevels replaces the carriers' service levels with those in storage, which may have been
+edited by hand. If they cannot be read or one is invalid the current levels are kept. It returns an
+audit entry, without an actor or reason, for each service level that was added, edited or removed.
+*/
+func loadServiceLevels() ([]audit.Entry, error) {
+	levels, err := storage.LoadServiceLevelsFunc()
+	if err != nil {
+		return nil, err
+	}
+	before := domain.ServiceLevels()
+	if err := domain.SetServiceLevels(levels); err != nil {
+		return nil, err
+	}
+	return serviceLevelChanges(before, domain.ServiceLevels()), nil
+}
+
+// serviceLevelChanges compares two sets of service levels by provider and service.
+func serviceLevelChanges(before, after []domain.ServiceLevel) []audit.Entry {
+	return configChanges("service_level", before, after, func(l domain.ServiceLevel) string { return l.Provider + ":" + string(l.Service) })
+}
+
+// loadBankHolidays replaces the bank holidays with those in storage. If they cannot be read or one is malformed the current ones are kept.
+func loadBankHolidays() error {
+	dates, err := storage.LoadBankHolidaysFunc()
+	if err != nil {
+		return err
+	}
+	return domain.SetBankHolidays(dates)
+}
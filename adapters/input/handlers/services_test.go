@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// useServiceLevels loads the given service levels from storage instead of SERVICE_LEVELS_FILE, until the test ends
+func useServiceLevels(t *testing.T, levels ...domain.ServiceLevel) {
+	t.Helper()
+	original := storage.LoadServiceLevelsFunc
+	storage.LoadServiceLevelsFunc = func() ([]domain.ServiceLevel, error) { return levels, nil }
+	t.Cleanup(func() {
+		storage.LoadServiceLevelsFunc = original
+		domain.SetServiceLevels(nil)
+	})
+}
+
+// TestDeliveryOptions tests listing the provider's service levels with each priced product, and reloading them
+func TestDeliveryOptions(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	t.Cleanup(func() { envFile = originalEnvFile })
+	t.Setenv("API_KEYS", "ops:admin:a")
+	t.Setenv("UPS_DELIVERY_PRICE", "1")
+	useServiceLevels(t,
+		domain.ServiceLevel{Provider: "DHL", Service: domain.ServiceStandard, TransitDays: 3, WorkingDaysOnly: true},
+		domain.ServiceLevel{Provider: "DHL", Service: domain.ServiceNextDay, Rate: 4, CutOff: "15:00", TransitDays: 1, WorkingDaysOnly: true},
+		domain.ServiceLevel{Provider: "DHL", Service: domain.ServiceTimed, Rate: 6, TransitDays: 1, WorkingDaysOnly: true, DeliverBy: "10:30"},
+	)
+	useZoneTables(t, domain.ZoneTable{Provider: "DHL", Rates: map[domain.Zone]float64{domain.ZoneEU: 8}})
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []domain.DeliveryOption
+	}{
+		{name: "every service", query: "", expected: []domain.DeliveryOption{
+			{Service: domain.ServiceStandard, DeliveryPrice: "3.00", TotalPrice: "23.00"},
+			{Service: domain.ServiceNextDay, DeliveryPrice: "6.00", TotalPrice: "26.00"},
+			{Service: domain.ServiceTimed, DeliveryPrice: "9.00", TotalPrice: "29.00", DeliverBy: "10:30"},
+		}},
+		{name: "only standard outside the mainland", query: "?destination=FR", expected: []domain.DeliveryOption{
+			{Service: domain.ServiceStandard, DeliveryPrice: "12.00", TotalPrice: "32.00"},
+		}},
+		{name: "provider without service levels", query: "?provider=UPS"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/v2/products/tv" + tc.query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			var product domain.PricedProduct
+			json.NewDecoder(resp.Body).Decode(&product)
+			if resp.StatusCode != http.StatusOK || len(product.DeliveryOptions) != len(tc.expected) {
+				t.Fatalf("expected %d delivery options, got %d %+v", len(tc.expected), resp.StatusCode, product)
+			}
+			london, _ := time.LoadLocation("Europe/London")
+			today := time.Now().In(london).Format(time.DateOnly)
+			for i, expected := range tc.expected {
+				option := product.DeliveryOptions[i]
+				expected.EstimatedDelivery = option.EstimatedDelivery
+				if option != expected {
+					t.Errorf("expected %+v, got %+v", expected, option)
+				}
+				// every service takes at least a day in transit
+				if _, err := time.Parse(time.DateOnly, option.EstimatedDelivery); err != nil || option.EstimatedDelivery <= today {
+					t.Errorf("expected a date after today, got %q", option.EstimatedDelivery)
+				}
+			}
+		})
+	}
+
+	etag := func() string {
+		resp, err := http.Get(ts.URL + "/v2/products/tv")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("ETag")
+	}
+	before := etag()
+
+	// a faster standard service from a reload is audited and changes the ETag
+	storage.LoadServiceLevelsFunc = func() ([]domain.ServiceLevel, error) {
+		return []domain.ServiceLevel{{Provider: "DHL", Service: domain.ServiceStandard, TransitDays: 2, WorkingDaysOnly: true}}, nil
+	}
+	if resp, body := adminRequest(t, "POST", ts.URL+"/admin/reload?reason=faster+standard", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d: %s", resp.StatusCode, body)
+	}
+	if etag() == before {
+		t.Error("expected the service levels to change the ETag")
+	}
+	records := auditRecords(t, ts.URL+"/audit?entity=service_level")
+	if len(records) != 3 || records[0].Reason != "faster standard" {
+		t.Errorf("expected the edited and removed service levels to be audited, got %+v", records)
+	}
+
+	// invalid service levels or bank holidays fail the reload and keep the current ones
+	storage.LoadServiceLevelsFunc = func() ([]domain.ServiceLevel, error) {
+		return []domain.ServiceLevel{{Provider: "DHL", Service: domain.ServiceNextDay}}, nil
+	}
+	if resp, _ := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected an invalid service level to fail the reload, got %d", resp.StatusCode)
+	}
+	if levels := domain.ServiceLevels(); len(levels) != 1 || levels[0].TransitDays != 2 {
+		t.Errorf("expected the current levels to be kept, got %+v", levels)
+	}
+
+	calendar := filepath.Join(t.TempDir(), "bank-holidays.txt")
+	os.WriteFile(calendar, []byte("# England and Wales\n2026-12-25\n\n2026-12-28\n"), 0o600)
+	t.Setenv("BANK_HOLIDAYS_FILE", calendar)
+	t.Cleanup(func() { domain.SetBankHolidays(nil) })
+	storage.LoadServiceLevelsFunc = func() ([]domain.ServiceLevel, error) { return nil, nil }
+	if resp, body := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d: %s", resp.StatusCode, body)
+	}
+	if holidays := domain.BankHolidays(); len(holidays) != 2 || holidays[1] != "2026-12-28" {
+		t.Errorf("expected the bank holidays to be loaded, got %v", holidays)
+	}
+	os.WriteFile(calendar, []byte("Christmas Day\n"), 0o600)
+	if resp, _ := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected a malformed bank holiday to fail the reload, got %d", resp.StatusCode)
+	}
+	var invalid *domain.ErrInvalidBankHoliday
+	if err := loadBankHolidays(); !errors.As(err, &invalid) || len(domain.BankHolidays()) != 2 {
+		t.Errorf("expected an *ErrInvalidBankHoliday and the bank holidays kept, got %v %v", err, domain.BankHolidays())
+	}
+}
@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// streamWriteTimeout is how long a single event may take to reach a client before the stream is dropped.
+const streamWriteTimeout = 10 * time.Second
+
+// streamClients is the number of open price streams.
+var streamClients = metrics.NewGauge("http_stream_clients")
+
+// errProviderNotSet is returned when a stream follows the default provider but DELIVERY_PROVIDER is empty.
+var errProviderNotSet = errors.New("delivery provider not set")
+
+// StreamConfig holds the settings for the price change streams.
+type StreamConfig struct {
+	PollInterval      time.Duration // how often the catalogue and pricing are checked for changes
+	HeartbeatInterval time.Duration // how often an idle stream sends a comment to keep proxies from closing it
+	Retry             time.Duration // how long clients wait before reconnecting
+	History           int           // how many diff events are kept for Last-Event-ID resume
+}
+
+// LoadStreamConfig reads the stream settings from environment variables, falling back to defaults.
+func LoadStreamConfig() StreamConfig {
+	return StreamConfig{
+		PollInterval:      env.Duration("STREAM_POLL_INTERVAL", 5*time.Second),
+		HeartbeatInterval: env.Duration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		Retry:             env.Duration("STREAM_RETRY", 3*time.Second),
+		History:           env.Int("STREAM_HISTORY", 100),
+	}
+}
+
+/*
+streamEvent is a change to a feed's priced catalogue. A snapshot holds every product; a diff
+holds the products that were added or changed and the names of those removed.
+*/
+type streamEvent struct {
+	id       uint64
+	name     string // "snapshot" or "diff"
+	provider string
+	products []domain.PricedProduct
+	removed  []string
+}
+
+// feedKey identifies a feed: the provider it prices with and the API version whose DTOs it diffs.
+type feedKey struct {
+	provider string
+	version  string
+}
+
+/*
+priceFeeds holds a feed per provider and API version, created when the first client streams it. Closing it ends
+every stream, which is how streams are closed cleanly on graceful shutdown.
+*/
+type priceFeeds struct {
+	config StreamConfig
+	done   chan struct{}
+	close  sync.Once
+
+	mu    sync.Mutex
+	feeds map[feedKey]*priceFeed
+}
+
+// newPriceFeeds returns an empty set of feeds using config.
+func newPriceFeeds(config StreamConfig) *priceFeeds {
+	return &priceFeeds{config: config, done: make(chan struct{}), feeds: make(map[feedKey]*priceFeed)}
+}
+
+/*
+feed returns the feed for provider in v's DTOs, creating it if needed. An empty provider follows
+DELIVERY_PROVIDER. Each version has its own feed, so a change only shows up in a version's
+stream if the version's DTO changed.
+*/
+func (pf *priceFeeds) feed(provider string, v apiVersion) *priceFeed {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	key := feedKey{provider: provider, version: v.name}
+	f, ok := pf.feeds[key]
+	if !ok {
+		f = &priceFeed{
+			provider:    provider,
+			presenter:   v.presenter,
+			epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+			config:      pf.config,
+			done:        pf.done,
+			subscribers: make(map[chan struct{}]bool),
+		}
+		pf.feeds[key] = f
+	}
+	return f
+}
+
+// refresh checks every feed with subscribers for changes straight away, rather than at its next poll.
+func (pf *priceFeeds) refresh() {
+	pf.mu.Lock()
+	feeds := make([]*priceFeed, 0, len(pf.feeds))
+	for _, f := range pf.feeds {
+		feeds = append(feeds, f)
+	}
+	pf.mu.Unlock()
+
+	for _, f := range feeds {
+		if f.active() {
+			if err := f.refresh(); err != nil {
+				logs.Logs(2, "failed to refresh price stream: "+err.Error(), f.provider)
+			}
+		}
+	}
+}
+
+// Close ends every stream and stops polling. It is safe to call more than once.
+func (pf *priceFeeds) Close() {
+	pf.close.Do(func() { close(pf.done) })
+}
+
+/*
+priceFeed tracks the priced catalogue for one provider and the recent changes to it. Event IDs
+are "<epoch>-<sequence>", where the epoch is unique to this feed, so an ID from before a restart
+is recognised as unknown and answered with a fresh snapshot.
+*/
+type priceFeed struct {
+	provider  string    // requested provider, empty to follow DELIVERY_PROVIDER
+	presenter presenter // the DTOs changes are looked for in
+	epoch     string
+	config    StreamConfig
+	done      <-chan struct{}
+
+	refreshing sync.Mutex // serialises refreshes so changes are applied in order
+
+	mu          sync.Mutex
+	seq         uint64 // ID of the latest event, 0 until the first successful refresh
+	resolved    string // provider the current prices were calculated with
+	key         string // fingerprint of the catalogue and pricing behind the current prices
+	current     []domain.PricedProduct
+	events      []streamEvent // recent diffs, oldest first
+	subscribers map[chan struct{}]bool
+	polling     bool
+}
+
+/*
+subscribe registers a client and returns a channel that receives a value whenever there are
+new events. Polling starts with the first subscriber and stops once the last has gone.
+*/
+func (f *priceFeed) subscribe() chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notify := make(chan struct{}, 1)
+	f.subscribers[notify] = true
+	if !f.polling {
+		f.polling = true
+		go f.poll()
+	}
+	streamClients.Inc()
+	return notify
+}
+
+// unsubscribe removes a client registered with subscribe.
+func (f *priceFeed) unsubscribe(notify chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers, notify)
+	streamClients.Dec()
+}
+
+// active reports whether any client is subscribed.
+func (f *priceFeed) active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers) > 0
+}
+
+// ready reports whether the feed has prices to send.
+func (f *priceFeed) ready() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq > 0
+}
+
+// poll refreshes the feed every PollInterval until it has no subscribers or the feeds are closed.
+func (f *priceFeed) poll() {
+	ticker := time.NewTicker(f.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		if len(f.subscribers) == 0 {
+			f.polling = false
+			f.mu.Unlock()
+			return
+		}
+		f.mu.Unlock()
+
+		if err := f.refresh(); err != nil {
+			logs.Logs(2, "failed to refresh price stream: "+err.Error(), f.provider)
+		}
+	}
+}
+
+/*
+refresh prices the catalogue if it, the provider or the provider's pricing has changed since the
+last refresh, and records a diff event for any products whose prices changed. On error the
+previous prices are kept, so clients see the last good prices until the problem is fixed.
+*/
+func (f *priceFeed) refresh() error {
+	f.refreshing.Lock()
+	defer f.refreshing.Unlock()
+
+	provider := f.provider
+	if provider == "" {
+		provider = domain.DefaultProvider()
+		if provider == "" {
+			return errProviderNotSet
+		}
+	}
+
+	products, err := storage.LoadProductsFunc()
+	if err != nil {
+		return err
+	}
+	key := pricedETag(products, provider)
+
+	f.mu.Lock()
+	unchanged := f.seq > 0 && key == f.key
+	f.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	priced, err := domain.PriceProductsFunc(products, provider)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	previousSeq := f.seq
+	if f.seq == 0 {
+		f.seq = 1 // the first prices are the snapshot every stream starts from
+	} else if changed, removed := diffPrices(f.presenter, f.current, priced); len(changed) > 0 || len(removed) > 0 {
+		f.seq++
+		f.events = append(f.events, streamEvent{id: f.seq, name: "diff", provider: provider, products: changed, removed: removed})
+		if len(f.events) > f.config.History {
+			f.events = f.events[len(f.events)-f.config.History:]
+		}
+		logs.Logs(1, fmt.Sprintf("prices changed: %d updated, %d removed", len(changed), len(removed)), provider)
+	}
+	f.resolved, f.key, f.current = provider, key, priced
+
+	if f.seq != previousSeq {
+		for notify := range f.subscribers {
+			select {
+			case notify <- struct{}{}:
+			default: // the subscriber already has a notification pending
+			}
+		}
+	}
+	return nil
+}
+
+/*
+since returns the events a client that last saw lastID needs to catch up. A client that is up
+to date gets none; one whose ID is from another epoch, is unknown or is older than the history
+gets a snapshot of the current prices instead.
+*/
+func (f *priceFeed) since(lastID string) []streamEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	epoch, seqText, _ := strings.Cut(lastID, "-")
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err == nil && epoch == f.epoch && seq <= f.seq {
+		if seq == f.seq {
+			return nil
+		}
+		if len(f.events) > 0 && f.events[0].id <= seq+1 {
+			start := len(f.events) - int(f.seq-seq)
+			return slices.Clone(f.events[start:])
+		}
+	}
+	return []streamEvent{{id: f.seq, name: "snapshot", provider: f.resolved, products: f.current}}
+}
+
+// eventID formats a sequence number as an event ID for this feed.
+func (f *priceFeed) eventID(seq uint64) string {
+	return f.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+/*
+diffPrices returns the products in next that are new or whose DTO from p differs from previous,
+and the names of those no longer present. Comparing the DTOs rather than the domain products
+means a version is not sent changes to fields it does not show.
+*/
+func diffPrices(p presenter, previous []domain.PricedProduct, next []domain.PricedProduct) (changed []domain.PricedProduct, removed []string) {
+	before := make(map[string]domain.PricedProduct, len(previous))
+	for _, product := range previous {
+		before[product.Name] = product
+	}
+	for _, product := range next {
+		old, ok := before[product.Name]
+		if !ok || !reflect.DeepEqual(p.present(old), p.present(product)) {
+			changed = append(changed, product)
+		}
+		delete(before, product.Name)
+	}
+	for _, product := range previous {
+		if _, ok := before[product.Name]; ok {
+			removed = append(removed, product.Name)
+		}
+	}
+	return changed, removed
+}
+
+/*
+streamHandler serves the catalogue's prices as Server-Sent Events in the version's DTOs. A client
+gets a snapshot when it connects, then a diff whenever a price changes, with a heartbeat comment
+when there is nothing to send. Reconnecting with Last-Event-ID replays only the missed diffs.
+*/
+func (s *Server) streamHandler(v apiVersion) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// check a default provider is configured, even if the client asked for another
+		if _, ok := resolveProvider(w, r); !ok {
+			return
+		}
+		provider := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("provider")))
+		if provider != "" && !slices.Contains(domain.Providers(), provider) {
+			logs.Logs(2, "Stream requested for unknown provider", provider)
+			writeDomainError(w, r, fmt.Errorf("%w: %q", domain.ErrUnknownProvider, provider), map[string]string{"provider": provider})
+			return
+		}
+
+		feed := s.feeds.feed(provider, v)
+		notify := feed.subscribe()
+		defer feed.unsubscribe(notify)
+
+		// the first client needs prices to start from; later ones reuse the feed's
+		if err := feed.refresh(); err != nil {
+			if !feed.ready() {
+				logs.Logs(3, "Failed to start price stream: "+err.Error(), provider)
+				writeDomainError(w, r, err, map[string]string{"provider": provider})
+				return
+			}
+			logs.Logs(2, "Streaming the last known prices: "+err.Error(), provider)
+		}
+
+		// streams outlive the server's read and write timeouts, so each write sets its own deadline
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // stop nginx buffering events
+		w.WriteHeader(http.StatusOK)
+
+		send := func(message string) bool {
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if _, err := fmt.Fprint(w, message); err != nil {
+				return false
+			}
+			return rc.Flush() == nil
+		}
+
+		if !send(fmt.Sprintf("retry: %d\n\n", s.feeds.config.Retry.Milliseconds())) {
+			return
+		}
+
+		lastID := r.Header.Get("Last-Event-ID")
+		catchUp := func() bool {
+			for _, event := range feed.since(lastID) {
+				message, err := formatStreamEvent(feed, v.presenter, event)
+				if err != nil {
+					logs.Logs(3, "Failed to encode stream event: "+err.Error(), event.provider)
+					return false
+				}
+				if !send(message) {
+					return false
+				}
+				lastID = feed.eventID(event.id)
+			}
+			return true
+		}
+		if !catchUp() {
+			return
+		}
+		logs.Logs(1, "price stream opened", provider)
+
+		heartbeat := time.NewTicker(s.feeds.config.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				logs.Logs(1, "price stream closed by client", provider)
+				return
+			case <-s.feeds.done:
+				logs.Logs(1, "price stream closed for shutdown", provider)
+				return
+			case <-notify:
+				if !catchUp() {
+					return
+				}
+			case <-heartbeat.C:
+				if !send(": heartbeat\n\n") {
+					return
+				}
+			}
+		}
+	})
+}
+
+// formatStreamEvent encodes an event in the text/event-stream format, presenting products with p.
+func formatStreamEvent(feed *priceFeed, p presenter, event streamEvent) (string, error) {
+	products := make([]any, len(event.products))
+	for i, product := range event.products {
+		products[i] = p.present(product)
+	}
+
+	var data any
+	if event.name == "snapshot" {
+		data = map[string]any{"provider": event.provider, "products": products}
+	} else {
+		removed := event.removed
+		if removed == nil {
+			removed = []string{}
+		}
+		data = map[string]any{"provider": event.provider, "changed": products, "removed": removed}
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", feed.eventID(event.id), event.name, body), nil
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// sseEvent is an event or comment read from a text/event-stream response
+type sseEvent struct {
+	id, event, data, comment string
+	retry                    string
+}
+
+// streamCatalogue is a catalogue that tests can change while a feed is polling it
+type streamCatalogue struct {
+	mu       sync.Mutex
+	products []domain.Product
+}
+
+func (c *streamCatalogue) set(products ...domain.Product) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.products = products
+}
+
+func (c *streamCatalogue) load() ([]domain.Product, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]domain.Product(nil), c.products...), nil
+}
+
+// openStream connects to a stream and returns its events as they arrive, closed when the stream ends
+func openStream(t *testing.T, url string, lastEventID string) (*http.Response, <-chan sseEvent) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "":
+				if value == "" {
+					events <- event
+					event = sseEvent{}
+					continue
+				}
+				event.comment = value
+			case "id":
+				event.id = value
+			case "event":
+				event.event = value
+			case "data":
+				event.data = value
+			case "retry":
+				event.retry = value
+			}
+		}
+	}()
+	return resp, events
+}
+
+// nextEvent returns the next event that is not a heartbeat, failing the test if none arrives in time
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream ended unexpectedly")
+			}
+			if event.comment == "" && event.retry == "" {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+}
+
+// TestPriceStream tests the snapshot, diffs, heartbeats and resuming a price stream
+func TestPriceStream(t *testing.T) {
+	t.Setenv("STREAM_POLL_INTERVAL", "10ms")
+	t.Setenv("STREAM_HEARTBEAT_INTERVAL", "20ms")
+	t.Setenv("STREAM_RETRY", "2s")
+	server, ts := newTestServer(t)
+
+	catalogue := &streamCatalogue{}
+	catalogue.set(domain.Product{Name: "TV", Weight: 1.5, Price: 20}, domain.Product{Name: "Lamp", Weight: 1, Price: 12})
+	storage.LoadProductsFunc = catalogue.load
+
+	resp, events := openStream(t, ts.URL+"/v1/products/stream", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected 200 text/event-stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// the retry hint comes first
+	if first := <-events; first.retry != "2000" {
+		t.Errorf("expected retry 2000, got %+v", first)
+	}
+
+	snapshot := nextEvent(t, events)
+	var snapshotData struct {
+		Provider string                 `json:"provider"`
+		Products []domain.PricedProduct `json:"products"`
+	}
+	json.Unmarshal([]byte(snapshot.data), &snapshotData)
+	if snapshot.event != "snapshot" || snapshotData.Provider != "DHL" || len(snapshotData.Products) != 2 || snapshotData.Products[0].TotalPrice != "23.00" {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	t.Run("heartbeat", func(t *testing.T) {
+		for event := range events {
+			if event.comment == "heartbeat" {
+				return
+			}
+		}
+		t.Error("stream ended before a heartbeat")
+	})
+
+	// reprice the TV, drop the lamp and add a radio
+	catalogue.set(domain.Product{Name: "TV", Weight: 1.5, Price: 25}, domain.Product{Name: "Radio", Weight: 0.5, Price: 7.5})
+	diff := nextEvent(t, events)
+	var diffData struct {
+		Provider string                 `json:"provider"`
+		Changed  []domain.PricedProduct `json:"changed"`
+		Removed  []string               `json:"removed"`
+	}
+	json.Unmarshal([]byte(diff.data), &diffData)
+	if diff.event != "diff" || len(diffData.Changed) != 2 || diffData.Changed[0].TotalPrice != "28.00" || diffData.Changed[1].Name != "Radio" || len(diffData.Removed) != 1 || diffData.Removed[0] != "Lamp" {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	tests := []struct {
+		name          string
+		lastEventID   string
+		expectedEvent string
+		expectedID    string
+	}{
+		{name: "resume after the snapshot replays the diff", lastEventID: snapshot.id, expectedEvent: "diff", expectedID: diff.id},
+		{name: "unknown epoch gets a snapshot", lastEventID: "old-1", expectedEvent: "snapshot", expectedID: diff.id},
+		{name: "id from the future gets a snapshot", lastEventID: strings.Split(diff.id, "-")[0] + "-99", expectedEvent: "snapshot", expectedID: diff.id},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, resumed := openStream(t, ts.URL+"/v1/products/stream", tc.lastEventID)
+			event := nextEvent(t, resumed)
+			if event.event != tc.expectedEvent || event.id != tc.expectedID {
+				t.Errorf("expected %s %s, got %+v", tc.expectedEvent, tc.expectedID, event)
+			}
+		})
+	}
+
+	t.Run("up to date client only gets heartbeats", func(t *testing.T) {
+		_, resumed := openStream(t, ts.URL+"/v1/products/stream", diff.id)
+		timeout := time.After(100 * time.Millisecond)
+		for {
+			select {
+			case event := <-resumed:
+				if event.event != "" {
+					t.Fatalf("expected no events, got %+v", event)
+				}
+			case <-timeout:
+				return
+			}
+		}
+	})
+
+	t.Run("close ends the stream", func(t *testing.T) {
+		server.Close()
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			case <-timeout:
+				t.Fatal("stream still open after Close")
+			}
+		}
+	})
+}
+
+// TestPriceStreamErrors tests streams that cannot be started
+func TestPriceStreamErrors(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "unknown provider", path: "/products/stream?provider=fedex", expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidProvider},
+		{name: "provider price missing", path: "/products/stream?provider=ups", expectedStatus: http.StatusServiceUnavailable, expectedCode: codeProviderPriceUnset},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if errResp := decodeError(t, resp); resp.StatusCode != tc.expectedStatus || errResp.Code != tc.expectedCode {
+				t.Errorf("expected %d %s, got %d %s", tc.expectedStatus, tc.expectedCode, resp.StatusCode, errResp.Code)
+			}
+		})
+	}
+}
+
+// TestDiffPrices tests finding the products whose prices changed between two snapshots
+func TestDiffPrices(t *testing.T) {
+	tv := domain.PricedProduct{Name: "TV", TotalPrice: "23.00"}
+	radio := domain.PricedProduct{Name: "Radio", TotalPrice: "8.00"}
+	cheaperTV := domain.PricedProduct{Name: "TV", TotalPrice: "21.00"}
+	volumetricTV := domain.PricedProduct{Name: "TV", TotalPrice: "23.00", WeightBasis: domain.WeightVolumetric}
+
+	tests := []struct {
+		name            string
+		presenter       presenter
+		previous, next  []domain.PricedProduct
+		expectedChanged []string
+		expectedRemoved []string
+	}{
+		{name: "unchanged", presenter: v2Presenter{}, previous: []domain.PricedProduct{tv, radio}, next: []domain.PricedProduct{tv, radio}},
+		{name: "price changed", presenter: v2Presenter{}, previous: []domain.PricedProduct{tv, radio}, next: []domain.PricedProduct{cheaperTV, radio}, expectedChanged: []string{"TV"}},
+		{name: "added", presenter: v2Presenter{}, previous: []domain.PricedProduct{tv}, next: []domain.PricedProduct{tv, radio}, expectedChanged: []string{"Radio"}},
+		{name: "removed", presenter: v2Presenter{}, previous: []domain.PricedProduct{tv, radio}, next: []domain.PricedProduct{radio}, expectedRemoved: []string{"TV"}},
+		{name: "field shown by version", presenter: v2Presenter{}, previous: []domain.PricedProduct{tv}, next: []domain.PricedProduct{volumetricTV}, expectedChanged: []string{"TV"}},
+		{name: "field not shown by version", presenter: v1Presenter{}, previous: []domain.PricedProduct{tv}, next: []domain.PricedProduct{volumetricTV}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changed, removed := diffPrices(tc.presenter, tc.previous, tc.next)
+			var changedNames []string
+			for _, product := range changed {
+				changedNames = append(changedNames, product.Name)
+			}
+			if strings.Join(changedNames, ",") != strings.Join(tc.expectedChanged, ",") || strings.Join(removed, ",") != strings.Join(tc.expectedRemoved, ",") {
+				t.Errorf("expected changed %v removed %v, got %v %v", tc.expectedChanged, tc.expectedRemoved, changedNames, removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// certCheckInterval limits how often the certificate files are checked for changes.
+const certCheckInterval = 10 * time.Second
+
+/*
+certReloader serves a TLS certificate loaded from disk and reloads it when the certificate
+or key file changes, so certificates can be rotated without restarting the server.
+If a reload fails, for example while the files are half written, the previous certificate is kept.
+*/
+type certReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader loads the certificate and key, returning an error if they cannot be used.
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, checkInterval: certCheckInterval}
+	err := c.reload()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastCheck) >= c.checkInterval {
+		c.lastCheck = time.Now()
+		if c.changed() {
+			err := c.reloadLocked()
+			if err != nil {
+				logs.Logs(2, "failed to reload TLS certificate, keeping the previous one: "+err.Error(), "")
+			} else {
+				logs.Logs(1, "reloaded TLS certificate from "+c.certFile, "")
+			}
+		}
+	}
+	return c.cert, nil
+}
+
+// TLSConfig returns a tls.Config that serves the reloadable certificate.
+func (c *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+func (c *certReloader) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reloadLocked()
+}
+
+func (c *certReloader) reloadLocked() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	c.cert = &cert
+	c.certMod = certInfo.ModTime()
+	c.keyMod = keyInfo.ModTime()
+	c.lastCheck = time.Now()
+	return nil
+}
+
+// changed reports whether either file has a different modification time to the loaded certificate.
+func (c *certReloader) changed() bool {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return false
+	}
+	return !certInfo.ModTime().Equal(c.certMod) || !keyInfo.ModTime().Equal(c.keyMod)
+}
+
+/*
+httpsRedirectHandler redirects every plain HTTP request to the same path over HTTPS on tlsPort.
+The port is left out of the URL when it is the default HTTPS port.
+*/
+func httpsRedirectHandler(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if tlsPort != "" && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
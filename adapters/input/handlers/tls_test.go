@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// writeSelfSignedCert generates a self-signed certificate for localhost and writes it to certFile and keyFile
+func writeSelfSignedCert(t *testing.T, certFile string, keyFile string, commonName string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// TestCertReloader tests serving a certificate from disk and picking up a rotated one
+func TestCertReloader(t *testing.T) {
+	go logs.ProcessLogs()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	first := writeSelfSignedCert(t, certFile, keyFile, "first")
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+	reloader.checkInterval = 0 // check the files on every handshake
+
+	// serve with our own TLS listener, httptest.StartTLS would add its own certificate
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", reloader.TLSConfig())
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	httpServer := &http.Server{Handler: http.HandlerFunc(Hello)}
+	go httpServer.Serve(ln)
+	defer httpServer.Close()
+	url := "https://" + ln.Addr().String()
+
+	servedCommonName := func(trusted *x509.Certificate) string {
+		pool := x509.NewCertPool()
+		pool.AddCert(trusted)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := servedCommonName(first); got != "first" {
+		t.Errorf("expected first certificate, got %q", got)
+	}
+
+	// rotate the certificate and make sure the modification time moves on
+	second := writeSelfSignedCert(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	if got := servedCommonName(second); got != "second" {
+		t.Errorf("expected rotated certificate, got %q", got)
+	}
+}
+
+// TestCertReloaderKeepsCertificateOnBadReload tests that a broken rotation keeps serving the old certificate
+func TestCertReloaderKeepsCertificateOnBadReload(t *testing.T) {
+	go logs.ProcessLogs()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "first")
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+	reloader.checkInterval = 0
+
+	os.WriteFile(certFile, []byte("not a certificate"), 0o600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+
+	cert, err := reloader.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("expected previous certificate, got %v, %v", cert, err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	if leaf.Subject.CommonName != "first" {
+		t.Errorf("expected first certificate, got %q", leaf.Subject.CommonName)
+	}
+}
+
+// TestHTTPSRedirectHandler tests redirecting plain HTTP requests to HTTPS
+func TestHTTPSRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		tlsPort  string
+		target   string
+		expected string
+	}{
+		{name: "custom port", tlsPort: "8443", target: "http://example.com:8080/products?provider=ups", expected: "https://example.com:8443/products?provider=ups"},
+		{name: "default port", tlsPort: "443", target: "http://example.com/products", expected: "https://example.com/products"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			httpsRedirectHandler(tc.tlsPort).ServeHTTP(w, httptest.NewRequest("GET", tc.target, nil))
+
+			if w.Code != http.StatusPermanentRedirect {
+				t.Errorf("expected code 308, got %d", w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tc.expected {
+				t.Errorf("expected Location %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestLoadServerConfig tests reading server timeouts and limits from the environment
+func TestLoadServerConfig(t *testing.T) {
+	go logs.ProcessLogs()
+
+	t.Setenv("APP_PORT", "9000")
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "2s")
+	t.Setenv("HTTP_READ_TIMEOUT", "3s")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "4s")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "not-a-duration")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "4096")
+
+	httpServer := newHTTPServer(LoadServerConfig(), http.HandlerFunc(Hello))
+
+	if httpServer.Addr != ":9000" {
+		t.Errorf("expected addr :9000, got %q", httpServer.Addr)
+	}
+	if httpServer.ReadHeaderTimeout != 2*time.Second || httpServer.ReadTimeout != 3*time.Second || httpServer.WriteTimeout != 4*time.Second {
+		t.Errorf("unexpected timeouts: %v %v %v", httpServer.ReadHeaderTimeout, httpServer.ReadTimeout, httpServer.WriteTimeout)
+	}
+	if httpServer.IdleTimeout != 60*time.Second {
+		t.Errorf("expected invalid idle timeout to fall back to 60s, got %v", httpServer.IdleTimeout)
+	}
+	if httpServer.MaxHeaderBytes != 4096 {
+		t.Errorf("expected max header bytes 4096, got %d", httpServer.MaxHeaderBytes)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// deprecatedRequestsTotal counts requests to deprecated API versions, to tell when one can be removed.
+var deprecatedRequestsTotal = metrics.Counter("http_deprecated_requests_total")
+
+// unversionedDeprecation is when the unversioned paths were deprecated in favour of /v1.
+var unversionedDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+/*
+presenter turns priced products from the domain into one API version's response DTOs.
+Every version shares the domain and handlers, so a new version with a different response shape,
+such as tax fields or money as numbers, only needs a new presenter.
+*/
+type presenter interface {
+	// present returns the DTO encoded as JSON and XML.
+	present(product domain.PricedProduct) any
+	// csvHeader returns the CSV header row, matching the DTO's field names.
+	csvHeader() []string
+	// csvRecord returns the product as a CSV row in the order of csvHeader.
+	csvRecord(product domain.PricedProduct) []string
+}
+
+// v1 is the first versioned API, whose response shape the unversioned paths also use. It is succeeded by v2.
+var v1 = apiVersion{name: "v1", prefix: "/v1", presenter: v1Presenter{}, successor: "/v2"}
+
+/*
+v1Product is the v1 response DTO. Its fields are frozen: fields added to domain.PricedProduct
+are only shown by later versions, so existing clients never see the response change shape.
+*/
+type v1Product struct {
+	Name            string `json:"name" xml:"name"`
+	ProductPrice    string `json:"product_price" xml:"product_price"`
+	DeliveryPrice   string `json:"delivery_price" xml:"delivery_price"`
+	TotalPrice      string `json:"total_price" xml:"total_price"`
+	DeliveryService string `json:"delivery_service" xml:"delivery_service"`
+}
+
+// v1Presenter renders products as v1Product, with money as two decimal strings.
+type v1Presenter struct{}
+
+func (v1Presenter) present(product domain.PricedProduct) any {
+	return v1Product{
+		Name:            product.Name,
+		ProductPrice:    product.ProductPrice,
+		DeliveryPrice:   product.DeliveryPrice,
+		TotalPrice:      product.TotalPrice,
+		DeliveryService: product.DeliveryService,
+	}
+}
+
+func (v1Presenter) csvHeader() []string {
+	return []string{"name", "product_price", "delivery_price", "total_price", "delivery_service"}
+}
+
+func (v1Presenter) csvRecord(product domain.PricedProduct) []string {
+	return []string{product.Name, product.ProductPrice, product.DeliveryPrice, product.TotalPrice, product.DeliveryService}
+}
+
+// v2 is the current API, adding how delivery was charged, the zone, delivery options and promotions to v1.
+var v2 = apiVersion{name: "v2", prefix: "/v2", presenter: v2Presenter{}}
+
+/*
+v2Product is the v2 response DTO: v1Product plus the weight delivery was charged on, the zone
+priced for, the delivery options and the promotions that applied. Fields that are only given in
+some cases are omitted when empty.
+*/
+type v2Product struct {
+	Name                  string             `json:"name" xml:"name"`
+	ProductPrice          string             `json:"product_price" xml:"product_price"`
+	DeliveryPrice         string             `json:"delivery_price" xml:"delivery_price"`
+	TotalPrice            string             `json:"total_price" xml:"total_price"`
+	DeliveryService       string             `json:"delivery_service" xml:"delivery_service"`
+	ChargeableWeight      float64            `json:"chargeable_weight" xml:"chargeable_weight"`
+	WeightBasis           string             `json:"weight_basis" xml:"weight_basis"`
+	Zone                  string             `json:"zone,omitempty" xml:"zone,omitempty"`
+	DeliveryOptions       []v2DeliveryOption `json:"delivery_options,omitempty" xml:"delivery_options>option,omitempty"`
+	OriginalProductPrice  string             `json:"original_product_price,omitempty" xml:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string             `json:"original_delivery_price,omitempty" xml:"original_delivery_price,omitempty"`
+	Promotions            []v2Promotion      `json:"promotions,omitempty" xml:"promotions>promotion,omitempty"`
+}
+
+// v2DeliveryOption is a service level in v2Product's delivery options.
+type v2DeliveryOption struct {
+	Service           string `json:"service" xml:"service"`
+	DeliveryPrice     string `json:"delivery_price" xml:"delivery_price"`
+	TotalPrice        string `json:"total_price" xml:"total_price"`
+	EstimatedDelivery string `json:"estimated_delivery" xml:"estimated_delivery"`
+	DeliverBy         string `json:"deliver_by,omitempty" xml:"deliver_by,omitempty"`
+}
+
+// v2Promotion is a promotion or discount code that took money off a v2Product.
+type v2Promotion struct {
+	ID           string `json:"id" xml:"id"`
+	Name         string `json:"name,omitempty" xml:"name,omitempty"`
+	Target       string `json:"target" xml:"target"`
+	Discount     string `json:"discount" xml:"discount"`
+	DiscountCode bool   `json:"discount_code,omitempty" xml:"discount_code,omitempty"`
+}
+
+/*
+v2Presenter renders products as v2Product. Its CSV has v2Product's single valued fields; the
+delivery options and promotions are lists, so they are only given in JSON and XML.
+*/
+type v2Presenter struct{}
+
+func (v2Presenter) present(product domain.PricedProduct) any {
+	dto := v2Product{
+		Name:                  product.Name,
+		ProductPrice:          product.ProductPrice,
+		DeliveryPrice:         product.DeliveryPrice,
+		TotalPrice:            product.TotalPrice,
+		DeliveryService:       product.DeliveryService,
+		ChargeableWeight:      product.ChargeableWeight,
+		WeightBasis:           string(product.WeightBasis),
+		Zone:                  string(product.Zone),
+		OriginalProductPrice:  product.OriginalProductPrice,
+		OriginalDeliveryPrice: product.OriginalDeliveryPrice,
+	}
+	for _, option := range product.DeliveryOptions {
+		dto.DeliveryOptions = append(dto.DeliveryOptions, v2DeliveryOption{
+			Service:           string(option.Service),
+			DeliveryPrice:     option.DeliveryPrice,
+			TotalPrice:        option.TotalPrice,
+			EstimatedDelivery: option.EstimatedDelivery,
+			DeliverBy:         option.DeliverBy,
+		})
+	}
+	for _, promotion := range product.Promotions {
+		dto.Promotions = append(dto.Promotions, v2Promotion{
+			ID:           promotion.ID,
+			Name:         promotion.Name,
+			Target:       string(promotion.Target),
+			Discount:     promotion.Discount,
+			DiscountCode: promotion.DiscountCode,
+		})
+	}
+	return dto
+}
+
+func (v2Presenter) csvHeader() []string {
+	return []string{"name", "product_price", "delivery_price", "total_price", "delivery_service", "chargeable_weight", "weight_basis", "zone", "original_product_price", "original_delivery_price"}
+}
+
+func (v2Presenter) csvRecord(product domain.PricedProduct) []string {
+	return []string{
+		product.Name, product.ProductPrice, product.DeliveryPrice, product.TotalPrice, product.DeliveryService,
+		strconv.FormatFloat(product.ChargeableWeight, 'f', -1, 64), string(product.WeightBasis), string(product.Zone),
+		product.OriginalProductPrice, product.OriginalDeliveryPrice,
+	}
+}
+
+/*
+apiVersion is a version of the API mounted under a path prefix. A deprecated version answers
+with Deprecation, Sunset and a Link to its successor so clients know to move.
+*/
+type apiVersion struct {
+	name        string    // e.g. "v1", included in ETags so versions are cached apart
+	prefix      string    // e.g. "/v1", empty for the unversioned alias
+	presenter   presenter // response DTOs for this version
+	deprecation time.Time // when the version was deprecated, zero if it is current
+	sunset      time.Time // when the version will be removed, zero if not yet planned
+	successor   string    // path prefix of the version that replaces this one
+}
+
+/*
+withSchedule returns v with its deprecation and sunset dates read from API_<NAME>_DEPRECATION
+and API_<NAME>_SUNSET, as RFC 3339 timestamps or YYYY-MM-DD dates.
+*/
+func (v apiVersion) withSchedule() apiVersion {
+	prefix := "API_" + strings.ToUpper(v.name)
+	v.deprecation = env.Time(prefix+"_DEPRECATION", v.deprecation)
+	v.sunset = env.Time(prefix+"_SUNSET", v.sunset)
+	return v
+}
+
+/*
+alias returns v mounted at the unversioned paths. The alias is kept for existing clients
+but is deprecated, pointing them at v's versioned paths.
+*/
+func (v apiVersion) alias() apiVersion {
+	alias := v
+	alias.prefix = ""
+	alias.deprecation = unversionedDeprecation
+	alias.sunset = env.Time("API_UNVERSIONED_SUNSET", time.Time{})
+	alias.successor = v.prefix
+	return alias
+}
+
+// deprecated reports whether the version has been deprecated.
+func (v apiVersion) deprecated() bool {
+	return !v.deprecation.IsZero()
+}
+
+/*
+deprecationMiddleware adds the Deprecation (RFC 9745), Sunset (RFC 8594) and successor Link
+headers to every response from a deprecated version. Current versions pass through untouched.
+*/
+func deprecationMiddleware(v apiVersion) Middleware {
+	return func(next http.Handler) http.Handler {
+		if !v.deprecated() {
+			return next
+		}
+		deprecation := "@" + strconv.FormatInt(v.deprecation.Unix(), 10)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deprecatedRequestsTotal.Add(1)
+			w.Header().Set("Deprecation", deprecation)
+			if !v.sunset.IsZero() {
+				w.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+			}
+			if v.successor != "" {
+				link := v.successor + strings.TrimPrefix(r.URL.Path, v.prefix)
+				w.Header().Add("Link", "<"+link+`>; rel="successor-version"`)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestVersionedRoutes tests that /v1 and /v2 are current and the unversioned alias is deprecated
+func TestVersionedRoutes(t *testing.T) {
+	t.Setenv("API_UNVERSIONED_SUNSET", "2027-04-01")
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		deprecated  bool
+		expectedURL string
+	}{
+		{name: "v1 list", path: "/v1/products"},
+		{name: "v1 product", path: "/v1/products/TV"},
+		{name: "v2 list", path: "/v2/products"},
+		{name: "v2 product", path: "/v2/products/TV"},
+		{name: "alias list", path: "/products", deprecated: true, expectedURL: "</v1/products>; rel=\"successor-version\""},
+		{name: "alias product", path: "/products/TV", deprecated: true, expectedURL: "</v1/products/TV>; rel=\"successor-version\""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d", resp.StatusCode)
+			}
+			if !tc.deprecated {
+				if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" {
+					t.Errorf("expected no deprecation headers, got %q %q", resp.Header.Get("Deprecation"), resp.Header.Get("Sunset"))
+				}
+				return
+			}
+			if got := resp.Header.Get("Deprecation"); got != "@"+strconv.FormatInt(unversionedDeprecation.Unix(), 10) {
+				t.Errorf("unexpected Deprecation %q", got)
+			}
+			if got := resp.Header.Get("Sunset"); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+				t.Errorf("unexpected Sunset %q", got)
+			}
+			if got := resp.Header.Get("Link"); got != tc.expectedURL {
+				t.Errorf("expected Link %q, got %q", tc.expectedURL, got)
+			}
+		})
+	}
+}
+
+// testV3Presenter is a response shape with money as numbers, standing in for a future version
+type testV3Presenter struct{}
+
+func (testV3Presenter) present(product domain.PricedProduct) any {
+	total, _ := strconv.ParseFloat(product.TotalPrice, 64)
+	return map[string]any{"name": product.Name, "total": total}
+}
+
+func (testV3Presenter) csvHeader() []string { return []string{"name", "total"} }
+
+func (testV3Presenter) csvRecord(product domain.PricedProduct) []string {
+	return []string{product.Name, product.TotalPrice}
+}
+
+// TestMountNewVersion tests that a second version shares the domain but uses its own DTOs, and deprecates its predecessor
+func TestMountNewVersion(t *testing.T) {
+	t.Setenv("API_V2_DEPRECATION", "2027-01-01")
+	server, _ := newTestServer(t)
+
+	v3 := apiVersion{name: "v3", prefix: "/v3", presenter: testV3Presenter{}}
+	old := v2.withSchedule()
+	old.prefix = "/legacy/v2" // a fresh path, as the server already mounts /v2
+	old.successor = v3.prefix
+	server.versionRoutes(v3)
+	server.versionRoutes(old)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/v3/products/TV", nil))
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	if body["total"] != 23.0 {
+		t.Errorf("expected v3 DTO with numeric total, got %v", body)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/legacy/v2/products/TV", nil))
+	if w.Header().Get("Deprecation") == "" {
+		t.Error("expected the old version to be deprecated")
+	}
+	if got := w.Header().Get("Link"); got != `</v3/products/TV>; rel="successor-version"` {
+		t.Errorf("unexpected Link %q", got)
+	}
+}
+
+// TestVersionResponseShapes tests that v1 keeps its original fields while v2 adds the newer ones
+func TestVersionResponseShapes(t *testing.T) {
+	usePromotions(t, domain.Promotion{ID: "tv-offer", Target: domain.PromotionProduct, Kind: domain.PromotionFixed, Value: 2})
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "v1 json", path: "/v1/products/TV", expected: `{"name":"TV","product_price":"18.00","delivery_price":"3.00","total_price":"21.00","delivery_service":"DHL"}` + "\n"},
+		{name: "alias json", path: "/products/TV", expected: `{"name":"TV","product_price":"18.00","delivery_price":"3.00","total_price":"21.00","delivery_service":"DHL"}` + "\n"},
+		{name: "v1 csv", path: "/v1/products/TV?format=csv", expected: "name,product_price,delivery_price,total_price,delivery_service\nTV,18.00,3.00,21.00,DHL\n"},
+		{name: "v2 json", path: "/v2/products/TV", expected: `{"name":"TV","product_price":"18.00","delivery_price":"3.00","total_price":"21.00","delivery_service":"DHL","chargeable_weight":1.5,"weight_basis":"actual","original_product_price":"20.00","original_delivery_price":"3.00","promotions":[{"id":"tv-offer","target":"product","discount":"2.00"}]}` + "\n"},
+		{name: "v2 csv", path: "/v2/products/TV?format=csv", expected: "name,product_price,delivery_price,total_price,delivery_service,chargeable_weight,weight_basis,zone,original_product_price,original_delivery_price\nTV,18.00,3.00,21.00,DHL,1.5,actual,,20.00,3.00\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != tc.expected {
+				t.Errorf("expected 200 %q, got %d %q", tc.expected, resp.StatusCode, body)
+			}
+		})
+	}
+}
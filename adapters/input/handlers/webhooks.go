@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/webhooks"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// maxWebhookBodyBytes limits the size of a subscription request.
+const maxWebhookBodyBytes = 64 << 10
+
+// webhookRequest is the body of POST /admin/webhooks.
+type webhookRequest struct {
+	URL      string `json:"url"`
+	Provider string `json:"provider"`
+	Secret   string `json:"secret"`
+}
+
+/*
+webhookWatchers follows the price feed of every provider that has webhook subscriptions and
+publishes a price.changed event whenever a product's total price changes. Watchers are started
+and stopped by sync as subscriptions come and go.
+*/
+type webhookWatchers struct {
+	feeds      *priceFeeds
+	dispatcher *webhooks.Dispatcher
+
+	mu      sync.Mutex
+	running map[string]chan struct{} // stop channel per subscribed provider, "" for the default
+}
+
+// newWebhookWatchers returns watchers for dispatcher's subscriptions, which start on the first call to sync.
+func newWebhookWatchers(feeds *priceFeeds, dispatcher *webhooks.Dispatcher) *webhookWatchers {
+	return &webhookWatchers{feeds: feeds, dispatcher: dispatcher, running: make(map[string]chan struct{})}
+}
+
+// sync starts a watcher for each newly subscribed provider and stops those no longer subscribed to.
+func (ww *webhookWatchers) sync() {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	providers := ww.dispatcher.Providers()
+	for provider, stop := range ww.running {
+		if !slices.Contains(providers, provider) {
+			close(stop)
+			delete(ww.running, provider)
+		}
+	}
+	for _, provider := range providers {
+		if _, ok := ww.running[provider]; !ok {
+			stop := make(chan struct{})
+			ww.running[provider] = stop
+			go ww.watch(provider, stop)
+		}
+	}
+}
+
+/*
+watch keeps its own copy of the provider's prices, so it can tell old prices from new whether
+the feed sends it a diff or, after falling behind, a fresh snapshot.
+*/
+func (ww *webhookWatchers) watch(provider string, stop chan struct{}) {
+	// the webhook payload is in the shape of v2's DTO, so v2's feed has every change it sends
+	feed := ww.feeds.feed(provider, v2)
+	notify := feed.subscribe()
+	defer feed.unsubscribe(notify)
+
+	if err := feed.refresh(); err != nil {
+		logs.Logs(2, "webhook prices not available yet: "+err.Error(), provider)
+	}
+
+	var known map[string]domain.PricedProduct
+	lastID := ""
+	for {
+		if feed.ready() {
+			for _, event := range feed.since(lastID) {
+				var changes []webhooks.PriceChange
+				changes, known = priceChanges(known, event)
+				ww.dispatcher.Publish(provider, event.provider, changes)
+				lastID = feed.eventID(event.id)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ww.feeds.done:
+			return
+		case <-notify:
+		}
+	}
+}
+
+/*
+priceChanges applies a feed event to the known prices and returns the products whose total
+price changed, including those added or removed. The first event only sets the known prices.
+*/
+func priceChanges(known map[string]domain.PricedProduct, event streamEvent) ([]webhooks.PriceChange, map[string]domain.PricedProduct) {
+	next := make(map[string]domain.PricedProduct, len(event.products))
+	if event.name == "diff" {
+		for name, product := range known {
+			next[name] = product
+		}
+		for _, name := range event.removed {
+			delete(next, name)
+		}
+	}
+	for _, product := range event.products {
+		next[product.Name] = product
+	}
+	if known == nil {
+		return nil, next
+	}
+
+	var changes []webhooks.PriceChange
+	for _, product := range event.products {
+		old, existed := known[product.Name]
+		if existed && old.TotalPrice == product.TotalPrice {
+			continue
+		}
+		change := webhooks.PriceChange{Name: product.Name, New: webhooks.NewProduct(product)}
+		if existed {
+			change.Old = webhooks.NewProduct(old)
+		}
+		changes = append(changes, change)
+	}
+
+	var removed []string
+	for name := range known {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(removed)
+	for _, name := range removed {
+		changes = append(changes, webhooks.PriceChange{Name: name, Old: webhooks.NewProduct(known[name])})
+	}
+	return changes, next
+}
+
+// createWebhookHandler registers a webhook subscription, returning it with its secret.
+func (s *Server) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var body webhookRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Request body must be a JSON object with a url", map[string]string{"reason": err.Error()})
+		return
+	}
+
+	subscription, err := s.webhooks.Subscribe(body.URL, body.Provider, body.Secret)
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	s.watchers.sync()
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "webhook "+subscription.ID+" to "+subscription.URL+" created by key id "+principal.KeyID, subscription.Provider)
+	w.Header().Set("Location", "/admin/webhooks/"+subscription.ID)
+	writeJSON(w, http.StatusCreated, subscription)
+}
+
+// listWebhooksHandler returns every webhook subscription, without secrets.
+func (s *Server) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhooks.Subscriptions())
+}
+
+// getWebhookHandler returns a single webhook subscription, without its secret.
+func (s *Server) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, err := s.webhooks.Subscription(r.PathValue("id"))
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subscription)
+}
+
+// deleteWebhookHandler removes a webhook subscription and abandons its pending retries.
+func (s *Server) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.webhooks.Unsubscribe(id); err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	s.watchers.sync()
+
+	principal, _ := principalFromContext(r.Context())
+	logs.Logs(1, "webhook "+id+" deleted by key id "+principal.KeyID, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookDeliveriesHandler returns a subscription's recent deliveries and their attempts.
+func (s *Server) webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := s.webhooks.Deliveries(r.PathValue("id"))
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// deadLettersHandler returns the deliveries that ran out of attempts.
+func (s *Server) deadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhooks.DeadLetters())
+}
+
+// redeliverHandler takes a delivery off the dead-letter list and retries it in the background.
+func (s *Server) redeliverHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.webhooks.Redeliver(r.PathValue("id"))
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// writeWebhookError writes the error response for an error from the webhook dispatcher.
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+		writeError(w, r, http.StatusNotFound, codeWebhookNotFound, "Webhook subscription not found", map[string]string{"id": r.PathValue("id")})
+	case errors.Is(err, webhooks.ErrDeliveryNotFound):
+		writeError(w, r, http.StatusNotFound, codeDeliveryNotFound, "No dead-lettered delivery with this ID", map[string]string{"id": r.PathValue("id")})
+	case errors.Is(err, webhooks.ErrInvalidURL):
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Webhook URL must be an absolute http or https URL", nil)
+	case errors.Is(err, webhooks.ErrForbiddenTarget):
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "Webhook URL must not point at a loopback, link-local or private address", nil)
+	case errors.Is(err, domain.ErrUnknownProvider):
+		writeDomainError(w, r, err, nil)
+	default:
+		logs.Logs(3, "webhook request failed: "+err.Error(), "")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "Internal server error", nil)
+	}
+}
+
+// writeJSON writes value as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logs.Logs(3, "Failed to write response: "+err.Error(), "")
+	}
+}
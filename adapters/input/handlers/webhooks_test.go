@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/adapters/output/webhooks"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// adminRequest sends a request with the test admin key and returns the response and its body
+func adminRequest(t *testing.T, method string, url string, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer ops.a")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+// TestWebhookAdminAPI tests creating, reading and deleting webhook subscriptions
+func TestWebhookAdminAPI(t *testing.T) {
+	t.Setenv("API_KEYS", "web:reader:r,ops:admin:a")
+	_, ts := newTestServer(t)
+
+	resp, body := adminRequest(t, "POST", ts.URL+"/admin/webhooks", `{"url": "https://example.com/hooks", "provider": "ups"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var created webhooks.Subscription
+	json.Unmarshal(body, &created)
+	if created.Secret == "" || created.Provider != "UPS" || resp.Header.Get("Location") != "/admin/webhooks/"+created.ID {
+		t.Errorf("unexpected subscription %s, Location %q", body, resp.Header.Get("Location"))
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedBody   string
+	}{
+		{name: "list hides secrets", method: "GET", path: "/admin/webhooks", expectedStatus: http.StatusOK, expectedBody: created.ID},
+		{name: "get", method: "GET", path: "/admin/webhooks/" + created.ID, expectedStatus: http.StatusOK, expectedBody: `"provider":"UPS"`},
+		{name: "no deliveries yet", method: "GET", path: "/admin/webhooks/" + created.ID + "/deliveries", expectedStatus: http.StatusOK, expectedBody: "[]"},
+		{name: "no dead letters", method: "GET", path: "/admin/webhooks/dead-letters", expectedStatus: http.StatusOK, expectedBody: "[]"},
+		{name: "invalid body", method: "POST", path: "/admin/webhooks", body: `{"url": 1}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "unknown field", method: "POST", path: "/admin/webhooks", body: `{"url": "https://example.com", "event": "all"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "invalid url", method: "POST", path: "/admin/webhooks", body: `{"url": "example.com"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "private url", method: "POST", path: "/admin/webhooks", body: `{"url": "http://192.168.0.10/hooks"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidRequest},
+		{name: "unknown provider", method: "POST", path: "/admin/webhooks", body: `{"url": "https://example.com", "provider": "fedex"}`, expectedStatus: http.StatusBadRequest, expectedCode: codeInvalidProvider},
+		{name: "unknown subscription", method: "GET", path: "/admin/webhooks/wh_missing", expectedStatus: http.StatusNotFound, expectedCode: codeWebhookNotFound},
+		{name: "unknown dead letter", method: "POST", path: "/admin/webhooks/dead-letters/dlv_missing/retry", expectedStatus: http.StatusNotFound, expectedCode: codeDeliveryNotFound},
+		{name: "delete", method: "DELETE", path: "/admin/webhooks/" + created.ID, expectedStatus: http.StatusNoContent},
+		{name: "deleted", method: "DELETE", path: "/admin/webhooks/" + created.ID, expectedStatus: http.StatusNotFound, expectedCode: codeWebhookNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := adminRequest(t, tc.method, ts.URL+tc.path, tc.body)
+			if resp.StatusCode != tc.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tc.expectedStatus, resp.StatusCode, body)
+			}
+			if bytes.Contains(body, []byte(created.Secret)) {
+				t.Errorf("response leaks the secret: %s", body)
+			}
+			if tc.expectedCode != "" {
+				var errResp ErrorResponse
+				json.Unmarshal(body, &errResp)
+				if errResp.Code != tc.expectedCode {
+					t.Errorf("expected code %q, got %q", tc.expectedCode, errResp.Code)
+				}
+			}
+			if tc.expectedBody != "" && !strings.Contains(string(body), tc.expectedBody) {
+				t.Errorf("expected body containing %q, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+
+	t.Run("readers cannot manage webhooks", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", ts.URL+"/admin/webhooks", nil)
+		req.Header.Set("Authorization", "Bearer web.r")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// TestWebhookPriceChange tests that a change to the catalogue is delivered to a subscribed receiver
+func TestWebhookPriceChange(t *testing.T) {
+	t.Setenv("API_KEYS", "ops:admin:a")
+	t.Setenv("STREAM_POLL_INTERVAL", "10ms")
+	t.Setenv("WEBHOOK_ALLOWED_TARGETS", "127.0.0.1")
+	_, ts := newTestServer(t)
+
+	catalogue := &streamCatalogue{}
+	catalogue.set(domain.Product{Name: "TV", Weight: 1.5, Price: 20}, domain.Product{Name: "Lamp", Weight: 1, Price: 12})
+	storage.LoadProductsFunc = catalogue.load
+
+	var mu sync.Mutex
+	var events []webhooks.Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhooks.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	resp, body := adminRequest(t, "POST", ts.URL+"/admin/webhooks", `{"url": "`+receiver.URL+`"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.StatusCode, body)
+	}
+	var subscription webhooks.Subscription
+	json.Unmarshal(body, &subscription)
+
+	// give the watcher time to learn the current prices, then change them
+	time.Sleep(30 * time.Millisecond)
+	catalogue.set(domain.Product{Name: "TV", Weight: 1.5, Price: 25}, domain.Product{Name: "Lamp", Weight: 1, Price: 12}, domain.Product{Name: "Radio", Weight: 0.5, Price: 7.5})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		received := len(events)
+		mu.Unlock()
+		if received > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a webhook")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	event := events[0]
+	mu.Unlock()
+	if len(event.Changes) != 2 || event.Provider != "DHL" {
+		t.Fatalf("expected changes to the TV and Radio, got %+v", event)
+	}
+	if tv := event.Changes[0]; tv.Name != "TV" || tv.Old.TotalPrice != "23.00" || tv.New.TotalPrice != "28.00" {
+		t.Errorf("unexpected TV change %+v %+v", tv.Old, tv.New)
+	}
+	if radio := event.Changes[1]; radio.Name != "Radio" || radio.Old != nil || radio.New.TotalPrice != "8.50" {
+		t.Errorf("unexpected Radio change %+v", radio)
+	}
+
+	// the receiver has answered, but the dispatcher may not have recorded it yet
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		_, body = adminRequest(t, "GET", ts.URL+"/admin/webhooks/"+subscription.ID+"/deliveries", "")
+		if strings.Contains(string(body), `"status":"delivered"`) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a delivered delivery in the history, got %s", body)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestPriceChanges tests working out which total prices changed from feed events
+func TestPriceChanges(t *testing.T) {
+	tv := domain.PricedProduct{Name: "TV", DeliveryPrice: "3.00", TotalPrice: "23.00"}
+	radio := domain.PricedProduct{Name: "Radio", TotalPrice: "8.00"}
+	known := map[string]domain.PricedProduct{"TV": tv, "Radio": radio}
+
+	tests := []struct {
+		name     string
+		known    map[string]domain.PricedProduct
+		event    streamEvent
+		expected []string // names of the changed products
+	}{
+		{name: "first snapshot only learns prices", event: streamEvent{name: "snapshot", products: []domain.PricedProduct{tv, radio}}},
+		{name: "total changed", known: known, event: streamEvent{name: "diff", products: []domain.PricedProduct{{Name: "TV", TotalPrice: "25.00"}}}, expected: []string{"TV"}},
+		{name: "same total is not a change", known: known, event: streamEvent{name: "diff", products: []domain.PricedProduct{{Name: "TV", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "UPS"}}}},
+		{name: "removed", known: known, event: streamEvent{name: "diff", removed: []string{"Radio"}}, expected: []string{"Radio"}},
+		{name: "snapshot after falling behind", known: known, event: streamEvent{name: "snapshot", products: []domain.PricedProduct{tv, {Name: "Lamp", TotalPrice: "15.00"}}}, expected: []string{"Lamp", "Radio"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changes, next := priceChanges(tc.known, tc.event)
+			var names []string
+			for _, change := range changes {
+				names = append(names, change.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("expected changes %v, got %v", tc.expected, names)
+			}
+			if next == nil {
+				t.Error("expected the known prices to be returned")
+			}
+		})
+	}
+}
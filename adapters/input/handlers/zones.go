@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/audit"
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+/*
+loadZoneTables replaces the carriers' zone tables with those in storage, which may have been edited
+by hand. If they cannot be read or one is invalid the current tables are kept. It returns an audit
+entry, without an actor or reason, for each carrier whose table was added, edited or removed.
+*/
+func loadZoneTables() ([]audit.Entry, error) {
+	tables, err := storage.LoadZoneTablesFunc()
+	if err != nil {
+		return nil, err
+	}
+	before := domain.ZoneTables()
+	if err := domain.SetZoneTables(tables); err != nil {
+		return nil, err
+	}
+	return zoneTableChanges(before, domain.ZoneTables()), nil
+}
+
+// zoneTableChanges compares two sets of zone tables by provider.
+func zoneTableChanges(before, after []domain.ZoneTable) []audit.Entry {
+	return configChanges("zone_table", before, after, func(t domain.ZoneTable) string { return t.Provider })
+}
+
+/*
+resolveDestination returns the destination given by ?destination=, a country code optionally
+followed by a comma and a postcode, such as GB,IV2 3AB or FR, or nil if there is none. The
+destination is checked when the zone is looked up. A destination cannot be combined with ?as_of=,
+as zone tables are not kept in the price history. If it is empty or combined with as_of an error
+response is written and ok is false.
+*/
+func resolveDestination(w http.ResponseWriter, r *http.Request) (destination *domain.Destination, ok bool) {
+	query := r.URL.Query()
+	if !query.Has("destination") {
+		return nil, true
+	}
+	value := strings.TrimSpace(query.Get("destination"))
+	if value == "" {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "destination must not be empty", nil)
+		return nil, false
+	}
+	if query.Has("as_of") {
+		writeError(w, r, http.StatusBadRequest, codeInvalidRequest, "destination cannot be combined with as_of", map[string]string{"destination": value})
+		return nil, false
+	}
+	country, postcode, _ := strings.Cut(value, ",")
+	return &domain.Destination{Country: country, Postcode: postcode}, true
+}
+
+/*
+resolveZone looks up the zone provider delivers destination in, or returns "" if there is no
+destination. If the destination is malformed or not served an error response is written and ok
+is false.
+*/
+func resolveZone(w http.ResponseWriter, r *http.Request, provider string, destination *domain.Destination) (zone domain.Zone, ok bool) {
+	if destination == nil {
+		return "", true
+	}
+	zone, err := domain.DeliveryZone(provider, *destination)
+	if err != nil {
+		logs.Logs(2, "Delivery zone lookup failed: "+err.Error(), provider)
+		writeDomainError(w, r, err, map[string]string{"provider": provider})
+		return "", false
+	}
+	return zone, true
+}
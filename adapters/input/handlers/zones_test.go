@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// useZoneTables loads the given zone tables from storage instead of DELIVERY_ZONES_FILE, until the test ends
+func useZoneTables(t *testing.T, tables ...domain.ZoneTable) {
+	t.Helper()
+	original := storage.LoadZoneTablesFunc
+	storage.LoadZoneTablesFunc = func() ([]domain.ZoneTable, error) { return tables, nil }
+	t.Cleanup(func() {
+		storage.LoadZoneTablesFunc = original
+		domain.SetZoneTables(nil)
+	})
+}
+
+// TestDestinationPricing tests pricing products for a destination and the errors for destinations that cannot be priced
+func TestDestinationPricing(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(envPath, []byte("DELIVERY_PROVIDER=DHL\n"), 0o600)
+	originalEnvFile := envFile
+	envFile = envPath
+	t.Cleanup(func() { envFile = originalEnvFile })
+	t.Setenv("API_KEYS", "ops:admin:a")
+	useZoneTables(t, domain.ZoneTable{Provider: "DHL", Rates: map[domain.Zone]float64{domain.ZoneHighlandsIslands: 5, domain.ZoneNorthernIreland: 4}})
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		errorCode     string
+		deliveryPrice string
+		zone          domain.Zone
+	}{
+		{name: "no destination", query: "", expectedCode: http.StatusOK, deliveryPrice: "3.00"},
+		{name: "mainland", query: "?destination=GB,M1+1AA", expectedCode: http.StatusOK, deliveryPrice: "3.00", zone: domain.ZoneMainland},
+		{name: "highlands", query: "?destination=gb,kw14aa", expectedCode: http.StatusOK, deliveryPrice: "7.50", zone: domain.ZoneHighlandsIslands},
+		{name: "northern ireland", query: "?destination=UK,BT1", expectedCode: http.StatusOK, deliveryPrice: "6.00", zone: domain.ZoneNorthernIreland},
+		{name: "zone not served", query: "?destination=FR", expectedCode: http.StatusBadRequest, errorCode: codeZoneNotServed},
+		{name: "provider without a zone table", query: "?destination=GB,HS1&provider=ups", expectedCode: http.StatusBadRequest, errorCode: codeZoneNotServed},
+		{name: "no postcode", query: "?destination=GB", expectedCode: http.StatusBadRequest, errorCode: codeInvalidDestination},
+		{name: "empty destination", query: "?destination=", expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+		{name: "destination with as_of", query: "?destination=FR&as_of=2020-01-01T00:00:00Z", expectedCode: http.StatusBadRequest, errorCode: codeInvalidRequest},
+	}
+
+	etags := map[string]bool{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/v2/products/tv" + tc.query)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+			if tc.expectedCode != http.StatusOK {
+				if errResp := decodeError(t, resp); errResp.Code != tc.errorCode {
+					t.Errorf("expected %s, got %+v", tc.errorCode, errResp)
+				}
+				return
+			}
+
+			var product domain.PricedProduct
+			json.NewDecoder(resp.Body).Decode(&product)
+			if product.DeliveryPrice != tc.deliveryPrice || product.Zone != tc.zone {
+				t.Errorf("expected %s in zone %q, got %+v", tc.deliveryPrice, tc.zone, product)
+			}
+			etag := resp.Header.Get("ETag")
+			if etags[etag] {
+				t.Errorf("expected each zone to have its own ETag, got %s again", etag)
+			}
+			etags[etag] = true
+		})
+	}
+
+	resp, _ := http.Get(ts.URL + "/v2/products/tv?destination=fr")
+	errResp := decodeError(t, resp)
+	if errResp.Details["provider"] != "DHL" || errResp.Details["zone"] != "eu" || errResp.Details["country"] != "FR" {
+		t.Errorf("expected the provider, zone and country in the error, got %+v", errResp)
+	}
+
+	// serving the EU from a reload is audited
+	storage.LoadZoneTablesFunc = func() ([]domain.ZoneTable, error) {
+		return []domain.ZoneTable{{Provider: "DHL", Rates: map[domain.Zone]float64{domain.ZoneHighlandsIslands: 5, domain.ZoneNorthernIreland: 4, domain.ZoneEU: 8}}}, nil
+	}
+	if resp, body := adminRequest(t, "POST", ts.URL+"/admin/reload?reason=now+shipping+to+the+eu", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d: %s", resp.StatusCode, body)
+	}
+	resp, _ = http.Get(ts.URL + "/v2/products?destination=FR")
+	var products []domain.PricedProduct
+	json.NewDecoder(resp.Body).Decode(&products)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(products) != 1 || products[0].DeliveryPrice != "12.00" || products[0].Zone != domain.ZoneEU {
+		t.Errorf("expected the EU rate after the reload, got %d %+v", resp.StatusCode, products)
+	}
+	records := auditRecords(t, ts.URL+"/audit?entity=zone_table")
+	if len(records) != 1 || records[0].Entity != "zone_table:DHL" || records[0].Reason != "now shipping to the eu" {
+		t.Errorf("expected the zone table change to be audited, got %+v", records)
+	}
+
+	// an invalid zone table fails the reload and keeps the current tables
+	storage.LoadZoneTablesFunc = func() ([]domain.ZoneTable, error) {
+		return []domain.ZoneTable{{Provider: "DHL", Rates: map[domain.Zone]float64{domain.ZoneMainland: 1}}}, nil
+	}
+	if resp, _ := adminRequest(t, "POST", ts.URL+"/admin/reload", ""); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected an invalid zone table to fail the reload, got %d", resp.StatusCode)
+	}
+	if tables := domain.ZoneTables(); len(tables) != 1 || tables[0].Rates[domain.ZoneEU] != 8 {
+		t.Errorf("expected the current tables to be kept, got %+v", tables)
+	}
+}
@@ -0,0 +1,291 @@
+// Package audit keeps an append-only, hash-chained trail of changes to products and pricing.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genesisHash is the previous hash of the first record.
+var genesisHash = strings.Repeat("0", 64)
+
+// Entry is a change to be audited.
+type Entry struct {
+	Actor  string // API key ID, or "system" for changes the server noticed itself
+	Action string // e.g. "rate.changed"
+	Entity string // e.g. "rate:DHL"
+	Before any    // nil if the entity is new
+	After  any    // nil if the entity was removed
+	Reason string
+}
+
+/*
+Record is an audited change. Hash covers every other field, including PrevHash, the hash of the
+record before it, so editing, removing or reordering records breaks the chain from that point on.
+*/
+type Record struct {
+	Seq      uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Actor    string          `json:"actor"`
+	Action   string          `json:"action"`
+	Entity   string          `json:"entity"`
+	Before   json.RawMessage `json:"before"`
+	After    json.RawMessage `json:"after"`
+	Reason   string          `json:"reason,omitempty"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// digest returns the hash of the record's fields other than Hash.
+func (r Record) digest() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r) // a Record only holds strings, numbers, a time and valid JSON
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+Filter selects audit records. Entity matches either a whole entity, such as "rate:DHL", or every
+entity of a type, such as "rate". Zero fields match everything.
+*/
+type Filter struct {
+	Actor  string
+	Action string
+	Entity string
+	Since  time.Time // inclusive
+	Until  time.Time // exclusive
+	Limit  int       // most recent records returned, 0 for all
+}
+
+// matches reports whether the record is selected by the filter, ignoring Limit.
+func (f Filter) matches(r Record) bool {
+	entityType, _, _ := strings.Cut(r.Entity, ":")
+	switch {
+	case f.Actor != "" && r.Actor != f.Actor:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.Entity != "" && r.Entity != f.Entity && entityType != f.Entity:
+		return false
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Verification is the result of checking the hash chain.
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Records  int    `json:"records"`
+	BrokenAt uint64 `json:"broken_at,omitempty"` // sequence number of the first record that does not match
+	Reason   string `json:"reason,omitempty"`
+}
+
+/*
+Log is the audit trail. Records are only ever appended: to a JSON Lines file when one is
+configured, and in memory for queries. There is no way to change or remove a record.
+*/
+type Log struct {
+	file string
+
+	mu      sync.Mutex
+	records []Record
+}
+
+/*
+Open returns the audit log saved in file, or an in-memory log if file is empty. A missing file is
+not an error, as it is created with the first record. A chain broken by tampering is not an error
+either, so new changes are still audited; use Verify to check it.
+*/
+func Open(file string) (*Log, error) {
+	l := &Log{file: file}
+	if file == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, fmt.Errorf("reading audit log: %w", err)
+	}
+	l.records, err = parse(data)
+	return l, err
+}
+
+// parse reads records from JSON Lines.
+func parse(data []byte) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("parsing audit log line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+/*
+Append records entries in order, chaining each to the record before it. Either every entry is
+written or, if the file cannot be written, none are.
+*/
+func (l *Log) Append(entries ...Entry) ([]Record, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seq, prevHash := uint64(0), genesisHash
+	if n := len(l.records); n > 0 {
+		seq, prevHash = l.records[n-1].Seq, l.records[n-1].Hash
+	}
+	now := time.Now().UTC()
+
+	var lines bytes.Buffer
+	records := make([]Record, len(entries))
+	for i, entry := range entries {
+		before, err := marshalValue(entry.Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := marshalValue(entry.After)
+		if err != nil {
+			return nil, err
+		}
+		seq++
+		record := Record{
+			Seq:      seq,
+			Time:     now,
+			Actor:    entry.Actor,
+			Action:   entry.Action,
+			Entity:   entry.Entity,
+			Before:   before,
+			After:    after,
+			Reason:   entry.Reason,
+			PrevHash: prevHash,
+		}
+		record.Hash = record.digest()
+		prevHash = record.Hash
+		records[i] = record
+
+		line, _ := json.Marshal(record)
+		lines.Write(append(line, '\n'))
+	}
+
+	if err := l.write(lines.Bytes()); err != nil {
+		return nil, err
+	}
+	l.records = append(l.records, records...)
+	return records, nil
+}
+
+// marshalValue encodes a before or after value, with nil as JSON null.
+func marshalValue(value any) (json.RawMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encoding audit value: %w", err)
+	}
+	return data, nil
+}
+
+// write appends lines to the file, if there is one, and flushes them to disk. The caller must hold l.mu.
+func (l *Log) write(lines []byte) error {
+	if l.file == "" {
+		return nil
+	}
+	f, err := os.OpenFile(l.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	if _, err := f.Write(lines); err != nil {
+		f.Close()
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// Query returns the records selected by filter, most recent first.
+func (l *Log) Query(filter Filter) []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := []Record{}
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(records) == filter.Limit {
+			break
+		}
+		if filter.matches(l.records[i]) {
+			records = append(records, l.records[i])
+		}
+	}
+	return records
+}
+
+/*
+Verify checks the hash chain. When the log is kept in a file the file is read again, so changes
+made to it behind the server's back are found.
+*/
+func (l *Log) Verify() (Verification, error) {
+	l.mu.Lock()
+	records := l.records
+	l.mu.Unlock()
+
+	if l.file != "" {
+		data, err := os.ReadFile(l.file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Verification{}, fmt.Errorf("reading audit log: %w", err)
+		}
+		if records, err = parse(data); err != nil {
+			// the records before the one that cannot be read may still be intact
+			if result := verify(records); !result.Valid {
+				return result, nil
+			}
+			broken := uint64(len(records)) + 1
+			return Verification{Records: len(records), BrokenAt: broken, Reason: fmt.Sprintf("record %d cannot be read: %v", broken, err)}, nil
+		}
+	}
+	return verify(records), nil
+}
+
+// verify checks that every record follows on from the one before and matches its hash.
+func verify(records []Record) Verification {
+	prevHash := genesisHash
+	for i, record := range records {
+		broken := func(reason string) Verification {
+			return Verification{Records: len(records), BrokenAt: uint64(i) + 1, Reason: reason}
+		}
+		switch {
+		case record.Seq != uint64(i)+1:
+			return broken(fmt.Sprintf("record %d has sequence number %d", i+1, record.Seq))
+		case record.PrevHash != prevHash:
+			return broken(fmt.Sprintf("record %d does not follow on from the record before it", record.Seq))
+		case record.Hash != record.digest():
+			return broken(fmt.Sprintf("record %d does not match its hash", record.Seq))
+		}
+		prevHash = record.Hash
+	}
+	return Verification{Valid: true, Records: len(records)}
+}
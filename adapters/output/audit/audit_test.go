@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAppend tests that records are chained and kept across restarts
+func TestAppend(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	first, err := log.Append(Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL", Before: 2.0, After: 3.0, Reason: "reloaded"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if first[0].Seq != 1 || first[0].PrevHash != genesisHash || string(first[0].Before) != "2" || string(first[0].After) != "3" {
+		t.Errorf("unexpected first record %+v", first[0])
+	}
+
+	// reopening continues the chain from the file
+	log, err = Open(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	second, _ := log.Append(
+		Entry{Actor: "key_1", Action: "product.changed", Entity: "product:TV", After: map[string]any{"name": "TV"}},
+		Entry{Actor: "key_1", Action: "product.changed", Entity: "product:Radio", Before: map[string]any{"name": "Radio"}},
+	)
+	if second[0].Seq != 2 || second[0].PrevHash != first[0].Hash || second[1].PrevHash != second[0].Hash {
+		t.Errorf("expected records to be chained, got %+v", second)
+	}
+	if string(second[1].After) != "null" {
+		t.Errorf("expected a removal to have a null after, got %s", second[1].After)
+	}
+
+	if result, err := log.Verify(); err != nil || !result.Valid || result.Records != 3 {
+		t.Errorf("expected a valid chain of 3 records, got %+v, %v", result, err)
+	}
+}
+
+// TestAppendFailure tests that nothing is recorded when the file cannot be written
+func TestAppendFailure(t *testing.T) {
+	log, _ := Open(filepath.Join(t.TempDir(), "missing", "audit.jsonl"))
+	if _, err := log.Append(Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if records := log.Query(Filter{}); len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+}
+
+// TestQuery tests filtering audit records
+func TestQuery(t *testing.T) {
+	log, _ := Open("")
+	log.Append(
+		Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL"},
+		Entry{Actor: "key_1", Action: "product.changed", Entity: "product:TV"},
+		Entry{Actor: "key_1", Action: "rate.changed", Entity: "rate:UPS"},
+		Entry{Actor: "system", Action: "product.changed", Entity: "product:TV"},
+	)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []uint64
+	}{
+		{name: "all, newest first", filter: Filter{}, expected: []uint64{4, 3, 2, 1}},
+		{name: "actor", filter: Filter{Actor: "key_1"}, expected: []uint64{3, 2}},
+		{name: "action", filter: Filter{Action: "rate.changed"}, expected: []uint64{3, 1}},
+		{name: "entity", filter: Filter{Entity: "product:TV"}, expected: []uint64{4, 2}},
+		{name: "entity type", filter: Filter{Entity: "rate"}, expected: []uint64{3, 1}},
+		{name: "entity prefix is not a type", filter: Filter{Entity: "rat"}, expected: nil},
+		{name: "limit", filter: Filter{Limit: 2}, expected: []uint64{4, 3}},
+		{name: "since", filter: Filter{Since: now.Add(-time.Minute)}, expected: []uint64{4, 3, 2, 1}},
+		{name: "until", filter: Filter{Until: now.Add(-time.Minute)}, expected: nil},
+		{name: "combined", filter: Filter{Actor: "system", Entity: "product", Limit: 5}, expected: []uint64{4}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seqs []uint64
+			for _, record := range log.Query(tc.filter) {
+				seqs = append(seqs, record.Seq)
+			}
+			if len(seqs) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, seqs)
+			}
+			for i := range seqs {
+				if seqs[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, seqs)
+				}
+			}
+		})
+	}
+}
+
+// TestVerify tests that changes made to the file are detected
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(lines [][]byte) [][]byte
+		brokenAt uint64
+	}{
+		{name: "edited value", tamper: func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"after":3`), []byte(`"after":30`), 1)
+			return lines
+		}, brokenAt: 2},
+		{name: "edited reason", tamper: func(lines [][]byte) [][]byte {
+			lines[2] = bytes.Replace(lines[2], []byte(`"reason":"third"`), []byte(`"reason":"other"`), 1)
+			return lines
+		}, brokenAt: 3},
+		{name: "removed record", tamper: func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, brokenAt: 2},
+		{name: "reordered records", tamper: func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, brokenAt: 2},
+		{name: "corrupted line", tamper: func(lines [][]byte) [][]byte {
+			lines[2] = []byte("{")
+			return lines
+		}, brokenAt: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "audit.jsonl")
+			log, _ := Open(file)
+			log.Append(
+				Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL", After: 2, Reason: "first"},
+				Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL", Before: 2, After: 3, Reason: "second"},
+				Entry{Actor: "system", Action: "rate.changed", Entity: "rate:DHL", Before: 3, After: 4, Reason: "third"},
+			)
+
+			data, _ := os.ReadFile(file)
+			lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+			os.WriteFile(file, append(bytes.Join(tc.tamper(lines), []byte("\n")), '\n'), 0o600)
+
+			result, err := log.Verify()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if result.Valid || result.BrokenAt != tc.brokenAt || !strings.Contains(result.Reason, "record") {
+				t.Errorf("expected the chain to break at %d, got %+v", tc.brokenAt, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+)
+
+var mu sync.Mutex
+
+// runtimeVars are the variables expvar publishes itself, which are left out of the metrics endpoint:
+// cmdline can hold secrets passed as flags, and memstats says more about the process than callers need.
+var runtimeVars = map[string]bool{"cmdline": true, "memstats": true}
+
+/*
+Counter returns the named counter, creating it the first time it is asked for.
+Counters are published with expvar so they can be scraped from the metrics endpoint.
+*/
+func Counter(name string) *expvar.Int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := expvar.Get(name).(*expvar.Int); ok {
+		return existing
+	}
+	return expvar.NewInt(name)
+}
+
+/*
+Gauge is a value that goes down as well as up, such as the number of open connections.
+It is published with expvar like a counter, but is not meant to be read as a running total.
+*/
+type Gauge struct {
+	value expvar.Int
+}
+
+// NewGauge returns the named gauge, creating it the first time it is asked for.
+func NewGauge(name string) *Gauge {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := expvar.Get(name).(*Gauge); ok {
+		return existing
+	}
+	g := &Gauge{}
+	expvar.Publish(name, g)
+	return g
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() { g.value.Add(1) }
+
+// Dec takes one from the gauge.
+func (g *Gauge) Dec() { g.value.Add(-1) }
+
+// Set sets the gauge to value.
+func (g *Gauge) Set(value int64) { g.value.Set(value) }
+
+// Value returns the gauge's current value.
+func (g *Gauge) Value() int64 { return g.value.Value() }
+
+// String returns the value as JSON, so the gauge can be published with expvar.
+func (g *Gauge) String() string { return g.value.String() }
+
+// Handler serves the application's own published metrics as a JSON object.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		published := map[string]json.RawMessage{}
+		expvar.Do(func(kv expvar.KeyValue) {
+			if !runtimeVars[kv.Key] {
+				published[kv.Key] = json.RawMessage(kv.Value.String())
+			}
+		})
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(published)
+	})
+}
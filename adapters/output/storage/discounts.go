@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadDiscountCodesFunc = LoadDiscountCodes // Function to load the discount codes, can be mocked in tests
+	SaveDiscountCodesFunc = SaveDiscountCodes // Function to save the discount codes, can be mocked in tests
+)
+
+// discountCodesMu serialises SaveCurrentDiscountCodes, so an older snapshot never overwrites a newer one.
+var discountCodesMu sync.Mutex
+
+/*
+LoadDiscountCodes reads the discount codes, with their redemption counts, from the JSON file at DISCOUNT_CODES_FILE.
+An unset variable or missing file means there are no codes.
+*/
+func LoadDiscountCodes() ([]domain.DiscountCode, error) {
+	path := os.Getenv("DISCOUNT_CODES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading discount codes: %w", err)
+	}
+
+	var codes []domain.DiscountCode
+	if err := json.Unmarshal(data, &codes); err != nil {
+		return nil, fmt.Errorf("parsing discount codes: %w", err)
+	}
+	return codes, nil
+}
+
+/*
+SaveDiscountCodes writes the discount codes to DISCOUNT_CODES_FILE, replacing it atomically.
+If the variable is not set the codes are only kept in memory.
+*/
+func SaveDiscountCodes(codes []domain.DiscountCode) error {
+	path := os.Getenv("DISCOUNT_CODES_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(codes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("saving discount codes: %w", err)
+	}
+	return nil
+}
+
+/*
+SaveCurrentDiscountCodes saves the domain's discount codes as they are now. It is called after a
+code is redeemed or the store is edited; the snapshot is taken while saves are serialised, so
+concurrent redemptions always leave the latest counts in the file.
+*/
+func SaveCurrentDiscountCodes() error {
+	discountCodesMu.Lock()
+	defer discountCodesMu.Unlock()
+	return SaveDiscountCodesFunc(domain.DiscountCodes())
+}
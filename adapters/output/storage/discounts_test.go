@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestDiscountCodesRoundTrip tests that saved discount codes, with their redemption counts, load back unchanged
+func TestDiscountCodesRoundTrip(t *testing.T) {
+	t.Setenv("DISCOUNT_CODES_FILE", filepath.Join(t.TempDir(), "codes.json"))
+
+	endsAt := time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)
+	codes := []domain.DiscountCode{
+		{Code: "WELCOME10", Kind: domain.DiscountPercent, Value: 10, MinSpend: 20, UsageLimit: 100, Redemptions: 3},
+		{Code: "FREESHIP", Kind: domain.DiscountFreeDelivery, Providers: []string{"DHL"}, EndsAt: &endsAt},
+	}
+
+	if err := SaveDiscountCodes(codes); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := LoadDiscountCodes()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, codes) {
+		t.Errorf("expected %+v, got %+v", codes, loaded)
+	}
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+/*
+WriteFileAtomic writes data to path with the permissions perm, replacing any existing file
+atomically: the data is written and synced to a temporary file in the same directory, which is
+then renamed over path. Readers see either the old file or the new one, never a partial write.
+*/
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
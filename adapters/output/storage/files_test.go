@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFlags(0) // Disable log timestamps for cleaner test output
+	go logs.ProcessLogs()
+	os.Exit(m.Run())
+}
+
+// TestWriteFileAtomic tests that a file is replaced with the new data and permissions, leaving no temporary file behind
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	os.WriteFile(path, []byte("old"), 0o644)
+
+	if err := WriteFileAtomic(path, []byte("new"), 0o600); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("expected %q, got %q, %v", "new", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v, %v", info.Mode().Perm(), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the written file in the directory, got %d entries", len(entries))
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "data.json"), []byte("new"), 0o600); err == nil {
+		t.Error("expected an error writing to a missing directory")
+	}
+}
+
+// TestLoadMissingFiles tests that an unset variable or missing file loads as empty, and a broken file is an error
+func TestLoadMissingFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   string
+		empty func() (bool, error)
+	}{
+		{name: "history", env: "PRICE_HISTORY_FILE", empty: func() (bool, error) {
+			history, err := LoadHistory()
+			return len(history.Products) == 0 && len(history.Rates) == 0, err
+		}},
+		{name: "schedule", env: "PRICING_SCHEDULE_FILE", empty: func() (bool, error) {
+			changes, err := LoadSchedule()
+			return len(changes) == 0, err
+		}},
+		{name: "discount codes", env: "DISCOUNT_CODES_FILE", empty: func() (bool, error) {
+			codes, err := LoadDiscountCodes()
+			return len(codes) == 0, err
+		}},
+		{name: "promotions", env: "PROMOTIONS_FILE", empty: func() (bool, error) {
+			promotions, err := LoadPromotions()
+			return len(promotions) == 0, err
+		}},
+		{name: "service levels", env: "SERVICE_LEVELS_FILE", empty: func() (bool, error) {
+			levels, err := LoadServiceLevels()
+			return len(levels) == 0, err
+		}},
+		{name: "zone tables", env: "DELIVERY_ZONES_FILE", empty: func() (bool, error) {
+			tables, err := LoadZoneTables()
+			return len(tables) == 0, err
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			t.Setenv(tc.env, "")
+			if empty, err := tc.empty(); !empty || err != nil {
+				t.Errorf("expected nothing for an unset variable, got empty %v, %v", empty, err)
+			}
+
+			t.Setenv(tc.env, filepath.Join(dir, "missing.json"))
+			if empty, err := tc.empty(); !empty || err != nil {
+				t.Errorf("expected nothing for a missing file, got empty %v, %v", empty, err)
+			}
+
+			broken := filepath.Join(dir, "broken.json")
+			os.WriteFile(broken, []byte("{not json"), 0o644)
+			t.Setenv(tc.env, broken)
+			if _, err := tc.empty(); err == nil {
+				t.Error("expected an error for a broken file")
+			}
+		})
+	}
+}
+
+// TestLoadProducts tests reading the catalogue, and that every failure is reported as storage unavailable
+func TestLoadProducts(t *testing.T) {
+	dir := t.TempDir()
+	catalogue := filepath.Join(dir, "products.json")
+	os.WriteFile(catalogue, []byte(`[{"name": "TV", "weight": 1.5, "price": 20}]`), 0o644)
+	broken := filepath.Join(dir, "broken.json")
+	os.WriteFile(broken, []byte("{not json"), 0o644)
+
+	tests := []struct {
+		name        string
+		path        string
+		expectedErr bool
+	}{
+		{name: "catalogue", path: catalogue},
+		{name: "unset", path: "", expectedErr: true},
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), expectedErr: true},
+		{name: "broken file", path: broken, expectedErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PRODUCTS_FILE_PATH", tc.path)
+			products, err := LoadProducts()
+			if tc.expectedErr {
+				if !errors.Is(err, domain.ErrStorageUnavailable) {
+					t.Errorf("expected a storage unavailable error, got %v", err)
+				}
+				return
+			}
+			if err != nil || len(products) != 1 || products[0].Name != "TV" || products[0].Price != 20 {
+				t.Errorf("unexpected products %+v, %v", products, err)
+			}
+		})
+	}
+}
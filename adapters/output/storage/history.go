@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadHistoryFunc = LoadHistory // Function to load the price history, can be mocked in tests
+	SaveHistoryFunc = SaveHistory // Function to save the price history, can be mocked in tests
+)
+
+/*
+LoadHistory reads the price history from the JSON file at PRICE_HISTORY_FILE.
+An unset variable or missing file is an empty history.
+*/
+func LoadHistory() (domain.PriceHistory, error) {
+	path := os.Getenv("PRICE_HISTORY_FILE")
+	if path == "" {
+		return domain.PriceHistory{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.PriceHistory{}, nil
+	}
+	if err != nil {
+		return domain.PriceHistory{}, fmt.Errorf("reading price history: %w", err)
+	}
+
+	var history domain.PriceHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return domain.PriceHistory{}, fmt.Errorf("parsing price history: %w", err)
+	}
+	return history, nil
+}
+
+/*
+SaveHistory writes the price history to PRICE_HISTORY_FILE, replacing it atomically.
+If the variable is not set the history is only kept in memory.
+*/
+func SaveHistory(history domain.PriceHistory) error {
+	path := os.Getenv("PRICE_HISTORY_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("saving price history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestHistoryRoundTrip tests that saved price history loads back unchanged
+func TestHistoryRoundTrip(t *testing.T) {
+	t.Setenv("PRICE_HISTORY_FILE", filepath.Join(t.TempDir(), "history.json"))
+
+	from := time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	history := domain.PriceHistory{
+		Products: []domain.ProductVersion{{Product: domain.Product{Name: "TV", Weight: 1.5, Price: 20}, Validity: domain.Validity{ValidFrom: from}}},
+		Rates: []domain.RateVersion{
+			{Provider: "DHL", Rate: 2, Validity: domain.Validity{ValidFrom: from, ValidTo: &to}},
+			{Provider: "DHL", Rate: 2.5, Validity: domain.Validity{ValidFrom: to}},
+		},
+	}
+
+	if err := SaveHistory(history); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := LoadHistory()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, history) {
+		t.Errorf("expected %+v, got %+v", history, loaded)
+	}
+}
+
+// TestSaveHistoryUnset tests that history is only kept in memory without PRICE_HISTORY_FILE
+func TestSaveHistoryUnset(t *testing.T) {
+	t.Setenv("PRICE_HISTORY_FILE", "")
+	if err := SaveHistory(domain.PriceHistory{Rates: []domain.RateVersion{{Provider: "DHL", Rate: 2}}}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
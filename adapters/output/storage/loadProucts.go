@@ -2,26 +2,33 @@ package storage
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
 	"github.com/PythonAkoto/base_techtest/domain"
 )
 
 var (
-	LoadProductsFunc = LoadProducts // Function to load products, can be mocked in tests
+	LoadProductsFunc     = LoadProducts     // Function to load products, can be mocked in tests
+	CatalogueModTimeFunc = CatalogueModTime // Function to get the catalogue modification time, can be mocked in tests
 )
 
+/*
+LoadProducts reads the product catalogue from the JSON file at PRODUCTS_FILE_PATH.
+Any failure to reach or read the file is wrapped with domain.ErrStorageUnavailable.
+*/
 func LoadProducts() ([]domain.Product, error) {
 	path := os.Getenv("PRODUCTS_FILE_PATH") // Get the path to the products file from environment variable
 	if path == "" {
 		logs.Logs(3, "PRODUCTS_FILE_PATH environment variable not set", "")
-		return nil, os.ErrNotExist
+		return nil, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, os.ErrNotExist)
 	}
 
 	file, err := os.Open(path) // Open the products file
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, err)
 	}
 	defer file.Close() // Ensure the file is closed after reading
 
@@ -29,8 +36,22 @@ func LoadProducts() ([]domain.Product, error) {
 	decoder := json.NewDecoder(file)
 	err = decoder.Decode(&products) // Decode the JSON data into the products slice
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, err)
 	}
 
 	return products, nil // Return the loaded products
 }
+
+// CatalogueModTime returns when the products file at PRODUCTS_FILE_PATH was last modified.
+func CatalogueModTime() (time.Time, error) {
+	path := os.Getenv("PRODUCTS_FILE_PATH")
+	if path == "" {
+		return time.Time{}, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, os.ErrNotExist)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, err)
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadPromotionsFunc = LoadPromotions // Function to load the promotions, can be mocked in tests
+)
+
+/*
+LoadPromotions reads the promotions from the JSON file at PROMOTIONS_FILE, in the order they are applied.
+An unset variable or missing file means there are no promotions.
+*/
+func LoadPromotions() ([]domain.Promotion, error) {
+	path := os.Getenv("PROMOTIONS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading promotions: %w", err)
+	}
+
+	var promotions []domain.Promotion
+	if err := json.Unmarshal(data, &promotions); err != nil {
+		return nil, fmt.Errorf("parsing promotions: %w", err)
+	}
+	return promotions, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadScheduleFunc = LoadSchedule // Function to load the pricing schedule, can be mocked in tests
+	SaveScheduleFunc = SaveSchedule // Function to save the pricing schedule, can be mocked in tests
+)
+
+/*
+LoadSchedule reads the pricing schedule from the JSON file at PRICING_SCHEDULE_FILE.
+An unset variable or missing file is an empty schedule.
+*/
+func LoadSchedule() ([]domain.ScheduledChange, error) {
+	path := os.Getenv("PRICING_SCHEDULE_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading pricing schedule: %w", err)
+	}
+
+	var changes []domain.ScheduledChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, fmt.Errorf("parsing pricing schedule: %w", err)
+	}
+	return changes, nil
+}
+
+/*
+SaveSchedule writes the pricing schedule to PRICING_SCHEDULE_FILE, replacing it atomically.
+If the variable is not set the schedule is only kept in memory.
+*/
+func SaveSchedule(changes []domain.ScheduledChange) error {
+	path := os.Getenv("PRICING_SCHEDULE_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("saving pricing schedule: %w", err)
+	}
+	return nil
+}
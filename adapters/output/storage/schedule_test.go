@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+// TestScheduleRoundTrip tests that a saved pricing schedule loads back unchanged
+func TestScheduleRoundTrip(t *testing.T) {
+	t.Setenv("PRICING_SCHEDULE_FILE", filepath.Join(t.TempDir(), "schedule.json"))
+
+	changes := []domain.ScheduledChange{
+		{ID: "sch_1", EffectiveAt: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), Rates: map[string]float64{"DHL": 2.5}, Note: "winter rates"},
+		{ID: "sch_2", EffectiveAt: time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC), Provider: "UPS"},
+	}
+
+	if err := SaveSchedule(changes); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	loaded, err := LoadSchedule()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, changes) {
+		t.Errorf("expected %+v, got %+v", changes, loaded)
+	}
+}
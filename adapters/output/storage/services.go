@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadServiceLevelsFunc = LoadServiceLevels // Function to load the carriers' service levels, can be mocked in tests
+	LoadBankHolidaysFunc  = LoadBankHolidays  // Function to load the bank holidays, can be mocked in tests
+)
+
+/*
+LoadServiceLevels reads the carriers' service levels from the JSON file at SERVICE_LEVELS_FILE.
+An unset variable or missing file means no carrier lists delivery options.
+*/
+func LoadServiceLevels() ([]domain.ServiceLevel, error) {
+	path := os.Getenv("SERVICE_LEVELS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading service levels: %w", err)
+	}
+
+	var levels []domain.ServiceLevel
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return nil, fmt.Errorf("parsing service levels: %w", err)
+	}
+	return levels, nil
+}
+
+/*
+LoadBankHolidays reads the bank holidays from the calendar file at BANK_HOLIDAYS_FILE, one date
+such as 2026-12-25 per line. Empty lines and lines starting with # are ignored. An unset variable
+or missing file means there are no bank holidays.
+*/
+func LoadBankHolidays() ([]string, error) {
+	path := os.Getenv("BANK_HOLIDAYS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening bank holidays: %w", err)
+	}
+	defer file.Close()
+
+	var dates []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// ignore empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dates = append(dates, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading bank holidays: %w", err)
+	}
+	return dates, nil
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestLoadBankHolidays tests reading the bank holiday calendar, skipping blank lines and comments
+func TestLoadBankHolidays(t *testing.T) {
+	dir := t.TempDir()
+	calendar := filepath.Join(dir, "holidays.txt")
+	os.WriteFile(calendar, []byte("# England and Wales\n2026-12-25\n\n  2026-12-28  \n"), 0o644)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{name: "calendar", path: calendar, expected: []string{"2026-12-25", "2026-12-28"}},
+		{name: "unset", path: ""},
+		{name: "missing file", path: filepath.Join(dir, "missing.txt")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("BANK_HOLIDAYS_FILE", tc.path)
+			holidays, err := LoadBankHolidays()
+			if err != nil || !slices.Equal(holidays, tc.expected) {
+				t.Errorf("expected %v, got %v, %v", tc.expected, holidays, err)
+			}
+		})
+	}
+}
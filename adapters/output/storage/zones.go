@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+var (
+	LoadZoneTablesFunc = LoadZoneTables // Function to load the carriers' zone tables, can be mocked in tests
+)
+
+/*
+LoadZoneTables reads the carriers' zone tables from the JSON file at DELIVERY_ZONES_FILE.
+An unset variable or missing file means every carrier only delivers to the mainland.
+*/
+func LoadZoneTables() ([]domain.ZoneTable, error) {
+	path := os.Getenv("DELIVERY_ZONES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading zone tables: %w", err)
+	}
+
+	var tables []domain.ZoneTable
+	if err := json.Unmarshal(data, &tables); err != nil {
+		return nil, fmt.Errorf("parsing zone tables: %w", err)
+	}
+	return tables, nil
+}
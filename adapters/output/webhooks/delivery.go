@@ -0,0 +1,366 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/metrics"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"   // being attempted or waiting to retry
+	StatusDelivered = "delivered" // the receiver answered with a 2xx status
+	StatusFailed    = "failed"    // every attempt failed, the delivery is dead-lettered
+	StatusCancelled = "cancelled" // the subscription was removed before the delivery succeeded
+)
+
+var (
+	deliveriesTotal  = metrics.Counter("webhook_deliveries_total")
+	failuresTotal    = metrics.Counter("webhook_attempt_failures_total")
+	deadLettersTotal = metrics.Counter("webhook_dead_letters_total")
+)
+
+// Attempt is one try at delivering an event.
+type Attempt struct {
+	Number     int       `json:"number"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+}
+
+// Delivery is an event sent to one subscription, with the history of its attempts.
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	Attempts       []Attempt  `json:"attempts"`
+
+	payload []byte
+}
+
+// snapshot returns a copy of the delivery that is safe to use without holding the dispatcher's lock.
+func (dl *Delivery) snapshot() Delivery {
+	copied := *dl
+	copied.Attempts = slices.Clone(dl.Attempts)
+	if dl.NextAttemptAt != nil {
+		next := *dl.NextAttemptAt
+		copied.NextAttemptAt = &next
+	}
+	return copied
+}
+
+/*
+Sign returns the signature sent in the Webhook-Signature header: an HMAC-SHA256 of the timestamp,
+a full stop and the body, keyed with the subscription's secret, as hex. Receivers should compute
+the same value, compare it in constant time and reject old timestamps to stop replays.
+*/
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliveries returns the recent deliveries to a subscription, oldest first.
+func (d *Dispatcher) Deliveries(subscriptionID string) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.find(subscriptionID); !ok {
+		return nil, fmt.Errorf("%w: %q", ErrSubscriptionNotFound, subscriptionID)
+	}
+	deliveries := make([]Delivery, 0, len(d.history[subscriptionID]))
+	for _, delivery := range d.history[subscriptionID] {
+		deliveries = append(deliveries, delivery.snapshot())
+	}
+	return deliveries, nil
+}
+
+// DeadLetters returns the deliveries that ran out of attempts, oldest first.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(d.deadLetters))
+	for _, delivery := range d.deadLetters {
+		deliveries = append(deliveries, delivery.snapshot())
+	}
+	return deliveries
+}
+
+// Redeliver takes a delivery off the dead-letter list and tries it again with a fresh set of attempts.
+func (d *Dispatcher) Redeliver(deliveryID string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := slices.IndexFunc(d.deadLetters, func(dl *Delivery) bool { return dl.ID == deliveryID })
+	if i < 0 {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrDeliveryNotFound, deliveryID)
+	}
+	delivery := d.deadLetters[i]
+	if _, ok := d.find(delivery.SubscriptionID); !ok {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrSubscriptionNotFound, delivery.SubscriptionID)
+	}
+	d.deadLetters = slices.Delete(d.deadLetters, i, i+1)
+	delivery.Status = StatusPending
+	d.saveDeliveries()
+	d.start(delivery)
+	return delivery.snapshot(), nil
+}
+
+// record adds a delivery to its subscription's history, dropping the oldest beyond config.History. The caller must hold d.mu.
+func (d *Dispatcher) record(delivery *Delivery) {
+	history := append(d.history[delivery.SubscriptionID], delivery)
+	if len(history) > d.config.History {
+		history = history[len(history)-d.config.History:]
+	}
+	d.history[delivery.SubscriptionID] = history
+}
+
+/*
+deliveryRecord is a delivery as saved to the deliveries file, with the payload needed to retry it.
+The payload is kept as a string so a retry sends exactly the bytes that were first sent.
+*/
+type deliveryRecord struct {
+	Delivery
+	Payload string `json:"payload"`
+}
+
+// savedDeliveries is the content of the deliveries file.
+type savedDeliveries struct {
+	History     map[string][]deliveryRecord `json:"history"` // by subscription ID, oldest first
+	DeadLetters []deliveryRecord            `json:"dead_letters"`
+}
+
+/*
+deliveriesFile is where the delivery history and dead letters are saved, next to the
+subscriptions: with config.File "webhooks.json" they are kept in "webhooks.deliveries.json".
+*/
+func (c Config) deliveriesFile() string {
+	if c.File == "" {
+		return ""
+	}
+	ext := filepath.Ext(c.File)
+	return strings.TrimSuffix(c.File, ext) + ".deliveries" + ext
+}
+
+/*
+loadDeliveries reads the delivery history and dead letters saved by saveDeliveries. A dead letter
+that is also in its subscription's history is loaded as the same delivery, as it was saved.
+Deliveries still pending when the service stopped are returned so they can be resumed.
+*/
+func (d *Dispatcher) loadDeliveries() ([]*Delivery, error) {
+	data, err := os.ReadFile(d.config.deliveriesFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading webhook deliveries: %w", err)
+	}
+	var saved savedDeliveries
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parsing webhook deliveries: %w", err)
+	}
+
+	byID := make(map[string]*Delivery)
+	var pending []*Delivery
+	for subscriptionID, records := range saved.History {
+		for _, record := range records {
+			delivery := record.delivery()
+			byID[delivery.ID] = delivery
+			d.history[subscriptionID] = append(d.history[subscriptionID], delivery)
+			if delivery.Status == StatusPending {
+				pending = append(pending, delivery)
+			}
+		}
+	}
+	for _, record := range saved.DeadLetters {
+		delivery, ok := byID[record.ID]
+		if !ok {
+			delivery = record.delivery()
+		}
+		d.deadLetters = append(d.deadLetters, delivery)
+	}
+	return pending, nil
+}
+
+// delivery returns the saved delivery with its payload.
+func (r deliveryRecord) delivery() *Delivery {
+	delivery := r.Delivery
+	delivery.payload = []byte(r.Payload)
+	return &delivery
+}
+
+// record returns the delivery as it is saved.
+func (dl *Delivery) record() deliveryRecord {
+	return deliveryRecord{Delivery: dl.snapshot(), Payload: string(dl.payload)}
+}
+
+/*
+saveDeliveries writes the delivery history and dead letters next to the subscriptions, if they are
+saved, so a restart neither loses dead letters nor forgets what was sent. A failure is logged, as
+the deliveries are still held in memory. The caller must hold d.mu.
+*/
+func (d *Dispatcher) saveDeliveries() {
+	path := d.config.deliveriesFile()
+	if path == "" {
+		return
+	}
+
+	saved := savedDeliveries{History: make(map[string][]deliveryRecord, len(d.history)), DeadLetters: make([]deliveryRecord, len(d.deadLetters))}
+	for subscriptionID, deliveries := range d.history {
+		records := make([]deliveryRecord, len(deliveries))
+		for i, delivery := range deliveries {
+			records[i] = delivery.record()
+		}
+		saved.History[subscriptionID] = records
+	}
+	for i, delivery := range d.deadLetters {
+		saved.DeadLetters[i] = delivery.record()
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err == nil {
+		err = storage.WriteFileAtomic(path, data, 0o600)
+	}
+	if err != nil {
+		logs.Logs(3, "failed to save webhook deliveries: "+err.Error(), "")
+	}
+}
+
+// start attempts the delivery in the background. The caller must hold d.mu.
+func (d *Dispatcher) start(delivery *Delivery) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(delivery)
+	}()
+}
+
+/*
+deliver attempts the delivery up to config.MaxAttempts times, waiting RetryBase, then twice that,
+and so on up to RetryMax between attempts. A delivery that never succeeds is dead-lettered.
+*/
+func (d *Dispatcher) deliver(delivery *Delivery) {
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		d.mu.Lock()
+		subscription, ok := d.find(delivery.SubscriptionID)
+		if !ok {
+			delivery.Status = StatusCancelled
+			delivery.NextAttemptAt = nil
+			d.saveDeliveries()
+			d.mu.Unlock()
+			return
+		}
+		d.mu.Unlock()
+
+		result := d.attempt(subscription, delivery)
+
+		d.mu.Lock()
+		result.Number = len(delivery.Attempts) + 1
+		delivery.Attempts = append(delivery.Attempts, result)
+		delivery.NextAttemptAt = nil
+		if result.Error == "" {
+			delivery.Status = StatusDelivered
+			d.saveDeliveries()
+			d.mu.Unlock()
+			deliveriesTotal.Add(1)
+			return
+		}
+		failuresTotal.Add(1)
+		if attempt == d.config.MaxAttempts {
+			d.mu.Unlock()
+			break
+		}
+		delay := backoff(d.config.RetryBase, d.config.RetryMax, attempt)
+		next := time.Now().Add(delay).UTC()
+		delivery.NextAttemptAt = &next
+		d.saveDeliveries()
+		d.mu.Unlock()
+
+		logs.Logs(2, fmt.Sprintf("webhook delivery %s to %s failed (%s), retrying in %s", delivery.ID, subscription.URL, result.Error, delay), "")
+		timer := time.NewTimer(delay)
+		select {
+		case <-d.done:
+			timer.Stop()
+			return // left pending, as it was never given up on
+		case <-timer.C:
+		}
+	}
+
+	d.mu.Lock()
+	delivery.Status = StatusFailed
+	d.deadLetters = append(d.deadLetters, delivery)
+	if len(d.deadLetters) > d.config.MaxDeadLetters {
+		d.deadLetters = d.deadLetters[len(d.deadLetters)-d.config.MaxDeadLetters:]
+	}
+	d.saveDeliveries()
+	attempts := len(delivery.Attempts)
+	d.mu.Unlock()
+
+	deadLettersTotal.Add(1)
+	logs.Logs(3, fmt.Sprintf("webhook delivery %s to subscription %s dead-lettered after %d attempts", delivery.ID, delivery.SubscriptionID, attempts), "")
+}
+
+// attempt posts the delivery's payload to the subscription once. A non-2xx status counts as a failure.
+func (d *Dispatcher) attempt(subscription Subscription, delivery *Delivery) (result Attempt) {
+	start := time.Now()
+	result.At = start.UTC()
+	defer func() { result.Duration = time.Since(start).Round(time.Millisecond).String() }()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "base-techtest-webhooks/1")
+	req.Header.Set("Webhook-ID", delivery.EventID)
+	req.Header.Set("Webhook-Version", strconv.Itoa(EventVersion))
+	req.Header.Set("Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Webhook-Signature", "v1="+Sign(subscription.Secret, timestamp, delivery.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // drain so the connection can be reused
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Error = "receiver answered " + resp.Status
+	}
+	return result
+}
+
+// backoff returns the delay after the given failed attempt: base doubled for each earlier failure, capped at maxDelay.
+func backoff(base time.Duration, maxDelay time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,79 @@
+package webhooks
+
+import "github.com/PythonAkoto/base_techtest/domain"
+
+/*
+EventVersion is the version of the event payload, sent in every event and as the Webhook-Version
+header. The payload's DTOs below are frozen: fields added to domain.PricedProduct are only sent
+in a new version, so receivers never see an event change shape.
+*/
+const EventVersion = 1
+
+// Product is a product's prices in a PriceChange, in the shape of the /v2 API's products.
+type Product struct {
+	Name                  string           `json:"name"`
+	ProductPrice          string           `json:"product_price"`
+	DeliveryPrice         string           `json:"delivery_price"`
+	TotalPrice            string           `json:"total_price"`
+	DeliveryService       string           `json:"delivery_service"`
+	ChargeableWeight      float64          `json:"chargeable_weight"`
+	WeightBasis           string           `json:"weight_basis"`
+	Zone                  string           `json:"zone,omitempty"`
+	DeliveryOptions       []DeliveryOption `json:"delivery_options,omitempty"`
+	OriginalProductPrice  string           `json:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string           `json:"original_delivery_price,omitempty"`
+	Promotions            []Promotion      `json:"promotions,omitempty"`
+}
+
+// DeliveryOption is a service level in a Product's delivery options.
+type DeliveryOption struct {
+	Service           string `json:"service"`
+	DeliveryPrice     string `json:"delivery_price"`
+	TotalPrice        string `json:"total_price"`
+	EstimatedDelivery string `json:"estimated_delivery"`
+	DeliverBy         string `json:"deliver_by,omitempty"`
+}
+
+// Promotion is a promotion or discount code that took money off a Product.
+type Promotion struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Target       string `json:"target"`
+	Discount     string `json:"discount"`
+	DiscountCode bool   `json:"discount_code,omitempty"`
+}
+
+// NewProduct returns the payload DTO for a priced product.
+func NewProduct(product domain.PricedProduct) *Product {
+	dto := &Product{
+		Name:                  product.Name,
+		ProductPrice:          product.ProductPrice,
+		DeliveryPrice:         product.DeliveryPrice,
+		TotalPrice:            product.TotalPrice,
+		DeliveryService:       product.DeliveryService,
+		ChargeableWeight:      product.ChargeableWeight,
+		WeightBasis:           string(product.WeightBasis),
+		Zone:                  string(product.Zone),
+		OriginalProductPrice:  product.OriginalProductPrice,
+		OriginalDeliveryPrice: product.OriginalDeliveryPrice,
+	}
+	for _, option := range product.DeliveryOptions {
+		dto.DeliveryOptions = append(dto.DeliveryOptions, DeliveryOption{
+			Service:           string(option.Service),
+			DeliveryPrice:     option.DeliveryPrice,
+			TotalPrice:        option.TotalPrice,
+			EstimatedDelivery: option.EstimatedDelivery,
+			DeliverBy:         option.DeliverBy,
+		})
+	}
+	for _, promotion := range product.Promotions {
+		dto.Promotions = append(dto.Promotions, Promotion{
+			ID:           promotion.ID,
+			Name:         promotion.Name,
+			Target:       string(promotion.Target),
+			Discount:     promotion.Discount,
+			DiscountCode: promotion.DiscountCode,
+		})
+	}
+	return dto
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+/*
+targetPolicy decides which hosts webhooks may be sent to. Loopback, link-local and private
+addresses are refused, so a subscription cannot be used to reach the service itself or the
+network behind it, unless an entry of the allow-list names the host, its address or a CIDR
+range containing it.
+*/
+type targetPolicy struct {
+	hosts    []string
+	prefixes []netip.Prefix
+}
+
+/*
+newTargetPolicy parses the allow-list, where each entry is a host name, an IP address or a CIDR
+range. Invalid entries are logged and ignored, which only ever refuses more targets.
+*/
+func newTargetPolicy(allowed []string) targetPolicy {
+	var policy targetPolicy
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			policy.prefixes = append(policy.prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			policy.prefixes = append(policy.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		if strings.ContainsAny(entry, "/:") {
+			logs.Logs(3, fmt.Sprintf("ignoring invalid webhook allowed target %q", entry), "")
+			continue
+		}
+		policy.hosts = append(policy.hosts, strings.ToLower(entry))
+	}
+	return policy
+}
+
+// allowsHost reports whether host is on the allow-list by name or address.
+func (p targetPolicy) allowsHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.allowsAddr(addr)
+	}
+	for _, allowed := range p.hosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAddr reports whether addr may be sent to: it is public, or on the allow-list.
+func (p targetPolicy) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsPrivate() && !addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+checkHost rejects a subscription host that is refused by the policy before anything is sent to it:
+"localhost" and literal loopback, link-local and private addresses. Other names are checked when
+they are resolved, by dialContext.
+*/
+func (p targetPolicy) checkHost(host string) error {
+	if p.allowsHost(host) {
+		return nil
+	}
+	lower := strings.ToLower(host)
+	if lower == "localhost" || strings.HasSuffix(lower, ".localhost") {
+		return fmt.Errorf("%w: %q", ErrForbiddenTarget, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !p.allowsAddr(addr) {
+		return fmt.Errorf("%w: %q", ErrForbiddenTarget, host)
+	}
+	return nil
+}
+
+/*
+dialContext returns the dial function for the delivery client. Unless the host is allowed by name
+it checks every address the host resolves to as it is connected to, so a name that resolves, or
+is later changed to resolve, to a private address cannot be used to get around checkHost.
+*/
+func (p targetPolicy) dialContext(dialer *net.Dialer) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	checked := *dialer
+	checked.Control = func(_ string, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !p.allowsAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+		}
+		return nil
+	}
+
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && p.allowsHost(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return checked.DialContext(ctx, network, address)
+	}
+}
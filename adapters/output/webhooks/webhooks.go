@@ -0,0 +1,350 @@
+// Package webhooks delivers signed notifications of price changes to subscribed downstream systems.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/adapters/output/storage"
+	"github.com/PythonAkoto/base_techtest/domain"
+	"github.com/PythonAkoto/base_techtest/env"
+)
+
+// EventPriceChanged is the type of event sent when products' total prices change.
+const EventPriceChanged = "price.changed"
+
+var (
+	// ErrSubscriptionNotFound is returned when no subscription has the requested ID.
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+	// ErrDeliveryNotFound is returned when no dead-lettered delivery has the requested ID.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// ErrInvalidURL is returned when a subscription's URL is not an absolute http or https URL.
+	ErrInvalidURL = errors.New("webhook URL must be an absolute http or https URL")
+
+	// ErrForbiddenTarget is returned when a subscription's URL points at a loopback, link-local or private address that is not allowed.
+	ErrForbiddenTarget = errors.New("webhook URL must not point at a loopback, link-local or private address")
+)
+
+// Config holds the delivery settings for webhooks.
+type Config struct {
+	MaxAttempts    int           // attempts before a delivery is dead-lettered
+	RetryBase      time.Duration // delay before the first retry, doubled for each one after
+	RetryMax       time.Duration // longest delay between retries
+	Timeout        time.Duration // how long a receiver has to answer each attempt
+	History        int           // deliveries kept per subscription
+	MaxDeadLetters int           // dead-lettered deliveries kept, the oldest are dropped first
+	File           string        // where subscriptions are saved, with their deliveries next to it; empty to keep them in memory only
+	AllowedTargets []string      // hosts, IP addresses and CIDR ranges that may be sent to although they are loopback, link-local or private
+}
+
+// LoadConfig reads the webhook settings from environment variables, falling back to defaults.
+func LoadConfig() Config {
+	return Config{
+		MaxAttempts:    env.Int("WEBHOOK_MAX_ATTEMPTS", 6),
+		RetryBase:      env.Duration("WEBHOOK_RETRY_BASE", 2*time.Second),
+		RetryMax:       env.Duration("WEBHOOK_RETRY_MAX", 5*time.Minute),
+		Timeout:        env.Duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		History:        env.Int("WEBHOOK_HISTORY", 50),
+		MaxDeadLetters: env.Int("WEBHOOK_MAX_DEAD_LETTERS", 1000),
+		File:           os.Getenv("WEBHOOKS_FILE"),
+		AllowedTargets: strings.Split(os.Getenv("WEBHOOK_ALLOWED_TARGETS"), ","),
+	}
+}
+
+/*
+Subscription is a receiver of price change events. Provider limits it to changes in one
+provider's prices; when empty it follows the default provider. The secret signs every payload
+and is only returned when the subscription is created.
+*/
+type Subscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Provider  string    `json:"provider,omitempty"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// redacted returns the subscription without its secret.
+func (s Subscription) redacted() Subscription {
+	s.Secret = ""
+	return s
+}
+
+// PriceChange is a product whose total price changed. Old is nil for a new product and New is nil for a removed one.
+type PriceChange struct {
+	Name string   `json:"name"`
+	Old  *Product `json:"old"`
+	New  *Product `json:"new"`
+}
+
+// Event is the JSON payload posted to subscribers, in the shape given by Version.
+type Event struct {
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+	Provider  string        `json:"provider"`
+	Changes   []PriceChange `json:"changes"`
+}
+
+/*
+Dispatcher keeps the webhook subscriptions and delivers events to them. Each delivery runs in
+its own goroutine, retrying with exponential backoff until it succeeds or runs out of attempts,
+when it is moved to the dead-letter list to be retried by hand.
+*/
+type Dispatcher struct {
+	config  Config
+	targets targetPolicy
+	client  *http.Client
+	done    chan struct{}
+	close   sync.Once
+	wg      sync.WaitGroup
+
+	mu            sync.Mutex
+	subscriptions []Subscription
+	history       map[string][]*Delivery // deliveries per subscription ID, oldest first
+	deadLetters   []*Delivery
+}
+
+/*
+NewDispatcher returns a dispatcher using config, loading any subscriptions saved in config.File
+and the deliveries saved next to it. A missing file is not an error, as it is created when the
+first subscription is saved. Deliveries that were pending when the service stopped are resumed
+with a fresh set of attempts.
+*/
+func NewDispatcher(config Config) (*Dispatcher, error) {
+	targets := newTargetPolicy(config.AllowedTargets)
+	d := &Dispatcher{
+		config:  config,
+		targets: targets,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{DialContext: targets.dialContext(&net.Dialer{Timeout: config.Timeout}), Proxy: http.ProxyFromEnvironment},
+			// a redirect would send the signed payload somewhere the subscriber did not register, so the
+			// 3xx is returned as the receiver's answer and the attempt fails
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		done:    make(chan struct{}),
+		history: make(map[string][]*Delivery),
+	}
+	if config.File == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(config.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return d, fmt.Errorf("reading webhook subscriptions: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.subscriptions); err != nil {
+		return d, fmt.Errorf("parsing webhook subscriptions: %w", err)
+	}
+
+	pending, err := d.loadDeliveries()
+	if err != nil {
+		return d, err
+	}
+	for _, delivery := range pending {
+		d.start(delivery)
+	}
+	return d, nil
+}
+
+/*
+Subscribe registers rawURL to receive price change events for provider, or the default provider
+if empty. rawURL must be http or https, and must not point at a loopback, link-local or private
+address unless config.AllowedTargets allows it. If secret is empty a random one is generated.
+The returned subscription includes the secret, which is not shown again.
+*/
+func (d *Dispatcher) Subscribe(rawURL string, provider string, secret string) (Subscription, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+	if err := d.targets.checkHost(parsed.Hostname()); err != nil {
+		return Subscription{}, err
+	}
+	provider = strings.ToUpper(strings.TrimSpace(provider))
+	if provider != "" && !slices.Contains(domain.Providers(), provider) {
+		return Subscription{}, fmt.Errorf("%w: %q", domain.ErrUnknownProvider, provider)
+	}
+	if secret == "" {
+		secret = "whsec_" + randomHex(24)
+	}
+
+	subscription := Subscription{
+		ID:        "wh_" + randomHex(8),
+		URL:       parsed.String(),
+		Provider:  provider,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscriptions = append(d.subscriptions, subscription)
+	if err := d.save(); err != nil {
+		d.subscriptions = d.subscriptions[:len(d.subscriptions)-1]
+		return Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe removes a subscription. Its pending retries are abandoned.
+func (d *Dispatcher) Unsubscribe(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := slices.IndexFunc(d.subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrSubscriptionNotFound, id)
+	}
+	removed := d.subscriptions[i]
+	d.subscriptions = slices.Delete(d.subscriptions, i, i+1)
+	if err := d.save(); err != nil {
+		d.subscriptions = slices.Insert(d.subscriptions, i, removed)
+		return err
+	}
+	delete(d.history, id)
+	d.saveDeliveries()
+	return nil
+}
+
+// Subscription returns the subscription with the given ID, without its secret.
+func (d *Dispatcher) Subscription(id string) (Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscription, ok := d.find(id)
+	if !ok {
+		return Subscription{}, fmt.Errorf("%w: %q", ErrSubscriptionNotFound, id)
+	}
+	return subscription.redacted(), nil
+}
+
+// Subscriptions returns every subscription in the order they were created, without their secrets.
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subscriptions := make([]Subscription, len(d.subscriptions))
+	for i, subscription := range d.subscriptions {
+		subscriptions[i] = subscription.redacted()
+	}
+	return subscriptions
+}
+
+// Providers returns the distinct providers subscribed to, where "" is the default provider.
+func (d *Dispatcher) Providers() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var providers []string
+	for _, subscription := range d.subscriptions {
+		if !slices.Contains(providers, subscription.Provider) {
+			providers = append(providers, subscription.Provider)
+		}
+	}
+	return providers
+}
+
+/*
+Publish sends an event describing changes to every subscription for subscribedProvider, where
+"" means subscriptions following the default provider. provider is the provider the prices were
+calculated with. Delivery happens in the background.
+*/
+func (d *Dispatcher) Publish(subscribedProvider string, provider string, changes []PriceChange) {
+	if len(changes) == 0 {
+		return
+	}
+	event := Event{
+		ID:        "evt_" + randomHex(12),
+		Type:      EventPriceChanged,
+		Version:   EventVersion,
+		CreatedAt: time.Now().UTC(),
+		Provider:  provider,
+		Changes:   changes,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logs.Logs(3, "failed to encode webhook event: "+err.Error(), provider)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, subscription := range d.subscriptions {
+		if subscription.Provider != subscribedProvider {
+			continue
+		}
+		delivery := &Delivery{
+			ID:             "dlv_" + randomHex(12),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			Status:         StatusPending,
+			CreatedAt:      event.CreatedAt,
+			payload:        payload,
+		}
+		d.record(delivery)
+		d.start(delivery)
+	}
+	d.saveDeliveries()
+}
+
+// Close abandons pending retries and waits for attempts in flight to finish.
+func (d *Dispatcher) Close() {
+	d.close.Do(func() { close(d.done) })
+	d.wg.Wait()
+}
+
+// find returns the subscription with the given ID. The caller must hold d.mu.
+func (d *Dispatcher) find(id string) (Subscription, bool) {
+	i := slices.IndexFunc(d.subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return Subscription{}, false
+	}
+	return d.subscriptions[i], true
+}
+
+/*
+save writes the subscriptions to config.File, if set, replacing it atomically. The file holds the
+signing secrets in plain text, so it is only readable and writable by the owner. The caller must
+hold d.mu.
+*/
+func (d *Dispatcher) save() error {
+	if d.config.File == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(d.subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := storage.WriteFileAtomic(d.config.File, data, 0o600); err != nil {
+		return fmt.Errorf("saving webhook subscriptions: %w", err)
+	}
+	return nil
+}
+
+// randomHex returns n random bytes as hex, for IDs and secrets.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) // crypto/rand never returns an error on supported platforms
+	return hex.EncodeToString(b)
+}
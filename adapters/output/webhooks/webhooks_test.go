@@ -0,0 +1,375 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/domain"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFlags(0)
+	go logs.ProcessLogs()
+	os.Exit(m.Run())
+}
+
+// testConfig retries quickly so tests do not wait on real backoff, and allows the loopback receivers
+func testConfig() Config {
+	return Config{MaxAttempts: 3, RetryBase: 5 * time.Millisecond, RetryMax: 20 * time.Millisecond, Timeout: time.Second, History: 10, MaxDeadLetters: 10, AllowedTargets: []string{"127.0.0.1"}}
+}
+
+// receivedRequest is a webhook as seen by a receiver
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts an httptest receiver that answers with the status returned by respond for each request
+func newReceiver(t *testing.T, respond func(n int) int) (*httptest.Server, func() []receivedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		n := len(received)
+		mu.Unlock()
+		w.WriteHeader(respond(n))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), received...)
+	}
+}
+
+// waitFor polls condition until it is true, failing the test after a second
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// testChanges is a price change for the TV
+func testChanges() []PriceChange {
+	return []PriceChange{{
+		Name: "TV",
+		Old:  NewProduct(domain.PricedProduct{Name: "TV", ProductPrice: "20.00", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "DHL"}),
+		New:  NewProduct(domain.PricedProduct{Name: "TV", ProductPrice: "25.00", DeliveryPrice: "3.00", TotalPrice: "28.00", DeliveryService: "DHL"}),
+	}}
+}
+
+// TestDeliverySigned tests that receivers get a signed payload with the old and new prices
+func TestDeliverySigned(t *testing.T) {
+	receiver, received := newReceiver(t, func(int) int { return http.StatusNoContent })
+	d, _ := NewDispatcher(testConfig())
+	defer d.Close()
+
+	subscription, err := d.Subscribe(receiver.URL, "", "s3cret")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	other, _ := d.Subscribe(receiver.URL, "UPS", "")
+	d.Publish("", "DHL", testChanges())
+
+	waitFor(t, "delivery", func() bool {
+		deliveries, _ := d.Deliveries(subscription.ID)
+		return len(deliveries) == 1 && deliveries[0].Status == StatusDelivered
+	})
+	if deliveries, _ := d.Deliveries(other.ID); len(deliveries) != 0 {
+		t.Errorf("expected no deliveries to a UPS subscription, got %d", len(deliveries))
+	}
+
+	requests := received()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	request := requests[0]
+	timestamp, _ := strconv.ParseInt(request.header.Get("Webhook-Timestamp"), 10, 64)
+	if expected := "v1=" + Sign("s3cret", timestamp, request.body); request.header.Get("Webhook-Signature") != expected {
+		t.Errorf("expected signature %q, got %q", expected, request.header.Get("Webhook-Signature"))
+	}
+
+	var event Event
+	if err := json.Unmarshal(request.body, &event); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if event.Type != EventPriceChanged || event.Version != EventVersion || event.Provider != "DHL" || event.ID != request.header.Get("Webhook-ID") {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if request.header.Get("Webhook-Version") != "1" {
+		t.Errorf("expected Webhook-Version 1, got %q", request.header.Get("Webhook-Version"))
+	}
+	if len(event.Changes) != 1 || event.Changes[0].Old.TotalPrice != "23.00" || event.Changes[0].New.TotalPrice != "28.00" {
+		t.Errorf("unexpected changes %+v", event.Changes)
+	}
+}
+
+// TestDeliveryRetries tests retrying failed deliveries, dead-lettering and redelivering them
+func TestDeliveryRetries(t *testing.T) {
+	var healthy atomic.Bool
+	receiver, received := newReceiver(t, func(n int) int {
+		if healthy.Load() || n == 3 {
+			return http.StatusOK
+		}
+		return http.StatusServiceUnavailable
+	})
+	d, _ := NewDispatcher(testConfig())
+	defer d.Close()
+	subscription, _ := d.Subscribe(receiver.URL, "", "")
+
+	t.Run("succeeds on the last attempt", func(t *testing.T) {
+		d.Publish("", "DHL", testChanges())
+		waitFor(t, "delivery", func() bool {
+			deliveries, _ := d.Deliveries(subscription.ID)
+			return len(deliveries) == 1 && deliveries[0].Status == StatusDelivered
+		})
+		deliveries, _ := d.Deliveries(subscription.ID)
+		attempts := deliveries[0].Attempts
+		if len(attempts) != 3 || attempts[0].StatusCode != http.StatusServiceUnavailable || attempts[2].StatusCode != http.StatusOK || attempts[2].Number != 3 {
+			t.Errorf("unexpected attempts %+v", attempts)
+		}
+		if len(received()) != 3 {
+			t.Errorf("expected 3 requests, got %d", len(received()))
+		}
+	})
+
+	t.Run("dead-lettered after every attempt fails", func(t *testing.T) {
+		d.Publish("", "DHL", testChanges())
+		waitFor(t, "dead letter", func() bool { return len(d.DeadLetters()) == 1 })
+		deadLetter := d.DeadLetters()[0]
+		if deadLetter.Status != StatusFailed || len(deadLetter.Attempts) != 3 || deadLetter.Attempts[0].Error == "" {
+			t.Errorf("unexpected dead letter %+v", deadLetter)
+		}
+
+		healthy.Store(true)
+		if _, err := d.Redeliver(deadLetter.ID); err != nil {
+			t.Fatalf("redeliver failed: %v", err)
+		}
+		waitFor(t, "redelivery", func() bool {
+			deliveries, _ := d.Deliveries(subscription.ID)
+			return deliveries[1].Status == StatusDelivered
+		})
+		if len(d.DeadLetters()) != 0 {
+			t.Errorf("expected the dead letter list to be empty")
+		}
+		if _, err := d.Redeliver(deadLetter.ID); !errors.Is(err, ErrDeliveryNotFound) {
+			t.Errorf("expected ErrDeliveryNotFound, got %v", err)
+		}
+	})
+}
+
+// TestDeliveryRedirect tests that a redirect counts as a failed attempt and is not followed
+func TestDeliveryRedirect(t *testing.T) {
+	elsewhere, receivedElsewhere := newReceiver(t, func(int) int { return http.StatusOK })
+	receiver := httptest.NewServer(http.RedirectHandler(elsewhere.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(receiver.Close)
+	config := testConfig()
+	config.MaxAttempts = 1
+	d, _ := NewDispatcher(config)
+	defer d.Close()
+	subscription, _ := d.Subscribe(receiver.URL, "", "")
+
+	d.Publish("", "DHL", testChanges())
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters()) == 1 })
+	deliveries, _ := d.Deliveries(subscription.ID)
+	if attempts := deliveries[0].Attempts; len(attempts) != 1 || attempts[0].StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("expected a failed attempt with status 307, got %+v", attempts)
+	}
+	if len(receivedElsewhere()) != 0 {
+		t.Error("expected the redirect not to be followed")
+	}
+}
+
+// TestBackoff tests the delay between attempts
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 1, expected: time.Second},
+		{attempt: 2, expected: 2 * time.Second},
+		{attempt: 4, expected: 8 * time.Second},
+		{attempt: 7, expected: 30 * time.Second},
+		{attempt: 100, expected: 30 * time.Second},
+	}
+
+	for _, tc := range tests {
+		if delay := backoff(time.Second, 30*time.Second, tc.attempt); delay != tc.expected {
+			t.Errorf("backoff after attempt %d: expected %s, got %s", tc.attempt, tc.expected, delay)
+		}
+	}
+}
+
+// TestSubscribe tests validating subscriptions and saving them to a file
+func TestSubscribe(t *testing.T) {
+	config := testConfig()
+	config.File = filepath.Join(t.TempDir(), "webhooks.json")
+	d, err := NewDispatcher(config)
+	if err != nil {
+		t.Fatalf("missing file should not be an error: %v", err)
+	}
+	defer d.Close()
+
+	tests := []struct {
+		name        string
+		url         string
+		provider    string
+		expectedErr error
+	}{
+		{name: "valid", url: "https://example.com/hooks", provider: "ups"},
+		{name: "relative url", url: "/hooks", expectedErr: ErrInvalidURL},
+		{name: "unsupported scheme", url: "ftp://example.com/hooks", expectedErr: ErrInvalidURL},
+		{name: "localhost", url: "http://localhost:8080/hooks", expectedErr: ErrForbiddenTarget},
+		{name: "loopback not allowed", url: "http://127.0.0.2/hooks", expectedErr: ErrForbiddenTarget},
+		{name: "IPv6 loopback", url: "http://[::1]/hooks", expectedErr: ErrForbiddenTarget},
+		{name: "private", url: "https://10.1.2.3/hooks", expectedErr: ErrForbiddenTarget},
+		{name: "link-local metadata service", url: "http://169.254.169.254/latest", expectedErr: ErrForbiddenTarget},
+		{name: "unknown provider", url: "https://example.com/hooks", provider: "FEDEX", expectedErr: domain.ErrUnknownProvider},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			subscription, err := d.Subscribe(tc.url, tc.provider, "")
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && (subscription.Provider != "UPS" || subscription.Secret == "") {
+				t.Errorf("unexpected subscription %+v", subscription)
+			}
+		})
+	}
+
+	// the file holds the signing secrets, so only the owner may read it
+	info, err := os.Stat(config.File)
+	if err != nil {
+		t.Fatalf("expected the subscriptions to be saved: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the subscriptions file to have mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := NewDispatcher(config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	subscriptions := reloaded.Subscriptions()
+	if len(subscriptions) != 1 || subscriptions[0].URL != "https://example.com/hooks" || subscriptions[0].Secret != "" {
+		t.Errorf("unexpected saved subscriptions %+v", subscriptions)
+	}
+
+	if err := reloaded.Unsubscribe(subscriptions[0].ID); err != nil {
+		t.Fatalf("unsubscribe failed: %v", err)
+	}
+	if err := reloaded.Unsubscribe(subscriptions[0].ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+// TestDeliveriesSaved tests that delivery history and dead letters are saved next to the subscriptions and can be retried after a restart
+func TestDeliveriesSaved(t *testing.T) {
+	var healthy atomic.Bool
+	receiver, received := newReceiver(t, func(int) int {
+		if healthy.Load() {
+			return http.StatusOK
+		}
+		return http.StatusServiceUnavailable
+	})
+	config := testConfig()
+	config.MaxAttempts = 1
+	config.File = filepath.Join(t.TempDir(), "webhooks.json")
+	d, _ := NewDispatcher(config)
+	subscription, _ := d.Subscribe(receiver.URL, "", "")
+	d.Publish("", "DHL", testChanges())
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters()) == 1 })
+	d.Close()
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(config.File), "webhooks.deliveries.json")); err != nil {
+		t.Fatalf("expected the deliveries to be saved next to the subscriptions: %v", err)
+	}
+
+	reloaded, err := NewDispatcher(config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	defer reloaded.Close()
+	deadLetters := reloaded.DeadLetters()
+	deliveries, _ := reloaded.Deliveries(subscription.ID)
+	if len(deadLetters) != 1 || len(deliveries) != 1 || deliveries[0].ID != deadLetters[0].ID || len(deadLetters[0].Attempts) != 1 {
+		t.Fatalf("expected the dead letter and its history to survive a restart, got %+v and %+v", deadLetters, deliveries)
+	}
+
+	healthy.Store(true)
+	if _, err := reloaded.Redeliver(deadLetters[0].ID); err != nil {
+		t.Fatalf("redeliver failed: %v", err)
+	}
+	waitFor(t, "redelivery", func() bool {
+		deliveries, _ := reloaded.Deliveries(subscription.ID)
+		return deliveries[0].Status == StatusDelivered
+	})
+	requests := received()
+	if len(requests) != 2 || string(requests[1].body) != string(requests[0].body) {
+		t.Errorf("expected the saved payload to be redelivered, got %d requests", len(requests))
+	}
+}
+
+// TestTargetPolicy tests which addresses webhooks may be sent to, and that resolved addresses are checked when dialling
+func TestTargetPolicy(t *testing.T) {
+	policy := newTargetPolicy([]string{"10.0.0.0/8", "hooks.internal", "fd00::1", "not a/cidr"})
+
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "example.com", expected: true},
+		{host: "93.184.216.34", expected: true},
+		{host: "10.20.30.40", expected: true},
+		{host: "hooks.internal", expected: true},
+		{host: "fd00::1", expected: true},
+		{host: "fd00::2", expected: false},
+		{host: "192.168.1.1", expected: false},
+		{host: "::ffff:127.0.0.1", expected: false},
+		{host: "0.0.0.0", expected: false},
+		{host: "LOCALHOST", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.host, func(t *testing.T) {
+			if err := policy.checkHost(tc.host); (err == nil) != tc.expected {
+				t.Errorf("expected allowed %t, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	receiver, _ := newReceiver(t, func(int) int { return http.StatusOK })
+	dial := newTargetPolicy(nil).dialContext(&net.Dialer{Timeout: time.Second})
+	if _, err := dial(context.Background(), "tcp", receiver.Listener.Addr().String()); !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("expected dialling a loopback address to be refused, got %v", err)
+	}
+	conn, err := newTargetPolicy([]string{"127.0.0.1"}).dialContext(&net.Dialer{Timeout: time.Second})(context.Background(), "tcp", receiver.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("expected an allowed loopback address to be dialled, got %v", err)
+	}
+	conn.Close()
+}
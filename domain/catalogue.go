@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+/*
+CatalogueVersion returns a fingerprint of the catalogue contents. It changes whenever a product
+is added, removed or edited, so it can be used to tell whether a priced response is still current.
+*/
+func CatalogueVersion(products []Product) string {
+	data, _ := json.Marshal(products) // Product only holds strings and numbers, so this cannot fail
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+PricingConfigVersion returns a fingerprint of the configuration that affects prices for the
+given provider, including its zone table, its service levels with their delivery estimates and the
+promotions running now. Together with CatalogueVersion it identifies a priced catalogue without
+pricing it.
+*/
+func PricingConfigVersion(provider string) string {
+	now := time.Now()
+	envVar := providerPriceEnv[provider]
+	version := provider + "|" + envVar + "=" + os.Getenv(envVar)
+	if change, ok := rateChangeAt(provider, now); ok {
+		version += "|" + change.ID
+	}
+	if divisorVar := volumetricDivisorEnv(provider); os.Getenv(divisorVar) != "" {
+		version += "|" + divisorVar + "=" + os.Getenv(divisorVar)
+	}
+	if table := zoneTable(provider); table.Provider != "" {
+		data, _ := json.Marshal(table) // a ZoneTable only holds strings and numbers
+		version += "|" + string(data)
+	}
+	for _, level := range providerServiceLevels(provider) {
+		data, _ := json.Marshal(level) // a ServiceLevel only holds strings and numbers
+		version += "|" + string(data) + "@" + level.EstimatedDelivery(now).Format(time.DateOnly)
+	}
+	for _, promotion := range PromotionsAt(now) {
+		if len(promotion.Providers) == 0 || contains(promotion.Providers, provider) {
+			data, _ := json.Marshal(promotion) // a Promotion only holds strings, numbers and times
+			version += "|" + string(data)
+		}
+	}
+	return version
+}
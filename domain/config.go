@@ -0,0 +1,65 @@
+package domain
+
+/*
+Config is the pricing configuration kept in storage rather than the environment: the pricing
+schedule, promotions, discount codes, zone tables, service levels and bank holidays.
+*/
+type Config struct {
+	Schedule      []ScheduledChange
+	Promotions    []Promotion
+	DiscountCodes []DiscountCode
+	ZoneTables    []ZoneTable
+	ServiceLevels []ServiceLevel
+	BankHolidays  []string
+}
+
+/*
+SetConfig replaces every part of the configuration at once. Each part is validated first, and if
+any is invalid nothing is replaced and the error SetSchedule, SetPromotions, SetDiscountCodes,
+SetZoneTables, SetServiceLevels or SetBankHolidays would have returned is returned, so a reload
+never leaves pricing half reconfigured.
+*/
+func SetConfig(c Config) error {
+	changes, err := normaliseSchedule(c.Schedule)
+	if err != nil {
+		return err
+	}
+	promotionList, err := normalisePromotions(c.Promotions)
+	if err != nil {
+		return err
+	}
+	codes, err := normaliseDiscountCodes(c.DiscountCodes)
+	if err != nil {
+		return err
+	}
+	tables, err := normaliseZoneTables(c.ZoneTables)
+	if err != nil {
+		return err
+	}
+	levels, err := normaliseServiceLevels(c.ServiceLevels)
+	if err != nil {
+		return err
+	}
+	holidays, err := parseBankHolidays(c.BankHolidays)
+	if err != nil {
+		return err
+	}
+
+	scheduleMu.Lock()
+	schedule = changes
+	scheduleMu.Unlock()
+	promotionsMu.Lock()
+	promotions = promotionList
+	promotionsMu.Unlock()
+	discountsMu.Lock()
+	discountCodes = codes
+	discountsMu.Unlock()
+	zonesMu.Lock()
+	zoneTables = tables
+	zonesMu.Unlock()
+	servicesMu.Lock()
+	serviceLevels = levels
+	bankHolidays = holidays
+	servicesMu.Unlock()
+	return nil
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSetConfig tests replacing the whole configuration only when every part of it is valid
+func TestSetConfig(t *testing.T) {
+	t.Cleanup(func() { SetConfig(Config{}) })
+	valid := Config{
+		Promotions:    []Promotion{{ID: "ten-off", Target: PromotionProduct, Kind: PromotionFixed, Value: 10}},
+		DiscountCodes: []DiscountCode{{Code: "save5", Kind: DiscountFixed, Value: 5}},
+		BankHolidays:  []string{"2030-12-25"},
+	}
+	if err := SetConfig(valid); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(Promotions()) != 1 || len(DiscountCodes()) != 1 || DiscountCodes()[0].Code != "SAVE5" || len(BankHolidays()) != 1 {
+		t.Fatalf("expected the configuration to be applied and normalised, got %+v %+v %+v", Promotions(), DiscountCodes(), BankHolidays())
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		err    any
+	}{
+		{name: "invalid bank holiday", config: Config{BankHolidays: []string{"tomorrow"}}, err: new(*ErrInvalidBankHoliday)},
+		{name: "invalid service level", config: Config{ServiceLevels: []ServiceLevel{{Provider: "FEDEX", Service: ServiceStandard}}}, err: new(*ErrInvalidServiceLevel)},
+		{name: "invalid promotion", config: Config{Promotions: []Promotion{{ID: "bad", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 150}}}, err: new(*ErrInvalidPromotion)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetConfig(tc.config); !errors.As(err, tc.err) {
+				t.Fatalf("expected %T, got %v", tc.err, err)
+			}
+			if len(Promotions()) != 1 || len(DiscountCodes()) != 1 || len(BankHolidays()) != 1 {
+				t.Errorf("expected nothing to be replaced, got %+v %+v %+v", Promotions(), DiscountCodes(), BankHolidays())
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiscountKind is what a discount code takes off.
+type DiscountKind string
+
+const (
+	DiscountPercent      DiscountKind = "percent"       // Value percent off the product price
+	DiscountFixed        DiscountKind = "fixed"         // Value off the product price, never taking it below zero
+	DiscountFreeDelivery DiscountKind = "free_delivery" // the whole delivery price off
+)
+
+// DiscountCodeReason is why a discount code was rejected.
+type DiscountCodeReason string
+
+const (
+	DiscountCodeUnknown       DiscountCodeReason = "unknown"
+	DiscountCodeNotStarted    DiscountCodeReason = "not_started"
+	DiscountCodeExpired       DiscountCodeReason = "expired"
+	DiscountCodeExhausted     DiscountCodeReason = "exhausted"
+	DiscountCodeBelowMinimum  DiscountCodeReason = "below_minimum"
+	DiscountCodeWrongProvider DiscountCodeReason = "wrong_provider"
+)
+
+/*
+DiscountCode is a code a customer enters to get money off. Like a promotion it can be limited
+to a minimum spend, to providers and to a window between StartsAt and EndsAt; it can also be
+limited to UsageLimit redemptions. Codes are matched case-insensitively.
+*/
+type DiscountCode struct {
+	Code        string       `json:"code"`
+	Kind        DiscountKind `json:"kind"`
+	Value       float64      `json:"value,omitempty"` // not used by free_delivery
+	MinSpend    float64      `json:"min_spend,omitempty"`
+	Providers   []string     `json:"providers,omitempty"`
+	StartsAt    *time.Time   `json:"starts_at,omitempty"`   // inclusive
+	EndsAt      *time.Time   `json:"ends_at,omitempty"`     // exclusive
+	UsageLimit  int          `json:"usage_limit,omitempty"` // redemptions allowed, 0 for no limit
+	Redemptions int          `json:"redemptions"`
+}
+
+var (
+	// discountsMu is held while a code is checked, applied and redeemed, so concurrent requests cannot redeem it past its limit.
+	discountsMu   sync.Mutex
+	discountCodes []DiscountCode // sorted by code
+)
+
+/*
+SetDiscountCodes replaces the discount code store. Codes and provider names are normalised to
+upper case. If any code is invalid the store is left as it was and an *ErrInvalidDiscountCode is returned.
+*/
+func SetDiscountCodes(codes []DiscountCode) error {
+	normalised, err := normaliseDiscountCodes(codes)
+	if err != nil {
+		return err
+	}
+
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	discountCodes = normalised
+	return nil
+}
+
+// normaliseDiscountCodes validates and normalises codes as SetDiscountCodes does, sorted by code.
+func normaliseDiscountCodes(codes []DiscountCode) ([]DiscountCode, error) {
+	normalised := make([]DiscountCode, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for i, code := range codes {
+		code = normaliseDiscountCode(code)
+		if err := ValidateDiscountCode(code); err != nil {
+			return nil, err
+		}
+		if seen[code.Code] {
+			return nil, &ErrInvalidDiscountCode{Code: code.Code, Reason: "code is used more than once"}
+		}
+		seen[code.Code] = true
+		normalised[i] = code
+	}
+	slices.SortFunc(normalised, func(a, b DiscountCode) int { return strings.Compare(a.Code, b.Code) })
+	return normalised, nil
+}
+
+// DiscountCodes returns a copy of the discount code store, sorted by code.
+func DiscountCodes() []DiscountCode {
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	codes := slices.Clone(discountCodes)
+	for i := range codes {
+		codes[i].Providers = slices.Clone(codes[i].Providers)
+	}
+	return codes
+}
+
+/*
+AddDiscountCode adds a code to the store, returning it as stored. It returns an
+*ErrInvalidDiscountCode if the code is invalid or already in the store.
+*/
+func AddDiscountCode(code DiscountCode) (DiscountCode, error) {
+	code = normaliseDiscountCode(code)
+	if err := ValidateDiscountCode(code); err != nil {
+		return DiscountCode{}, err
+	}
+
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	i, found := slices.BinarySearchFunc(discountCodes, code.Code, func(c DiscountCode, code string) int { return strings.Compare(c.Code, code) })
+	if found {
+		return DiscountCode{}, &ErrInvalidDiscountCode{Code: code.Code, Reason: "code already exists"}
+	}
+	discountCodes = slices.Insert(discountCodes, i, code)
+	return code, nil
+}
+
+// RemoveDiscountCode removes a code from the store, returning it and whether it was there.
+func RemoveDiscountCode(code string) (DiscountCode, bool) {
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	i := slices.IndexFunc(discountCodes, func(c DiscountCode) bool { return strings.EqualFold(c.Code, code) })
+	if i < 0 {
+		return DiscountCode{}, false
+	}
+	removed := discountCodes[i]
+	discountCodes = slices.Delete(discountCodes, i, i+1)
+	return removed, true
+}
+
+// releaseRedemption takes back a redemption of code counted by CheckOut, if the code is still in the store.
+func releaseRedemption(code string) {
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	i := slices.IndexFunc(discountCodes, func(c DiscountCode) bool { return strings.EqualFold(c.Code, code) })
+	if i >= 0 && discountCodes[i].Redemptions > 0 {
+		discountCodes[i].Redemptions--
+	}
+}
+
+// ValidateDiscountCode checks that a code can be stored, returning an *ErrInvalidDiscountCode if not.
+func ValidateDiscountCode(c DiscountCode) error {
+	invalid := func(reason string) error { return &ErrInvalidDiscountCode{Code: c.Code, Reason: reason} }
+	switch {
+	case strings.TrimSpace(c.Code) == "" || strings.ContainsAny(c.Code, " \t\r\n"):
+		return invalid("code must not be empty or contain spaces")
+	case c.Kind != DiscountPercent && c.Kind != DiscountFixed && c.Kind != DiscountFreeDelivery:
+		return invalid(fmt.Sprintf("kind must be %q, %q or %q", DiscountPercent, DiscountFixed, DiscountFreeDelivery))
+	case c.Value < 0 || math.IsNaN(c.Value) || math.IsInf(c.Value, 0):
+		return invalid("value must not be negative")
+	case c.Kind == DiscountPercent && c.Value > 100:
+		return invalid("a percentage must not be more than 100")
+	case c.MinSpend < 0 || math.IsNaN(c.MinSpend) || math.IsInf(c.MinSpend, 0):
+		return invalid("min_spend must not be negative")
+	case c.UsageLimit < 0:
+		return invalid("usage_limit must not be negative")
+	case c.Redemptions < 0:
+		return invalid("redemptions must not be negative")
+	case c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt):
+		return invalid("ends_at must be after starts_at")
+	}
+	for _, provider := range c.Providers {
+		if !contains(allowedProviders, provider) {
+			return invalid(fmt.Sprintf("unknown provider %q", provider))
+		}
+	}
+	return nil
+}
+
+// normaliseDiscountCode upper-cases the code and its providers.
+func normaliseDiscountCode(c DiscountCode) DiscountCode {
+	c.Code = strings.ToUpper(strings.TrimSpace(c.Code))
+	c.Providers = slices.Clone(c.Providers)
+	for i, provider := range c.Providers {
+		c.Providers[i] = strings.ToUpper(strings.TrimSpace(provider))
+	}
+	return c
+}
+
+/*
+priceProductsWithCode prices products like PriceProducts and then applies a discount code to each
+product whose price, after promotions, meets the code's minimum spend. Like promotions, the code is
+quoted per line: each product is priced as an order of one, so a fixed code takes its whole value
+off every product that qualifies. To apply a code once to several products, price them as a
+shipment with PriceShipment or CheckOut. It is a quote, so the code is checked but not redeemed;
+codes are only redeemed by CheckOut. If it cannot be used an *ErrDiscountCodeRejected says why: it is unknown, not
+started, expired, exhausted, not valid with the provider or no product meets the minimum spend.
+*/
+func priceProductsWithCode(products []Product, provider string, code string, destination *Destination) ([]PricedProduct, error) {
+	at := time.Now()
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+
+	discount, err := findDiscountCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	return priceProducts(products, provider, currentPricing, at, discount, destination)
+}
+
+/*
+findDiscountCode returns the stored code matching code case-insensitively, or an
+*ErrDiscountCodeRejected if there is none. The caller must hold discountsMu.
+*/
+func findDiscountCode(code string) (*DiscountCode, error) {
+	i := slices.IndexFunc(discountCodes, func(c DiscountCode) bool { return strings.EqualFold(c.Code, strings.TrimSpace(code)) })
+	if i < 0 {
+		return nil, &ErrDiscountCodeRejected{Code: code, Reason: DiscountCodeUnknown}
+	}
+	return &discountCodes[i], nil
+}
+
+// check returns an *ErrDiscountCodeRejected if the code cannot be used with provider at the given time, whatever is bought.
+func (c DiscountCode) check(provider string, at time.Time) error {
+	rejected := func(reason DiscountCodeReason, detail string) error {
+		return &ErrDiscountCodeRejected{Code: c.Code, Reason: reason, Detail: detail}
+	}
+	switch {
+	case c.StartsAt != nil && at.Before(*c.StartsAt):
+		return rejected(DiscountCodeNotStarted, "valid from "+c.StartsAt.UTC().Format(time.RFC3339))
+	case c.EndsAt != nil && !at.Before(*c.EndsAt):
+		return rejected(DiscountCodeExpired, "expired at "+c.EndsAt.UTC().Format(time.RFC3339))
+	case c.UsageLimit > 0 && c.Redemptions >= c.UsageLimit:
+		return rejected(DiscountCodeExhausted, fmt.Sprintf("all %d uses redeemed", c.UsageLimit))
+	case len(c.Providers) > 0 && !contains(c.Providers, provider):
+		return rejected(DiscountCodeWrongProvider, "only valid with "+strings.Join(c.Providers, ", "))
+	}
+	return nil
+}
+
+// belowMinimum returns the *ErrDiscountCodeRejected for an order that does not meet the code's minimum spend.
+func (c DiscountCode) belowMinimum() error {
+	return &ErrDiscountCodeRejected{Code: c.Code, Reason: DiscountCodeBelowMinimum, Detail: fmt.Sprintf("minimum spend is %.2f", c.MinSpend)}
+}
+
+/*
+apply takes the code's discount off an order, a single product or a whole shipment, that meets
+its minimum spend, returning the new prices and the discount applied, or ok false if the order
+does not qualify.
+*/
+func (c DiscountCode) apply(productPrice, deliveryPrice float64) (float64, float64, AppliedPromotion, bool) {
+	if productPrice < c.MinSpend {
+		return productPrice, deliveryPrice, AppliedPromotion{}, false
+	}
+	applied := AppliedPromotion{ID: c.Code, Target: PromotionProduct, DiscountCode: true}
+	var discount float64
+	switch c.Kind {
+	case DiscountFreeDelivery:
+		applied.Target = PromotionDelivery
+		discount = deliveryPrice
+		deliveryPrice = 0
+	case DiscountPercent:
+		discount = percentOff(productPrice, c.Value)
+		productPrice = math.Round((productPrice-discount)*100) / 100
+	case DiscountFixed:
+		discount = math.Min(c.Value, productPrice)
+		productPrice = math.Round((productPrice-discount)*100) / 100
+	}
+	applied.Discount = fmt.Sprintf("%.2f", discount)
+	return productPrice, deliveryPrice, applied, true
+}
+
+// percentOff returns percent of price, rounded to the nearest penny.
+func percentOff(price, percent float64) float64 {
+	return math.Round(price*percent) / 100
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSetDiscountCodes tests validating and normalising the discount code store
+func TestSetDiscountCodes(t *testing.T) {
+	t.Cleanup(func() { SetDiscountCodes(nil) })
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		codes []DiscountCode
+		valid bool
+	}{
+		{name: "percent", codes: []DiscountCode{{Code: "save10", Kind: DiscountPercent, Value: 10}}, valid: true},
+		{name: "free delivery", codes: []DiscountCode{{Code: "SHIPFREE", Kind: DiscountFreeDelivery, Providers: []string{"dhl"}, StartsAt: &start, EndsAt: &end, UsageLimit: 5}}, valid: true},
+		{name: "empty code", codes: []DiscountCode{{Code: " ", Kind: DiscountFixed, Value: 1}}},
+		{name: "code with a space", codes: []DiscountCode{{Code: "SAVE 10", Kind: DiscountFixed, Value: 1}}},
+		{name: "unknown kind", codes: []DiscountCode{{Code: "A", Kind: "bogof"}}},
+		{name: "negative value", codes: []DiscountCode{{Code: "A", Kind: DiscountFixed, Value: -1}}},
+		{name: "over 100 percent", codes: []DiscountCode{{Code: "A", Kind: DiscountPercent, Value: 101}}},
+		{name: "negative min spend", codes: []DiscountCode{{Code: "A", Kind: DiscountFixed, Value: 1, MinSpend: -1}}},
+		{name: "negative usage limit", codes: []DiscountCode{{Code: "A", Kind: DiscountFixed, Value: 1, UsageLimit: -1}}},
+		{name: "unknown provider", codes: []DiscountCode{{Code: "A", Kind: DiscountFixed, Value: 1, Providers: []string{"FEDEX"}}}},
+		{name: "ends before it starts", codes: []DiscountCode{{Code: "A", Kind: DiscountFixed, Value: 1, StartsAt: &end, EndsAt: &start}}},
+		{name: "duplicate ignoring case", codes: []DiscountCode{{Code: "a", Kind: DiscountFixed, Value: 1}, {Code: "A", Kind: DiscountFixed, Value: 2}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetDiscountCodes(nil)
+			err := SetDiscountCodes(tc.codes)
+			var invalid *ErrInvalidDiscountCode
+			if tc.valid != (err == nil) || (err != nil && !errors.As(err, &invalid)) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.valid && len(DiscountCodes()) != 0 {
+				t.Error("expected invalid codes not to be stored")
+			}
+		})
+	}
+
+	SetDiscountCodes([]DiscountCode{{Code: "b", Kind: DiscountFixed, Value: 1, Providers: []string{"dpd"}}})
+	if _, err := AddDiscountCode(DiscountCode{Code: "a", Kind: DiscountPercent, Value: 5, Redemptions: 3}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := AddDiscountCode(DiscountCode{Code: "B", Kind: DiscountPercent, Value: 5}); err == nil {
+		t.Error("expected adding an existing code to fail")
+	}
+	codes := DiscountCodes()
+	if len(codes) != 2 || codes[0].Code != "A" || codes[0].Redemptions != 3 || codes[1].Code != "B" || codes[1].Providers[0] != "DPD" {
+		t.Errorf("expected normalised codes sorted by code, got %+v", codes)
+	}
+	if removed, ok := RemoveDiscountCode("a"); !ok || removed.Code != "A" || len(DiscountCodes()) != 1 {
+		t.Errorf("expected A to be removed, got %+v %v", removed, ok)
+	}
+	if _, ok := RemoveDiscountCode("a"); ok {
+		t.Error("expected removing a missing code to fail")
+	}
+}
+
+// TestPriceProductsWithCode tests applying discount codes and the reasons codes are rejected
+func TestPriceProductsWithCode(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("DPD_DELIVERY_PRICE", "1")
+	t.Cleanup(func() {
+		SetDiscountCodes(nil)
+		SetPromotions(nil)
+	})
+
+	now := time.Now()
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+	sofa := Product{Name: "Sofa", Weight: 40, Price: 600}
+
+	tests := []struct {
+		name       string
+		code       DiscountCode
+		promotions []Promotion
+		products   []Product
+		provider   string
+		expected   []PricedProduct
+		rejected   DiscountCodeReason
+	}{
+		{
+			name:     "percent",
+			code:     DiscountCode{Code: "SAVE10", Kind: DiscountPercent, Value: 10},
+			products: []Product{tv},
+			provider: "DHL",
+			expected: []PricedProduct{{Name: "TV", ProductPrice: "18.00", DeliveryPrice: "3.00", TotalPrice: "21.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual,
+				OriginalProductPrice: "20.00", OriginalDeliveryPrice: "3.00", Promotions: []AppliedPromotion{{ID: "SAVE10", Target: PromotionProduct, Discount: "2.00", DiscountCode: true}}}},
+		},
+		{
+			name:     "fixed stops at zero",
+			code:     DiscountCode{Code: "FIVER", Kind: DiscountFixed, Value: 25},
+			products: []Product{tv},
+			provider: "DHL",
+			expected: []PricedProduct{{Name: "TV", ProductPrice: "0.00", DeliveryPrice: "3.00", TotalPrice: "3.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual,
+				OriginalProductPrice: "20.00", OriginalDeliveryPrice: "3.00", Promotions: []AppliedPromotion{{ID: "FIVER", Target: PromotionProduct, Discount: "20.00", DiscountCode: true}}}},
+		},
+		{
+			name:       "free delivery after promotions",
+			code:       DiscountCode{Code: "SHIPFREE", Kind: DiscountFreeDelivery},
+			promotions: []Promotion{{ID: "ten-off", Target: PromotionProduct, Kind: PromotionFixed, Value: 10}},
+			products:   []Product{tv},
+			provider:   "DHL",
+			expected: []PricedProduct{{Name: "TV", ProductPrice: "10.00", DeliveryPrice: "0.00", TotalPrice: "10.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual,
+				OriginalProductPrice: "20.00", OriginalDeliveryPrice: "3.00", Promotions: []AppliedPromotion{{ID: "ten-off", Target: PromotionProduct, Discount: "10.00"}, {ID: "SHIPFREE", Target: PromotionDelivery, Discount: "3.00", DiscountCode: true}}}},
+		},
+		{
+			name:     "only products over the minimum",
+			code:     DiscountCode{Code: "BIG", Kind: DiscountPercent, Value: 50, MinSpend: 500},
+			products: []Product{tv, sofa},
+			provider: "DHL",
+			expected: []PricedProduct{
+				{Name: "TV", ProductPrice: "20.00", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual},
+				{Name: "Sofa", ProductPrice: "300.00", DeliveryPrice: "80.00", TotalPrice: "380.00", DeliveryService: "DHL", ChargeableWeight: 40, WeightBasis: WeightActual,
+					OriginalProductPrice: "600.00", OriginalDeliveryPrice: "80.00", Promotions: []AppliedPromotion{{ID: "BIG", Target: PromotionProduct, Discount: "300.00", DiscountCode: true}}},
+			},
+		},
+		{
+			name:     "fixed per product",
+			code:     DiscountCode{Code: "FIVER", Kind: DiscountFixed, Value: 5, MinSpend: 10},
+			products: []Product{tv, {Name: "Radio", Weight: 0.5, Price: 7.5}, sofa},
+			provider: "DHL",
+			expected: []PricedProduct{
+				{Name: "TV", ProductPrice: "15.00", DeliveryPrice: "3.00", TotalPrice: "18.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual,
+					OriginalProductPrice: "20.00", OriginalDeliveryPrice: "3.00", Promotions: []AppliedPromotion{{ID: "FIVER", Target: PromotionProduct, Discount: "5.00", DiscountCode: true}}},
+				{Name: "Radio", ProductPrice: "7.50", DeliveryPrice: "1.00", TotalPrice: "8.50", DeliveryService: "DHL", ChargeableWeight: 0.5, WeightBasis: WeightActual},
+				{Name: "Sofa", ProductPrice: "595.00", DeliveryPrice: "80.00", TotalPrice: "675.00", DeliveryService: "DHL", ChargeableWeight: 40, WeightBasis: WeightActual,
+					OriginalProductPrice: "600.00", OriginalDeliveryPrice: "80.00", Promotions: []AppliedPromotion{{ID: "FIVER", Target: PromotionProduct, Discount: "5.00", DiscountCode: true}}},
+			},
+		},
+		{name: "below minimum", code: DiscountCode{Code: "BIG", Kind: DiscountPercent, Value: 50, MinSpend: 500}, products: []Product{tv}, provider: "DHL", rejected: DiscountCodeBelowMinimum},
+		{name: "not started", code: DiscountCode{Code: "SOON", Kind: DiscountPercent, Value: 5, StartsAt: &tomorrow}, products: []Product{tv}, provider: "DHL", rejected: DiscountCodeNotStarted},
+		{name: "expired", code: DiscountCode{Code: "OLD", Kind: DiscountPercent, Value: 5, EndsAt: &yesterday}, products: []Product{tv}, provider: "DHL", rejected: DiscountCodeExpired},
+		{name: "exhausted", code: DiscountCode{Code: "USED", Kind: DiscountPercent, Value: 5, UsageLimit: 2, Redemptions: 2}, products: []Product{tv}, provider: "DHL", rejected: DiscountCodeExhausted},
+		{name: "wrong provider", code: DiscountCode{Code: "DPDONLY", Kind: DiscountPercent, Value: 5, Providers: []string{"DPD"}}, products: []Product{tv}, provider: "DHL", rejected: DiscountCodeWrongProvider},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetPromotions(tc.promotions); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err := SetDiscountCodes([]DiscountCode{tc.code}); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			priced, err := PriceProductsWithOptions(tc.products, tc.provider, PricingOptions{Code: " " + tc.code.Code + " "})
+			redemptions := DiscountCodes()[0].Redemptions
+			if tc.rejected != "" {
+				var rejected *ErrDiscountCodeRejected
+				if !errors.As(err, &rejected) || rejected.Reason != tc.rejected {
+					t.Fatalf("expected the code to be rejected as %s, got %v", tc.rejected, err)
+				}
+				if redemptions != tc.code.Redemptions {
+					t.Errorf("expected a rejected code not to be redeemed, got %d redemptions", redemptions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(priced, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, priced)
+			}
+			if redemptions != 0 {
+				t.Errorf("expected a quote not to redeem the code, got %d redemptions", redemptions)
+			}
+		})
+	}
+
+	var rejected *ErrDiscountCodeRejected
+	if _, err := PriceProductsWithOptions([]Product{tv}, "DHL", PricingOptions{Code: "NOPE"}); !errors.As(err, &rejected) || rejected.Reason != DiscountCodeUnknown {
+		t.Errorf("expected an unknown code to be rejected, got %v", err)
+	}
+	if _, err := PriceProductsWithOptions([]Product{tv}, "FEDEX", PricingOptions{Code: "NOPE"}); !errors.As(err, &rejected) {
+		t.Errorf("expected the code to be checked first, got %v", err)
+	}
+}
+
+// TestDiscountCodeRedemptionsConcurrent tests that concurrent checkouts cannot redeem a code past its usage limit
+func TestDiscountCodeRedemptionsConcurrent(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Cleanup(func() { SetDiscountCodes(nil) })
+	const limit, attempts = 10, 50
+	if err := SetDiscountCodes([]DiscountCode{{Code: "LIMITED", Kind: DiscountFixed, Value: 1, UsageLimit: limit}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted, exhausted := 0, 0
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := CheckOut([]ShipmentItem{{Product: Product{Name: "TV", Weight: 1.5, Price: 20}, Quantity: 1}}, "DHL", PricingOptions{Code: "limited"}, func() error { return nil })
+			var rejected *ErrDiscountCodeRejected
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case errors.As(err, &rejected) && rejected.Reason == DiscountCodeExhausted:
+				exhausted++
+			default:
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != limit || exhausted != attempts-limit {
+		t.Errorf("expected %d redemptions and %d rejections, got %d and %d", limit, attempts-limit, accepted, exhausted)
+	}
+	if redemptions := DiscountCodes()[0].Redemptions; redemptions != limit {
+		t.Errorf("expected %d redemptions recorded, got %d", limit, redemptions)
+	}
+}
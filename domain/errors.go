@@ -0,0 +1,217 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the domain. Callers should inspect them with errors.Is
+// rather than comparing error strings, as they are usually wrapped with extra
+// context such as the provider or product name.
+var (
+	// ErrUnknownProvider is returned when a delivery provider is not one we support.
+	ErrUnknownProvider = errors.New("unknown delivery provider")
+
+	// ErrProviderPriceMissing is returned when a supported provider has no delivery price configured.
+	ErrProviderPriceMissing = errors.New("delivery price not configured for provider")
+
+	// ErrProductNotFound is returned when a product cannot be found in the catalogue.
+	ErrProductNotFound = errors.New("product not found")
+
+	// ErrStorageUnavailable is returned by storage adapters when the catalogue cannot be read.
+	ErrStorageUnavailable = errors.New("product storage unavailable")
+
+	// ErrRedemptionNotSaved is returned by CheckOut when a discount code redemption cannot be saved.
+	ErrRedemptionNotSaved = errors.New("discount code redemption could not be saved")
+
+	// ErrNoPriceHistory is returned when prices are asked for at a time before any were recorded.
+	ErrNoPriceHistory = errors.New("no prices recorded at the requested time")
+)
+
+/*
+ErrInvalidProviderPrice is returned when a provider's delivery price is configured
+but cannot be used, for example because it is not a number or is negative.
+Use errors.As to get at the provider and the offending value.
+*/
+type ErrInvalidProviderPrice struct {
+	Provider string
+	Value    string
+	Err      error // underlying parse error, if any
+}
+
+func (e *ErrInvalidProviderPrice) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid delivery price %q for provider %s: %s", e.Value, e.Provider, e.Err.Error())
+	}
+	return fmt.Sprintf("invalid delivery price %q for provider %s", e.Value, e.Provider)
+}
+
+func (e *ErrInvalidProviderPrice) Unwrap() error {
+	return e.Err
+}
+
+/*
+ErrInvalidVolumetricDivisor is returned when a provider's volumetric divisor is configured but
+cannot be used, for example because it is not a number or is not positive.
+*/
+type ErrInvalidVolumetricDivisor struct {
+	Provider string
+	Value    string
+	Err      error // underlying parse error, if any
+}
+
+func (e *ErrInvalidVolumetricDivisor) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid volumetric divisor %q for provider %s: %s", e.Value, e.Provider, e.Err.Error())
+	}
+	return fmt.Sprintf("invalid volumetric divisor %q for provider %s", e.Value, e.Provider)
+}
+
+func (e *ErrInvalidVolumetricDivisor) Unwrap() error {
+	return e.Err
+}
+
+/*
+ErrInvalidProduct is returned when a product in the catalogue cannot be priced
+because its data is wrong, such as a negative weight or price.
+*/
+type ErrInvalidProduct struct {
+	Name   string
+	Reason string
+}
+
+func (e *ErrInvalidProduct) Error() string {
+	return fmt.Sprintf("invalid product %q: %s", e.Name, e.Reason)
+}
+
+// ErrInvalidShipment is returned when a shipment cannot be priced, such as one with no items.
+type ErrInvalidShipment struct {
+	Reason string
+}
+
+func (e *ErrInvalidShipment) Error() string {
+	return "invalid shipment: " + e.Reason
+}
+
+/*
+ErrInvalidScheduledChange is returned when a scheduled pricing change cannot be applied,
+such as one naming an unknown provider or setting a negative rate.
+*/
+type ErrInvalidScheduledChange struct {
+	ID     string
+	Reason string
+}
+
+func (e *ErrInvalidScheduledChange) Error() string {
+	return fmt.Sprintf("invalid scheduled change %q: %s", e.ID, e.Reason)
+}
+
+/*
+ErrInvalidPromotion is returned when a promotion cannot be applied, such as one taking more
+than 100% off or ending before it starts.
+*/
+type ErrInvalidPromotion struct {
+	ID     string
+	Reason string
+}
+
+func (e *ErrInvalidPromotion) Error() string {
+	return fmt.Sprintf("invalid promotion %q: %s", e.ID, e.Reason)
+}
+
+/*
+ErrInvalidDiscountCode is returned when a discount code cannot be added to the store, such as one
+taking more than 100% off or ending before it starts.
+*/
+type ErrInvalidDiscountCode struct {
+	Code   string
+	Reason string
+}
+
+func (e *ErrInvalidDiscountCode) Error() string {
+	return fmt.Sprintf("invalid discount code %q: %s", e.Code, e.Reason)
+}
+
+/*
+ErrDiscountCodeRejected is returned when a customer's discount code cannot be applied to a price.
+Reason says why, so the customer can be told precisely; Detail adds context such as the minimum spend.
+*/
+type ErrDiscountCodeRejected struct {
+	Code   string
+	Reason DiscountCodeReason
+	Detail string
+}
+
+func (e *ErrDiscountCodeRejected) Error() string {
+	message := fmt.Sprintf("discount code %q rejected: %s", e.Code, e.Reason)
+	if e.Detail != "" {
+		message += " (" + e.Detail + ")"
+	}
+	return message
+}
+
+/*
+ErrInvalidZoneTable is returned when a carrier's zone table cannot be used, such as one with a
+negative rate or a postcode prefix that is not a postcode area or district.
+*/
+type ErrInvalidZoneTable struct {
+	Provider string
+	Reason   string
+}
+
+func (e *ErrInvalidZoneTable) Error() string {
+	return fmt.Sprintf("invalid zone table for provider %q: %s", e.Provider, e.Reason)
+}
+
+// ErrInvalidDestination is returned when a delivery destination is not a valid country and postcode.
+type ErrInvalidDestination struct {
+	Country  string
+	Postcode string
+	Reason   string
+}
+
+func (e *ErrInvalidDestination) Error() string {
+	return fmt.Sprintf("invalid destination %q %q: %s", e.Country, e.Postcode, e.Reason)
+}
+
+/*
+ErrZoneNotServed is returned when a carrier does not deliver to the zone a destination is in,
+because its zone table has no rate for it.
+*/
+type ErrZoneNotServed struct {
+	Provider string
+	Zone     Zone
+	Country  string // the destination's country, if known
+}
+
+func (e *ErrZoneNotServed) Error() string {
+	return fmt.Sprintf("provider %s does not deliver to zone %s", e.Provider, e.Zone)
+}
+
+/*
+ErrInvalidServiceLevel is returned when a carrier's service level cannot be used, such as a
+next-day service without a rate or a cut-off that is not a time of day.
+*/
+type ErrInvalidServiceLevel struct {
+	Provider string
+	Service  Service
+	Reason   string
+}
+
+func (e *ErrInvalidServiceLevel) Error() string {
+	return fmt.Sprintf("invalid %s service level for provider %q: %s", e.Service, e.Provider, e.Reason)
+}
+
+// ErrInvalidBankHoliday is returned when a bank holiday is not a date such as 2026-12-25.
+type ErrInvalidBankHoliday struct {
+	Date string
+	Err  error // underlying parse error
+}
+
+func (e *ErrInvalidBankHoliday) Error() string {
+	return fmt.Sprintf("invalid bank holiday %q: %s", e.Date, e.Err.Error())
+}
+
+func (e *ErrInvalidBankHoliday) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,428 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Validity is the interval a recorded price was in force for. ValidTo is nil while it still is.
+type Validity struct {
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+// covers reports whether the interval includes the given time. ValidFrom is inclusive and ValidTo exclusive.
+func (v Validity) covers(at time.Time) bool {
+	return !at.Before(v.ValidFrom) && (v.ValidTo == nil || at.Before(*v.ValidTo))
+}
+
+// open reports whether the interval has not ended.
+func (v Validity) open() bool {
+	return v.ValidTo == nil
+}
+
+// ProductVersion is a product's weight and price as they were for an interval.
+type ProductVersion struct {
+	Product
+	Validity
+}
+
+// RateVersion is a provider's price per unit weight as it was for an interval.
+type RateVersion struct {
+	Provider string  `json:"provider"`
+	Rate     float64 `json:"rate"`
+	Validity
+}
+
+// DivisorVersion is a provider's volumetric divisor as it was for an interval.
+type DivisorVersion struct {
+	Provider string  `json:"provider"`
+	Divisor  float64 `json:"divisor"`
+	Validity
+}
+
+// PromotionVersion is a promotion as it was configured for an interval, at its place in the order promotions are applied.
+type PromotionVersion struct {
+	Promotion
+	Position int `json:"position"`
+	Validity
+}
+
+// ProviderVersion is the default delivery provider as it was for an interval.
+type ProviderVersion struct {
+	Provider string `json:"provider"`
+	Validity
+}
+
+/*
+PriceHistory is every product price, carrier rate, volumetric divisor, promotion and default
+provider that has been in force, each with the interval it was valid for. It is built up by
+RecordPrices as prices are seen and lets a catalogue be priced again as it was at an earlier time.
+*/
+type PriceHistory struct {
+	Products   []ProductVersion   `json:"products"`
+	Rates      []RateVersion      `json:"rates"`
+	Divisors   []DivisorVersion   `json:"divisors"` // providers without a divisor charged on actual weight
+	Promotions []PromotionVersion `json:"promotions"`
+	Providers  []ProviderVersion  `json:"providers"`
+}
+
+// HistoricalPricing is the catalogue and carrier configuration in force at a moment.
+type HistoricalPricing struct {
+	At              time.Time
+	Products        []Product
+	Rates           map[string]float64 // providers without a usable rate are left out
+	Divisors        map[string]float64 // providers charging on actual weight only are left out
+	Promotions      []Promotion        // configured at the time, in the order they are applied
+	DefaultProvider string
+}
+
+var (
+	historyMu sync.RWMutex
+	history   PriceHistory
+)
+
+// SetPriceHistory replaces the price history, such as with one loaded from storage.
+func SetPriceHistory(h PriceHistory) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	history = clonePriceHistory(h)
+}
+
+// History returns a copy of the price history.
+func History() PriceHistory {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	return clonePriceHistory(history)
+}
+
+// RecordedChange is a product, rate, divisor, promotion or default provider that RecordPrices found had changed.
+type RecordedChange struct {
+	Entity string // "product:<name>", "rate:<provider>", "divisor:<provider>", "promotion:<id>" or "default_provider"
+	Before any    // the Product, rate, divisor, Promotion or provider before, nil if it is new
+	After  any    // the Product, rate, divisor, Promotion or provider after, nil if it was removed
+}
+
+/*
+RecordPrices records the catalogue, the providers' rates and volumetric divisors, the promotions
+and the default provider in force at the given time. Anything that changed since the last record
+has its old version closed at that time and a new one opened. It returns what changed, so callers
+know to save and audit the history.
+*/
+func RecordPrices(at time.Time, products []Product) []RecordedChange {
+	at = at.UTC()
+	rates := make(map[string]float64, len(allowedProviders))
+	divisors := make(map[string]float64)
+	for _, provider := range allowedProviders {
+		if rate, err := ProviderRateAt(provider, at); err == nil {
+			rates[provider] = rate
+		}
+		if divisor, err := VolumetricDivisor(provider); err == nil && divisor > 0 {
+			divisors[provider] = divisor
+		}
+	}
+	defaultProvider := DefaultProviderAt(at)
+	configured := Promotions()
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	// intervals must not end before they start, so a late record is treated as made at the latest
+	if latest := history.latest(); at.Before(latest) {
+		at = latest
+	}
+
+	var changes []RecordedChange
+	index := make(map[string]int)
+	change := func(entity string, before any, after any) {
+		if i, ok := index[entity]; ok {
+			changes[i].After = after
+			return
+		}
+		index[entity] = len(changes)
+		changes = append(changes, RecordedChange{Entity: entity, Before: before, After: after})
+	}
+
+	current := make(map[string]Product, len(products))
+	for _, product := range products {
+		current[product.Name] = product
+	}
+	for i := range history.Products {
+		version := &history.Products[i]
+		if product, ok := current[version.Name]; version.open() && (!ok || product != version.Product) {
+			version.ValidTo = &at
+			change("product:"+version.Name, version.Product, nil)
+		}
+	}
+	for _, product := range products {
+		if !slices.ContainsFunc(history.Products, func(v ProductVersion) bool { return v.open() && v.Product == product }) {
+			history.Products = append(history.Products, ProductVersion{Product: product, Validity: Validity{ValidFrom: at}})
+			change("product:"+product.Name, nil, product)
+		}
+	}
+
+	for i := range history.Rates {
+		version := &history.Rates[i]
+		if rate, ok := rates[version.Provider]; version.open() && (!ok || rate != version.Rate) {
+			version.ValidTo = &at
+			change("rate:"+version.Provider, version.Rate, nil)
+		}
+	}
+	for _, provider := range slices.Sorted(maps.Keys(rates)) {
+		rate := rates[provider]
+		if !slices.ContainsFunc(history.Rates, func(v RateVersion) bool { return v.open() && v.Provider == provider && v.Rate == rate }) {
+			history.Rates = append(history.Rates, RateVersion{Provider: provider, Rate: rate, Validity: Validity{ValidFrom: at}})
+			change("rate:"+provider, nil, rate)
+		}
+	}
+
+	for i := range history.Divisors {
+		version := &history.Divisors[i]
+		if divisor, ok := divisors[version.Provider]; version.open() && (!ok || divisor != version.Divisor) {
+			version.ValidTo = &at
+			change("divisor:"+version.Provider, version.Divisor, nil)
+		}
+	}
+	for _, provider := range slices.Sorted(maps.Keys(divisors)) {
+		divisor := divisors[provider]
+		if !slices.ContainsFunc(history.Divisors, func(v DivisorVersion) bool { return v.open() && v.Provider == provider && v.Divisor == divisor }) {
+			history.Divisors = append(history.Divisors, DivisorVersion{Provider: provider, Divisor: divisor, Validity: Validity{ValidFrom: at}})
+			change("divisor:"+provider, nil, divisor)
+		}
+	}
+
+	for i := range history.Promotions {
+		version := &history.Promotions[i]
+		if p := version.Position; version.open() && (p >= len(configured) || !configured[p].equal(version.Promotion)) {
+			version.ValidTo = &at
+			change("promotion:"+version.ID, version.Promotion, nil)
+		}
+	}
+	for position, promotion := range configured {
+		if !slices.ContainsFunc(history.Promotions, func(v PromotionVersion) bool {
+			return v.open() && v.Position == position && v.Promotion.equal(promotion)
+		}) {
+			history.Promotions = append(history.Promotions, PromotionVersion{Promotion: promotion, Position: position, Validity: Validity{ValidFrom: at}})
+			change("promotion:"+promotion.ID, nil, promotion)
+		}
+	}
+
+	i := slices.IndexFunc(history.Providers, func(v ProviderVersion) bool { return v.open() })
+	if i >= 0 && history.Providers[i].Provider != defaultProvider {
+		history.Providers[i].ValidTo = &at
+		change("default_provider", history.Providers[i].Provider, nil)
+	}
+	if (i < 0 || history.Providers[i].ValidTo != nil) && defaultProvider != "" {
+		history.Providers = append(history.Providers, ProviderVersion{Provider: defaultProvider, Validity: Validity{ValidFrom: at}})
+		change("default_provider", nil, defaultProvider)
+	}
+	return changes
+}
+
+// latest returns the latest time an interval starts or ends, or the zero time for an empty history.
+func (h PriceHistory) latest() time.Time {
+	var latest time.Time
+	later := func(v Validity) {
+		if v.ValidFrom.After(latest) {
+			latest = v.ValidFrom
+		}
+		if v.ValidTo != nil && v.ValidTo.After(latest) {
+			latest = *v.ValidTo
+		}
+	}
+	for _, version := range h.Products {
+		later(version.Validity)
+	}
+	for _, version := range h.Rates {
+		later(version.Validity)
+	}
+	for _, version := range h.Divisors {
+		later(version.Validity)
+	}
+	for _, version := range h.Promotions {
+		later(version.Validity)
+	}
+	for _, version := range h.Providers {
+		later(version.Validity)
+	}
+	return latest
+}
+
+/*
+PricingAt returns the catalogue and carrier configuration in force at the given time, from the
+price history. It returns an error wrapping ErrNoPriceHistory if nothing was recorded by then.
+*/
+func PricingAt(at time.Time) (HistoricalPricing, error) {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+
+	recorded := false
+	pricing := HistoricalPricing{At: at.UTC(), Rates: make(map[string]float64), Divisors: make(map[string]float64)}
+	for _, version := range history.Products {
+		recorded = recorded || !at.Before(version.ValidFrom)
+		if version.covers(at) {
+			pricing.Products = append(pricing.Products, version.Product)
+		}
+	}
+	for _, version := range history.Rates {
+		recorded = recorded || !at.Before(version.ValidFrom)
+		if version.covers(at) {
+			pricing.Rates[version.Provider] = version.Rate
+		}
+	}
+	for _, version := range history.Divisors {
+		if version.covers(at) {
+			pricing.Divisors[version.Provider] = version.Divisor
+		}
+	}
+	var promotions []PromotionVersion
+	for _, version := range history.Promotions {
+		if version.covers(at) {
+			promotions = append(promotions, version)
+		}
+	}
+	slices.SortFunc(promotions, func(a, b PromotionVersion) int { return a.Position - b.Position })
+	for _, version := range promotions {
+		pricing.Promotions = append(pricing.Promotions, version.Promotion)
+	}
+	for _, version := range history.Providers {
+		recorded = recorded || !at.Before(version.ValidFrom)
+		if version.covers(at) {
+			pricing.DefaultProvider = version.Provider
+		}
+	}
+	if !recorded {
+		return HistoricalPricing{}, fmt.Errorf("%w: %s", ErrNoPriceHistory, at.UTC().Format(time.RFC3339))
+	}
+	return pricing, nil
+}
+
+/*
+Price prices the historical catalogue with provider's rate and volumetric divisor at the time,
+returning the same errors as PriceProducts. The promotions running at the time are applied, as
+they were configured then. Products have no delivery options.
+*/
+func (p HistoricalPricing) Price(provider string) ([]PricedProduct, error) {
+	priced, err := priceProducts(p.Products, provider, p.source(), p.At, nil, nil)
+	// service levels are not kept in the history, and estimates for orders in the past mean nothing
+	for i := range priced {
+		priced[i].DeliveryOptions = nil
+	}
+	return priced, err
+}
+
+// Version returns the pricing version of the historical catalogue priced with provider. See PricingVersion.
+func (p HistoricalPricing) Version(provider string) string {
+	rate, err := p.rate(provider)
+	if err != nil {
+		return ""
+	}
+	divisor, _ := p.divisor(provider)
+	return PricingVersion(p.Products, provider, rate, divisor, p.promotionsAt(p.At), "")
+}
+
+// source returns the configuration at the time, to price with.
+func (p HistoricalPricing) source() pricingSource {
+	return pricingSource{rate: p.rate, divisor: p.divisor, promotions: p.promotionsAt}
+}
+
+// promotionsAt returns the promotions configured at the time that run at the given time, in the order they are applied.
+func (p HistoricalPricing) promotionsAt(at time.Time) []Promotion {
+	var active []Promotion
+	for _, promotion := range p.Promotions {
+		if promotion.activeAt(at) {
+			active = append(active, promotion)
+		}
+	}
+	return active
+}
+
+// rate returns provider's rate at the time, or an error wrapping ErrProviderPriceMissing if it had none.
+func (p HistoricalPricing) rate(provider string) (float64, error) {
+	if _, ok := providerPriceEnv[provider]; !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	rate, ok := p.Rates[provider]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s (no rate in force at %s)", ErrProviderPriceMissing, provider, p.At.Format(time.RFC3339))
+	}
+	return rate, nil
+}
+
+// divisor returns provider's volumetric divisor at the time, or 0 if it charged on actual weight.
+func (p HistoricalPricing) divisor(provider string) (float64, error) {
+	return p.Divisors[provider], nil
+}
+
+/*
+PricingVersion returns a short fingerprint of everything a priced catalogue depends on: the
+products, the provider, its rate, its volumetric divisor, 0 if it has none, the IDs of the active
+promotions that apply to the provider and the delivery zone if the catalogue was priced for a
+destination. Discount codes are entered per request and are not part of it. A catalogue priced now and one priced again from the
+history have the same version if they were priced from the same configuration.
+*/
+func PricingVersion(products []Product, provider string, rate, divisor float64, active []Promotion, zone Zone) string {
+	key := CatalogueVersion(products) + "|" + provider + "|" + strconv.FormatFloat(rate, 'g', -1, 64)
+	if zone != "" {
+		key += "|" + string(zone)
+	}
+	if divisor > 0 {
+		key += "|" + strconv.FormatFloat(divisor, 'g', -1, 64)
+	}
+	var ids []string
+	for _, promotion := range active {
+		if promotion.appliesTo(provider) {
+			ids = append(ids, promotion.ID)
+		}
+	}
+	if len(ids) > 0 {
+		key += "|promotions:" + strings.Join(ids, ",")
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// clonePriceHistory returns a copy of h that shares no slices or interval ends with it.
+func clonePriceHistory(h PriceHistory) PriceHistory {
+	clone := PriceHistory{
+		Products:   slices.Clone(h.Products),
+		Rates:      slices.Clone(h.Rates),
+		Divisors:   slices.Clone(h.Divisors),
+		Promotions: slices.Clone(h.Promotions),
+		Providers:  slices.Clone(h.Providers),
+	}
+	for i := range clone.Products {
+		clone.Products[i].ValidTo = cloneTime(clone.Products[i].ValidTo)
+	}
+	for i := range clone.Rates {
+		clone.Rates[i].ValidTo = cloneTime(clone.Rates[i].ValidTo)
+	}
+	for i := range clone.Divisors {
+		clone.Divisors[i].ValidTo = cloneTime(clone.Divisors[i].ValidTo)
+	}
+	for i := range clone.Promotions {
+		version := &clone.Promotions[i]
+		version.Providers = slices.Clone(version.Providers)
+		version.StartsAt, version.EndsAt, version.ValidTo = cloneTime(version.StartsAt), cloneTime(version.EndsAt), cloneTime(version.ValidTo)
+	}
+	for i := range clone.Providers {
+		clone.Providers[i].ValidTo = cloneTime(clone.Providers[i].ValidTo)
+	}
+	return clone
+}
+
+// cloneTime returns a pointer to a copy of *t, or nil.
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
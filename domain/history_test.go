@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRecordPrices tests recording prices with validity intervals and pricing from them again
+func TestRecordPrices(t *testing.T) {
+	t.Setenv("DELIVERY_PROVIDER", "DHL")
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("UPS_DELIVERY_PRICE", "")
+	SetPriceHistory(PriceHistory{})
+	t.Cleanup(func() { SetPriceHistory(PriceHistory{}) })
+
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+	lamp := Product{Name: "Lamp", Weight: 1, Price: 12}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if changes := RecordPrices(start, []Product{tv, lamp}); len(changes) != 4 {
+		t.Fatalf("expected the first record to add two products, a rate and the default provider, got %+v", changes)
+	}
+	if changes := RecordPrices(start.Add(time.Hour), []Product{tv, lamp}); len(changes) != 0 {
+		t.Errorf("expected the same prices not to be a change, got %+v", changes)
+	}
+	t.Setenv("DHL_DELIVERY_PRICE", "3")
+	t.Setenv("DELIVERY_PROVIDER", "UPS")
+	t.Setenv("UPS_DELIVERY_PRICE", "1")
+	cheaperTV := Product{Name: "TV", Weight: 1.5, Price: 18}
+	expected := []RecordedChange{
+		{Entity: "product:TV", Before: tv, After: cheaperTV},
+		{Entity: "product:Lamp", Before: lamp},
+		{Entity: "rate:DHL", Before: 2.0, After: 3.0},
+		{Entity: "rate:UPS", After: 1.0},
+		{Entity: "default_provider", Before: "DHL", After: "UPS"},
+	}
+	if changes := RecordPrices(start.Add(2*time.Hour), []Product{cheaperTV}); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+	// a record made late is treated as made at the latest record, so no interval ends before it starts
+	RecordPrices(start, []Product{cheaperTV, lamp})
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		products []Product
+		provider string
+		rates    map[string]float64
+	}{
+		{name: "first prices", at: start.Add(90 * time.Minute), products: []Product{tv, lamp}, provider: "DHL", rates: map[string]float64{"DHL": 2}},
+		{name: "second prices", at: start.Add(2 * time.Hour), products: []Product{cheaperTV, lamp}, provider: "UPS", rates: map[string]float64{"DHL": 3, "UPS": 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pricing, err := PricingAt(tc.at)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(pricing.Products) != len(tc.products) || pricing.DefaultProvider != tc.provider || len(pricing.Rates) != len(tc.rates) {
+				t.Fatalf("unexpected pricing %+v", pricing)
+			}
+			for i, product := range tc.products {
+				if pricing.Products[i] != product {
+					t.Errorf("expected %+v, got %+v", product, pricing.Products[i])
+				}
+			}
+			for provider, rate := range tc.rates {
+				if pricing.Rates[provider] != rate {
+					t.Errorf("expected %s at %v, got %v", provider, rate, pricing.Rates[provider])
+				}
+			}
+		})
+	}
+
+	if _, err := PricingAt(start.Add(-time.Second)); !errors.Is(err, ErrNoPriceHistory) {
+		t.Errorf("expected ErrNoPriceHistory before the first record, got %v", err)
+	}
+	for _, version := range History().Products {
+		if version.ValidTo != nil && version.ValidTo.Before(version.ValidFrom) {
+			t.Errorf("interval ends before it starts: %+v", version)
+		}
+	}
+}
+
+// TestHistoricalPricing tests pricing from a historical configuration
+func TestHistoricalPricing(t *testing.T) {
+	products := []Product{{Name: "TV", Weight: 1.5, Price: 18}}
+	pricing := HistoricalPricing{At: time.Now(), Products: products, Rates: map[string]float64{"DHL": 1}, DefaultProvider: "DHL"}
+
+	priced, err := pricing.Price("DHL")
+	if err != nil || len(priced) != 1 || priced[0].TotalPrice != "19.50" {
+		t.Fatalf("unexpected prices %+v, error %v", priced, err)
+	}
+	if pricing.Version("DHL") != PricingVersion(products, "DHL", 1, 0, nil, "") {
+		t.Error("expected the version to match one worked out from the same configuration")
+	}
+	if pricing.Version("DHL") == PricingVersion(products, "DHL", 2, 0, nil, "") {
+		t.Error("expected a different rate to change the version")
+	}
+	if _, err := pricing.Price("UPS"); !errors.Is(err, ErrProviderPriceMissing) {
+		t.Errorf("expected ErrProviderPriceMissing for a provider without a rate, got %v", err)
+	}
+	if _, err := pricing.Price("FEDEX"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+// TestHistoricalDivisor tests pricing from the history with the volumetric divisor in force at the time
+func TestHistoricalDivisor(t *testing.T) {
+	t.Setenv("DELIVERY_PROVIDER", "DHL")
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "5000")
+	SetPriceHistory(PriceHistory{})
+	t.Cleanup(func() { SetPriceHistory(PriceHistory{}) })
+
+	pillow := Product{Name: "Pillow", Weight: 1, Price: 10, Length: 50, Width: 40, Height: 30}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	RecordPrices(start, []Product{pillow})
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "4000")
+	expected := []RecordedChange{{Entity: "divisor:DHL", Before: 5000.0, After: 4000.0}}
+	if changes := RecordPrices(start.Add(time.Hour), []Product{pillow}); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+
+	tests := []struct {
+		name          string
+		at            time.Time
+		expectedTotal string
+		divisor       float64
+	}{
+		{name: "before the change", at: start, expectedTotal: "34.00", divisor: 5000}, // 50 × 40 × 30 / 5000 = 12kg at 2
+		{name: "after the change", at: start.Add(time.Hour), expectedTotal: "40.00", divisor: 4000},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pricing, err := PricingAt(tc.at)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			priced, err := pricing.Price("DHL")
+			if err != nil || priced[0].TotalPrice != tc.expectedTotal || priced[0].WeightBasis != WeightVolumetric {
+				t.Fatalf("unexpected prices %+v, error %v", priced, err)
+			}
+			if pricing.Version("DHL") != PricingVersion([]Product{pillow}, "DHL", 2, tc.divisor, nil, "") {
+				t.Error("expected the version to include the divisor in force at the time")
+			}
+		})
+	}
+}
+
+// TestHistoricalPromotions tests pricing from the history with the promotions configured at the time
+func TestHistoricalPromotions(t *testing.T) {
+	t.Setenv("DELIVERY_PROVIDER", "DHL")
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	SetPriceHistory(PriceHistory{})
+	t.Cleanup(func() {
+		SetPriceHistory(PriceHistory{})
+		SetPromotions(nil)
+	})
+
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+	halfDelivery := Promotion{ID: "half-delivery", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 50}
+	tenOff := Promotion{ID: "ten-off", Target: PromotionProduct, Kind: PromotionFixed, Value: 10}
+	dpdOnly := Promotion{ID: "dpd-only", Target: PromotionProduct, Kind: PromotionFixed, Value: 1, Providers: []string{"DPD"}}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	SetPromotions([]Promotion{halfDelivery, dpdOnly})
+	RecordPrices(start, []Product{tv})
+	SetPromotions([]Promotion{tenOff})
+	expected := []RecordedChange{
+		{Entity: "promotion:half-delivery", Before: halfDelivery},
+		{Entity: "promotion:dpd-only", Before: dpdOnly},
+		{Entity: "promotion:ten-off", After: tenOff},
+	}
+	if changes := RecordPrices(start.Add(time.Hour), []Product{tv}); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+
+	tests := []struct {
+		name          string
+		at            time.Time
+		expectedTotal string
+		active        []Promotion
+	}{
+		{name: "before the change", at: start, expectedTotal: "21.50", active: []Promotion{halfDelivery}},
+		{name: "after the change", at: start.Add(time.Hour), expectedTotal: "13.00", active: []Promotion{tenOff}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pricing, err := PricingAt(tc.at)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			priced, err := pricing.Price("DHL")
+			if err != nil || priced[0].TotalPrice != tc.expectedTotal {
+				t.Fatalf("unexpected prices %+v, error %v", priced, err)
+			}
+			if pricing.Version("DHL") != PricingVersion([]Product{tv}, "DHL", 2, 0, tc.active, "") {
+				t.Error("expected the version to include the promotions running for the provider at the time")
+			}
+		})
+	}
+
+	if PricingVersion([]Product{tv}, "DHL", 2, 0, []Promotion{halfDelivery}, "") == PricingVersion([]Product{tv}, "DHL", 2, 0, nil, "") {
+		t.Error("expected a running promotion to change the version")
+	}
+	if PricingVersion([]Product{tv}, "DHL", 2, 0, []Promotion{dpdOnly}, "") != PricingVersion([]Product{tv}, "DHL", 2, 0, nil, "") {
+		t.Error("expected a promotion for another provider not to change the version")
+	}
+}
@@ -2,60 +2,216 @@ package domain
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
 )
 
 var (
-	PriceProductsFunc = PriceProducts // Function to price products, can be mocked in tests
+	PriceProductsFunc            = PriceProducts            // Function to price products, can be mocked in tests
+	PriceProductsWithOptionsFunc = PriceProductsWithOptions // Function to price products with options, can be mocked in tests
 )
 
+// allowedProviders lists the supported delivery providers.
+var allowedProviders = []string{"DHL", "UPS", "AMAZON", "ROYALMAIL", "DPD", "YODEL"}
+
+// Providers returns the supported delivery providers.
+func Providers() []string {
+	return append([]string(nil), allowedProviders...)
+}
+
 /*
 PriceProducts calculates the delivery price and total price for a list of products
 based on their weight and the delivery provider specified in the environment.
+The promotions running now are applied to each product in turn.
 It returns a slice of PricedProduct containing the pricing details for each product,
-or an error if the delivery provider is not set in the environment variables.
+or an error if the products cannot be priced. See errors.go for the errors that may be returned.
 */
 func PriceProducts(products []Product, provider string) ([]PricedProduct, error) {
-	// provider := os.Getenv("DELIVERY_PROVIDER")
-	// Check if the delivery provider is set in the environment variables
-	allowedProviders := []string{"DHL", "UPS", "AMAZON", "ROYALMAIL", "DPD", "YODEL"}
-	if !contains(allowedProviders, provider) {
-		// Log an error if the delivery provider is not set
-		logs.Logs(3, "DELIVERY_PROVIDER environment variable not set", provider)
-		return nil, fmt.Errorf("delivery provider not set")
+	return priceProducts(products, provider, currentPricing, time.Now(), nil, nil)
+}
+
+// PricingOptions are the optional parts of a pricing request. The zero value prices as PriceProducts does.
+type PricingOptions struct {
+	Code        string       // discount code to apply, if not empty; quotes never redeem it, see CheckOut
+	Destination *Destination // where the products are delivered, to charge the zone's rate; nil for the flat rate
+}
+
+/*
+PriceProductsWithOptions prices products like PriceProducts, then applies the discount code without
+redeeming it if one is given (see priceProductsWithCode) and charges delivery at the rate for the
+destination's zone if one is given. Besides the errors PriceProducts returns, it returns an
+*ErrInvalidDestination for a malformed destination and an *ErrZoneNotServed if the provider does
+not deliver there. Without options it calls PriceProductsFunc, so every API can price through it.
+*/
+func PriceProductsWithOptions(products []Product, provider string, options PricingOptions) ([]PricedProduct, error) {
+	if options == (PricingOptions{}) {
+		return PriceProductsFunc(products, provider)
+	}
+	if options.Code != "" {
+		return priceProductsWithCode(products, provider, options.Code, options.Destination)
+	}
+	return priceProducts(products, provider, currentPricing, time.Now(), nil, options.Destination)
+}
+
+/*
+pricingSource is where pricing looks up the configuration it depends on, either the configuration
+in force now or one from the price history.
+*/
+type pricingSource struct {
+	rate       func(provider string) (float64, error) // price per unit weight
+	divisor    func(provider string) (float64, error) // volumetric divisor, 0 for actual weight only
+	promotions func(at time.Time) []Promotion         // promotions running at the time, in the order they are applied
+}
+
+// currentPricing prices with the configuration in force now.
+var currentPricing = pricingSource{rate: ProviderRate, divisor: VolumetricDivisor, promotions: PromotionsAt}
+
+/*
+priceProducts prices products like PriceProducts, looking up the provider's rate, its volumetric
+divisor and the promotions running at the given time in source. If code is not nil it is
+applied after the promotions, and an *ErrDiscountCodeRejected is returned if it cannot be used
+or no product meets its minimum spend. The caller must hold discountsMu to use a code. If
+destination is not nil, delivery outside the mainland is charged at the zone's rate instead.
+Each product lists the provider's service levels as delivery options, estimated from the given time.
+*/
+func priceProducts(products []Product, provider string, source pricingSource, at time.Time, code *DiscountCode, destination *Destination) ([]PricedProduct, error) {
+	pricing, err := newOrderPricing(provider, source, at, code, destination)
+	if err != nil {
+		return nil, err
 	}
 
 	var result []PricedProduct
+	codeApplied := false
 
 	for _, product := range products {
-		deliveryPrice, err := calculateDeliveryPrice(product.Weight, provider)
+		err := validateProduct(product)
 		if err != nil {
-			logs.Logs(3, fmt.Sprintf("failed to calculate delivery price for product %s: %s", product.Name, err.Error()), provider)
+			logs.Logs(3, "failed to validate product: "+err.Error(), provider)
 			return nil, err
 		}
 
-		// convert and calculate prices
-		productPrincing := roundToTwoDecimalPlaces(product.Price)
-		deliveryPricing := roundToTwoDecimalPlaces(deliveryPrice)
-		total := productPrincing + deliveryPricing
-
-		finalPrice := PricedProduct{
-			Name:            product.Name,
-			ProductPrice:    fmt.Sprintf("%.2f", productPrincing),
-			DeliveryPrice:   fmt.Sprintf("%.2f", deliveryPricing),
-			TotalPrice:      fmt.Sprintf("%.2f", total),
-			DeliveryService: provider,
+		weight, basis := chargeableWeight(product, pricing.divisor)
+		finalPrice, applied, err := pricing.price(product.Price, weight, basis)
+		if err != nil {
+			logs.Logs(3, fmt.Sprintf("failed to calculate delivery price for product %s: %s", product.Name, err.Error()), provider)
+			return nil, err
 		}
+		finalPrice.Name = product.Name
+		codeApplied = codeApplied || applied
 		result = append(result, finalPrice)
-		logs.Logs(1, "Product priced successfully: "+product.Name+" with total price: "+fmt.Sprintf("%.2f", total), provider)
+		logs.Logs(1, "Product priced successfully: "+product.Name+" with total price: "+finalPrice.TotalPrice, provider)
 	}
 
+	if code != nil && !codeApplied {
+		return nil, code.belowMinimum()
+	}
 	return result, nil
 }
 
+// orderPricing is what pricing an order with a provider at a given time depends on, worked out once for all its products.
+type orderPricing struct {
+	provider string
+	rate     func(provider string) (float64, error)
+	at       time.Time
+	code     *DiscountCode // nil if no code is used
+	zone     Zone          // empty unless priced for a destination
+	divisor  float64       // the provider's volumetric divisor, 0 for actual weight only
+	active   []Promotion
+}
+
+/*
+newOrderPricing checks the provider and discount code, and looks up the destination's zone and
+the provider's volumetric divisor, returning the same errors as priceProducts.
+*/
+func newOrderPricing(provider string, source pricingSource, at time.Time, code *DiscountCode, destination *Destination) (orderPricing, error) {
+	// Check the delivery provider is one we can price with
+	if !contains(allowedProviders, provider) {
+		// Log the provider asked for, so a typo in DELIVERY_PROVIDER or a request is easy to spot
+		if provider == "" {
+			logs.Logs(3, "delivery provider not set", provider)
+		} else {
+			logs.Logs(3, fmt.Sprintf("unknown delivery provider %q", provider), provider)
+		}
+		return orderPricing{}, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+
+	if code != nil {
+		if err := code.check(provider, at); err != nil {
+			return orderPricing{}, err
+		}
+	}
+
+	rate := source.rate
+	var zone Zone
+	if destination != nil {
+		var err error
+		zone, err = DeliveryZone(provider, *destination)
+		if err != nil {
+			logs.Logs(2, "failed to find delivery zone: "+err.Error(), provider)
+			return orderPricing{}, err
+		}
+		if zone != ZoneMainland {
+			rate = func(provider string) (float64, error) { return ProviderRateIn(provider, zone) }
+		}
+	}
+
+	divisor, err := source.divisor(provider)
+	if err != nil {
+		logs.Logs(3, "failed to read volumetric divisor: "+err.Error(), provider)
+		return orderPricing{}, err
+	}
+	return orderPricing{provider: provider, rate: rate, at: at, code: code, zone: zone, divisor: divisor, active: source.promotions(at)}, nil
+}
+
+/*
+price prices one order with the given product price and chargeable weight: delivery is charged
+on the weight, then the promotions and the discount code, if any, are applied. It returns the
+priced order without a name and whether the code applied, which it does not if the product price
+after promotions is under the code's minimum spend.
+*/
+func (p orderPricing) price(productPrice, weight float64, basis WeightBasis) (PricedProduct, bool, error) {
+	rate, err := p.rate(p.provider)
+	if err != nil {
+		return PricedProduct{}, false, err
+	}
+	deliveryPrice := weight * rate
+
+	// convert and calculate prices
+	productPrincing := roundToTwoDecimalPlaces(productPrice)
+	deliveryPricing := roundToTwoDecimalPlaces(deliveryPrice)
+	discountedProduct, discountedDelivery, applied := applyPromotions(p.active, p.provider, productPrincing, deliveryPricing)
+	codeApplied := false
+	if p.code != nil {
+		var discount AppliedPromotion
+		if discountedProduct, discountedDelivery, discount, codeApplied = p.code.apply(discountedProduct, discountedDelivery); codeApplied {
+			applied = append(applied, discount)
+		}
+	}
+	total := discountedProduct + discountedDelivery
+
+	finalPrice := PricedProduct{
+		ProductPrice:     fmt.Sprintf("%.2f", discountedProduct),
+		DeliveryPrice:    fmt.Sprintf("%.2f", discountedDelivery),
+		TotalPrice:       fmt.Sprintf("%.2f", total),
+		DeliveryService:  p.provider,
+		ChargeableWeight: weight,
+		WeightBasis:      basis,
+		Zone:             p.zone,
+	}
+	finalPrice.DeliveryOptions = deliveryOptions(p.provider, finalPrice, discountedProduct, discountedDelivery, p.at)
+	if len(applied) > 0 {
+		finalPrice.OriginalProductPrice = fmt.Sprintf("%.2f", productPrincing)
+		finalPrice.OriginalDeliveryPrice = fmt.Sprintf("%.2f", deliveryPricing)
+		finalPrice.Promotions = applied
+	}
+	return finalPrice, codeApplied, nil
+}
+
 /*
 Contains checks if a string provider is present in a slice of strings.
 It iterates through the slice and returns true if the provider is found,
@@ -70,6 +226,24 @@ func contains(slice []string, provider string) bool {
 	return false
 }
 
+/*
+validateProduct checks that a product has the data needed to price it.
+It returns an *ErrInvalidProduct describing the first problem found.
+*/
+func validateProduct(product Product) error {
+	switch {
+	case strings.TrimSpace(product.Name) == "":
+		return &ErrInvalidProduct{Name: product.Name, Reason: "name is empty"}
+	case product.Weight < 0 || math.IsNaN(product.Weight):
+		return &ErrInvalidProduct{Name: product.Name, Reason: "weight must not be negative"}
+	case product.Price < 0 || math.IsNaN(product.Price):
+		return &ErrInvalidProduct{Name: product.Name, Reason: "price must not be negative"}
+	case product.Length < 0 || product.Width < 0 || product.Height < 0 || math.IsNaN(product.Length+product.Width+product.Height):
+		return &ErrInvalidProduct{Name: product.Name, Reason: "dimensions must not be negative"}
+	}
+	return nil
+}
+
 /*
 roundToTwoDecimalPlaces rounds a given float64 value to two decimal places.
 
@@ -86,75 +260,59 @@ func roundToTwoDecimalPlaces(value float64) float64 {
 	return float64(int(value*100)) / 100.0
 }
 
+// providerPriceEnv maps each supported delivery provider to the environment variable holding its price per unit weight.
+var providerPriceEnv = map[string]string{
+	"DHL":       "DHL_DELIVERY_PRICE",
+	"UPS":       "UPS_DELIVERY_PRICE",
+	"AMAZON":    "AMAZON_DELIVERY_PRICE",
+	"ROYALMAIL": "ROYAL_MAIL_DELIVERY_PRICE",
+	"DPD":       "DPD_DELIVERY_PRICE",
+	"YODEL":     "YODEL_DELIVERY_PRICE",
+}
+
 /*
-CalculateDeliveryPrice returns the delivery price based on the weight of the product and the provider.
-If the provider does not match any of the cases, it will default to 0.20 * weight.
-If the environment variable for the provider is not set, it will return an error.
-If the environment variable for the provider is set, but has an invalid value, it will return an error.
+ProviderRate returns a provider's delivery price per unit weight, read from the environment
+variable for it in providerPriceEnv. It returns an error wrapping ErrProviderPriceMissing if that
+variable is not set, an *ErrInvalidProviderPrice if it is not a usable price and an error wrapping
+ErrUnknownProvider if the provider is not in the table, the same errors as pricing would, so it can
+be used to check that a provider is configured before using it.
 */
-func calculateDeliveryPrice(weight float64, provider string) (float64, error) {
-	switch provider {
-	case "DHL":
-		DHL_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("DHL_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("DHL_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid DHL_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * DHL_DELIVERY_PRICE, nil
-
-	case "UPS":
-		UPS_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("UPS_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("UPS_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid UPS_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * UPS_DELIVERY_PRICE, nil
-
-	case "AMAZON":
-		AMAZON_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("AMAZON_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("AMAZON_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid AMAZON_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * AMAZON_DELIVERY_PRICE, nil
-
-	case "ROYALMAIL":
-		ROYAL_MAIL_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("ROYAL_MAIL_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("ROYAL_MAIL_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid ROYAL_MAIL_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * ROYAL_MAIL_DELIVERY_PRICE, nil
+func ProviderRate(provider string) (float64, error) {
+	return ProviderRateAt(provider, time.Now())
+}
 
-	case "DPD":
-		DPD_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("DPD_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("DPD_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid DPD_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * DPD_DELIVERY_PRICE, nil
+/*
+ProviderRateAt returns a provider's delivery price per unit weight at the given time. A scheduled
+rate change in effect by then takes precedence over the provider's environment variable.
+*/
+func ProviderRateAt(provider string, at time.Time) (float64, error) {
+	envVar, ok := providerPriceEnv[provider]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	if change, ok := rateChangeAt(provider, at); ok {
+		return change.Rates[provider], nil
+	}
+	return providerPrice(provider, envVar)
+}
 
-	case "YODEL":
-		YODEL_DELIVERY_PRICE, err := strconv.ParseFloat(os.Getenv("YODEL_DELIVERY_PRICE"), 64)
-		if err != nil {
-			if err == strconv.ErrSyntax {
-				return 0, fmt.Errorf("YODEL_DELIVERY_PRICE environment variable not set")
-			}
-			return 0, fmt.Errorf("invalid YODEL_DELIVERY_PRICE: %s", err.Error())
-		}
-		return weight * YODEL_DELIVERY_PRICE, nil
+/*
+providerPrice reads and parses a provider's delivery price from the named environment variable.
+An unset or blank variable is reported as ErrProviderPriceMissing, so that callers can tell
+"not configured" apart from a value that is set but malformed or negative.
+*/
+func providerPrice(provider string, envVar string) (float64, error) {
+	value := strings.TrimSpace(os.Getenv(envVar))
+	if value == "" {
+		return 0, fmt.Errorf("%w: %s (%s not set)", ErrProviderPriceMissing, provider, envVar)
+	}
 
-	default:
-		return weight * 0.20, nil // Default case if no provider matches
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ErrInvalidProviderPrice{Provider: provider, Value: value, Err: err}
+	}
+	if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return 0, &ErrInvalidProviderPrice{Provider: provider, Value: value}
 	}
+	return price, nil
 }
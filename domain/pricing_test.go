@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFlags(0) // Disable log timestamps for cleaner test output
+	go logs.ProcessLogs()
+	os.Exit(m.Run())
+}
+
+// TestProviderRateErrors checks that callers can tell a missing price from a malformed one
+func TestProviderRateErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		value       string
+		set         bool
+		expectedErr error
+		invalid     bool
+	}{
+		{name: "price not set", provider: "DHL", set: false, expectedErr: ErrProviderPriceMissing},
+		{name: "price blank", provider: "UPS", value: "  ", set: true, expectedErr: ErrProviderPriceMissing},
+		{name: "price not a number", provider: "DPD", value: "abc", set: true, expectedErr: strconv.ErrSyntax, invalid: true},
+		{name: "price negative", provider: "YODEL", value: "-1", set: true, invalid: true},
+		{name: "unknown provider", provider: "FEDEX", expectedErr: ErrUnknownProvider},
+	}
+
+	envVars := map[string]string{
+		"DHL":   "DHL_DELIVERY_PRICE",
+		"UPS":   "UPS_DELIVERY_PRICE",
+		"DPD":   "DPD_DELIVERY_PRICE",
+		"YODEL": "YODEL_DELIVERY_PRICE",
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if envVar, ok := envVars[tc.provider]; ok {
+				if tc.set {
+					t.Setenv(envVar, tc.value)
+				} else {
+					os.Unsetenv(envVar)
+				}
+			}
+
+			_, err := ProviderRate(tc.provider)
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if tc.expectedErr != nil && !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error wrapping %v, got %v", tc.expectedErr, err)
+			}
+
+			var invalidPrice *ErrInvalidProviderPrice
+			if errors.As(err, &invalidPrice) != tc.invalid {
+				t.Errorf("expected ErrInvalidProviderPrice to be %t, got %v", tc.invalid, err)
+			}
+			if tc.invalid && (invalidPrice.Provider != tc.provider || invalidPrice.Value != tc.value) {
+				t.Errorf("unexpected error fields: %+v", invalidPrice)
+			}
+		})
+	}
+}
+
+// TestPriceProducts checks pricing of valid and invalid catalogues
+func TestPriceProducts(t *testing.T) {
+	t.Setenv("UPS_DELIVERY_PRICE", "0.01")
+
+	priced, err := PriceProducts([]Product{{Name: "Phone", Weight: 221, Price: 1000}}, "UPS")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := PricedProduct{Name: "Phone", ProductPrice: "1000.00", DeliveryPrice: "2.21", TotalPrice: "1002.21", DeliveryService: "UPS", ChargeableWeight: 221, WeightBasis: WeightActual}
+	if len(priced) != 1 || !reflect.DeepEqual(priced[0], expected) {
+		t.Errorf("expected %+v, got %+v", expected, priced)
+	}
+
+	_, err = PriceProducts([]Product{{Name: "Phone", Weight: -1, Price: 1000}}, "UPS")
+	var invalidProduct *ErrInvalidProduct
+	if !errors.As(err, &invalidProduct) || invalidProduct.Name != "Phone" {
+		t.Errorf("expected ErrInvalidProduct for Phone, got %v", err)
+	}
+
+	_, err = PriceProducts(nil, "FEDEX")
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
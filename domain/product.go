@@ -1,16 +1,54 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Product is an item in the catalogue. Length, width and height are optional, and only used to work
+out volumetric weight for carriers with a volumetric divisor, so they must be in the units the
+divisors are set for.
+*/
 type Product struct {
 	Name   string  `json:"name"`
 	Weight float64 `json:"weight"`
 	Price  float64 `json:"price"`
+	Length float64 `json:"length,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Height float64 `json:"height,omitempty"`
 }
 
 type PricedProduct struct {
-	Name string `json:"name"`
+	Name string `json:"name" xml:"name"`
 	// convert to strings in order to keep trailing zeros (.00) in JSON response
-	ProductPrice    string `json:"product_price"`
-	DeliveryPrice   string `json:"delivery_price"`
-	TotalPrice      string `json:"total_price"`
-	DeliveryService string `json:"delivery_service"`
+	ProductPrice    string `json:"product_price" xml:"product_price"`
+	DeliveryPrice   string `json:"delivery_price" xml:"delivery_price"`
+	TotalPrice      string `json:"total_price" xml:"total_price"`
+	DeliveryService string `json:"delivery_service" xml:"delivery_service"`
+	// the weight delivery was charged on, and whether that was the actual or volumetric weight
+	ChargeableWeight float64     `json:"chargeable_weight" xml:"chargeable_weight"`
+	WeightBasis      WeightBasis `json:"weight_basis" xml:"weight_basis"`
+	// set only when priced for a destination, to the zone delivered to
+	Zone Zone `json:"zone,omitempty" xml:"zone,omitempty"`
+	// set only when the provider has service levels, to the ways the product can be delivered
+	DeliveryOptions []DeliveryOption `json:"delivery_options,omitempty" xml:"delivery_options>option,omitempty"`
+	// set only when a promotion applied, to the prices before it
+	OriginalProductPrice  string             `json:"original_product_price,omitempty" xml:"original_product_price,omitempty"`
+	OriginalDeliveryPrice string             `json:"original_delivery_price,omitempty" xml:"original_delivery_price,omitempty"`
+	Promotions            []AppliedPromotion `json:"promotions,omitempty" xml:"promotions>promotion,omitempty"`
+}
+
+/*
+FindProduct returns the product with the given name from the catalogue.
+The match is case-insensitive so that "tv" and "TV" refer to the same product.
+It returns an error wrapping ErrProductNotFound if no product matches.
+*/
+func FindProduct(products []Product, name string) (Product, error) {
+	for _, product := range products {
+		if strings.EqualFold(product.Name, name) {
+			return product, nil
+		}
+	}
+	return Product{}, fmt.Errorf("%w: %q", ErrProductNotFound, name)
 }
@@ -0,0 +1,200 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PromotionTarget is the part of a price a promotion takes money off.
+type PromotionTarget string
+
+const (
+	PromotionDelivery PromotionTarget = "delivery"
+	PromotionProduct  PromotionTarget = "product"
+)
+
+// PromotionKind is how a promotion works out its discount.
+type PromotionKind string
+
+const (
+	PromotionPercentage PromotionKind = "percentage" // Value percent off, so 100 makes it free
+	PromotionFixed      PromotionKind = "fixed"      // Value off, never taking the price below zero
+)
+
+/*
+Promotion is a discount on the delivery or product price. It only applies to products whose
+price, after any earlier promotions, is at least MinSpend, to the listed providers if any are
+given, and between StartsAt and EndsAt if they are set. Each product is priced as an order of one,
+so "free delivery on orders over 500" is a 100% delivery promotion with a MinSpend of 500.
+*/
+type Promotion struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name,omitempty"`
+	Target    PromotionTarget `json:"target"`
+	Kind      PromotionKind   `json:"kind"`
+	Value     float64         `json:"value"`
+	MinSpend  float64         `json:"min_spend,omitempty"`
+	Providers []string        `json:"providers,omitempty"`
+	StartsAt  *time.Time      `json:"starts_at,omitempty"` // inclusive
+	EndsAt    *time.Time      `json:"ends_at,omitempty"`   // exclusive
+}
+
+// AppliedPromotion is a promotion that took money off a priced product.
+type AppliedPromotion struct {
+	ID       string          `json:"id" xml:"id"`
+	Name     string          `json:"name,omitempty" xml:"name,omitempty"`
+	Target   PromotionTarget `json:"target" xml:"target"`
+	Discount string          `json:"discount" xml:"discount"` // the amount taken off, to two decimal places
+	// DiscountCode is true for a discount code entered by the customer, whose ID is the code
+	DiscountCode bool `json:"discount_code,omitempty" xml:"discount_code,omitempty"`
+}
+
+var (
+	promotionsMu sync.RWMutex
+	promotions   []Promotion // in the order they are applied
+)
+
+/*
+SetPromotions replaces the promotions, which are applied in the order given. Provider names are
+normalised to upper case. If any promotion is invalid the promotions are left as they were and
+an *ErrInvalidPromotion is returned.
+*/
+func SetPromotions(list []Promotion) error {
+	normalised, err := normalisePromotions(list)
+	if err != nil {
+		return err
+	}
+
+	promotionsMu.Lock()
+	defer promotionsMu.Unlock()
+	promotions = normalised
+	return nil
+}
+
+// normalisePromotions validates and normalises promotions as SetPromotions does, keeping their order.
+func normalisePromotions(list []Promotion) ([]Promotion, error) {
+	normalised := make([]Promotion, len(list))
+	ids := make(map[string]bool, len(list))
+	for i, promotion := range list {
+		promotion.Providers = slices.Clone(promotion.Providers)
+		for j, provider := range promotion.Providers {
+			promotion.Providers[j] = strings.ToUpper(strings.TrimSpace(provider))
+		}
+		if err := ValidatePromotion(promotion); err != nil {
+			return nil, err
+		}
+		if ids[promotion.ID] {
+			return nil, &ErrInvalidPromotion{ID: promotion.ID, Reason: "id is used more than once"}
+		}
+		ids[promotion.ID] = true
+		normalised[i] = promotion
+	}
+	return normalised, nil
+}
+
+// Promotions returns a copy of the promotions, in the order they are applied.
+func Promotions() []Promotion {
+	promotionsMu.RLock()
+	defer promotionsMu.RUnlock()
+	return slices.Clone(promotions)
+}
+
+// ValidatePromotion checks that a promotion can be applied, returning an *ErrInvalidPromotion if not.
+func ValidatePromotion(p Promotion) error {
+	invalid := func(reason string) error { return &ErrInvalidPromotion{ID: p.ID, Reason: reason} }
+	switch {
+	case strings.TrimSpace(p.ID) == "":
+		return invalid("id is empty")
+	case p.Target != PromotionDelivery && p.Target != PromotionProduct:
+		return invalid(fmt.Sprintf("target must be %q or %q", PromotionDelivery, PromotionProduct))
+	case p.Kind != PromotionPercentage && p.Kind != PromotionFixed:
+		return invalid(fmt.Sprintf("kind must be %q or %q", PromotionPercentage, PromotionFixed))
+	case p.Value < 0 || math.IsNaN(p.Value) || math.IsInf(p.Value, 0):
+		return invalid("value must not be negative")
+	case p.Kind == PromotionPercentage && p.Value > 100:
+		return invalid("a percentage must not be more than 100")
+	case p.MinSpend < 0 || math.IsNaN(p.MinSpend) || math.IsInf(p.MinSpend, 0):
+		return invalid("min_spend must not be negative")
+	case p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt):
+		return invalid("ends_at must be after starts_at")
+	}
+	for _, provider := range p.Providers {
+		if !contains(allowedProviders, provider) {
+			return invalid(fmt.Sprintf("unknown provider %q", provider))
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the promotion applies to the provider's deliveries.
+func (p Promotion) appliesTo(provider string) bool {
+	return len(p.Providers) == 0 || contains(p.Providers, provider)
+}
+
+// equal reports whether two promotions are configured the same, comparing times by the instant they name.
+func (p Promotion) equal(other Promotion) bool {
+	sameTime := func(a, b *time.Time) bool { return a == nil && b == nil || a != nil && b != nil && a.Equal(*b) }
+	return p.ID == other.ID && p.Name == other.Name && p.Target == other.Target && p.Kind == other.Kind &&
+		p.Value == other.Value && p.MinSpend == other.MinSpend && slices.Equal(p.Providers, other.Providers) &&
+		sameTime(p.StartsAt, other.StartsAt) && sameTime(p.EndsAt, other.EndsAt)
+}
+
+// activeAt reports whether the promotion runs at the given time.
+func (p Promotion) activeAt(at time.Time) bool {
+	return (p.StartsAt == nil || !at.Before(*p.StartsAt)) && (p.EndsAt == nil || at.Before(*p.EndsAt))
+}
+
+// discount returns the amount the promotion takes off price, rounded to the nearest penny.
+func (p Promotion) discount(price float64) float64 {
+	amount := p.Value
+	if p.Kind == PromotionPercentage {
+		amount = percentOff(price, p.Value)
+	}
+	return math.Min(amount, price)
+}
+
+// PromotionsAt returns the promotions running at the given time, in the order they are applied.
+func PromotionsAt(at time.Time) []Promotion {
+	promotionsMu.RLock()
+	defer promotionsMu.RUnlock()
+	var active []Promotion
+	for _, promotion := range promotions {
+		if promotion.activeAt(at) {
+			active = append(active, promotion)
+		}
+	}
+	return active
+}
+
+/*
+applyPromotions applies each promotion in turn to a product's price and delivery price, returning
+the discounted prices and the promotions that took money off. A promotion's minimum spend is
+checked against the product price left by the promotions before it.
+*/
+func applyPromotions(active []Promotion, provider string, productPrice, deliveryPrice float64) (float64, float64, []AppliedPromotion) {
+	var applied []AppliedPromotion
+	for _, promotion := range active {
+		if !promotion.appliesTo(provider) {
+			continue
+		}
+		if productPrice < promotion.MinSpend {
+			continue
+		}
+
+		price := &deliveryPrice
+		if promotion.Target == PromotionProduct {
+			price = &productPrice
+		}
+		discount := promotion.discount(*price)
+		if discount <= 0 {
+			continue
+		}
+		*price = math.Round((*price-discount)*100) / 100
+		applied = append(applied, AppliedPromotion{ID: promotion.ID, Name: promotion.Name, Target: promotion.Target, Discount: fmt.Sprintf("%.2f", discount)})
+	}
+	return productPrice, deliveryPrice, applied
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSetPromotions tests validating promotions
+func TestSetPromotions(t *testing.T) {
+	t.Cleanup(func() { SetPromotions(nil) })
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		valid      bool
+	}{
+		{name: "percentage", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 100}}, valid: true},
+		{name: "fixed", promotions: []Promotion{{ID: "a", Target: PromotionProduct, Kind: PromotionFixed, Value: 5, Providers: []string{"dpd"}, StartsAt: &start, EndsAt: &end}}, valid: true},
+		{name: "missing id", promotions: []Promotion{{Target: PromotionDelivery, Kind: PromotionFixed, Value: 1}}},
+		{name: "unknown target", promotions: []Promotion{{ID: "a", Target: "tax", Kind: PromotionFixed, Value: 1}}},
+		{name: "unknown kind", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: "bogof", Value: 1}}},
+		{name: "negative value", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionFixed, Value: -1}}},
+		{name: "over 100 percent", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 101}}},
+		{name: "negative min spend", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionFixed, Value: 1, MinSpend: -1}}},
+		{name: "unknown provider", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionFixed, Value: 1, Providers: []string{"FEDEX"}}}},
+		{name: "ends before it starts", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionFixed, Value: 1, StartsAt: &end, EndsAt: &start}}},
+		{name: "duplicate id", promotions: []Promotion{{ID: "a", Target: PromotionDelivery, Kind: PromotionFixed, Value: 1}, {ID: "a", Target: PromotionProduct, Kind: PromotionFixed, Value: 1}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetPromotions(nil)
+			err := SetPromotions(tc.promotions)
+			var invalid *ErrInvalidPromotion
+			if tc.valid != (err == nil) || (err != nil && !errors.As(err, &invalid)) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.valid && len(Promotions()) != 0 {
+				t.Error("expected invalid promotions not to be applied")
+			}
+		})
+	}
+}
+
+// TestPromotions tests applying ordered promotions to product and delivery prices
+func TestPromotions(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("DPD_DELIVERY_PRICE", "1")
+	t.Cleanup(func() { SetPromotions(nil) })
+
+	now := time.Now()
+	lastWeek, yesterday, tomorrow := now.Add(-7*24*time.Hour), now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	freeOver500 := Promotion{ID: "free-over-500", Name: "Free delivery over 500", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 100, MinSpend: 500}
+	dpdHalf := Promotion{ID: "dpd-half", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 50, Providers: []string{"DPD"}, StartsAt: &yesterday, EndsAt: &tomorrow}
+	tenOff := Promotion{ID: "ten-off", Target: PromotionProduct, Kind: PromotionFixed, Value: 10}
+	expired := Promotion{ID: "expired", Target: PromotionProduct, Kind: PromotionPercentage, Value: 90, StartsAt: &lastWeek, EndsAt: &yesterday}
+	upcoming := Promotion{ID: "upcoming", Target: PromotionProduct, Kind: PromotionPercentage, Value: 90, StartsAt: &tomorrow}
+
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		product    Product
+		provider   string
+		expected   PricedProduct
+	}{
+		{
+			name:       "no promotions",
+			promotions: []Promotion{expired, upcoming},
+			product:    Product{Name: "TV", Weight: 1.5, Price: 20},
+			provider:   "DHL",
+			expected:   PricedProduct{Name: "TV", ProductPrice: "20.00", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual},
+		},
+		{
+			name:       "free delivery over the threshold",
+			promotions: []Promotion{freeOver500},
+			product:    Product{Name: "Sofa", Weight: 40, Price: 600},
+			provider:   "DHL",
+			expected: PricedProduct{Name: "Sofa", ProductPrice: "600.00", DeliveryPrice: "0.00", TotalPrice: "600.00", DeliveryService: "DHL", ChargeableWeight: 40, WeightBasis: WeightActual,
+				OriginalProductPrice: "600.00", OriginalDeliveryPrice: "80.00", Promotions: []AppliedPromotion{{ID: "free-over-500", Name: "Free delivery over 500", Target: PromotionDelivery, Discount: "80.00"}}},
+		},
+		{
+			name:       "threshold is inclusive",
+			promotions: []Promotion{freeOver500},
+			product:    Product{Name: "Desk", Weight: 10, Price: 500},
+			provider:   "DHL",
+			expected: PricedProduct{Name: "Desk", ProductPrice: "500.00", DeliveryPrice: "0.00", TotalPrice: "500.00", DeliveryService: "DHL", ChargeableWeight: 10, WeightBasis: WeightActual,
+				OriginalProductPrice: "500.00", OriginalDeliveryPrice: "20.00", Promotions: []AppliedPromotion{{ID: "free-over-500", Name: "Free delivery over 500", Target: PromotionDelivery, Discount: "20.00"}}},
+		},
+		{
+			name:       "earlier discount takes the product under the threshold",
+			promotions: []Promotion{tenOff, freeOver500},
+			product:    Product{Name: "Desk", Weight: 10, Price: 505},
+			provider:   "DHL",
+			expected: PricedProduct{Name: "Desk", ProductPrice: "495.00", DeliveryPrice: "20.00", TotalPrice: "515.00", DeliveryService: "DHL", ChargeableWeight: 10, WeightBasis: WeightActual,
+				OriginalProductPrice: "505.00", OriginalDeliveryPrice: "20.00", Promotions: []AppliedPromotion{{ID: "ten-off", Target: PromotionProduct, Discount: "10.00"}}},
+		},
+		{
+			name:       "provider specific",
+			promotions: []Promotion{dpdHalf},
+			product:    Product{Name: "TV", Weight: 1.5, Price: 20},
+			provider:   "DHL",
+			expected:   PricedProduct{Name: "TV", ProductPrice: "20.00", DeliveryPrice: "3.00", TotalPrice: "23.00", DeliveryService: "DHL", ChargeableWeight: 1.5, WeightBasis: WeightActual},
+		},
+		{
+			name:       "percentage rounds to the penny",
+			promotions: []Promotion{dpdHalf},
+			product:    Product{Name: "Radio", Weight: 1.25, Price: 7},
+			provider:   "DPD",
+			expected: PricedProduct{Name: "Radio", ProductPrice: "7.00", DeliveryPrice: "0.62", TotalPrice: "7.62", DeliveryService: "DPD", ChargeableWeight: 1.25, WeightBasis: WeightActual,
+				OriginalProductPrice: "7.00", OriginalDeliveryPrice: "1.25", Promotions: []AppliedPromotion{{ID: "dpd-half", Target: PromotionDelivery, Discount: "0.63"}}},
+		},
+		{
+			name:       "fixed amount stops at zero",
+			promotions: []Promotion{tenOff},
+			product:    Product{Name: "Cable", Weight: 0.5, Price: 4},
+			provider:   "DHL",
+			expected: PricedProduct{Name: "Cable", ProductPrice: "0.00", DeliveryPrice: "1.00", TotalPrice: "1.00", DeliveryService: "DHL", ChargeableWeight: 0.5, WeightBasis: WeightActual,
+				OriginalProductPrice: "4.00", OriginalDeliveryPrice: "1.00", Promotions: []AppliedPromotion{{ID: "ten-off", Target: PromotionProduct, Discount: "4.00"}}},
+		},
+		{
+			name:       "nothing to take off",
+			promotions: []Promotion{freeOver500},
+			product:    Product{Name: "Voucher", Weight: 0, Price: 900},
+			provider:   "DHL",
+			expected:   PricedProduct{Name: "Voucher", ProductPrice: "900.00", DeliveryPrice: "0.00", TotalPrice: "900.00", DeliveryService: "DHL", ChargeableWeight: 0, WeightBasis: WeightActual},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetPromotions(tc.promotions); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			priced, err := PriceProducts([]Product{tc.product}, tc.provider)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(priced[0], tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, priced[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ScheduledChange is a pricing change that takes effect at EffectiveAt. It can switch the default
+delivery provider, set providers' prices per unit weight, or both. Once in effect it takes
+precedence over DELIVERY_PROVIDER and the *_DELIVERY_PRICE variables, which only apply until
+the first change.
+*/
+type ScheduledChange struct {
+	ID          string             `json:"id"`
+	EffectiveAt time.Time          `json:"effective_at"`
+	Provider    string             `json:"provider,omitempty"` // new default provider
+	Rates       map[string]float64 `json:"rates,omitempty"`    // new price per unit weight, by provider
+	Note        string             `json:"note,omitempty"`
+}
+
+var (
+	scheduleMu sync.RWMutex
+	schedule   []ScheduledChange // sorted by EffectiveAt, then ID
+)
+
+/*
+SetSchedule replaces the pricing schedule. Provider names are normalised to upper case. If any
+change is invalid the schedule is left as it was and an *ErrInvalidScheduledChange is returned.
+*/
+func SetSchedule(changes []ScheduledChange) error {
+	normalised, err := normaliseSchedule(changes)
+	if err != nil {
+		return err
+	}
+
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	schedule = normalised
+	return nil
+}
+
+// normaliseSchedule validates and normalises changes as SetSchedule does, sorted in the order they take effect.
+func normaliseSchedule(changes []ScheduledChange) ([]ScheduledChange, error) {
+	normalised := make([]ScheduledChange, len(changes))
+	ids := make(map[string]bool, len(changes))
+	for i, change := range changes {
+		change = normaliseChange(change)
+		if err := ValidateScheduledChange(change); err != nil {
+			return nil, err
+		}
+		if ids[change.ID] {
+			return nil, &ErrInvalidScheduledChange{ID: change.ID, Reason: "id is used more than once"}
+		}
+		ids[change.ID] = true
+		normalised[i] = change
+	}
+	slices.SortStableFunc(normalised, func(a, b ScheduledChange) int {
+		if c := a.EffectiveAt.Compare(b.EffectiveAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return normalised, nil
+}
+
+// Schedule returns every scheduled change, past and upcoming, in the order they take effect.
+func Schedule() []ScheduledChange {
+	scheduleMu.RLock()
+	defer scheduleMu.RUnlock()
+	return slices.Clone(schedule)
+}
+
+/*
+ValidateScheduledChange checks that a change can be applied: it needs an ID, an effective time,
+and a supported provider or rates for supported providers that are not negative.
+*/
+func ValidateScheduledChange(change ScheduledChange) error {
+	invalid := func(reason string) error {
+		return &ErrInvalidScheduledChange{ID: change.ID, Reason: reason}
+	}
+	switch {
+	case strings.TrimSpace(change.ID) == "":
+		return invalid("id is empty")
+	case change.EffectiveAt.IsZero():
+		return invalid("effective_at is not set")
+	case change.Provider == "" && len(change.Rates) == 0:
+		return invalid("change sets neither a provider nor rates")
+	case change.Provider != "" && !contains(allowedProviders, strings.ToUpper(change.Provider)):
+		return invalid("unknown provider " + change.Provider)
+	}
+	for provider, rate := range change.Rates {
+		if !contains(allowedProviders, strings.ToUpper(provider)) {
+			return invalid("rate for unknown provider " + provider)
+		}
+		if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			return invalid("rate for " + provider + " must be a non-negative number")
+		}
+	}
+	return nil
+}
+
+// normaliseChange upper-cases the provider names in a change.
+func normaliseChange(change ScheduledChange) ScheduledChange {
+	change.Provider = strings.ToUpper(strings.TrimSpace(change.Provider))
+	if change.Rates != nil {
+		rates := make(map[string]float64, len(change.Rates))
+		for provider, rate := range change.Rates {
+			rates[strings.ToUpper(strings.TrimSpace(provider))] = rate
+		}
+		change.Rates = rates
+	}
+	return change
+}
+
+/*
+NextScheduledChange returns the first change that takes effect after the given time,
+reporting false if none is scheduled.
+*/
+func NextScheduledChange(after time.Time) (ScheduledChange, bool) {
+	scheduleMu.RLock()
+	defer scheduleMu.RUnlock()
+	for _, change := range schedule {
+		if change.EffectiveAt.After(after) {
+			return change, true
+		}
+	}
+	return ScheduledChange{}, false
+}
+
+// DefaultProvider returns the delivery provider in effect now.
+func DefaultProvider() string {
+	return DefaultProviderAt(time.Now())
+}
+
+/*
+DefaultProviderAt returns the delivery provider in effect at the given time: that of the latest
+change in effect which sets one, or DELIVERY_PROVIDER if there is none. It is empty if neither is set.
+*/
+func DefaultProviderAt(at time.Time) string {
+	scheduleMu.RLock()
+	defer scheduleMu.RUnlock()
+	for i := len(schedule) - 1; i >= 0; i-- {
+		if change := schedule[i]; change.Provider != "" && !change.EffectiveAt.After(at) {
+			return change.Provider
+		}
+	}
+	return strings.ToUpper(os.Getenv("DELIVERY_PROVIDER"))
+}
+
+// rateChangeAt returns the latest change in effect at the given time that sets provider's rate.
+func rateChangeAt(provider string, at time.Time) (ScheduledChange, bool) {
+	scheduleMu.RLock()
+	defer scheduleMu.RUnlock()
+	for i := len(schedule) - 1; i >= 0; i-- {
+		change := schedule[i]
+		if _, ok := change.Rates[provider]; ok && !change.EffectiveAt.After(at) {
+			return change, true
+		}
+	}
+	return ScheduledChange{}, false
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSetSchedule tests validating scheduled changes
+func TestSetSchedule(t *testing.T) {
+	t.Cleanup(func() { SetSchedule(nil) })
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		changes []ScheduledChange
+		valid   bool
+	}{
+		{name: "provider", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Provider: "ups"}}, valid: true},
+		{name: "rates", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Rates: map[string]float64{"dhl": 0}}}, valid: true},
+		{name: "missing id", changes: []ScheduledChange{{EffectiveAt: at, Provider: "UPS"}}},
+		{name: "missing time", changes: []ScheduledChange{{ID: "a", Provider: "UPS"}}},
+		{name: "no change", changes: []ScheduledChange{{ID: "a", EffectiveAt: at}}},
+		{name: "unknown provider", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Provider: "FEDEX"}}},
+		{name: "rate for unknown provider", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Rates: map[string]float64{"FEDEX": 1}}}},
+		{name: "negative rate", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Rates: map[string]float64{"DHL": -1}}}},
+		{name: "duplicate id", changes: []ScheduledChange{{ID: "a", EffectiveAt: at, Provider: "UPS"}, {ID: "a", EffectiveAt: at, Provider: "DHL"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetSchedule(nil)
+			err := SetSchedule(tc.changes)
+			var invalid *ErrInvalidScheduledChange
+			if tc.valid != (err == nil) || (err != nil && !errors.As(err, &invalid)) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.valid && len(Schedule()) != 0 {
+				t.Error("expected an invalid schedule not to be applied")
+			}
+		})
+	}
+}
+
+// TestScheduleAt tests working out the provider and rates in effect at a given time
+func TestScheduleAt(t *testing.T) {
+	t.Setenv("DELIVERY_PROVIDER", "dhl")
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("UPS_DELIVERY_PRICE", "1")
+	t.Cleanup(func() { SetSchedule(nil) })
+
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := SetSchedule([]ScheduledChange{
+		{ID: "third", EffectiveAt: start.Add(48 * time.Hour), Provider: "DHL"},
+		{ID: "first", EffectiveAt: start, Provider: "ups", Rates: map[string]float64{"DHL": 3}},
+		{ID: "second", EffectiveAt: start.Add(24 * time.Hour), Rates: map[string]float64{"DHL": 4, "UPS": 1.5}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		provider string
+		dhl      float64
+		ups      float64
+	}{
+		{name: "before the schedule", at: start.Add(-time.Second), provider: "DHL", dhl: 2, ups: 1},
+		{name: "first change", at: start, provider: "UPS", dhl: 3, ups: 1},
+		{name: "second change", at: start.Add(30 * time.Hour), provider: "UPS", dhl: 4, ups: 1.5},
+		{name: "third change", at: start.Add(72 * time.Hour), provider: "DHL", dhl: 4, ups: 1.5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if provider := DefaultProviderAt(tc.at); provider != tc.provider {
+				t.Errorf("expected provider %s, got %s", tc.provider, provider)
+			}
+			if rate, _ := ProviderRateAt("DHL", tc.at); rate != tc.dhl {
+				t.Errorf("expected DHL rate %v, got %v", tc.dhl, rate)
+			}
+			if rate, _ := ProviderRateAt("UPS", tc.at); rate != tc.ups {
+				t.Errorf("expected UPS rate %v, got %v", tc.ups, rate)
+			}
+		})
+	}
+
+	if next, ok := NextScheduledChange(start); !ok || next.ID != "second" {
+		t.Errorf("expected the second change to be next, got %+v", next)
+	}
+	if _, ok := NextScheduledChange(start.Add(48 * time.Hour)); ok {
+		t.Error("expected no change after the last")
+	}
+}
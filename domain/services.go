@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // so service level timezones load without the host's zoneinfo
+)
+
+// Service is a delivery service level a carrier offers at its own price and speed.
+type Service string
+
+const (
+	ServiceStandard Service = "standard" // charged at the carrier's flat or zone rate
+	ServiceNextDay  Service = "next_day"
+	ServiceTimed    Service = "timed" // delivered by a time of day
+)
+
+// services lists every service level, in the order delivery options are listed.
+var services = []Service{ServiceStandard, ServiceNextDay, ServiceTimed}
+
+// defaultServiceTimezone is the timezone cut-offs are in when a service level does not give one.
+const defaultServiceTimezone = "Europe/London"
+
+/*
+ServiceLevel is one of a carrier's delivery services and its transit rules. Rate is its price per
+unit weight, which standard does not have, being charged at the carrier's flat or zone rate.
+Orders placed at or after CutOff, a time such as 15:00, are dispatched the next day, and the
+delivery arrives TransitDays after dispatch. If WorkingDaysOnly is set, orders are only dispatched
+and delivered on working days, Monday to Friday apart from bank holidays. Timed services are
+delivered by DeliverBy on that day.
+*/
+type ServiceLevel struct {
+	Provider        string  `json:"provider"`
+	Service         Service `json:"service"`
+	Rate            float64 `json:"rate,omitempty"`
+	CutOff          string  `json:"cut_off,omitempty"`
+	TransitDays     int     `json:"transit_days"`
+	WorkingDaysOnly bool    `json:"working_days_only,omitempty"`
+	DeliverBy       string  `json:"deliver_by,omitempty"` // timed only
+	Timezone        string  `json:"timezone,omitempty"`   // IANA name, Europe/London if empty
+}
+
+/*
+DeliveryOption is a service level a product can be delivered with, its prices and when it is
+expected to arrive. EstimatedDelivery is a date, such as 2026-10-20.
+*/
+type DeliveryOption struct {
+	Service           Service `json:"service" xml:"service"`
+	DeliveryPrice     string  `json:"delivery_price" xml:"delivery_price"`
+	TotalPrice        string  `json:"total_price" xml:"total_price"`
+	EstimatedDelivery string  `json:"estimated_delivery" xml:"estimated_delivery"`
+	DeliverBy         string  `json:"deliver_by,omitempty" xml:"deliver_by,omitempty"`
+}
+
+var (
+	servicesMu    sync.RWMutex
+	serviceLevels []ServiceLevel  // sorted by provider and service
+	bankHolidays  map[string]bool // by date, such as 2026-12-25
+)
+
+/*
+SetServiceLevels replaces the carriers' service levels. Provider names are normalised to upper
+case. If any level is invalid the levels are left as they were and an *ErrInvalidServiceLevel is
+returned.
+*/
+func SetServiceLevels(levels []ServiceLevel) error {
+	normalised, err := normaliseServiceLevels(levels)
+	if err != nil {
+		return err
+	}
+
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	serviceLevels = normalised
+	return nil
+}
+
+// normaliseServiceLevels validates and normalises levels as SetServiceLevels does, sorted by provider and service.
+func normaliseServiceLevels(levels []ServiceLevel) ([]ServiceLevel, error) {
+	normalised := make([]ServiceLevel, 0, len(levels))
+	for _, level := range levels {
+		level.Provider = strings.ToUpper(strings.TrimSpace(level.Provider))
+		if err := ValidateServiceLevel(level); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(normalised, func(l ServiceLevel) bool { return l.Provider == level.Provider && l.Service == level.Service }) {
+			return nil, &ErrInvalidServiceLevel{Provider: level.Provider, Service: level.Service, Reason: "provider has this service more than once"}
+		}
+		normalised = append(normalised, level)
+	}
+	slices.SortFunc(normalised, func(a, b ServiceLevel) int {
+		if c := strings.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		return slices.Index(services, a.Service) - slices.Index(services, b.Service)
+	})
+	return normalised, nil
+}
+
+// ServiceLevels returns the carriers' service levels, sorted by provider and service.
+func ServiceLevels() []ServiceLevel {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	return slices.Clone(serviceLevels)
+}
+
+// providerServiceLevels returns provider's service levels, in the order delivery options are listed.
+func providerServiceLevels(provider string) []ServiceLevel {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	var levels []ServiceLevel
+	for _, level := range serviceLevels {
+		if level.Provider == provider {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// ValidateServiceLevel checks that a service level can be used, returning an *ErrInvalidServiceLevel if not.
+func ValidateServiceLevel(l ServiceLevel) error {
+	invalid := func(reason string) error {
+		return &ErrInvalidServiceLevel{Provider: l.Provider, Service: l.Service, Reason: reason}
+	}
+	switch {
+	case !contains(allowedProviders, l.Provider):
+		return invalid(fmt.Sprintf("unknown provider %q", l.Provider))
+	case !slices.Contains(services, l.Service):
+		return invalid(fmt.Sprintf("unknown service %q", l.Service))
+	case l.Service == ServiceStandard && l.Rate != 0:
+		return invalid("standard delivery is charged at the provider's flat or zone rate, so cannot be given a rate")
+	case l.Service != ServiceStandard && (l.Rate <= 0 || math.IsNaN(l.Rate) || math.IsInf(l.Rate, 0)):
+		return invalid("rate must be a positive number")
+	case l.TransitDays < 0:
+		return invalid("transit_days must not be negative")
+	case (l.Service == ServiceTimed) != (l.DeliverBy != ""):
+		return invalid("deliver_by must be given for timed delivery, and only for timed delivery")
+	}
+	for name, value := range map[string]string{"cut_off": l.CutOff, "deliver_by": l.DeliverBy} {
+		if _, err := time.Parse("15:04", value); value != "" && err != nil {
+			return invalid(fmt.Sprintf("%s %q is not a time such as 15:00", name, value))
+		}
+	}
+	if _, err := l.location(); err != nil {
+		return invalid(fmt.Sprintf("unknown timezone %q", l.Timezone))
+	}
+	return nil
+}
+
+/*
+SetBankHolidays replaces the bank holidays, dates such as 2026-12-25 on which services that only
+run on working days neither dispatch nor deliver. If a date is malformed the bank holidays are
+left as they were and an *ErrInvalidBankHoliday is returned.
+*/
+func SetBankHolidays(dates []string) error {
+	holidays, err := parseBankHolidays(dates)
+	if err != nil {
+		return err
+	}
+
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	bankHolidays = holidays
+	return nil
+}
+
+// parseBankHolidays checks dates as SetBankHolidays does, returning them as a set.
+func parseBankHolidays(dates []string) (map[string]bool, error) {
+	holidays := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		date = strings.TrimSpace(date)
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, &ErrInvalidBankHoliday{Date: date, Err: err}
+		}
+		holidays[date] = true
+	}
+	return holidays, nil
+}
+
+// BankHolidays returns the bank holidays, in date order.
+func BankHolidays() []string {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	dates := make([]string, 0, len(bankHolidays))
+	for date := range bankHolidays {
+		dates = append(dates, date)
+	}
+	slices.Sort(dates)
+	return dates
+}
+
+// isWorkingDay reports whether day is a weekday that is not a bank holiday.
+func isWorkingDay(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+	return !bankHolidays[day.Format(time.DateOnly)]
+}
+
+/*
+EstimatedDelivery returns the day an order placed at the given time is expected to arrive with
+this service level, at midnight in its timezone. The order is dispatched that day if it is placed
+before the cut-off, or else the next day, moving on to the next working day for services that only
+run on working days, and then spends TransitDays in transit, counting only working days for those
+services.
+*/
+func (l ServiceLevel) EstimatedDelivery(at time.Time) time.Time {
+	location, _ := l.location() // checked by ValidateServiceLevel
+	local := at.In(location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	if cutOff, err := time.Parse("15:04", l.CutOff); err == nil && local.Hour()*60+local.Minute() >= cutOff.Hour()*60+cutOff.Minute() {
+		day = day.AddDate(0, 0, 1)
+	}
+
+	next := func(day time.Time) time.Time {
+		for l.WorkingDaysOnly && !isWorkingDay(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day
+	}
+	day = next(day)
+	for range l.TransitDays {
+		day = next(day.AddDate(0, 0, 1))
+	}
+	return day
+}
+
+// location returns the timezone the service level's cut-off is in.
+func (l ServiceLevel) location() (*time.Location, error) {
+	if l.Timezone == "" {
+		return time.LoadLocation(defaultServiceTimezone)
+	}
+	return time.LoadLocation(l.Timezone)
+}
+
+/*
+deliveryOptions returns the ways a product priced with provider at the given time can be
+delivered, using the prices already worked out for standard delivery. Other services are charged
+their own rate on the chargeable weight, without delivery promotions or discount codes, and are
+only offered on the mainland. It returns nil if the provider has no service levels.
+*/
+func deliveryOptions(provider string, priced PricedProduct, productPrice, standardDelivery float64, at time.Time) []DeliveryOption {
+	var options []DeliveryOption
+	for _, level := range providerServiceLevels(provider) {
+		delivery := standardDelivery
+		if level.Service != ServiceStandard {
+			if priced.Zone != "" && priced.Zone != ZoneMainland {
+				continue
+			}
+			delivery = roundToTwoDecimalPlaces(priced.ChargeableWeight * level.Rate)
+		}
+		options = append(options, DeliveryOption{
+			Service:           level.Service,
+			DeliveryPrice:     fmt.Sprintf("%.2f", delivery),
+			TotalPrice:        fmt.Sprintf("%.2f", productPrice+delivery),
+			EstimatedDelivery: level.EstimatedDelivery(at).Format(time.DateOnly),
+			DeliverBy:         level.DeliverBy,
+		})
+	}
+	return options
+}
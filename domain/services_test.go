@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSetServiceLevels tests validating and normalising the carriers' service levels
+func TestSetServiceLevels(t *testing.T) {
+	t.Cleanup(func() { SetServiceLevels(nil) })
+
+	tests := []struct {
+		name   string
+		levels []ServiceLevel
+		valid  bool
+	}{
+		{name: "every service", levels: []ServiceLevel{
+			{Provider: "dhl", Service: ServiceTimed, Rate: 6, TransitDays: 1, DeliverBy: "10:30"},
+			{Provider: "DHL", Service: ServiceStandard, TransitDays: 3, WorkingDaysOnly: true},
+			{Provider: "DHL", Service: ServiceNextDay, Rate: 4, CutOff: "15:00", TransitDays: 1, Timezone: "Europe/Dublin"},
+		}, valid: true},
+		{name: "unknown provider", levels: []ServiceLevel{{Provider: "FEDEX", Service: ServiceStandard}}},
+		{name: "unknown service", levels: []ServiceLevel{{Provider: "DHL", Service: "same_day", Rate: 1}}},
+		{name: "standard rate", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceStandard, Rate: 1}}},
+		{name: "next day without a rate", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceNextDay, TransitDays: 1}}},
+		{name: "negative transit days", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceStandard, TransitDays: -1}}},
+		{name: "malformed cut-off", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceStandard, CutOff: "3pm"}}},
+		{name: "timed without deliver_by", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceTimed, Rate: 6}}},
+		{name: "deliver_by on another service", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceNextDay, Rate: 4, DeliverBy: "10:30"}}},
+		{name: "unknown timezone", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceStandard, Timezone: "Mars/Olympus"}}},
+		{name: "duplicate service", levels: []ServiceLevel{{Provider: "DHL", Service: ServiceStandard}, {Provider: "dhl", Service: ServiceStandard}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetServiceLevels(nil)
+			err := SetServiceLevels(tc.levels)
+			var invalid *ErrInvalidServiceLevel
+			if tc.valid != (err == nil) || (err != nil && !errors.As(err, &invalid)) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.valid && len(ServiceLevels()) != 0 {
+				t.Error("expected invalid levels not to be stored")
+			}
+		})
+	}
+
+	SetServiceLevels(tests[0].levels)
+	levels := ServiceLevels()
+	if len(levels) != 3 || levels[0].Service != ServiceStandard || levels[1].Service != ServiceNextDay || levels[2].Provider != "DHL" {
+		t.Errorf("expected the levels in service order with normalised providers, got %+v", levels)
+	}
+}
+
+// TestEstimatedDelivery tests working out delivery dates from cut-offs, transit days, weekends and bank holidays
+func TestEstimatedDelivery(t *testing.T) {
+	if err := SetBankHolidays([]string{"2026-12-25", " 2026-12-28"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { SetBankHolidays(nil) })
+	nextDay := ServiceLevel{Provider: "DHL", Service: ServiceNextDay, Rate: 4, CutOff: "15:00", TransitDays: 1, WorkingDaysOnly: true}
+	london, _ := time.LoadLocation("Europe/London")
+
+	tests := []struct {
+		name     string
+		level    ServiceLevel
+		at       time.Time
+		expected string
+	}{
+		{name: "before the cut-off", level: nextDay, at: time.Date(2026, 10, 15, 14, 0, 0, 0, london), expected: "2026-10-16"},
+		{name: "at the cut-off", level: nextDay, at: time.Date(2026, 10, 15, 15, 0, 0, 0, london), expected: "2026-10-19"},
+		{name: "cut-off in the level's timezone", level: nextDay, at: time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC), expected: "2026-10-19"},
+		{name: "over the weekend", level: nextDay, at: time.Date(2026, 10, 16, 14, 0, 0, 0, london), expected: "2026-10-19"},
+		{name: "ordered at the weekend", level: nextDay, at: time.Date(2026, 10, 17, 10, 0, 0, 0, london), expected: "2026-10-20"},
+		{name: "over bank holidays", level: nextDay, at: time.Date(2026, 12, 24, 10, 0, 0, 0, london), expected: "2026-12-29"},
+		{name: "calendar days", level: ServiceLevel{Service: ServiceNextDay, CutOff: "15:00", TransitDays: 1}, at: time.Date(2026, 10, 16, 14, 0, 0, 0, london), expected: "2026-10-17"},
+		{name: "no cut-off", level: ServiceLevel{Service: ServiceStandard, TransitDays: 3, WorkingDaysOnly: true}, at: time.Date(2026, 10, 16, 23, 0, 0, 0, london), expected: "2026-10-21"},
+		{name: "same day dispatched on the next working day", level: ServiceLevel{Service: ServiceStandard, WorkingDaysOnly: true}, at: time.Date(2026, 10, 18, 9, 0, 0, 0, london), expected: "2026-10-19"},
+		{name: "another timezone", level: ServiceLevel{Service: ServiceNextDay, CutOff: "15:00", TransitDays: 1, WorkingDaysOnly: true, Timezone: "America/New_York"}, at: time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC), expected: "2026-10-19"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.level.EstimatedDelivery(tc.at).Format(time.DateOnly); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+
+	if err := SetBankHolidays([]string{"25/12/2026"}); err == nil || len(BankHolidays()) != 2 {
+		t.Errorf("expected a malformed date to be rejected and the bank holidays kept, got %v %v", err, BankHolidays())
+	}
+}
+
+// TestPriceProductsWithServiceLevels tests listing each service level as a delivery option with its own price
+func TestPriceProductsWithServiceLevels(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Cleanup(func() {
+		SetPromotions(nil)
+		SetServiceLevels(nil)
+		SetZoneTables(nil)
+	})
+	SetPromotions([]Promotion{{ID: "free-delivery", Target: PromotionDelivery, Kind: PromotionPercentage, Value: 100}})
+	err := SetServiceLevels([]ServiceLevel{
+		{Provider: "DHL", Service: ServiceStandard, TransitDays: 3},
+		{Provider: "DHL", Service: ServiceTimed, Rate: 6, TransitDays: 1, DeliverBy: "10:30"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	SetZoneTables([]ZoneTable{{Provider: "DHL", Rates: map[Zone]float64{ZoneEU: 5}}})
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+
+	tests := []struct {
+		name        string
+		provider    string
+		destination *Destination
+		expected    []DeliveryOption
+	}{
+		{name: "promotions only apply to standard", provider: "DHL", expected: []DeliveryOption{
+			{Service: ServiceStandard, DeliveryPrice: "0.00", TotalPrice: "20.00"},
+			{Service: ServiceTimed, DeliveryPrice: "9.00", TotalPrice: "29.00", DeliverBy: "10:30"},
+		}},
+		{name: "only standard outside the mainland", provider: "DHL", destination: &Destination{Country: "FR"}, expected: []DeliveryOption{
+			{Service: ServiceStandard, DeliveryPrice: "0.00", TotalPrice: "20.00"},
+		}},
+		{name: "provider without service levels", provider: "UPS"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("UPS_DELIVERY_PRICE", "1")
+			priced, err := PriceProductsWithOptions([]Product{tv}, tc.provider, PricingOptions{Destination: tc.destination})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			options := priced[0].DeliveryOptions
+			if len(options) != len(tc.expected) {
+				t.Fatalf("expected %d options, got %+v", len(tc.expected), options)
+			}
+			for i, expected := range tc.expected {
+				expected.EstimatedDelivery = options[i].EstimatedDelivery
+				if options[i] != expected {
+					t.Errorf("expected %+v, got %+v", expected, options[i])
+				}
+				if _, err := time.Parse(time.DateOnly, options[i].EstimatedDelivery); err != nil {
+					t.Errorf("expected a date, got %q", options[i].EstimatedDelivery)
+				}
+			}
+		})
+	}
+
+	historical := HistoricalPricing{At: time.Now(), Products: []Product{tv}, Rates: map[string]float64{"DHL": 2}, DefaultProvider: "DHL"}
+	if priced, err := historical.Price("DHL"); err != nil || priced[0].DeliveryOptions != nil {
+		t.Errorf("expected historical prices to have no delivery options, got %+v %v", priced, err)
+	}
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+)
+
+// ShipmentItem is a product and how many of it are sent in a shipment.
+type ShipmentItem struct {
+	Product  Product
+	Quantity int
+}
+
+// ShipmentLine is an item in a priced shipment, with the price of all its units before promotions.
+type ShipmentLine struct {
+	Name         string
+	Quantity     int
+	ProductPrice string
+}
+
+/*
+PricedShipment is a shipment priced as one order. Order has the shipment's product, delivery and
+total prices, how delivery was charged and the promotions that applied; its Name is empty.
+*/
+type PricedShipment struct {
+	Lines []ShipmentLine
+	Order PricedProduct
+}
+
+/*
+PriceShipment prices several products sent together as one parcel. Each line's product price
+covers all of its units, and delivery is charged once on the combined weight, or on the combined
+volume if that weighs more with the provider's volumetric divisor. Promotions and the discount
+code, if one is given, apply to the order as a whole, so the code's minimum spend is checked
+against the shipment's product price. It is a quote, so the code is not redeemed; see CheckOut.
+
+It returns an *ErrInvalidShipment if there are no items or a quantity is less than one, and
+otherwise the same errors as PriceProductsWithOptions.
+*/
+func PriceShipment(items []ShipmentItem, provider string, options PricingOptions) (PricedShipment, error) {
+	return priceShipmentWithCode(items, provider, options, false)
+}
+
+/*
+CheckOut prices a shipment like PriceShipment and redeems its discount code, if one is given,
+once the shipment has been priced. It is the only way a code is redeemed. The redemption is
+counted under the same lock as the check, so concurrent checkouts can never redeem a code past
+its usage limit; a rejected code is not redeemed.
+The redemption is then saved with save, such as storage.SaveCurrentDiscountCodes. If that fails
+the redemption is rolled back and an error wrapping ErrRedemptionNotSaved is returned, so a
+customer is never given a discount that is not counted.
+*/
+func CheckOut(items []ShipmentItem, provider string, options PricingOptions, save func() error) (PricedShipment, error) {
+	shipment, err := priceShipmentWithCode(items, provider, options, true)
+	if err != nil || options.Code == "" {
+		return shipment, err
+	}
+	if err := save(); err != nil {
+		releaseRedemption(options.Code)
+		return PricedShipment{}, fmt.Errorf("%w: %w", ErrRedemptionNotSaved, err)
+	}
+	return shipment, nil
+}
+
+// priceShipmentWithCode validates and prices a shipment for PriceShipment and CheckOut, redeeming the code if redeem is true.
+func priceShipmentWithCode(items []ShipmentItem, provider string, options PricingOptions, redeem bool) (PricedShipment, error) {
+	if len(items) == 0 {
+		return PricedShipment{}, &ErrInvalidShipment{Reason: "a shipment needs at least one item"}
+	}
+	for _, item := range items {
+		if item.Quantity < 1 {
+			return PricedShipment{}, &ErrInvalidShipment{Reason: fmt.Sprintf("quantity of %q must be at least 1", item.Product.Name)}
+		}
+		if err := validateProduct(item.Product); err != nil {
+			logs.Logs(3, "failed to validate product: "+err.Error(), provider)
+			return PricedShipment{}, err
+		}
+	}
+
+	if options.Code == "" {
+		return priceShipment(items, provider, time.Now(), nil, options.Destination)
+	}
+	discountsMu.Lock()
+	defer discountsMu.Unlock()
+	discount, err := findDiscountCode(options.Code)
+	if err != nil {
+		return PricedShipment{}, err
+	}
+	shipment, err := priceShipment(items, provider, time.Now(), discount, options.Destination)
+	if err != nil {
+		return PricedShipment{}, err
+	}
+	if redeem {
+		discount.Redemptions++
+	}
+	return shipment, nil
+}
+
+/*
+priceShipment prices valid shipment items as PriceShipment does, with the promotions running at
+the given time and the code, if not nil. The caller must hold discountsMu to use a code.
+*/
+func priceShipment(items []ShipmentItem, provider string, at time.Time, code *DiscountCode, destination *Destination) (PricedShipment, error) {
+	pricing, err := newOrderPricing(provider, currentPricing, at, code, destination)
+	if err != nil {
+		return PricedShipment{}, err
+	}
+
+	var shipment PricedShipment
+	var productPrice, weight, volume float64
+	for _, item := range items {
+		quantity := float64(item.Quantity)
+		linePrice := item.Product.Price * quantity
+		shipment.Lines = append(shipment.Lines, ShipmentLine{
+			Name:         item.Product.Name,
+			Quantity:     item.Quantity,
+			ProductPrice: fmt.Sprintf("%.2f", roundToTwoDecimalPlaces(linePrice)),
+		})
+		productPrice += linePrice
+		weight += item.Product.Weight * quantity
+		if item.Product.Length > 0 && item.Product.Width > 0 && item.Product.Height > 0 {
+			volume += item.Product.Length * item.Product.Width * item.Product.Height * quantity
+		}
+	}
+
+	chargeable, basis := chargeableWeightOf(weight, volume, pricing.divisor)
+	order, applied, err := pricing.price(productPrice, chargeable, basis)
+	if err != nil {
+		logs.Logs(3, "failed to calculate delivery price for shipment: "+err.Error(), provider)
+		return PricedShipment{}, err
+	}
+	if code != nil && !applied {
+		return PricedShipment{}, code.belowMinimum()
+	}
+	shipment.Order = order
+	logs.Logs(1, "Shipment priced successfully with total price: "+order.TotalPrice, provider)
+	return shipment, nil
+}
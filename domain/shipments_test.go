@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestPriceShipment tests pricing several products as one order
+func TestPriceShipment(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "5000")
+	t.Cleanup(func() {
+		SetDiscountCodes(nil)
+		SetPromotions(nil)
+	})
+
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+	radio := Product{Name: "Radio", Weight: 0.5, Price: 7.5}
+	pillow := Product{Name: "Pillow", Weight: 1, Price: 10, Length: 50, Width: 40, Height: 30}
+
+	tests := []struct {
+		name       string
+		items      []ShipmentItem
+		promotions []Promotion
+		code       *DiscountCode
+		expected   PricedShipment
+		invalid    bool
+		rejected   DiscountCodeReason
+	}{
+		{
+			name:  "combined weight",
+			items: []ShipmentItem{{Product: tv, Quantity: 1}, {Product: radio, Quantity: 2}},
+			expected: PricedShipment{
+				Lines: []ShipmentLine{{Name: "TV", Quantity: 1, ProductPrice: "20.00"}, {Name: "Radio", Quantity: 2, ProductPrice: "15.00"}},
+				Order: PricedProduct{ProductPrice: "35.00", DeliveryPrice: "5.00", TotalPrice: "40.00", DeliveryService: "DHL", ChargeableWeight: 2.5, WeightBasis: WeightActual},
+			},
+		},
+		{
+			name:  "combined volume",
+			items: []ShipmentItem{{Product: pillow, Quantity: 2}, {Product: radio, Quantity: 1}},
+			expected: PricedShipment{
+				Lines: []ShipmentLine{{Name: "Pillow", Quantity: 2, ProductPrice: "20.00"}, {Name: "Radio", Quantity: 1, ProductPrice: "7.50"}},
+				Order: PricedProduct{ProductPrice: "27.50", DeliveryPrice: "48.00", TotalPrice: "75.50", DeliveryService: "DHL", ChargeableWeight: 24, WeightBasis: WeightVolumetric},
+			},
+		},
+		{
+			name:       "fixed promotion and code once per order",
+			items:      []ShipmentItem{{Product: tv, Quantity: 1}, {Product: radio, Quantity: 2}},
+			promotions: []Promotion{{ID: "ten-off", Target: PromotionProduct, Kind: PromotionFixed, Value: 10}},
+			code:       &DiscountCode{Code: "FIVER", Kind: DiscountFixed, Value: 5, MinSpend: 25},
+			expected: PricedShipment{
+				Lines: []ShipmentLine{{Name: "TV", Quantity: 1, ProductPrice: "20.00"}, {Name: "Radio", Quantity: 2, ProductPrice: "15.00"}},
+				Order: PricedProduct{ProductPrice: "20.00", DeliveryPrice: "5.00", TotalPrice: "25.00", DeliveryService: "DHL", ChargeableWeight: 2.5, WeightBasis: WeightActual,
+					OriginalProductPrice: "35.00", OriginalDeliveryPrice: "5.00", Promotions: []AppliedPromotion{{ID: "ten-off", Target: PromotionProduct, Discount: "10.00"}, {ID: "FIVER", Target: PromotionProduct, Discount: "5.00", DiscountCode: true}}},
+			},
+		},
+		{
+			name:     "minimum spend checked against the order",
+			items:    []ShipmentItem{{Product: radio, Quantity: 3}},
+			code:     &DiscountCode{Code: "BIG", Kind: DiscountFreeDelivery, MinSpend: 25},
+			rejected: DiscountCodeBelowMinimum,
+		},
+		{name: "no items", invalid: true},
+		{name: "zero quantity", items: []ShipmentItem{{Product: tv}}, invalid: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetPromotions(tc.promotions); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var options PricingOptions
+			if tc.code != nil {
+				if err := SetDiscountCodes([]DiscountCode{*tc.code}); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				options.Code = tc.code.Code
+			}
+
+			shipment, err := PriceShipment(tc.items, "DHL", options)
+			var invalid *ErrInvalidShipment
+			var rejected *ErrDiscountCodeRejected
+			switch {
+			case tc.invalid:
+				if !errors.As(err, &invalid) {
+					t.Errorf("expected ErrInvalidShipment, got %v", err)
+				}
+			case tc.rejected != "":
+				if !errors.As(err, &rejected) || rejected.Reason != tc.rejected {
+					t.Errorf("expected the code to be rejected as %s, got %v", tc.rejected, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case !reflect.DeepEqual(shipment, tc.expected):
+				t.Errorf("expected %+v, got %+v", tc.expected, shipment)
+			}
+		})
+	}
+}
+
+// TestCheckOut tests that only checking out redeems a discount code
+func TestCheckOut(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Cleanup(func() { SetDiscountCodes(nil) })
+	if err := SetDiscountCodes([]DiscountCode{{Code: "ONCE", Kind: DiscountFreeDelivery, UsageLimit: 1}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+	items := []ShipmentItem{{Product: tv, Quantity: 1}}
+	saves := 0
+	saved := func() error { saves++; return nil }
+
+	for range 3 {
+		if _, err := PriceShipment(items, "DHL", PricingOptions{Code: "once"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if _, err := PriceProductsWithOptions([]Product{tv}, "DHL", PricingOptions{Code: "once"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if redemptions := DiscountCodes()[0].Redemptions; redemptions != 0 {
+		t.Fatalf("expected quotes not to redeem the code, got %d redemptions", redemptions)
+	}
+
+	shipment, err := CheckOut(items, "DHL", PricingOptions{Code: "once"}, saved)
+	if err != nil || shipment.Order.TotalPrice != "20.00" {
+		t.Fatalf("expected free delivery, got %+v %v", shipment, err)
+	}
+	if redemptions := DiscountCodes()[0].Redemptions; redemptions != 1 || saves != 1 {
+		t.Errorf("expected checking out to redeem and save the code once, got %d redemptions and %d saves", redemptions, saves)
+	}
+
+	var rejected *ErrDiscountCodeRejected
+	if _, err := CheckOut(items, "DHL", PricingOptions{Code: "once"}, saved); !errors.As(err, &rejected) || rejected.Reason != DiscountCodeExhausted {
+		t.Errorf("expected the exhausted code to be rejected, got %v", err)
+	}
+	if _, err := CheckOut(items, "DHL", PricingOptions{}, saved); err != nil || saves != 1 {
+		t.Errorf("expected checking out without a code to work without saving, got %v and %d saves", err, saves)
+	}
+}
+
+// TestCheckOutSaveFails tests that a redemption which cannot be saved is rolled back and the checkout fails
+func TestCheckOutSaveFails(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Cleanup(func() { SetDiscountCodes(nil) })
+	if err := SetDiscountCodes([]DiscountCode{{Code: "ONCE", Kind: DiscountFreeDelivery, UsageLimit: 1}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	items := []ShipmentItem{{Product: Product{Name: "TV", Weight: 1.5, Price: 20}, Quantity: 1}}
+
+	_, err := CheckOut(items, "DHL", PricingOptions{Code: "once"}, func() error { return errors.New("disk full") })
+	if !errors.Is(err, ErrRedemptionNotSaved) {
+		t.Fatalf("expected ErrRedemptionNotSaved, got %v", err)
+	}
+	if redemptions := DiscountCodes()[0].Redemptions; redemptions != 0 {
+		t.Errorf("expected the redemption to be rolled back, got %d", redemptions)
+	}
+
+	// the code's only use is still available
+	if _, err := CheckOut(items, "DHL", PricingOptions{Code: "once"}, func() error { return nil }); err != nil {
+		t.Errorf("expected the code to be redeemable after the rollback, got %v", err)
+	}
+}
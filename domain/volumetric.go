@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WeightBasis is which weight a delivery price was charged on.
+type WeightBasis string
+
+const (
+	WeightActual     WeightBasis = "actual"     // the product's weight
+	WeightVolumetric WeightBasis = "volumetric" // its length × width × height over the carrier's divisor
+)
+
+// volumetricDivisorEnv returns the environment variable holding a provider's volumetric divisor, such as DHL_VOLUMETRIC_DIVISOR.
+func volumetricDivisorEnv(provider string) string {
+	return strings.TrimSuffix(providerPriceEnv[provider], "DELIVERY_PRICE") + "VOLUMETRIC_DIVISOR"
+}
+
+/*
+VolumetricDivisor returns the provider's volumetric divisor, the volume that counts as one unit of
+weight, or 0 if the provider only charges on actual weight. It returns an
+*ErrInvalidVolumetricDivisor if the divisor is set to something other than a positive number.
+*/
+func VolumetricDivisor(provider string) (float64, error) {
+	if _, ok := providerPriceEnv[provider]; !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	value := strings.TrimSpace(os.Getenv(volumetricDivisorEnv(provider)))
+	if value == "" {
+		return 0, nil
+	}
+	divisor, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ErrInvalidVolumetricDivisor{Provider: provider, Value: value, Err: err}
+	}
+	if divisor <= 0 || math.IsNaN(divisor) || math.IsInf(divisor, 0) {
+		return 0, &ErrInvalidVolumetricDivisor{Provider: provider, Value: value}
+	}
+	return divisor, nil
+}
+
+/*
+chargeableWeight returns the weight a carrier with the given volumetric divisor charges for a
+product: the greater of its actual weight and its volumetric weight, rounded to two decimal
+places. Products without all three dimensions, and carriers without a divisor, are charged on
+actual weight, as is a tie.
+*/
+func chargeableWeight(product Product, divisor float64) (float64, WeightBasis) {
+	if product.Length <= 0 || product.Width <= 0 || product.Height <= 0 {
+		return product.Weight, WeightActual
+	}
+	return chargeableWeightOf(product.Weight, product.Length*product.Width*product.Height, divisor)
+}
+
+/*
+chargeableWeightOf returns the weight a carrier with the given volumetric divisor charges for
+something weighing weight and taking up volume, as chargeableWeight does for a product.
+*/
+func chargeableWeightOf(weight, volume, divisor float64) (float64, WeightBasis) {
+	if divisor <= 0 || volume <= 0 {
+		return weight, WeightActual
+	}
+	volumetric := math.Round(volume/divisor*100) / 100
+	if volumetric > weight {
+		return volumetric, WeightVolumetric
+	}
+	return weight, WeightActual
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestVolumetricDivisor tests reading a provider's volumetric divisor from the environment
+func TestVolumetricDivisor(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		value    string
+		expected float64
+		invalid  bool
+	}{
+		{name: "unset charges on actual weight", provider: "DHL", value: "", expected: 0},
+		{name: "set", provider: "DHL", value: "5000", expected: 5000},
+		{name: "multi-word provider", provider: "ROYALMAIL", value: " 6000 ", expected: 6000},
+		{name: "not a number", provider: "DHL", value: "lots", invalid: true},
+		{name: "zero", provider: "DHL", value: "0", invalid: true},
+		{name: "negative", provider: "DHL", value: "-5000", invalid: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(volumetricDivisorEnv(tc.provider), tc.value)
+			divisor, err := VolumetricDivisor(tc.provider)
+
+			var invalid *ErrInvalidVolumetricDivisor
+			if errors.As(err, &invalid) != tc.invalid {
+				t.Fatalf("expected ErrInvalidVolumetricDivisor to be %t, got %v", tc.invalid, err)
+			}
+			if !tc.invalid && (err != nil || divisor != tc.expected) {
+				t.Errorf("expected %v, got %v %v", tc.expected, divisor, err)
+			}
+		})
+	}
+
+	if _, err := VolumetricDivisor("FEDEX"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+// TestVolumetricPricing tests charging delivery on the greater of actual and volumetric weight
+func TestVolumetricPricing(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "5000")
+
+	tests := []struct {
+		name          string
+		product       Product
+		deliveryPrice string
+		weight        float64
+		basis         WeightBasis
+	}{
+		{name: "bulky and light", product: Product{Name: "Pillow", Weight: 1, Price: 10, Length: 50, Width: 40, Height: 30}, deliveryPrice: "24.00", weight: 12, basis: WeightVolumetric},
+		{name: "small and heavy", product: Product{Name: "Kettlebell", Weight: 16, Price: 40, Length: 20, Width: 20, Height: 25}, deliveryPrice: "32.00", weight: 16, basis: WeightActual},
+		{name: "tie charges on actual weight", product: Product{Name: "Box", Weight: 2, Price: 5, Length: 20, Width: 20, Height: 25}, deliveryPrice: "4.00", weight: 2, basis: WeightActual},
+		{name: "no dimensions", product: Product{Name: "TV", Weight: 1.5, Price: 20}, deliveryPrice: "3.00", weight: 1.5, basis: WeightActual},
+		{name: "incomplete dimensions", product: Product{Name: "Rug", Weight: 3, Price: 80, Length: 200, Width: 150}, deliveryPrice: "6.00", weight: 3, basis: WeightActual},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			priced, err := PriceProducts([]Product{tc.product}, "DHL")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if priced[0].DeliveryPrice != tc.deliveryPrice || priced[0].ChargeableWeight != tc.weight || priced[0].WeightBasis != tc.basis {
+				t.Errorf("expected %s on %v %s, got %+v", tc.deliveryPrice, tc.weight, tc.basis, priced[0])
+			}
+		})
+	}
+
+	// providers without a divisor keep charging on actual weight
+	t.Setenv("DPD_DELIVERY_PRICE", "1")
+	priced, err := PriceProducts([]Product{tests[0].product}, "DPD")
+	if err != nil || priced[0].DeliveryPrice != "1.00" || priced[0].WeightBasis != WeightActual {
+		t.Errorf("expected DPD to charge on actual weight, got %+v %v", priced, err)
+	}
+
+	_, err = PriceProducts([]Product{{Name: "Pillow", Weight: 1, Price: 10, Length: -50}}, "DHL")
+	var invalidProduct *ErrInvalidProduct
+	if !errors.As(err, &invalidProduct) {
+		t.Errorf("expected negative dimensions to be rejected, got %v", err)
+	}
+
+	t.Setenv("DHL_VOLUMETRIC_DIVISOR", "none")
+	var invalidDivisor *ErrInvalidVolumetricDivisor
+	if _, err := PriceProducts([]Product{tests[0].product}, "DHL"); !errors.As(err, &invalidDivisor) || invalidDivisor.Provider != "DHL" {
+		t.Errorf("expected ErrInvalidVolumetricDivisor, got %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Zone is an area a carrier charges its own delivery rate for.
+type Zone string
+
+const (
+	ZoneMainland         Zone = "mainland"          // mainland Great Britain, charged at the carrier's flat rate
+	ZoneHighlandsIslands Zone = "highlands_islands" // the Scottish Highlands and the islands around Great Britain
+	ZoneNorthernIreland  Zone = "northern_ireland"
+	ZoneEU               Zone = "eu"
+	ZoneRestOfWorld      Zone = "rest_of_world"
+)
+
+// zones lists every zone, in the order they are documented.
+var zones = []Zone{ZoneMainland, ZoneHighlandsIslands, ZoneNorthernIreland, ZoneEU, ZoneRestOfWorld}
+
+// ukZones are the zones inside the United Kingdom, which are told apart by postcode.
+var ukZones = []Zone{ZoneMainland, ZoneHighlandsIslands, ZoneNorthernIreland}
+
+// countryUK is the ISO 3166-1 code of the United Kingdom; "UK" is accepted for it too.
+const countryUK = "GB"
+
+/*
+Destination is where products are delivered to. Country is an ISO 3166-1 alpha-2 code, such as
+GB or FR. Postcode is needed in the United Kingdom, where it decides the zone, and ignored
+elsewhere. A full postcode or just its outward code, such as IV2, can be given.
+*/
+type Destination struct {
+	Country  string `json:"country"`
+	Postcode string `json:"postcode,omitempty"`
+}
+
+/*
+ZoneTable is a carrier's delivery zones. Rates are its prices per unit weight in each zone it
+serves apart from the mainland, which is always charged at its flat rate; a zone without a rate
+is not served. Postcodes and Countries move postcode prefixes and countries into a zone for this
+carrier, and are checked before the default tables.
+
+A postcode prefix is a postcode area such as IV, a district such as PA20, or a range of districts
+in one area such as PA20-PA49.
+*/
+type ZoneTable struct {
+	Provider  string            `json:"provider"`
+	Rates     map[Zone]float64  `json:"rates,omitempty"`
+	Postcodes map[Zone][]string `json:"postcodes,omitempty"` // mainland, highlands_islands or northern_ireland
+	Countries map[Zone][]string `json:"countries,omitempty"` // eu or rest_of_world
+}
+
+/*
+defaultPostcodes are the postcode prefixes outside the mainland zone that carriers commonly
+surcharge: the Highlands, the Scottish islands, the Isles of Scilly, the Isle of Wight and
+Northern Ireland. Every other United Kingdom postcode is in the mainland zone.
+*/
+var defaultPostcodes = map[Zone][]string{
+	ZoneHighlandsIslands: {
+		"AB31-AB38", "AB41-AB56", "FK17-FK21", "HS", "IV", "KA27-KA28", "KW",
+		"PA20-PA49", "PA60-PA78", "PH17-PH26", "PH30-PH44", "PH49-PH50", "ZE",
+		"TR21-TR25", "PO30-PO41",
+	},
+	ZoneNorthernIreland: {"BT"},
+}
+
+// defaultCountries are the members of the European Union. Every other country outside the United Kingdom is in the rest of the world.
+var defaultCountries = map[Zone][]string{
+	ZoneEU: {
+		"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU",
+		"IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK",
+	},
+}
+
+var (
+	countryPattern        = regexp.MustCompile(`^[A-Z]{2}$`)
+	outwardCodePattern    = regexp.MustCompile(`^([A-Z]{1,2})([0-9][0-9A-Z]?)$`)
+	inwardCodePattern     = regexp.MustCompile(`^[0-9][A-Z]{2}$`)
+	postcodePrefixPattern = regexp.MustCompile(`^([A-Z]{1,2})(?:([0-9]{1,2})(?:-([A-Z]{1,2})?([0-9]{1,2}))?)?$`)
+)
+
+var (
+	zonesMu    sync.RWMutex
+	zoneTables map[string]ZoneTable // by provider
+)
+
+/*
+SetZoneTables replaces the carriers' zone tables. Provider names, countries and postcode prefixes
+are normalised to upper case. If any table is invalid the tables are left as they were and an
+*ErrInvalidZoneTable is returned.
+*/
+func SetZoneTables(tables []ZoneTable) error {
+	normalised, err := normaliseZoneTables(tables)
+	if err != nil {
+		return err
+	}
+
+	zonesMu.Lock()
+	defer zonesMu.Unlock()
+	zoneTables = normalised
+	return nil
+}
+
+// normaliseZoneTables validates and normalises tables as SetZoneTables does, keyed by provider.
+func normaliseZoneTables(tables []ZoneTable) (map[string]ZoneTable, error) {
+	normalised := make(map[string]ZoneTable, len(tables))
+	for _, table := range tables {
+		table = normaliseZoneTable(table)
+		if err := ValidateZoneTable(table); err != nil {
+			return nil, err
+		}
+		if _, ok := normalised[table.Provider]; ok {
+			return nil, &ErrInvalidZoneTable{Provider: table.Provider, Reason: "provider has more than one zone table"}
+		}
+		normalised[table.Provider] = table
+	}
+	return normalised, nil
+}
+
+// ZoneTables returns the carriers' zone tables, sorted by provider.
+func ZoneTables() []ZoneTable {
+	zonesMu.RLock()
+	defer zonesMu.RUnlock()
+	tables := slices.Collect(maps.Values(zoneTables))
+	slices.SortFunc(tables, func(a, b ZoneTable) int { return strings.Compare(a.Provider, b.Provider) })
+	return tables
+}
+
+// zoneTable returns provider's zone table, which is empty if it has none and so only serves the mainland.
+func zoneTable(provider string) ZoneTable {
+	zonesMu.RLock()
+	defer zonesMu.RUnlock()
+	return zoneTables[provider]
+}
+
+// ValidateZoneTable checks that a zone table can be used, returning an *ErrInvalidZoneTable if not.
+func ValidateZoneTable(t ZoneTable) error {
+	invalid := func(reason string) error { return &ErrInvalidZoneTable{Provider: t.Provider, Reason: reason} }
+	if !contains(allowedProviders, t.Provider) {
+		return invalid(fmt.Sprintf("unknown provider %q", t.Provider))
+	}
+	for zone, rate := range t.Rates {
+		switch {
+		case !slices.Contains(zones, zone):
+			return invalid(fmt.Sprintf("unknown zone %q", zone))
+		case zone == ZoneMainland:
+			return invalid("the mainland is charged at the provider's flat rate, so cannot be given a rate")
+		case rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0):
+			return invalid(fmt.Sprintf("rate for %s must not be negative", zone))
+		}
+	}
+	for zone, prefixes := range t.Postcodes {
+		if !slices.Contains(ukZones, zone) {
+			return invalid(fmt.Sprintf("postcodes can only be given for %s, %s or %s, not %q", ZoneMainland, ZoneHighlandsIslands, ZoneNorthernIreland, zone))
+		}
+		for _, prefix := range prefixes {
+			if _, err := parsePostcodePrefix(prefix); err != nil {
+				return invalid(err.Error())
+			}
+		}
+	}
+	for zone, countries := range t.Countries {
+		if zone != ZoneEU && zone != ZoneRestOfWorld {
+			return invalid(fmt.Sprintf("countries can only be given for %s or %s, not %q", ZoneEU, ZoneRestOfWorld, zone))
+		}
+		for _, country := range countries {
+			if !countryPattern.MatchString(country) || country == countryUK || country == "UK" {
+				return invalid(fmt.Sprintf("%q is not a country outside the United Kingdom", country))
+			}
+		}
+	}
+	return nil
+}
+
+// normaliseZoneTable returns a copy of t with its provider, prefixes and countries in upper case.
+func normaliseZoneTable(t ZoneTable) ZoneTable {
+	t.Provider = strings.ToUpper(strings.TrimSpace(t.Provider))
+	t.Rates = maps.Clone(t.Rates)
+	upper := func(lists map[Zone][]string) map[Zone][]string {
+		if lists == nil {
+			return nil
+		}
+		normalised := make(map[Zone][]string, len(lists))
+		for zone, list := range lists {
+			for _, item := range list {
+				normalised[zone] = append(normalised[zone], strings.ToUpper(strings.ReplaceAll(item, " ", "")))
+			}
+		}
+		return normalised
+	}
+	t.Postcodes = upper(t.Postcodes)
+	t.Countries = upper(t.Countries)
+	return t
+}
+
+/*
+NormaliseDestination returns destination with its country and postcode in upper case, "UK" as GB
+and the postcode's space in its usual place. It returns an *ErrInvalidDestination if the country
+is not a two letter code, or a United Kingdom postcode is missing or malformed.
+*/
+func NormaliseDestination(destination Destination) (Destination, error) {
+	country := strings.ToUpper(strings.TrimSpace(destination.Country))
+	if country == "UK" {
+		country = countryUK
+	}
+	postcode := strings.ToUpper(strings.Join(strings.Fields(destination.Postcode), ""))
+	invalid := func(reason string) error {
+		return &ErrInvalidDestination{Country: destination.Country, Postcode: destination.Postcode, Reason: reason}
+	}
+
+	if !countryPattern.MatchString(country) {
+		return Destination{}, invalid("country must be a two letter ISO 3166-1 code")
+	}
+	if country != countryUK {
+		return Destination{Country: country, Postcode: postcode}, nil
+	}
+	if postcode == "" {
+		return Destination{}, invalid("a postcode is needed for the United Kingdom")
+	}
+	if len(postcode) > 4 && inwardCodePattern.MatchString(postcode[len(postcode)-3:]) {
+		postcode = postcode[:len(postcode)-3] + " " + postcode[len(postcode)-3:]
+	}
+	if !outwardCodePattern.MatchString(outwardCode(postcode)) {
+		return Destination{}, invalid("postcode is not a United Kingdom postcode")
+	}
+	return Destination{Country: country, Postcode: postcode}, nil
+}
+
+// outwardCode returns the part of a normalised postcode before the space, such as IV2 for IV2 3AB.
+func outwardCode(postcode string) string {
+	outward, _, _ := strings.Cut(postcode, " ")
+	return outward
+}
+
+/*
+DeliveryZone returns the zone provider delivers destination in, looking it up in the provider's
+zone table and then the default tables. It returns an *ErrInvalidDestination if the destination
+is malformed, and an *ErrZoneNotServed if the provider does not deliver to that zone.
+*/
+func DeliveryZone(provider string, destination Destination) (Zone, error) {
+	if !contains(allowedProviders, provider) {
+		return "", fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	destination, err := NormaliseDestination(destination)
+	if err != nil {
+		return "", err
+	}
+	table := zoneTable(provider)
+	zone, err := table.zone(destination)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := table.rate(zone); !ok {
+		return "", &ErrZoneNotServed{Provider: provider, Zone: zone, Country: destination.Country}
+	}
+	return zone, nil
+}
+
+/*
+ProviderRateIn returns provider's delivery price per unit weight in a zone: its flat rate, from
+ProviderRate, in the mainland or if no zone is given, and its zone table's rate elsewhere. It
+returns an *ErrZoneNotServed if the provider has no rate for the zone.
+*/
+func ProviderRateIn(provider string, zone Zone) (float64, error) {
+	if zone == "" || zone == ZoneMainland {
+		return ProviderRate(provider)
+	}
+	if !contains(allowedProviders, provider) {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	rate, ok := zoneTable(provider).rate(zone)
+	if !ok {
+		return 0, &ErrZoneNotServed{Provider: provider, Zone: zone}
+	}
+	return rate, nil
+}
+
+// zone returns the zone destination is in with this table.
+func (t ZoneTable) zone(destination Destination) (Zone, error) {
+	destination, err := NormaliseDestination(destination)
+	if err != nil {
+		return "", err
+	}
+
+	if destination.Country != countryUK {
+		for _, countries := range []map[Zone][]string{t.Countries, defaultCountries} {
+			for _, zone := range []Zone{ZoneEU, ZoneRestOfWorld} {
+				if slices.Contains(countries[zone], destination.Country) {
+					return zone, nil
+				}
+			}
+		}
+		return ZoneRestOfWorld, nil
+	}
+
+	outward := outwardCode(destination.Postcode)
+	for _, postcodes := range []map[Zone][]string{t.Postcodes, defaultPostcodes} {
+		for _, zone := range ukZones {
+			for _, prefix := range postcodes[zone] {
+				if matchesPostcodePrefix(outward, prefix) {
+					return zone, nil
+				}
+			}
+		}
+	}
+	return ZoneMainland, nil
+}
+
+// rate returns the table's rate in zone, and false if the zone is not served. The mainland is always served, at the flat rate, so its rate is 0.
+func (t ZoneTable) rate(zone Zone) (float64, bool) {
+	if zone == ZoneMainland {
+		return 0, true
+	}
+	rate, ok := t.Rates[zone]
+	return rate, ok
+}
+
+// postcodePrefix is a parsed postcode prefix: an area, and the districts in it if it is a district or range.
+type postcodePrefix struct {
+	area     string
+	district bool // false for a whole area
+	from, to int  // the first and last district numbers
+}
+
+// parsePostcodePrefix parses a normalised postcode prefix, such as IV, PA20 or PA20-PA49.
+func parsePostcodePrefix(prefix string) (postcodePrefix, error) {
+	match := postcodePrefixPattern.FindStringSubmatch(prefix)
+	if match == nil {
+		return postcodePrefix{}, fmt.Errorf("%q is not a postcode area, district or range of districts", prefix)
+	}
+	parsed := postcodePrefix{area: match[1]}
+	if match[2] == "" {
+		return parsed, nil
+	}
+	parsed.district = true
+	parsed.from, _ = strconv.Atoi(match[2])
+	parsed.to = parsed.from
+	if match[4] != "" {
+		if match[3] != "" && match[3] != parsed.area {
+			return postcodePrefix{}, fmt.Errorf("%q spans more than one postcode area", prefix)
+		}
+		parsed.to, _ = strconv.Atoi(match[4])
+		if parsed.to < parsed.from {
+			return postcodePrefix{}, fmt.Errorf("%q ends before it starts", prefix)
+		}
+	}
+	return parsed, nil
+}
+
+// matchesPostcodePrefix reports whether an outward code, such as PA34 or EC1A, is covered by a postcode prefix.
+func matchesPostcodePrefix(outward string, prefix string) bool {
+	parsed, err := parsePostcodePrefix(prefix)
+	match := outwardCodePattern.FindStringSubmatch(outward)
+	if err != nil || match == nil || match[1] != parsed.area {
+		return false
+	}
+	if !parsed.district {
+		return true
+	}
+	district, err := strconv.Atoi(strings.TrimRight(match[2], "ABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+	return err == nil && district >= parsed.from && district <= parsed.to
+}
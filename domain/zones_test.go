@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSetZoneTables tests validating and normalising the carriers' zone tables
+func TestSetZoneTables(t *testing.T) {
+	t.Cleanup(func() { SetZoneTables(nil) })
+
+	tests := []struct {
+		name   string
+		tables []ZoneTable
+		valid  bool
+	}{
+		{name: "rates", tables: []ZoneTable{{Provider: "dhl", Rates: map[Zone]float64{ZoneHighlandsIslands: 4, ZoneEU: 6}}}, valid: true},
+		{name: "own postcodes and countries", tables: []ZoneTable{{Provider: "DPD", Postcodes: map[Zone][]string{ZoneMainland: {"pa20-49"}, ZoneHighlandsIslands: {"IM", "ab10"}}, Countries: map[Zone][]string{ZoneEU: {"ch"}}}}, valid: true},
+		{name: "unknown provider", tables: []ZoneTable{{Provider: "FEDEX"}}},
+		{name: "unknown zone", tables: []ZoneTable{{Provider: "DHL", Rates: map[Zone]float64{"moon": 1}}}},
+		{name: "mainland rate", tables: []ZoneTable{{Provider: "DHL", Rates: map[Zone]float64{ZoneMainland: 1}}}},
+		{name: "negative rate", tables: []ZoneTable{{Provider: "DHL", Rates: map[Zone]float64{ZoneEU: -1}}}},
+		{name: "postcodes outside the United Kingdom", tables: []ZoneTable{{Provider: "DHL", Postcodes: map[Zone][]string{ZoneEU: {"BT"}}}}},
+		{name: "malformed postcode prefix", tables: []ZoneTable{{Provider: "DHL", Postcodes: map[Zone][]string{ZoneHighlandsIslands: {"IV2 3AB"}}}}},
+		{name: "range across areas", tables: []ZoneTable{{Provider: "DHL", Postcodes: map[Zone][]string{ZoneHighlandsIslands: {"PA20-PH49"}}}}},
+		{name: "backwards range", tables: []ZoneTable{{Provider: "DHL", Postcodes: map[Zone][]string{ZoneHighlandsIslands: {"PA49-PA20"}}}}},
+		{name: "countries inside the United Kingdom", tables: []ZoneTable{{Provider: "DHL", Countries: map[Zone][]string{ZoneEU: {"UK"}}}}},
+		{name: "duplicate provider", tables: []ZoneTable{{Provider: "DHL"}, {Provider: "dhl"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			SetZoneTables(nil)
+			err := SetZoneTables(tc.tables)
+			var invalid *ErrInvalidZoneTable
+			if tc.valid != (err == nil) || (err != nil && !errors.As(err, &invalid)) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.valid && len(ZoneTables()) != 0 {
+				t.Error("expected invalid tables not to be stored")
+			}
+		})
+	}
+}
+
+// TestDeliveryZone tests finding the zone a destination is in with the default and a carrier's own tables
+func TestDeliveryZone(t *testing.T) {
+	t.Cleanup(func() { SetZoneTables(nil) })
+	all := map[Zone]float64{ZoneHighlandsIslands: 4, ZoneNorthernIreland: 3, ZoneEU: 6, ZoneRestOfWorld: 10}
+	err := SetZoneTables([]ZoneTable{
+		{Provider: "DHL", Rates: all},
+		{Provider: "DPD", Rates: all, Postcodes: map[Zone][]string{ZoneMainland: {"PO30-PO41"}, ZoneHighlandsIslands: {"G83"}}, Countries: map[Zone][]string{ZoneEU: {"CH"}}},
+		{Provider: "YODEL", Rates: map[Zone]float64{ZoneNorthernIreland: 3}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		provider    string
+		destination Destination
+		expected    Zone
+		invalid     bool
+		notServed   bool
+	}{
+		{name: "mainland", provider: "DHL", destination: Destination{Country: "GB", Postcode: "SW1A 1AA"}, expected: ZoneMainland},
+		{name: "UK and a postcode without a space", provider: "DHL", destination: Destination{Country: "uk", Postcode: "iv23ab"}, expected: ZoneHighlandsIslands},
+		{name: "outward code only", provider: "DHL", destination: Destination{Country: "GB", Postcode: "ZE1"}, expected: ZoneHighlandsIslands},
+		{name: "district in a range", provider: "DHL", destination: Destination{Country: "GB", Postcode: "PA34 4AA"}, expected: ZoneHighlandsIslands},
+		{name: "district outside a range", provider: "DHL", destination: Destination{Country: "GB", Postcode: "PA1 1AA"}, expected: ZoneMainland},
+		{name: "district with a letter", provider: "DHL", destination: Destination{Country: "GB", Postcode: "EC1A 1BB"}, expected: ZoneMainland},
+		{name: "Northern Ireland", provider: "DHL", destination: Destination{Country: "GB", Postcode: "BT1 1AA"}, expected: ZoneNorthernIreland},
+		{name: "EU", provider: "DHL", destination: Destination{Country: "fr"}, expected: ZoneEU},
+		{name: "rest of the world", provider: "DHL", destination: Destination{Country: "US", Postcode: "10001"}, expected: ZoneRestOfWorld},
+		{name: "carrier moves a default prefix to the mainland", provider: "DPD", destination: Destination{Country: "GB", Postcode: "PO30 1AA"}, expected: ZoneMainland},
+		{name: "carrier adds a prefix", provider: "DPD", destination: Destination{Country: "GB", Postcode: "G83 0AA"}, expected: ZoneHighlandsIslands},
+		{name: "carrier adds a country", provider: "DPD", destination: Destination{Country: "CH"}, expected: ZoneEU},
+		{name: "carrier's prefixes do not affect others", provider: "DHL", destination: Destination{Country: "GB", Postcode: "G83 0AA"}, expected: ZoneMainland},
+		{name: "carrier without a table serves the mainland", provider: "UPS", destination: Destination{Country: "GB", Postcode: "M1 1AA"}, expected: ZoneMainland},
+		{name: "carrier without a table", provider: "UPS", destination: Destination{Country: "FR"}, notServed: true},
+		{name: "zone without a rate", provider: "YODEL", destination: Destination{Country: "GB", Postcode: "HS1 2AA"}, notServed: true},
+		{name: "no postcode in the UK", provider: "DHL", destination: Destination{Country: "GB"}, invalid: true},
+		{name: "malformed postcode", provider: "DHL", destination: Destination{Country: "GB", Postcode: "12345"}, invalid: true},
+		{name: "malformed country", provider: "DHL", destination: Destination{Country: "France"}, invalid: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			zone, err := DeliveryZone(tc.provider, tc.destination)
+			var invalid *ErrInvalidDestination
+			var notServed *ErrZoneNotServed
+			if errors.As(err, &invalid) != tc.invalid || errors.As(err, &notServed) != tc.notServed {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.invalid && !tc.notServed && zone != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, zone)
+			}
+		})
+	}
+}
+
+// TestPriceProductsToDestination tests charging delivery at the rate for the destination's zone
+func TestPriceProductsToDestination(t *testing.T) {
+	t.Setenv("DHL_DELIVERY_PRICE", "2")
+	t.Cleanup(func() { SetZoneTables(nil) })
+	if err := SetZoneTables([]ZoneTable{{Provider: "DHL", Rates: map[Zone]float64{ZoneHighlandsIslands: 5}}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tv := Product{Name: "TV", Weight: 1.5, Price: 20}
+
+	tests := []struct {
+		name          string
+		destination   *Destination
+		deliveryPrice string
+		zone          Zone
+		notServed     bool
+	}{
+		{name: "no destination", deliveryPrice: "3.00"},
+		{name: "mainland at the flat rate", destination: &Destination{Country: "GB", Postcode: "M1 1AA"}, deliveryPrice: "3.00", zone: ZoneMainland},
+		{name: "zone rate", destination: &Destination{Country: "GB", Postcode: "KW1 4AA"}, deliveryPrice: "7.50", zone: ZoneHighlandsIslands},
+		{name: "not served", destination: &Destination{Country: "DE"}, notServed: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			priced, err := PriceProductsWithOptions([]Product{tv}, "DHL", PricingOptions{Destination: tc.destination})
+			var notServed *ErrZoneNotServed
+			if errors.As(err, &notServed) != tc.notServed {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tc.notServed {
+				if notServed.Provider != "DHL" || notServed.Zone != ZoneEU || notServed.Country != "DE" {
+					t.Errorf("unexpected error fields %+v", notServed)
+				}
+				return
+			}
+			if priced[0].DeliveryPrice != tc.deliveryPrice || priced[0].Zone != tc.zone {
+				t.Errorf("expected %s in zone %q, got %+v", tc.deliveryPrice, tc.zone, priced[0])
+			}
+		})
+	}
+
+	if PricingVersion([]Product{tv}, "DHL", 2, 0, nil, "") == PricingVersion([]Product{tv}, "DHL", 2, 0, nil, ZoneMainland) {
+		t.Error("expected the zone to change the version")
+	}
+}
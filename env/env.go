@@ -2,17 +2,37 @@ package env
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
 )
 
+// LoadEnv sets the environment variables in the named file, which holds KEY=VALUE lines.
 func LoadEnv(filename string) error {
-	file, err := os.Open(filename)
+	vars, err := ReadEnv(filename)
 	if err != nil {
 		return err
 	}
+	Apply(vars)
+	return nil
+}
+
+/*
+ReadEnv reads the KEY=VALUE lines of the named file without setting them. Empty lines, comments
+starting with # and lines without an = are ignored.
+*/
+func ReadEnv(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
+	vars := make(map[string]string)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -25,10 +45,110 @@ func LoadEnv(filename string) error {
 		if len(parts) != 2 {
 			continue
 		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+		vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// Apply sets the environment variables and returns a function that puts back their previous values.
+func Apply(vars map[string]string) (restore func()) {
+	type previous struct {
+		value string
+		set   bool
+	}
+	before := make(map[string]previous, len(vars))
+	for key, value := range vars {
+		old, set := os.LookupEnv(key)
+		before[key] = previous{value: old, set: set}
 		// set environment variable
 		os.Setenv(key, value)
 	}
-	return scanner.Err()
+	return func() {
+		for key, old := range before {
+			if old.set {
+				os.Setenv(key, old.value)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+}
+
+/*
+Duration reads a time.Duration such as "5s" or "250ms" from the named environment variable.
+It returns fallback if the variable is not set or cannot be parsed, logging a warning in the latter case.
+*/
+func Duration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logs.Logs(2, fmt.Sprintf("invalid duration %q for %s, using default %s", value, key, fallback), "")
+		return fallback
+	}
+	return d
+}
+
+/*
+Int reads an integer from the named environment variable.
+It returns fallback if the variable is not set or cannot be parsed, logging a warning in the latter case.
+*/
+func Int(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logs.Logs(2, fmt.Sprintf("invalid integer %q for %s, using default %d", value, key, fallback), "")
+		return fallback
+	}
+	return n
+}
+
+/*
+Float reads a floating point number from the named environment variable.
+It returns fallback if the variable is not set or cannot be parsed, logging a warning in the latter case.
+*/
+func Float(key string, fallback float64) float64 {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logs.Logs(2, fmt.Sprintf("invalid number %q for %s, using default %g", value, key, fallback), "")
+		return fallback
+	}
+	return f
+}
+
+/*
+Time reads a point in time from the named environment variable, either an RFC 3339 timestamp
+or a YYYY-MM-DD date taken as midnight UTC. It returns fallback if the variable is not set or
+cannot be parsed, logging a warning in the latter case.
+*/
+func Time(key string, fallback time.Time) time.Time {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		logs.Logs(2, fmt.Sprintf("invalid time %q for %s, using default", value, key), "")
+		return fallback
+	}
+	return t
 }
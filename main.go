@@ -1,23 +1,53 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/PythonAkoto/base_techtest/adapters/input/access"
+	"github.com/PythonAkoto/base_techtest/adapters/input/grpcapi"
 	"github.com/PythonAkoto/base_techtest/adapters/input/handlers"
 	"github.com/PythonAkoto/base_techtest/adapters/output/logs"
+	"github.com/PythonAkoto/base_techtest/env"
 )
 
 func main() {
 	// Set log flags to 0 to disable timestamps and other formatting
 	log.SetFlags(0)
 
-	// go env.LoadEnv("env/.env") // Load environment variables in a separate goroutine
 	go logs.ProcessLogs() // Start processing logs in a separate goroutine
 
-	// go env.LoadEnv(".env")
+	// cancelled on Ctrl+C or when Docker stops the container, so the servers shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// load the environment once, before either server reads its configuration
+	if err := env.LoadEnv("env/.env"); err != nil {
+		logs.Logs(3, "failed to load environment variables: "+err.Error(), "")
+	}
+
+	// promotions, discount codes, zones, service levels and bank holidays are shared by both APIs
+	handlers.LoadDomainConfig()
+
+	// both APIs share the HTTP server's API keys and rate limiter
+	server := handlers.NewServer()
+	grpcAccess := grpcapi.Access{Keys: server.Keys(), Anonymous: access.AnonymousRole(), Limiter: server.Limiter()}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		handlers.StartHTTPServer(ctx, server) // Start the HTTP server
+	}()
+
 	go func() {
-		handlers.StartHTTPServer() // Start the HTTP server
+		defer wg.Done()
+		grpcapi.StartGRPCServer(ctx, grpcAccess) // Start the gRPC server on its own port
 	}()
 
-	select {} // Block forever to keep the main goroutine alive
+	wg.Wait() // Wait for both servers to stop
 }